@@ -4,6 +4,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"time"
 )
 
 type Config struct {
@@ -12,6 +13,21 @@ type Config struct {
 	JWTSecret   string
 	MapboxToken string
 
+	// Mapbox Directions API used by the mapbox routing engine
+	MapboxBaseURL    string
+	MapboxTimeout    time.Duration
+	MapboxMaxRetries int
+
+	// Routing: engine name (osm, mapbox, straight) and the road graph built by import-osm
+	RoutingEngine    string
+	RoutingGraphPath string
+
+	// Directory with SRTM .hgt(.zip) or GeoTIFF elevation tiles
+	ElevationDataPath string
+
+	// Directory with map tiles in the {z}/{x}/{y}.png layout, drawn under thumbnails
+	MapTilesPath string
+
 	// Email Configuration
 	SMTPHost     string
 	SMTPPort     int
@@ -23,12 +39,25 @@ type Config struct {
 
 func Load() *Config {
     smtpPort, _ := strconv.Atoi(getEnv("SMTP_PORT", "1025"))
+    mapboxTimeout, _ := strconv.Atoi(getEnv("MAPBOX_TIMEOUT_SECONDS", "10"))
+    mapboxMaxRetries, _ := strconv.Atoi(getEnv("MAPBOX_MAX_RETRIES", "2"))
     return &Config{
         Port:        getEnv("PORT", "8080"),
         DatabaseURL: getEnv("DATABASE_URL", "user:password@tcp(localhost:3306)/motocosmos?charset=utf8mb4&parseTime=True&loc=Local"),
         JWTSecret:   getEnv("JWT_SECRET", "your-secret-key"),
         MapboxToken: getEnv("MAPBOX_TOKEN", "your-mapbox-token"),
 
+        MapboxBaseURL:    getEnv("MAPBOX_BASE_URL", "https://api.mapbox.com"),
+        MapboxTimeout:    time.Duration(mapboxTimeout) * time.Second,
+        MapboxMaxRetries: mapboxMaxRetries,
+
+        RoutingEngine:    getEnv("ROUTING_ENGINE", "osm"),
+        RoutingGraphPath: getEnv("ROUTING_GRAPH_PATH", "./data/road-graph.gob"),
+
+        ElevationDataPath: getEnv("ELEVATION_DATA_PATH", "./data/dem"),
+
+        MapTilesPath: getEnv("MAP_TILES_PATH", "./data/tiles"),
+
         // Email settings for Mailhog in dev environment
         SMTPHost:     getEnv("SMTP_HOST", "mailhog"),
         SMTPPort:     smtpPort,
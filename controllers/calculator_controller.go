@@ -2,71 +2,73 @@
 package controllers
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"motocosmos-api/models"
+	"motocosmos-api/services"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type CalculatorController struct {
-	db *gorm.DB
+	db               *gorm.DB
+	tripCostService  *services.TripCostService
+	fuelPriceService *services.FuelPriceService
+	currencyService  *services.CurrencyService
 }
 
 func NewCalculatorController(db *gorm.DB) *CalculatorController {
-	return &CalculatorController{db: db}
+	return &CalculatorController{
+		db:               db,
+		tripCostService:  services.NewTripCostService(db),
+		fuelPriceService: services.NewFuelPriceService(db),
+		currencyService:  services.NewCurrencyService(db),
+	}
 }
 
+// CalculateTripRequest accepts either manual values or a saved route and a motorcycle.
+// Manual values always take precedence over the derived ones.
 type CalculateTripRequest struct {
-	RoadLength             float64 `json:"road_length" binding:"required,gt=0"`
-	AverageFuelPrice       float64 `json:"average_fuel_price" binding:"required,gt=0"`
-	AverageFuelConsumption float64 `json:"average_fuel_consumption" binding:"required,gt=0"`
+	RouteID                string  `json:"route_id"`
+	SharedRouteID          string  `json:"shared_route_id"`
+	MotorcycleID           string  `json:"motorcycle_id"`
+	FuelGrade              string  `json:"fuel_grade"` // 95, 98, diesel
+	Currency               string  `json:"currency"`   // Currency of the money values; defaults to the user's preferred currency
+	RoadLength             float64 `json:"road_length" binding:"gte=0"`
+	AverageFuelPrice       float64 `json:"average_fuel_price" binding:"gte=0"`
+	AverageFuelConsumption float64 `json:"average_fuel_consumption" binding:"gte=0"`
 	OtherCosts             float64 `json:"other_costs"`
+
+	// Vignettes and tolls of the crossed countries are added by default
+	IncludeTolls *bool  `json:"include_tolls"`
+	VehicleClass string `json:"vehicle_class"` // motorcycle (default), car
+	TripDays     int    `json:"trip_days" binding:"gte=0,lte=365"`
+	TripDate     string `json:"trip_date"` // YYYY-MM-DD, defaults to today
 }
 
 type SaveCalculationRequest struct {
-	RouteName              string  `json:"route_name" binding:"required"`
-	RoadLength             float64 `json:"road_length" binding:"required,gt=0"`
-	AverageFuelPrice       float64 `json:"average_fuel_price" binding:"required,gt=0"`
-	AverageFuelConsumption float64 `json:"average_fuel_consumption" binding:"required,gt=0"`
-	OtherCosts             float64 `json:"other_costs"`
+	RouteName string `json:"route_name"`
+	CalculateTripRequest
 }
 
 func (cc *CalculatorController) CalculateTrip(c *gin.Context) {
+	userID := c.GetString("user_id")
+
 	var req CalculateTripRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Validate input ranges
-	if req.RoadLength > 10000 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Road length cannot exceed 10,000 km"})
-		return
-	}
-	if req.AverageFuelPrice > 10 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Fuel price cannot exceed 10 EUR/L"})
-		return
-	}
-	if req.AverageFuelConsumption > 50 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Fuel consumption cannot exceed 50 L/100km"})
+	estimate, ok := cc.estimateTrip(c, userID, req)
+	if !ok {
 		return
 	}
 
-	// Calculate fuel needed and costs
-	fuelNeeded := (req.RoadLength * req.AverageFuelConsumption) / 100
-	fuelCost := fuelNeeded * req.AverageFuelPrice
-	totalCost := fuelCost + req.OtherCosts
-
-	result := gin.H{
-		"fuel_needed_liters": fuelNeeded,
-		"fuel_cost":          fuelCost,
-		"other_costs":        req.OtherCosts,
-		"total_cost":         totalCost,
-		"cost_per_km":        totalCost / req.RoadLength,
-	}
-
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, estimate)
 }
 
 func (cc *CalculatorController) SaveCalculation(c *gin.Context) {
@@ -78,20 +80,37 @@ func (cc *CalculatorController) SaveCalculation(c *gin.Context) {
 		return
 	}
 
-	// Calculate total cost
-	fuelNeeded := (req.RoadLength * req.AverageFuelConsumption) / 100
-	fuelCost := fuelNeeded * req.AverageFuelPrice
-	totalCost := fuelCost + req.OtherCosts
+	estimate, ok := cc.estimateTrip(c, userID, req.CalculateTripRequest)
+	if !ok {
+		return
+	}
+
+	routeName := req.RouteName
+	if routeName == "" {
+		routeName = estimate.RouteName
+	}
+	if routeName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Route name is required"})
+		return
+	}
 
 	calculation := models.TripCalculation{
 		ID:                     uuid.New().String(),
 		UserID:                 userID,
-		RouteName:              req.RouteName,
-		RoadLength:             req.RoadLength,
-		AverageFuelPrice:       req.AverageFuelPrice,
-		AverageFuelConsumption: req.AverageFuelConsumption,
-		OtherCosts:             req.OtherCosts,
-		TotalCost:              totalCost,
+		RouteName:              routeName,
+		RoadLength:             estimate.RoadLength,
+		AverageFuelPrice:       estimate.AverageFuelPrice,
+		AverageFuelConsumption: estimate.AverageFuelConsumption,
+		OtherCosts:             estimate.OtherCosts,
+		TotalCost:              estimate.TotalCost,
+		RouteID:                estimate.RouteID,
+		SharedRouteID:          estimate.SharedRouteID,
+		MotorcycleID:           estimate.MotorcycleID,
+		ConsumptionSource:      estimate.ConsumptionSource,
+		Currency:               estimate.Currency,
+		TollCosts:              estimate.TollCosts,
+		TollItems:              estimate.TollItems,
+		Countries:              models.StringSlice(estimate.CountryCodes()),
 	}
 
 	if err := cc.db.Create(&calculation).Error; err != nil {
@@ -111,6 +130,18 @@ func (cc *CalculatorController) GetHistory(c *gin.Context) {
 		return
 	}
 
+	// Show totals in the user's display currency as well
+	displayCurrency := cc.currencyService.PreferredCurrency(userID)
+	for i := range calculations {
+		currency := calculations[i].Currency
+		if currency == "" {
+			currency = models.DefaultCurrency
+		}
+		if amount, err := cc.currencyService.Convert(calculations[i].TotalCost, currency, displayCurrency); err == nil {
+			calculations[i].DisplayTotalCost = &models.Money{Amount: amount, Currency: displayCurrency}
+		}
+	}
+
 	c.JSON(http.StatusOK, calculations)
 }
 
@@ -125,20 +156,30 @@ func (cc *CalculatorController) ClearHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Calculation history cleared successfully"})
 }
 
+// GetFuelPrices returns the current national price per country for a grade
+// (?grade=95|98|diesel) in the requested or the user's preferred currency
 func (cc *CalculatorController) GetFuelPrices(c *gin.Context) {
-	fuelPrices := map[string]float64{
-		"Germany":        1.65,
-		"France":         1.72,
-		"Italy":          1.68,
-		"Spain":          1.45,
-		"Netherlands":    1.78,
-		"Austria":        1.52,
-		"Switzerland":    1.85,
-		"Czech Republic": 1.38,
-		"Hungary":        1.45,
-		"Poland":         1.35,
-		"Slovakia":       1.42,
-		"Slovenia":       1.48,
+	userID := c.GetString("user_id")
+
+	grade := c.DefaultQuery("grade", models.FuelGrade95)
+	if !models.IsValidFuelGrade(grade) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fuel grade"})
+		return
+	}
+
+	currency := strings.ToUpper(c.DefaultQuery("currency", cc.currencyService.PreferredCurrency(userID)))
+	rate, err := cc.currencyService.Rate(models.DefaultCurrency, currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+		return
+	}
+
+	prices := cc.fuelPriceService.NationalPrices(grade)
+	fuelPrices := make(map[string]float64, len(prices))
+	for code, price := range prices {
+		if country, ok := services.CountryByCode(code); ok {
+			fuelPrices[country.Name] = price * rate
+		}
 	}
 
 	c.JSON(http.StatusOK, fuelPrices)
@@ -161,3 +202,84 @@ func (cc *CalculatorController) GetFuelConsumption(c *gin.Context) {
 
 	c.JSON(http.StatusOK, fuelConsumption)
 }
+
+// estimateTrip resolves the trip inputs and validates the result, writing the error response on failure
+func (cc *CalculatorController) estimateTrip(c *gin.Context, userID string, req CalculateTripRequest) (*services.TripEstimate, bool) {
+	if req.RouteID != "" && req.SharedRouteID != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either route_id or shared_route_id, not both"})
+		return nil, false
+	}
+	if req.FuelGrade != "" && !models.IsValidFuelGrade(req.FuelGrade) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fuel grade"})
+		return nil, false
+	}
+
+	vehicleClass := strings.ToLower(req.VehicleClass)
+	if vehicleClass != "" && vehicleClass != models.VehicleClassMotorcycle && vehicleClass != models.VehicleClassCar {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle class"})
+		return nil, false
+	}
+
+	var tripDate time.Time
+	if req.TripDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.TripDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip date, expected YYYY-MM-DD"})
+			return nil, false
+		}
+		tripDate = parsed
+	}
+
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		currency = cc.currencyService.PreferredCurrency(userID)
+	}
+	if !models.IsSupportedCurrency(currency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+		return nil, false
+	}
+
+	estimate, err := cc.tripCostService.Estimate(userID, services.TripInput{
+		RouteID:                req.RouteID,
+		SharedRouteID:          req.SharedRouteID,
+		MotorcycleID:           req.MotorcycleID,
+		FuelGrade:              req.FuelGrade,
+		Currency:               currency,
+		RoadLength:             req.RoadLength,
+		AverageFuelPrice:       req.AverageFuelPrice,
+		AverageFuelConsumption: req.AverageFuelConsumption,
+		OtherCosts:             req.OtherCosts,
+		IncludeTolls:           req.IncludeTolls == nil || *req.IncludeTolls,
+		VehicleClass:           vehicleClass,
+		TripDays:               req.TripDays,
+		TripDate:               tripDate,
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrRouteNotFound) || errors.Is(err, services.ErrMotorcycleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate trip"})
+		}
+		return nil, false
+	}
+
+	// Validate input ranges
+	if estimate.RoadLength <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Road length is required when no route is given"})
+		return nil, false
+	}
+	if estimate.RoadLength > 10000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Road length cannot exceed 10,000 km"})
+		return nil, false
+	}
+	if estimate.AverageFuelPrice/estimate.ExchangeRate > 10 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fuel price cannot exceed 10 EUR/L or its equivalent"})
+		return nil, false
+	}
+	if estimate.AverageFuelConsumption > 50 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fuel consumption cannot exceed 50 L/100km"})
+		return nil, false
+	}
+
+	return estimate, true
+}
@@ -0,0 +1,160 @@
+// File: /controllers/currency_controller.go
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"math"
+	"motocosmos-api/models"
+	"motocosmos-api/services"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type CurrencyController struct {
+	db              *gorm.DB
+	currencyService *services.CurrencyService
+}
+
+func NewCurrencyController(db *gorm.DB) *CurrencyController {
+	return &CurrencyController{
+		db:              db,
+		currencyService: services.NewCurrencyService(db),
+	}
+}
+
+// GetCurrencies returns the supported currencies, the user's preferred one and
+// the current rates against ?base (defaults to the preferred currency)
+func (cc *CurrencyController) GetCurrencies(c *gin.Context) {
+	userID := c.GetString("user_id")
+	preferred := cc.currencyService.PreferredCurrency(userID)
+
+	base := strings.ToUpper(c.DefaultQuery("base", preferred))
+	rates, err := cc.currencyService.Rates(base)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"currencies":         models.SupportedCurrencies,
+		"preferred_currency": preferred,
+		"base":               base,
+		"rates":              rates,
+	})
+}
+
+// Convert converts an amount (?amount=&from=&to=, to defaults to the preferred currency)
+func (cc *CurrencyController) Convert(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	amount, err := strconv.ParseFloat(c.Query("amount"), 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Valid amount is required"})
+		return
+	}
+	from := strings.ToUpper(c.DefaultQuery("from", models.DefaultCurrency))
+	to := strings.ToUpper(c.DefaultQuery("to", cc.currencyService.PreferredCurrency(userID)))
+
+	rate, err := cc.currencyService.Rate(from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from": models.Money{Amount: amount, Currency: from},
+		"to":   models.Money{Amount: amount * rate, Currency: to},
+		"rate": rate,
+	})
+}
+
+// Admin endpoints
+
+// ListExchangeRates returns stored rates, newest first (?currency= filter)
+func (cc *CurrencyController) ListExchangeRates(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	offset := (page - 1) * limit
+
+	query := cc.db.Model(&models.ExchangeRate{})
+	if currency := c.Query("currency"); currency != "" {
+		query = query.Where("currency = ? OR base_currency = ?", strings.ToUpper(currency), strings.ToUpper(currency))
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var rates []models.ExchangeRate
+	if err := query.Order("effective_date DESC, currency ASC").Offset(offset).Limit(limit).Find(&rates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rates":       rates,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": int(math.Ceil(float64(total) / float64(limit))),
+	})
+}
+
+// CreateExchangeRate stores a rate; a rate for the same pair and date is replaced
+func (cc *CurrencyController) CreateExchangeRate(c *gin.Context) {
+	var req services.ExchangeRateInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rate, created, err := cc.currencyService.Save(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, rate)
+}
+
+// ImportExchangeRates bulk imports rates from an uploaded CSV or JSON file (form field "file")
+func (cc *CurrencyController) ImportExchangeRates(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file provided"})
+		return
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Filename)), ".")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only .csv and .json files are supported"})
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer src.Close()
+
+	result, err := cc.currencyService.Import(src, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
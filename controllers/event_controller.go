@@ -2,21 +2,72 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"motocosmos-api/models"
+	"motocosmos-api/services"
 	"net/http"
 	"strconv"
 	"time"
 )
 
 type EventController struct {
-	db *gorm.DB
+	db                *gorm.DB
+	difficultyService *services.DifficultyService
 }
 
 func NewEventController(db *gorm.DB) *EventController {
-	return &EventController{db: db}
+	return &EventController{
+		db:                db,
+		difficultyService: services.NewDifficultyService(db, nil, nil),
+	}
+}
+
+// eventDateRange reads the from and to dates (RFC 3339 or YYYY-MM-DD) and the
+// when=this_weekend|next_weekend shortcut; zero times leave a side open
+func eventDateRange(c *gin.Context, now time.Time) (from, to time.Time, err error) {
+	parse := func(value string) (time.Time, error) {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, nil
+		}
+		return time.ParseInLocation("2006-01-02", value, now.Location())
+	}
+
+	if value := c.Query("from"); value != "" {
+		if from, err = parse(value); err != nil {
+			return from, to, errors.New("from must be a date (YYYY-MM-DD) or RFC 3339 time")
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if to, err = parse(value); err != nil {
+			return from, to, errors.New("to must be a date (YYYY-MM-DD) or RFC 3339 time")
+		}
+		if len(value) == len("2006-01-02") {
+			to = to.AddDate(0, 0, 1) // include the whole day
+		}
+	}
+
+	switch when := c.Query("when"); when {
+	case "":
+	case "this_weekend", "next_weekend":
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		// Saturday of this weekend; on a Sunday that is yesterday
+		saturday := today.AddDate(0, 0, (int(time.Saturday)-int(today.Weekday())+7)%7)
+		if today.Weekday() == time.Sunday {
+			saturday = today.AddDate(0, 0, -1)
+		}
+		if when == "next_weekend" {
+			saturday = saturday.AddDate(0, 0, 7)
+		}
+		from, to = saturday, saturday.AddDate(0, 0, 2)
+	default:
+		return from, to, errors.New("when must be this_weekend or next_weekend")
+	}
+
+	return from, to, nil
 }
 
 type CreateEventRequest struct {
@@ -51,11 +102,33 @@ func (ec *EventController) GetEvents(c *gin.Context) {
 	if difficulty := c.Query("difficulty"); difficulty != "" {
 		query = query.Where("difficulty = ?", difficulty)
 	}
+	if difficulty := c.Query("computed_difficulty"); difficulty != "" {
+		query = query.Where("computed_difficulty = ?", difficulty)
+	}
 
 	if availableOnly := c.Query("available_only"); availableOnly == "true" {
 		query = query.Where("is_full = ?", false)
 	}
 
+	from, to, err := eventDateRange(c, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !from.IsZero() {
+		query = query.Where("event_date >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("event_date < ?", to)
+	}
+
+	spatialFilter, err := services.ParseSpatialFilter(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query = services.FilterPoints(query, "location_geohash", "location_latitude", "location_longitude", spatialFilter)
+
 	if err := query.Order("event_date ASC").Offset(offset).Limit(limit).Find(&events).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
 		return
@@ -107,6 +180,7 @@ func (ec *EventController) CreateEvent(c *gin.Context) {
 		LocationLatitude:  req.LocationLatitude,
 		LocationLongitude: req.LocationLongitude,
 		LocationAddress:   req.LocationAddress,
+		LocationGeohash:   services.PointGeohash(req.LocationLatitude, req.LocationLongitude),
 		Difficulty:        req.Difficulty,
 		EstimatedDistance: req.EstimatedDistance,
 		EstimatedDuration: req.EstimatedDuration,
@@ -129,6 +203,12 @@ func (ec *EventController) CreateEvent(c *gin.Context) {
 	}
 	ec.db.Create(&participant)
 
+	// Events take the computed difficulty of their route
+	if err := ec.difficultyService.ClassifyEvent(event.ID); err != nil {
+		fmt.Printf("Warning: Could not classify event difficulty: %v\n", err)
+	}
+	ec.db.First(&event, "id = ?", event.ID)
+
 	c.JSON(http.StatusCreated, event)
 }
 
@@ -176,6 +256,7 @@ func (ec *EventController) UpdateEvent(c *gin.Context) {
 		"location_latitude":  req.LocationLatitude,
 		"location_longitude": req.LocationLongitude,
 		"location_address":   req.LocationAddress,
+		"location_geohash":   services.PointGeohash(req.LocationLatitude, req.LocationLongitude),
 		"difficulty":         req.Difficulty,
 		"estimated_distance": req.EstimatedDistance,
 		"estimated_duration": req.EstimatedDuration,
@@ -198,6 +279,9 @@ func (ec *EventController) UpdateEvent(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
 		return
 	}
+	if err := ec.difficultyService.ClassifyEvent(eventID); err != nil {
+		fmt.Printf("Warning: Could not classify event difficulty: %v\n", err)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Event updated successfully"})
 }
@@ -0,0 +1,244 @@
+// File: /controllers/expense_controller.go
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"motocosmos-api/services"
+	"net/http"
+	"time"
+)
+
+type ExpenseController struct {
+	db                     *gorm.DB
+	expenseService         *services.ExpenseService
+	notificationController *NotificationController
+}
+
+func NewExpenseController(db *gorm.DB, notificationController *NotificationController) *ExpenseController {
+	return &ExpenseController{
+		db:                     db,
+		expenseService:         services.NewExpenseService(db),
+		notificationController: notificationController,
+	}
+}
+
+type CreateExpenseGroupRequest struct {
+	Name      string   `json:"name" binding:"max=255"`
+	EventID   string   `json:"event_id"`   // members are taken from the event
+	MemberIDs []string `json:"member_ids"` // friends to include when there is no event
+	Currency  string   `json:"currency"`
+}
+
+type AddExpenseRequest struct {
+	PaidByID    string                       `json:"paid_by_id"` // defaults to the current user
+	Description string                       `json:"description" binding:"required,max=255"`
+	Category    string                       `json:"category"` // fuel, accommodation, ferry, food, toll, other
+	Amount      float64                      `json:"amount" binding:"required,gt=0"`
+	Currency    string                       `json:"currency"`   // defaults to the group currency
+	SplitType   string                       `json:"split_type"` // equal (default) or weighted
+	Shares      []services.ExpenseShareInput `json:"shares"`     // defaults to all members
+	SpentAt     *time.Time                   `json:"spent_at"`
+}
+
+type RecordSettlementRequest struct {
+	FromUserID string  `json:"from_user_id" binding:"required"`
+	ToUserID   string  `json:"to_user_id" binding:"required"`
+	Amount     float64 `json:"amount" binding:"required,gt=0"`
+	Currency   string  `json:"currency"`
+	Note       string  `json:"note" binding:"max=255"`
+}
+
+// CreateGroup creates an expense ledger for an event or a group of friends
+func (ec *ExpenseController) CreateGroup(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req CreateExpenseGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := ec.expenseService.CreateGroup(userID, services.ExpenseGroupInput{
+		Name:      req.Name,
+		EventID:   req.EventID,
+		MemberIDs: req.MemberIDs,
+		Currency:  req.Currency,
+	})
+	if err != nil {
+		ec.respondError(c, err, "Failed to create expense group")
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+// GetGroups returns the expense groups the user belongs to
+func (ec *ExpenseController) GetGroups(c *gin.Context) {
+	groups, err := ec.expenseService.ListGroups(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch expense groups"})
+		return
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+// GetGroup returns a group with its members
+func (ec *ExpenseController) GetGroup(c *gin.Context) {
+	group, err := ec.expenseService.GetGroup(c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		ec.respondError(c, err, "Failed to fetch expense group")
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// AddMember adds a friend or event participant to the group
+func (ec *ExpenseController) AddMember(c *gin.Context) {
+	var req struct {
+		UserID string `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := ec.expenseService.AddMember(c.GetString("user_id"), c.Param("id"), req.UserID)
+	if err != nil {
+		ec.respondError(c, err, "Failed to add member")
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// RemoveMember removes a member without expenses from the group
+func (ec *ExpenseController) RemoveMember(c *gin.Context) {
+	if err := ec.expenseService.RemoveMember(c.GetString("user_id"), c.Param("id"), c.Param("user_id")); err != nil {
+		ec.respondError(c, err, "Failed to remove member")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// GetExpenses returns the group's expenses with their shares
+func (ec *ExpenseController) GetExpenses(c *gin.Context) {
+	expenses, err := ec.expenseService.ListExpenses(c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		ec.respondError(c, err, "Failed to fetch expenses")
+		return
+	}
+
+	c.JSON(http.StatusOK, expenses)
+}
+
+// AddExpense records a payment and notifies the other members
+func (ec *ExpenseController) AddExpense(c *gin.Context) {
+	userID := c.GetString("user_id")
+	groupID := c.Param("id")
+
+	var req AddExpenseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expense, err := ec.expenseService.AddExpense(userID, groupID, services.ExpenseInput{
+		PaidByID:    req.PaidByID,
+		Description: req.Description,
+		Category:    req.Category,
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+		SplitType:   req.SplitType,
+		Shares:      req.Shares,
+		SpentAt:     req.SpentAt,
+	})
+	if err != nil {
+		ec.respondError(c, err, "Failed to add expense")
+		return
+	}
+
+	// Notify other members (don't fail the request if notifications fail)
+	for _, memberID := range ec.expenseService.MemberIDs(groupID) {
+		if err := ec.notificationController.CreateExpenseNotification(userID, memberID, groupID); err != nil {
+			fmt.Printf("Failed to create expense notification: %v\n", err)
+		}
+	}
+
+	c.JSON(http.StatusCreated, expense)
+}
+
+// DeleteExpense removes an expense (creator, payer or group creator only)
+func (ec *ExpenseController) DeleteExpense(c *gin.Context) {
+	if err := ec.expenseService.DeleteExpense(c.GetString("user_id"), c.Param("id"), c.Param("expense_id")); err != nil {
+		ec.respondError(c, err, "Failed to delete expense")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Expense deleted successfully"})
+}
+
+// GetBalances returns every member's balance and settle-up suggestions (?currency=)
+func (ec *ExpenseController) GetBalances(c *gin.Context) {
+	balances, err := ec.expenseService.Balances(c.GetString("user_id"), c.Param("id"), c.Query("currency"))
+	if err != nil {
+		ec.respondError(c, err, "Failed to calculate balances")
+		return
+	}
+
+	c.JSON(http.StatusOK, balances)
+}
+
+// GetSettlements returns the transfers recorded in the group
+func (ec *ExpenseController) GetSettlements(c *gin.Context) {
+	settlements, err := ec.expenseService.ListSettlements(c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		ec.respondError(c, err, "Failed to fetch settlements")
+		return
+	}
+
+	c.JSON(http.StatusOK, settlements)
+}
+
+// RecordSettlement records a transfer between two members
+func (ec *ExpenseController) RecordSettlement(c *gin.Context) {
+	var req RecordSettlementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settlement, err := ec.expenseService.RecordSettlement(c.GetString("user_id"), c.Param("id"), services.SettlementInput{
+		FromUserID: req.FromUserID,
+		ToUserID:   req.ToUserID,
+		Amount:     req.Amount,
+		Currency:   req.Currency,
+		Note:       req.Note,
+	})
+	if err != nil {
+		ec.respondError(c, err, "Failed to record settlement")
+		return
+	}
+
+	c.JSON(http.StatusCreated, settlement)
+}
+
+func (ec *ExpenseController) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrExpenseGroupNotFound), errors.Is(err, services.ErrExpenseNotFound), errors.Is(err, services.ErrEventNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotExpenseMember), errors.Is(err, services.ErrNotFriend):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMemberHasExpenses):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidExpense), errors.Is(err, services.ErrUnsupportedCurrency):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,262 @@
+// File: /controllers/fuel_price_controller.go
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"math"
+	"motocosmos-api/models"
+	"motocosmos-api/services"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type FuelPriceController struct {
+	db               *gorm.DB
+	fuelPriceService *services.FuelPriceService
+}
+
+func NewFuelPriceController(db *gorm.DB) *FuelPriceController {
+	return &FuelPriceController{
+		db:               db,
+		fuelPriceService: services.NewFuelPriceService(db),
+	}
+}
+
+// GetLatestPrices returns the current prices of a grade for all countries and regions
+func (fc *FuelPriceController) GetLatestPrices(c *gin.Context) {
+	grade := c.DefaultQuery("grade", models.FuelGrade95)
+	if !models.IsValidFuelGrade(grade) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fuel grade"})
+		return
+	}
+
+	prices, err := fc.fuelPriceService.LatestPrices(grade)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch fuel prices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, prices)
+}
+
+// GetPriceHistory returns the price history of a country (?country=DE&grade=95&region=&days=90)
+func (fc *FuelPriceController) GetPriceHistory(c *gin.Context) {
+	country, grade, region, days, ok := fc.parseSeriesQuery(c)
+	if !ok {
+		return
+	}
+
+	history, err := fc.fuelPriceService.History(country, region, grade, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// GetPriceTrend returns how the price of a country developed over a period
+func (fc *FuelPriceController) GetPriceTrend(c *gin.Context) {
+	country, grade, region, days, ok := fc.parseSeriesQuery(c)
+	if !ok {
+		return
+	}
+
+	trend, err := fc.fuelPriceService.Trend(country, region, grade, days)
+	if err != nil {
+		if errors.Is(err, services.ErrFuelPriceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No prices found for this country and grade"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate price trend"})
+		return
+	}
+
+	c.JSON(http.StatusOK, trend)
+}
+
+// Admin endpoints
+
+// ListPrices returns all stored price rows, newest first, with optional filters
+func (fc *FuelPriceController) ListPrices(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	offset := (page - 1) * limit
+
+	query := fc.db.Model(&models.FuelPrice{})
+	if country := c.Query("country"); country != "" {
+		query = query.Where("country_code = ?", strings.ToUpper(country))
+	}
+	if grade := c.Query("grade"); grade != "" {
+		query = query.Where("grade = ?", grade)
+	}
+	if region, ok := c.GetQuery("region"); ok {
+		query = query.Where("region = ?", region)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var prices []models.FuelPrice
+	if err := query.Order("effective_from DESC, country_code ASC").Offset(offset).Limit(limit).Find(&prices).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch fuel prices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"prices":      prices,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": int(math.Ceil(float64(total) / float64(limit))),
+	})
+}
+
+// CreatePrice adds a price row; a row for the same country, region, grade and date is replaced
+func (fc *FuelPriceController) CreatePrice(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req services.FuelPriceInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	price, created, err := fc.fuelPriceService.Save(req, &userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, price)
+}
+
+// UpdatePrice corrects a stored price row
+func (fc *FuelPriceController) UpdatePrice(c *gin.Context) {
+	priceID := c.Param("id")
+
+	var price models.FuelPrice
+	if err := fc.db.First(&price, "id = ?", priceID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fuel price not found"})
+		return
+	}
+
+	var req struct {
+		Price    float64 `json:"price" binding:"required,gt=0"`
+		Currency string  `json:"currency"`
+		Source   string  `json:"source"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currency := price.Currency
+	if req.Currency != "" {
+		currency = strings.ToUpper(req.Currency)
+	}
+	priceEUR, err := services.NewCurrencyService(fc.db).Convert(req.Price, currency, models.DefaultCurrency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+		return
+	}
+	if priceEUR > 10 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Price cannot exceed 10 EUR/L or its equivalent"})
+		return
+	}
+
+	updates := map[string]interface{}{"price": req.Price, "currency": currency}
+	if req.Source != "" {
+		updates["source"] = req.Source
+	}
+
+	if err := fc.db.Model(&price).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update fuel price"})
+		return
+	}
+
+	c.JSON(http.StatusOK, price)
+}
+
+// DeletePrice removes a price row
+func (fc *FuelPriceController) DeletePrice(c *gin.Context) {
+	result := fc.db.Where("id = ?", c.Param("id")).Delete(&models.FuelPrice{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete fuel price"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fuel price not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Fuel price deleted successfully"})
+}
+
+// ImportPrices bulk imports prices from an uploaded CSV or JSON file (form field "file")
+func (fc *FuelPriceController) ImportPrices(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file provided"})
+		return
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Filename)), ".")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only .csv and .json files are supported"})
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer src.Close()
+
+	result, err := fc.fuelPriceService.Import(src, format, &userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (fc *FuelPriceController) parseSeriesQuery(c *gin.Context) (country, grade, region string, days int, ok bool) {
+	country = strings.ToUpper(c.Query("country"))
+	if _, found := services.CountryByCode(country); !found {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Valid country code is required"})
+		return "", "", "", 0, false
+	}
+
+	grade = c.DefaultQuery("grade", models.FuelGrade95)
+	if !models.IsValidFuelGrade(grade) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fuel grade"})
+		return "", "", "", 0, false
+	}
+
+	days, _ = strconv.Atoi(c.DefaultQuery("days", "90"))
+	if days < 1 || days > 3650 {
+		days = 90
+	}
+
+	return country, grade, c.Query("region"), days, true
+}
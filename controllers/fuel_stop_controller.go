@@ -0,0 +1,100 @@
+// File: /controllers/fuel_stop_controller.go
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"motocosmos-api/services"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+type FuelStopController struct {
+	db              *gorm.DB
+	fuelStopService *services.FuelStopService
+	poiService      *services.POIService
+}
+
+func NewFuelStopController(db *gorm.DB) *FuelStopController {
+	return &FuelStopController{
+		db:              db,
+		fuelStopService: services.NewFuelStopService(db),
+		poiService:      services.NewPOIService(db),
+	}
+}
+
+type PlanFuelStopsRequest struct {
+	MotorcycleID     string   `json:"motorcycle_id"`
+	TankCapacity     float64  `json:"tank_capacity" binding:"gte=0,lte=100"`   // liters, overrides the motorcycle
+	FuelConsumption  float64  `json:"fuel_consumption" binding:"gte=0,lte=50"` // L/100km, overrides the motorcycle
+	ReservePercent   *float64 `json:"reserve_percent" binding:"omitempty,gte=0,lte=90"`
+	StartFuelPercent *float64 `json:"start_fuel_percent" binding:"omitempty,gte=0,lte=100"`
+	SearchRadius     float64  `json:"search_radius" binding:"gte=0,lte=50"` // km around the route
+}
+
+// PlanFuelStops computes the required fuel stops along a route for a motorcycle
+func (fc *FuelStopController) PlanFuelStops(c *gin.Context) {
+	userID := c.GetString("user_id")
+	routeID := c.Param("id")
+
+	var req PlanFuelStopsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := fc.fuelStopService.Plan(userID, services.FuelStopInput{
+		RouteID:          routeID,
+		MotorcycleID:     req.MotorcycleID,
+		TankCapacity:     req.TankCapacity,
+		FuelConsumption:  req.FuelConsumption,
+		ReservePercent:   req.ReservePercent,
+		StartFuelPercent: req.StartFuelPercent,
+		SearchRadius:     req.SearchRadius,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRouteNotFound), errors.Is(err, services.ErrMotorcycleNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrRouteGeometryMissing), errors.Is(err, services.ErrTankCapacityMissing):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to plan fuel stops"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// ImportPOIs bulk imports POIs from an uploaded CSV, JSON or OSM XML file (form field "file", admin only)
+func (fc *FuelStopController) ImportPOIs(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file provided"})
+		return
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Filename)), ".")
+	if format != "csv" && format != "json" && format != "osm" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only .csv, .json and .osm files are supported"})
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer src.Close()
+
+	result, err := fc.poiService.Import(src, format, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
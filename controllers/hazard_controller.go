@@ -0,0 +1,197 @@
+// File: /controllers/hazard_controller.go
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"math"
+	"motocosmos-api/models"
+	"motocosmos-api/services"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultHazardRadiusKm = 10.0
+	maxHazardRadiusKm     = 100.0
+)
+
+type HazardController struct {
+	db                     *gorm.DB
+	hazardService          *services.HazardService
+	notificationController *NotificationController
+}
+
+func NewHazardController(db *gorm.DB, notificationController *NotificationController) *HazardController {
+	return &HazardController{
+		db:                     db,
+		hazardService:          services.NewHazardService(db),
+		notificationController: notificationController,
+	}
+}
+
+type ReportHazardRequest struct {
+	Type        string  `json:"type" binding:"required"`
+	Description string  `json:"description" binding:"max=500"`
+	Latitude    float64 `json:"latitude" binding:"required,gte=-90,lte=90"`
+	Longitude   float64 `json:"longitude" binding:"required,gte=-180,lte=180"`
+}
+
+type ConfirmHazardRequest struct {
+	StillThere *bool `json:"still_there" binding:"required"`
+}
+
+// GetHazardTypes returns the hazard types and how long reports of each stay active
+func (hc *HazardController) GetHazardTypes(c *gin.Context) {
+	types := make([]gin.H, 0, len(models.HazardLifetimes))
+	for hazardType, lifetime := range models.HazardLifetimes {
+		types = append(types, gin.H{"type": hazardType, "lifetime": int(lifetime.Seconds())})
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i]["type"].(string) < types[j]["type"].(string) })
+
+	c.JSON(http.StatusOK, gin.H{"types": types})
+}
+
+// GetNearbyHazards returns the active hazards around a point, nearest first
+func (hc *HazardController) GetNearbyHazards(c *gin.Context) {
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, errLng := strconv.ParseFloat(c.Query("lng"), 64)
+	if errLat != nil || errLng != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Valid lat and lng are required"})
+		return
+	}
+
+	radius, _ := strconv.ParseFloat(c.DefaultQuery("radius", "0"), 64)
+	if radius <= 0 {
+		radius = defaultHazardRadiusKm
+	}
+	radius = math.Min(radius, maxHazardRadiusKm)
+
+	var types []string
+	if value := c.Query("type"); value != "" {
+		for _, hazardType := range strings.Split(value, ",") {
+			hazardType = strings.ToLower(strings.TrimSpace(hazardType))
+			if !services.IsValidHazardType(hazardType) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown hazard type: " + hazardType})
+				return
+			}
+			types = append(types, hazardType)
+		}
+	}
+
+	hazards, err := hc.hazardService.Nearby(lat, lng, radius, types...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch hazards"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"hazards": hazards,
+		"radius":  radius,
+		"count":   len(hazards),
+	})
+}
+
+// GetHazard returns a single hazard report
+func (hc *HazardController) GetHazard(c *gin.Context) {
+	hazard, err := hc.hazardService.Get(c.Param("id"))
+	if err != nil {
+		respondHazardError(c, err, "Failed to fetch hazard")
+		return
+	}
+
+	c.JSON(http.StatusOK, hazard)
+}
+
+// ReportHazard reports a hazard at a location. A report close to an active
+// one of the same type confirms that one instead.
+func (hc *HazardController) ReportHazard(c *gin.Context) {
+	var req ReportHazardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hazard, created, err := hc.hazardService.Report(c.GetString("user_id"), services.HazardInput{
+		Type:        req.Type,
+		Description: req.Description,
+		Latitude:    req.Latitude,
+		Longitude:   req.Longitude,
+	})
+	if err != nil {
+		respondHazardError(c, err, "Failed to report hazard")
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, gin.H{
+		"hazard":  hazard,
+		"created": created,
+	})
+}
+
+// ConfirmHazard records whether the hazard is still there
+func (hc *HazardController) ConfirmHazard(c *gin.Context) {
+	var req ConfirmHazardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hazard, err := hc.hazardService.Confirm(c.GetString("user_id"), c.Param("id"), *req.StillThere)
+	if err != nil {
+		respondHazardError(c, err, "Failed to confirm hazard")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"hazard":   hazard,
+		"disputed": hazard.IsDisputed(),
+	})
+}
+
+// DeleteHazard removes a hazard reported by the current user
+func (hc *HazardController) DeleteHazard(c *gin.Context) {
+	if err := hc.hazardService.Delete(c.GetString("user_id"), c.Param("id")); err != nil {
+		respondHazardError(c, err, "Failed to delete hazard")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Hazard deleted successfully"})
+}
+
+// alertHazards warns a rider on an active ride about the hazards ahead of a
+// new position (don't fail the request if alerts fail)
+func alertHazards(hazardService *services.HazardService, notificationController *NotificationController, userID string, lat, lng float64) {
+	hazards, err := hazardService.AlertsFor(userID, lat, lng)
+	if err != nil {
+		fmt.Printf("Warning: Could not check hazards: %v\n", err)
+		return
+	}
+
+	for _, hazard := range hazards {
+		if err := notificationController.CreateHazardAlertNotification(hazard.ReporterID, userID, hazard.ID); err != nil {
+			fmt.Printf("Failed to create hazard alert notification: %v\n", err)
+		}
+	}
+}
+
+func respondHazardError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrHazardNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotHazardReporter), errors.Is(err, services.ErrOwnHazard):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidHazard):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
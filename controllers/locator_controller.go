@@ -11,17 +11,21 @@ import (
 )
 
 type LocatorController struct {
-	db              *gorm.DB
-	locationService *services.LocationService
+	db                     *gorm.DB
+	locationService        *services.LocationService
+	hazardService          *services.HazardService
+	notificationController *NotificationController
 }
 
-func NewLocatorController(db *gorm.DB) *LocatorController {
+func NewLocatorController(db *gorm.DB, notificationController *NotificationController) *LocatorController {
 	locationRepo := repositories.NewLocationRepository(db)
 	locationService := services.NewLocationService(locationRepo)
 
 	return &LocatorController{
-		db:              db,
-		locationService: locationService,
+		db:                     db,
+		locationService:        locationService,
+		hazardService:          services.NewHazardService(db),
+		notificationController: notificationController,
 	}
 }
 
@@ -71,6 +75,9 @@ func (lc *LocatorController) UpdateLocation(c *gin.Context) {
 		return
 	}
 
+	// Riders on an active ride hear about hazards ahead of them
+	alertHazards(lc.hazardService, lc.notificationController, userID, req.Latitude, req.Longitude)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Location updated successfully",
 	})
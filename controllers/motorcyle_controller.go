@@ -2,33 +2,51 @@
 package controllers
 
 import (
+	"context"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"io"
 	"motocosmos-api/models"
+	"motocosmos-api/services"
 	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 type MotorcycleController struct {
-	db *gorm.DB
+	db      *gorm.DB
+	storage *services.StorageService
 }
 
-func NewMotorcycleController(db *gorm.DB) *MotorcycleController {
-	return &MotorcycleController{db: db}
+func NewMotorcycleController(db *gorm.DB, storage *services.StorageService) *MotorcycleController {
+	return &MotorcycleController{
+		db:      db,
+		storage: storage,
+	}
 }
 
+// maxMotorcycleImages limits the size of a single motorcycle gallery
+const maxMotorcycleImages = 20
+
 type CreateMotorcycleRequest struct {
-	Brand    string `json:"brand" binding:"required"`
-	Model    string `json:"model" binding:"required"`
-	Year     string `json:"year" binding:"required"`
-	ImageURL string `json:"image_url"`
+	Brand           string  `json:"brand" binding:"required"`
+	Model           string  `json:"model" binding:"required"`
+	Year            string  `json:"year" binding:"required"`
+	ImageURL        string  `json:"image_url"`
+	FuelConsumption float64 `json:"fuel_consumption" binding:"gte=0,lte=50"`
+	TankCapacity    float64 `json:"tank_capacity" binding:"gte=0,lte=100"`
 }
 
 func (mc *MotorcycleController) GetMotorcycles(c *gin.Context) {
 	userID := c.GetString("user_id")
 
 	var motorcycles []models.Motorcycle
-	if err := mc.db.Where("user_id = ?", userID).Find(&motorcycles).Error; err != nil {
+	if err := mc.db.Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Preload("Mods").Where("user_id = ?", userID).Find(&motorcycles).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch motorcycles"})
 		return
 	}
@@ -52,6 +70,9 @@ func (mc *MotorcycleController) CreateMotorcycle(c *gin.Context) {
 		Model:    req.Model,
 		Year:     req.Year,
 		ImageURL: req.ImageURL,
+
+		FuelConsumption: req.FuelConsumption,
+		TankCapacity:    req.TankCapacity,
 	}
 
 	if err := mc.db.Create(&motorcycle).Error; err != nil {
@@ -79,10 +100,12 @@ func (mc *MotorcycleController) UpdateMotorcycle(c *gin.Context) {
 	}
 
 	updates := map[string]interface{}{
-		"brand":     req.Brand,
-		"model":     req.Model,
-		"year":      req.Year,
-		"image_url": req.ImageURL,
+		"brand":            req.Brand,
+		"model":            req.Model,
+		"year":             req.Year,
+		"image_url":        req.ImageURL,
+		"fuel_consumption": req.FuelConsumption,
+		"tank_capacity":    req.TankCapacity,
 	}
 
 	if err := mc.db.Model(&motorcycle).Updates(updates).Error; err != nil {
@@ -103,6 +126,18 @@ func (mc *MotorcycleController) DeleteMotorcycle(c *gin.Context) {
 		return
 	}
 
+	// Remove gallery images from storage and the mods list first
+	var images []models.MotorcycleImage
+	mc.db.Where("motorcycle_id = ?", motorcycleID).Find(&images)
+	for _, image := range images {
+		if err := mc.storage.RemoveObject(context.Background(), image.ObjectName); err != nil {
+			fmt.Printf("Warning: Could not delete motorcycle image %s: %v\n", image.ObjectName, err)
+		}
+	}
+	mc.db.Where("motorcycle_id = ?", motorcycleID).Delete(&models.MotorcycleImage{})
+	mc.db.Where("motorcycle_id = ?", motorcycleID).Delete(&models.MotorcycleMod{})
+	mc.db.Where("motorcycle_id = ?", motorcycleID).Delete(&models.FuelLog{})
+
 	if err := mc.db.Delete(&motorcycle).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete motorcycle"})
 		return
@@ -110,3 +145,454 @@ func (mc *MotorcycleController) DeleteMotorcycle(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Motorcycle deleted successfully"})
 }
+
+// UploadImages adds one or more images to a motorcycle's gallery
+func (mc *MotorcycleController) UploadImages(c *gin.Context) {
+	userID := c.GetString("user_id")
+	motorcycleID := c.Param("id")
+
+	var motorcycle models.Motorcycle
+	if err := mc.db.First(&motorcycle, "id = ? AND user_id = ?", motorcycleID, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Motorcycle not found or access denied"})
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form"})
+		return
+	}
+
+	files := form.File["images"]
+	files = append(files, form.File["image"]...)
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No images provided"})
+		return
+	}
+
+	var existingCount int64
+	mc.db.Model(&models.MotorcycleImage{}).Where("motorcycle_id = ?", motorcycleID).Count(&existingCount)
+	if int(existingCount)+len(files) > maxMotorcycleImages {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A motorcycle can have at most %d images", maxMotorcycleImages)})
+		return
+	}
+
+	ctx := context.Background()
+	uploaded := make([]models.MotorcycleImage, 0, len(files))
+	position := int(existingCount)
+
+	for _, file := range files {
+		// Same limits as post images
+		if file.Size > 10*1024*1024 || !isValidImageType(file) {
+			continue
+		}
+
+		ext := filepath.Ext(file.Filename)
+		filename := fmt.Sprintf("%s_%d%s", uuid.New().String(), time.Now().Unix(), ext)
+
+		// MinIO object path: motorcycles/{userID}/{filename}
+		objectName := fmt.Sprintf("motorcycles/%s/%s", userID, filename)
+		if _, err := mc.storage.UploadFile(ctx, objectName, file); err != nil {
+			fmt.Printf("[ERROR] Failed to upload motorcycle image: %v\n", err)
+			continue
+		}
+
+		image := models.MotorcycleImage{
+			ID:           uuid.New().String(),
+			MotorcycleID: motorcycleID,
+			UserID:       userID,
+			URL:          fmt.Sprintf("/api/v1/motorcycles/images/%s/%s", userID, filename),
+			ObjectName:   objectName,
+			Position:     position,
+			IsPrimary:    position == 0,
+		}
+		if err := mc.db.Create(&image).Error; err != nil {
+			mc.storage.RemoveObject(ctx, objectName)
+			continue
+		}
+
+		uploaded = append(uploaded, image)
+		position++
+	}
+
+	if len(uploaded) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid images were uploaded"})
+		return
+	}
+
+	// First image of an empty gallery becomes the cover image
+	if existingCount == 0 {
+		mc.db.Model(&motorcycle).Update("image_url", uploaded[0].URL)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"images":  uploaded,
+		"count":   len(uploaded),
+		"message": "Images uploaded successfully",
+	})
+}
+
+// GetImage streams a motorcycle image from MinIO
+func (mc *MotorcycleController) GetImage(c *gin.Context) {
+	userID := c.Param("user_id")
+	file := c.Param("file")
+	objectName := fmt.Sprintf("motorcycles/%s/%s", userID, file)
+
+	obj, stat, err := mc.storage.GetObject(context.Background(), objectName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	defer obj.Close()
+
+	contentType := stat.ContentType
+	if contentType == "" || contentType == "application/octet-stream" {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".jpg", ".jpeg":
+			contentType = "image/jpeg"
+		case ".png":
+			contentType = "image/png"
+		case ".webp":
+			contentType = "image/webp"
+		default:
+			contentType = "application/octet-stream"
+		}
+	}
+
+	c.Header("Content-Type", contentType)
+	if _, err := io.Copy(c.Writer, obj); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+}
+
+// DeleteImage removes an image from a motorcycle's gallery
+func (mc *MotorcycleController) DeleteImage(c *gin.Context) {
+	userID := c.GetString("user_id")
+	motorcycleID := c.Param("id")
+	imageID := c.Param("image_id")
+
+	var image models.MotorcycleImage
+	if err := mc.db.First(&image, "id = ? AND motorcycle_id = ? AND user_id = ?", imageID, motorcycleID, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found or access denied"})
+		return
+	}
+
+	if err := mc.storage.RemoveObject(context.Background(), image.ObjectName); err != nil {
+		fmt.Printf("[ERROR] Failed to delete from MinIO: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete image"})
+		return
+	}
+
+	if err := mc.db.Delete(&image).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete image"})
+		return
+	}
+
+	// Promote the next image to cover if the primary one was removed
+	if image.IsPrimary {
+		var next models.MotorcycleImage
+		coverURL := ""
+		if err := mc.db.Where("motorcycle_id = ?", motorcycleID).Order("position ASC").First(&next).Error; err == nil {
+			mc.db.Model(&next).Update("is_primary", true)
+			coverURL = next.URL
+		}
+		mc.db.Model(&models.Motorcycle{}).Where("id = ?", motorcycleID).Update("image_url", coverURL)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Image deleted successfully"})
+}
+
+// SetPrimaryImage marks a gallery image as the motorcycle's cover image
+func (mc *MotorcycleController) SetPrimaryImage(c *gin.Context) {
+	userID := c.GetString("user_id")
+	motorcycleID := c.Param("id")
+	imageID := c.Param("image_id")
+
+	var image models.MotorcycleImage
+	if err := mc.db.First(&image, "id = ? AND motorcycle_id = ? AND user_id = ?", imageID, motorcycleID, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found or access denied"})
+		return
+	}
+
+	err := mc.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.MotorcycleImage{}).Where("motorcycle_id = ?", motorcycleID).Update("is_primary", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&image).Update("is_primary", true).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Motorcycle{}).Where("id = ?", motorcycleID).Update("image_url", image.URL).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cover image"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cover image updated successfully"})
+}
+
+type MotorcycleModRequest struct {
+	Name        string     `json:"name" binding:"required"`
+	Category    string     `json:"category"`
+	Brand       string     `json:"brand"`
+	Description string     `json:"description"`
+	Cost        float64    `json:"cost" binding:"gte=0"`
+	InstalledAt *time.Time `json:"installed_at"`
+}
+
+// GetMods returns the mods list of a motorcycle
+func (mc *MotorcycleController) GetMods(c *gin.Context) {
+	motorcycleID := c.Param("id")
+
+	var motorcycle models.Motorcycle
+	if err := mc.db.First(&motorcycle, "id = ?", motorcycleID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Motorcycle not found"})
+		return
+	}
+
+	var mods []models.MotorcycleMod
+	if err := mc.db.Where("motorcycle_id = ?", motorcycleID).Order("installed_at DESC, created_at DESC").Find(&mods).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch mods"})
+		return
+	}
+
+	c.JSON(http.StatusOK, mods)
+}
+
+// AddMod adds a modification to a motorcycle
+func (mc *MotorcycleController) AddMod(c *gin.Context) {
+	userID := c.GetString("user_id")
+	motorcycleID := c.Param("id")
+
+	var motorcycle models.Motorcycle
+	if err := mc.db.First(&motorcycle, "id = ? AND user_id = ?", motorcycleID, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Motorcycle not found or access denied"})
+		return
+	}
+
+	var req MotorcycleModRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mod := models.MotorcycleMod{
+		ID:           uuid.New().String(),
+		MotorcycleID: motorcycleID,
+		UserID:       userID,
+		Name:         req.Name,
+		Category:     req.Category,
+		Brand:        req.Brand,
+		Description:  req.Description,
+		Cost:         req.Cost,
+		InstalledAt:  req.InstalledAt,
+	}
+
+	if err := mc.db.Create(&mod).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add mod"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, mod)
+}
+
+// UpdateMod updates a modification (owner only)
+func (mc *MotorcycleController) UpdateMod(c *gin.Context) {
+	userID := c.GetString("user_id")
+	motorcycleID := c.Param("id")
+	modID := c.Param("mod_id")
+
+	var mod models.MotorcycleMod
+	if err := mc.db.First(&mod, "id = ? AND motorcycle_id = ? AND user_id = ?", modID, motorcycleID, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mod not found or access denied"})
+		return
+	}
+
+	var req MotorcycleModRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{
+		"name":         req.Name,
+		"category":     req.Category,
+		"brand":        req.Brand,
+		"description":  req.Description,
+		"cost":         req.Cost,
+		"installed_at": req.InstalledAt,
+	}
+
+	if err := mc.db.Model(&mod).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update mod"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Mod updated successfully"})
+}
+
+// DeleteMod removes a modification (owner only)
+func (mc *MotorcycleController) DeleteMod(c *gin.Context) {
+	userID := c.GetString("user_id")
+	motorcycleID := c.Param("id")
+	modID := c.Param("mod_id")
+
+	result := mc.db.Where("id = ? AND motorcycle_id = ? AND user_id = ?", modID, motorcycleID, userID).Delete(&models.MotorcycleMod{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete mod"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mod not found or access denied"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Mod deleted successfully"})
+}
+
+type FuelLogRequest struct {
+	Liters        float64    `json:"liters" binding:"required,gt=0,lte=100"`
+	PricePerLiter float64    `json:"price_per_liter" binding:"gte=0"`
+	Currency      string     `json:"currency"` // Defaults to the user's preferred currency
+	Odometer      float64    `json:"odometer" binding:"gte=0"`
+	IsFullTank    *bool      `json:"is_full_tank"`
+	Country       string     `json:"country"`
+	FilledAt      *time.Time `json:"filled_at"`
+}
+
+// GetFuelLogs returns the refuelling history of a motorcycle together with the
+// consumption calculated from it (owner only)
+func (mc *MotorcycleController) GetFuelLogs(c *gin.Context) {
+	userID := c.GetString("user_id")
+	motorcycleID := c.Param("id")
+
+	var motorcycle models.Motorcycle
+	if err := mc.db.First(&motorcycle, "id = ? AND user_id = ?", motorcycleID, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Motorcycle not found or access denied"})
+		return
+	}
+
+	var logs []models.FuelLog
+	if err := mc.db.Where("motorcycle_id = ?", motorcycleID).Order("filled_at DESC").Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch fuel logs"})
+		return
+	}
+
+	consumption, source := services.NewTripCostService(mc.db).MotorcycleConsumption(&motorcycle)
+
+	// Sum up the spending in the user's display currency
+	currencyService := services.NewCurrencyService(mc.db)
+	displayCurrency := currencyService.PreferredCurrency(userID)
+	var totalSpent float64
+	for _, log := range logs {
+		currency := log.Currency
+		if currency == "" {
+			currency = models.DefaultCurrency
+		}
+		if amount, err := currencyService.Convert(log.TotalCost, currency, displayCurrency); err == nil {
+			totalSpent += amount
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"fuel_logs":          logs,
+		"fuel_consumption":   consumption,
+		"consumption_source": source,
+		"total_spent":        models.Money{Amount: totalSpent, Currency: displayCurrency},
+	})
+}
+
+// AddFuelLog records a refuelling (owner only)
+func (mc *MotorcycleController) AddFuelLog(c *gin.Context) {
+	userID := c.GetString("user_id")
+	motorcycleID := c.Param("id")
+
+	var motorcycle models.Motorcycle
+	if err := mc.db.First(&motorcycle, "id = ? AND user_id = ?", motorcycleID, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Motorcycle not found or access denied"})
+		return
+	}
+
+	var req FuelLogRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	country := ""
+	if req.Country != "" {
+		found, ok := services.CountryByCode(strings.ToUpper(req.Country))
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown country"})
+			return
+		}
+		country = found.Code
+	}
+
+	currencyService := services.NewCurrencyService(mc.db)
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		currency = currencyService.PreferredCurrency(userID)
+	}
+	priceEUR, err := currencyService.Convert(req.PricePerLiter, currency, models.DefaultCurrency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+		return
+	}
+	if priceEUR > 10 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fuel price cannot exceed 10 EUR/L or its equivalent"})
+		return
+	}
+
+	isFullTank := true
+	if req.IsFullTank != nil {
+		isFullTank = *req.IsFullTank
+	}
+
+	filledAt := time.Now()
+	if req.FilledAt != nil {
+		filledAt = *req.FilledAt
+	}
+
+	fuelLog := models.FuelLog{
+		ID:            uuid.New().String(),
+		MotorcycleID:  motorcycleID,
+		UserID:        userID,
+		Liters:        req.Liters,
+		PricePerLiter: req.PricePerLiter,
+		TotalCost:     req.Liters * req.PricePerLiter,
+		Currency:      currency,
+		Odometer:      req.Odometer,
+		IsFullTank:    isFullTank,
+		Country:       country,
+		FilledAt:      filledAt,
+	}
+
+	// Select keeps an explicit is_full_tank=false from being replaced by the column default
+	if err := mc.db.Select("*").Create(&fuelLog).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add fuel log"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, fuelLog)
+}
+
+// DeleteFuelLog removes a refuelling entry (owner only)
+func (mc *MotorcycleController) DeleteFuelLog(c *gin.Context) {
+	userID := c.GetString("user_id")
+	motorcycleID := c.Param("id")
+	logID := c.Param("log_id")
+
+	result := mc.db.Where("id = ? AND motorcycle_id = ? AND user_id = ?", logID, motorcycleID, userID).Delete(&models.FuelLog{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete fuel log"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fuel log not found or access denied"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Fuel log deleted successfully"})
+}
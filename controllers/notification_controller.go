@@ -201,6 +201,7 @@ func (nc *NotificationController) CreateNotification(params models.CreateNotific
 		TargetUserID: params.TargetUserID,
 		PostID:       params.PostID,
 		CommentID:    params.CommentID,
+		ReferenceID:  params.ReferenceID,
 		IsRead:       false,
 	}
 
@@ -258,3 +259,43 @@ func (nc *NotificationController) CreateShareNotification(actorUserID, targetUse
 		PostID:       &postID,
 	})
 }
+
+// CreateExpenseNotification notifies a group member about a new expense
+func (nc *NotificationController) CreateExpenseNotification(actorUserID, targetUserID, groupID string) error {
+	return nc.CreateNotification(models.CreateNotificationParams{
+		Type:         models.NotificationTypeExpense,
+		ActorUserID:  actorUserID,
+		TargetUserID: targetUserID,
+		ReferenceID:  &groupID,
+	})
+}
+
+// CreateRouteInviteNotification notifies a friend invited to plan a route
+func (nc *NotificationController) CreateRouteInviteNotification(actorUserID, targetUserID, routeID string) error {
+	return nc.CreateNotification(models.CreateNotificationParams{
+		Type:         models.NotificationTypeRouteInvite,
+		ActorUserID:  actorUserID,
+		TargetUserID: targetUserID,
+		ReferenceID:  &routeID,
+	})
+}
+
+// CreateRouteUpdateNotification notifies the owner or a collaborator that a shared plan changed
+func (nc *NotificationController) CreateRouteUpdateNotification(actorUserID, targetUserID, routeID string) error {
+	return nc.CreateNotification(models.CreateNotificationParams{
+		Type:         models.NotificationTypeRouteUpdate,
+		ActorUserID:  actorUserID,
+		TargetUserID: targetUserID,
+		ReferenceID:  &routeID,
+	})
+}
+
+// CreateHazardAlertNotification warns a rider about a hazard reported by another rider
+func (nc *NotificationController) CreateHazardAlertNotification(reporterID, targetUserID, hazardID string) error {
+	return nc.CreateNotification(models.CreateNotificationParams{
+		Type:         models.NotificationTypeHazardAlert,
+		ActorUserID:  reporterID,
+		TargetUserID: targetUserID,
+		ReferenceID:  &hazardID,
+	})
+}
@@ -2,21 +2,31 @@
 package controllers
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"math"
 	"motocosmos-api/models"
+	"motocosmos-api/services"
 	"net/http"
 	"strconv"
 )
 
 type PersonalRouteController struct {
-	db *gorm.DB
+	db                *gorm.DB
+	spatialService    *services.SpatialService
+	revisionService   *services.RouteRevisionService
+	difficultyService *services.DifficultyService
 }
 
 func NewPersonalRouteController(db *gorm.DB) *PersonalRouteController {
-	return &PersonalRouteController{db: db}
+	return &PersonalRouteController{
+		db:                db,
+		spatialService:    services.NewSpatialService(db),
+		revisionService:   services.NewRouteRevisionService(db),
+		difficultyService: services.NewDifficultyService(db, nil, nil),
+	}
 }
 
 type CreatePersonalRouteRequest struct {
@@ -149,7 +159,7 @@ func (prc *PersonalRouteController) CreatePersonalRoute(c *gin.Context) {
 		Difficulty:    req.Difficulty,
 		Tags:          models.StringSlice(req.Tags),
 		IsPublic:      req.IsPublic,
-		RouteGeometry: convertToJSONData(req.RouteGeometry),
+		RouteGeometry: requestGeometry(req.RouteGeometry),
 		RouteSettings: models.JSONData(req.RouteSettings),
 	}
 
@@ -172,7 +182,19 @@ func (prc *PersonalRouteController) CreatePersonalRoute(c *gin.Context) {
 	}
 
 	// Load the complete route with waypoints for response
-	prc.db.Preload("Waypoints").First(&route, "id = ?", route.ID)
+	prc.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).First(&route, "id = ?", route.ID)
+
+	if err := prc.spatialService.IndexRoute(models.RouteGeoCellOwnerRoute, route.ID, services.RoutePath(&route)); err != nil {
+		fmt.Printf("Warning: Could not index route location: %v\n", err)
+	}
+	if err := prc.difficultyService.ClassifyRoute(route.ID); err != nil {
+		fmt.Printf("Warning: Could not classify route difficulty: %v\n", err)
+	}
+	if _, err := prc.revisionService.Record(route.ID, userID, models.RouteRevisionCreated); err != nil {
+		fmt.Printf("Warning: Could not save route revision: %v\n", err)
+	}
 
 	// Convert to response format
 	routeResponse := PersonalRouteResponse{
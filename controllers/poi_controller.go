@@ -0,0 +1,345 @@
+// File: /controllers/poi_controller.go
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"math"
+	"motocosmos-api/models"
+	"motocosmos-api/services"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPOIRadiusKm      = 5.0
+	maxPOIRadiusKm          = 50.0
+	defaultRoutePOIRadiusKm = 2.0
+	maxRoutePOIRadiusKm     = 20.0
+)
+
+type POIController struct {
+	db         *gorm.DB
+	poiService *services.POIService
+}
+
+func NewPOIController(db *gorm.DB) *POIController {
+	return &POIController{
+		db:         db,
+		poiService: services.NewPOIService(db),
+	}
+}
+
+type SuggestPOIRequest struct {
+	Name         string   `json:"name" binding:"required,max=255"`
+	Category     string   `json:"category" binding:"required"`
+	Latitude     float64  `json:"latitude" binding:"required,gte=-90,lte=90"`
+	Longitude    float64  `json:"longitude" binding:"required,gte=-180,lte=180"`
+	Address      string   `json:"address" binding:"max=500"`
+	Description  string   `json:"description"`
+	Website      string   `json:"website" binding:"max=500"`
+	Phone        string   `json:"phone" binding:"max=50"`
+	OpeningHours string   `json:"opening_hours" binding:"max=255"`
+	Elevation    *float64 `json:"elevation"` // m, for passes
+}
+
+type POIsAlongRouteRequest struct {
+	RouteID       string          `json:"route_id"`
+	SharedRouteID string          `json:"shared_route_id"`
+	Points        []models.LatLng `json:"points"`                 // a geometry that is not saved yet
+	Radius        float64         `json:"radius" binding:"gte=0"` // km from the route
+	Categories    []string        `json:"categories"`             // defaults to all
+}
+
+type AttachPOIRequest struct {
+	POIID string `json:"poi_id" binding:"required"`
+	Note  string `json:"note" binding:"max=500"`
+}
+
+type ReviewPOIRequest struct {
+	Approve bool   `json:"approve"`
+	Note    string `json:"note" binding:"max=500"`
+}
+
+// GetCategories returns the supported POI categories
+func (pc *POIController) GetCategories(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"categories": models.POICategories})
+}
+
+// GetNearbyPOIs returns POIs around a point, e.g. the rider's position, nearest first
+func (pc *POIController) GetNearbyPOIs(c *gin.Context) {
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, errLng := strconv.ParseFloat(c.Query("lng"), 64)
+	if errLat != nil || errLng != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Valid lat and lng are required"})
+		return
+	}
+
+	radius, _ := strconv.ParseFloat(c.DefaultQuery("radius", "0"), 64)
+	if radius <= 0 {
+		radius = defaultPOIRadiusKm
+	}
+	radius = math.Min(radius, maxPOIRadiusKm)
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	categories, ok := poiCategories(c, splitQueryList(c.Query("category")))
+	if !ok {
+		return
+	}
+
+	pois, err := pc.poiService.Nearby(lat, lng, radius, limit, categories...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch points of interest"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pois":   pois,
+		"radius": radius,
+		"count":  len(pois),
+	})
+}
+
+// GetPOI returns a single POI
+func (pc *POIController) GetPOI(c *gin.Context) {
+	poi, err := pc.poiService.Get(c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		respondPOIError(c, err, "Failed to fetch point of interest")
+		return
+	}
+
+	c.JSON(http.StatusOK, poi)
+}
+
+// GetPOIsAlongRoute returns the POIs within a distance of a route, a shared
+// route or a submitted geometry, in the order they are passed
+func (pc *POIController) GetPOIsAlongRoute(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req POIsAlongRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	categories, ok := poiCategories(c, req.Categories)
+	if !ok {
+		return
+	}
+
+	radius := req.Radius
+	if radius == 0 {
+		radius = defaultRoutePOIRadiusKm
+	}
+	radius = math.Min(radius, maxRoutePOIRadiusKm)
+
+	points := req.Points
+	var roadDistance float64
+	switch {
+	case req.RouteID != "":
+		var route models.Route
+		if err := pc.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+			return db.Order("`order` ASC")
+		}).Preload("Collaborators").First(&route, "id = ?", req.RouteID).Error; err != nil || !route.IsAccessibleBy(userID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+			return
+		}
+		points = services.RoutePath(&route)
+		roadDistance = route.TotalDistance
+
+	case req.SharedRouteID != "":
+		var sharedRoute models.SharedRoute
+		if err := pc.db.First(&sharedRoute, "id = ?", req.SharedRouteID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Shared route not found"})
+			return
+		}
+		points = sharedRoute.GetRoutePointsAsLatLng()
+		roadDistance = sharedRoute.TotalDistance
+	}
+	if len(points) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "route_id, shared_route_id or at least 2 points are required"})
+		return
+	}
+
+	pois, err := pc.poiService.AlongPath(points, radius, categories...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch points of interest"})
+		return
+	}
+
+	// Report positions in road kilometres when the route's distance is known
+	if pathLength := services.PathLengthKm(points); roadDistance > pathLength && pathLength > 0 {
+		scale := roadDistance / pathLength
+		for i := range pois {
+			pois[i].DistanceFromStart *= scale
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pois":   pois,
+		"radius": radius,
+		"count":  len(pois),
+	})
+}
+
+// SuggestPOI lets a user propose a new POI; it is visible to others once an admin approves it
+func (pc *POIController) SuggestPOI(c *gin.Context) {
+	var req SuggestPOIRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	poi, err := pc.poiService.Suggest(c.GetString("user_id"), services.POIInput{
+		Name:         req.Name,
+		Category:     req.Category,
+		Latitude:     req.Latitude,
+		Longitude:    req.Longitude,
+		Address:      req.Address,
+		Description:  req.Description,
+		Website:      req.Website,
+		Phone:        req.Phone,
+		OpeningHours: req.OpeningHours,
+		Elevation:    req.Elevation,
+	})
+	if err != nil {
+		respondPOIError(c, err, "Failed to save suggestion")
+		return
+	}
+
+	c.JSON(http.StatusCreated, poi)
+}
+
+// GetSharedRoutePOIs returns the POIs attached to a shared route
+func (pc *POIController) GetSharedRoutePOIs(c *gin.Context) {
+	pois, err := pc.poiService.SharedRoutePOIs(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch points of interest"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pois": pois})
+}
+
+// AttachSharedRoutePOI attaches a POI to a shared route (creator only)
+func (pc *POIController) AttachSharedRoutePOI(c *gin.Context) {
+	var req AttachPOIRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	attached, err := pc.poiService.AttachToSharedRoute(c.GetString("user_id"), c.Param("id"), req.POIID, req.Note)
+	if err != nil {
+		respondPOIError(c, err, "Failed to attach point of interest")
+		return
+	}
+
+	c.JSON(http.StatusOK, attached)
+}
+
+// DetachSharedRoutePOI removes a POI from a shared route (creator only)
+func (pc *POIController) DetachSharedRoutePOI(c *gin.Context) {
+	if err := pc.poiService.DetachFromSharedRoute(c.GetString("user_id"), c.Param("id"), c.Param("poi_id")); err != nil {
+		respondPOIError(c, err, "Failed to detach point of interest")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Point of interest removed from route"})
+}
+
+// GetSuggestions lists POIs suggested by users for review (admin only)
+func (pc *POIController) GetSuggestions(c *gin.Context) {
+	status := c.DefaultQuery("status", models.POIStatusPending)
+	if status != models.POIStatusPending && status != models.POIStatusApproved && status != models.POIStatusRejected {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 50
+	}
+
+	pois, total, err := pc.poiService.Suggestions(status, limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch suggestions"})
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+	c.JSON(http.StatusOK, gin.H{
+		"pois":        pois,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"has_more":    page < totalPages,
+		"total_pages": totalPages,
+	})
+}
+
+// ReviewSuggestion approves or rejects a suggested POI (admin only)
+func (pc *POIController) ReviewSuggestion(c *gin.Context) {
+	var req ReviewPOIRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	poi, err := pc.poiService.Review(c.Param("id"), req.Approve, req.Note)
+	if err != nil {
+		respondPOIError(c, err, "Failed to review suggestion")
+		return
+	}
+
+	c.JSON(http.StatusOK, poi)
+}
+
+// poiCategories validates requested categories, answering 400 for unknown ones
+func poiCategories(c *gin.Context, categories []string) ([]string, bool) {
+	normalized := make([]string, 0, len(categories))
+	for _, category := range categories {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if category == "" {
+			continue
+		}
+		if !models.IsValidPOICategory(category) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown category: " + category, "categories": models.POICategories})
+			return nil, false
+		}
+		normalized = append(normalized, category)
+	}
+	return normalized, true
+}
+
+func splitQueryList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func respondPOIError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrPOINotFound), errors.Is(err, services.ErrRouteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotSharedRouteCreator):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrDuplicatePOI):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidPOI):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -8,14 +8,13 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
 	"gorm.io/gorm"
 	"io"
 	"math"
 	"mime/multipart"
 	"motocosmos-api/models"
+	"motocosmos-api/services"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -29,63 +28,12 @@ type PostController struct {
 	bucketName             string
 }
 
-func NewPostController(db *gorm.DB, notificationController *NotificationController) *PostController {
-	// MinIO konfiguráció környezeti változókból
-	endpoint := os.Getenv("MINIO_ENDPOINT")
-	accessKey := os.Getenv("MINIO_ACCESS_KEY")
-	secretKey := os.Getenv("MINIO_SECRET_KEY")
-	useSSL := os.Getenv("MINIO_USE_SSL") == "true"
-	bucketName := os.Getenv("MINIO_BUCKET_NAME")
-	
-	if bucketName == "" {
-		bucketName = "motocosmos-posts" // default bucket név
-	}
-
-	// MinIO client létrehozása
-	minioClient, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
-		Secure: useSSL,
-	})
-	if err != nil {
-		panic(fmt.Sprintf("Failed to create MinIO client: %v", err))
-	}
-
-	// Bucket létrehozása, ha nem létezik
-	ctx := context.Background()
-	exists, err := minioClient.BucketExists(ctx, bucketName)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to check bucket: %v", err))
-	}
-	
-	if !exists {
-		err = minioClient.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{})
-		if err != nil {
-			panic(fmt.Sprintf("Failed to create bucket: %v", err))
-		}
-		fmt.Printf("Bucket '%s' created successfully\n", bucketName)
-		
-		// Bucket policy beállítása (opcionális - public read access)
-		policy := fmt.Sprintf(`{
-			"Version": "2012-10-17",
-			"Statement": [{
-				"Effect": "Allow",
-				"Principal": {"AWS": ["*"]},
-				"Action": ["s3:GetObject"],
-				"Resource": ["arn:aws:s3:::%s/*"]
-			}]
-		}`, bucketName)
-		
-		err = minioClient.SetBucketPolicy(ctx, bucketName, policy)
-		if err != nil {
-			fmt.Printf("Warning: Failed to set bucket policy: %v\n", err)
-		}
-	}
-
+func NewPostController(db *gorm.DB, notificationController *NotificationController, storage *services.StorageService) *PostController {
 	return &PostController{
 		db:                     db,
 		notificationController: notificationController,
-		minioClient:            minioClient,
-		bucketName:             bucketName,
+		minioClient:            storage.Client(),
+		bucketName:             storage.BucketName(),
 	}
 }
 
@@ -409,6 +357,24 @@ type CreatePostRequest struct {
 	Distance  string   `json:"distance"`
 	Elevation string   `json:"elevation"`
 	ImageUrls []string `json:"image_urls"`
+	RouteID   *string  `json:"route_id"` // route the post is about, its thumbnail shows when there are no images
+}
+
+// routeThumbnail checks that the user may link the route to a post and
+// returns its current thumbnail
+func (pc *PostController) routeThumbnail(userID string, routeID *string) (string, bool) {
+	if routeID == nil {
+		return "", true
+	}
+
+	var route models.Route
+	if err := pc.db.Preload("Collaborators").First(&route, "id = ?", *routeID).Error; err != nil {
+		return "", false
+	}
+	if !route.IsAccessibleBy(userID) {
+		return "", false
+	}
+	return route.ThumbnailURL, true
 }
 
 func (pc *PostController) GetPosts(c *gin.Context) {
@@ -464,15 +430,23 @@ func (pc *PostController) CreatePost(c *gin.Context) {
 		return
 	}
 
+	thumbnailURL, ok := pc.routeThumbnail(userID, req.RouteID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Route not found"})
+		return
+	}
+
 	post := models.Post{
-		ID:        uuid.New().String(),
-		UserID:    userID,
-		Title:     req.Title,
-		Subtitle:  req.Subtitle,
-		Routes:    req.Routes,
-		Distance:  req.Distance,
-		Elevation: req.Elevation,
-		ImageUrls: models.StringSlice(req.ImageUrls),
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		Title:        req.Title,
+		Subtitle:     req.Subtitle,
+		Routes:       req.Routes,
+		Distance:     req.Distance,
+		Elevation:    req.Elevation,
+		ImageUrls:    models.StringSlice(req.ImageUrls),
+		RouteID:      req.RouteID,
+		ThumbnailURL: thumbnailURL,
 	}
 
 	if err := pc.db.Create(&post).Error; err != nil {
@@ -522,13 +496,21 @@ func (pc *PostController) UpdatePost(c *gin.Context) {
 		return
 	}
 
+	thumbnailURL, ok := pc.routeThumbnail(userID, req.RouteID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Route not found"})
+		return
+	}
+
 	updates := map[string]interface{}{
-		"title":      req.Title,
-		"subtitle":   req.Subtitle,
-		"routes":     req.Routes,
-		"distance":   req.Distance,
-		"elevation":  req.Elevation,
-		"image_urls": models.StringSlice(req.ImageUrls),
+		"title":         req.Title,
+		"subtitle":      req.Subtitle,
+		"routes":        req.Routes,
+		"distance":      req.Distance,
+		"elevation":     req.Elevation,
+		"image_urls":    models.StringSlice(req.ImageUrls),
+		"route_id":      req.RouteID,
+		"thumbnail_url": thumbnailURL,
 	}
 
 	if err := pc.db.Model(&post).Updates(updates).Error; err != nil {
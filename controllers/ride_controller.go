@@ -5,21 +5,32 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"math"
 	"motocosmos-api/models"
+	"motocosmos-api/services"
 	"net/http"
 	"time"
 )
 
 type RideController struct {
-	db *gorm.DB
+	db                     *gorm.DB
+	elevationService       *services.ElevationService
+	hazardService          *services.HazardService
+	notificationController *NotificationController
 }
 
-func NewRideController(db *gorm.DB) *RideController {
-	return &RideController{db: db}
+func NewRideController(db *gorm.DB, elevationService *services.ElevationService, notificationController *NotificationController) *RideController {
+	return &RideController{
+		db:                     db,
+		elevationService:       elevationService,
+		hazardService:          services.NewHazardService(db),
+		notificationController: notificationController,
+	}
 }
 
 type StartRideRequest struct {
 	MotorcycleID string `json:"motorcycle_id" binding:"required"`
+	RouteID      string `json:"route_id"` // planned route, riders are warned about hazards ahead on it
 }
 
 type RoutePointRequest struct {
@@ -59,6 +70,16 @@ func (rc *RideController) StartRide(c *gin.Context) {
 		return
 	}
 
+	var routeID *string
+	if req.RouteID != "" {
+		var route models.Route
+		if err := rc.db.Preload("Collaborators").First(&route, "id = ?", req.RouteID).Error; err != nil || !route.IsAccessibleBy(userID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+			return
+		}
+		routeID = &route.ID
+	}
+
 	// Check if user has an active ride
 	var activeRide models.RideRecord
 	if err := rc.db.Where("user_id = ? AND is_completed = ?", userID, false).First(&activeRide).Error; err == nil {
@@ -71,6 +92,7 @@ func (rc *RideController) StartRide(c *gin.Context) {
 		UserID:         userID,
 		MotorcycleID:   req.MotorcycleID,
 		MotorcycleName: motorcycle.Brand + " " + motorcycle.Model,
+		RouteID:        routeID,
 		StartTime:      time.Now(),
 		IsCompleted:    false,
 	}
@@ -124,6 +146,8 @@ func (rc *RideController) StopRide(c *gin.Context) {
 	endTime := time.Now()
 	duration := int(endTime.Sub(ride.StartTime).Seconds())
 
+	rc.fillAltitudes(ride.RoutePoints)
+
 	// Calculate statistics from route points
 	distance, maxSpeed, averageSpeed, maxAltitude, totalElevation := rc.calculateRideStatistics(ride.RoutePoints)
 
@@ -216,9 +240,32 @@ func (rc *RideController) AddRoutePoint(c *gin.Context) {
 		return
 	}
 
+	alertHazards(rc.hazardService, rc.notificationController, userID, req.Latitude, req.Longitude)
+
 	c.JSON(http.StatusCreated, routePoint)
 }
 
+// fillAltitudes takes the altitudes of a track recorded without any from the
+// elevation tiles. Tracks with device altitudes are left alone so the two
+// sources are not mixed.
+func (rc *RideController) fillAltitudes(points []models.RoutePoint) {
+	for _, point := range points {
+		if point.Altitude != nil {
+			return
+		}
+	}
+
+	for i := range points {
+		elevation, ok := rc.elevationService.Elevation(points[i].Latitude, points[i].Longitude)
+		if !ok {
+			continue
+		}
+		altitude := math.Round(elevation*10) / 10
+		points[i].Altitude = &altitude
+		rc.db.Model(&points[i]).UpdateColumn("altitude", altitude)
+	}
+}
+
 func (rc *RideController) calculateRideStatistics(routePoints []models.RoutePoint) (float64, float64, float64, float64, float64) {
 	if len(routePoints) < 2 {
 		return 0, 0, 0, 0, 0
@@ -2,22 +2,56 @@
 package controllers
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"io"
 	"math"
+	"mime"
 	"motocosmos-api/models"
+	"motocosmos-api/services"
 	"net/http"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 type RouteController struct {
-	db *gorm.DB
+	db                     *gorm.DB
+	routingEngine          services.RoutingEngine
+	elevationService       *services.ElevationService
+	spatialService         *services.SpatialService
+	loopGenerator          *services.LoopGenerator
+	optimizer              *services.WaypointOptimizer
+	revisionService        *services.RouteRevisionService
+	collaborationService   *services.RouteCollaborationService
+	poiService             *services.POIService
+	difficultyService      *services.DifficultyService
+	sharingService         *services.RouteSharingService
+	notificationController *NotificationController
 }
 
-func NewRouteController(db *gorm.DB) *RouteController {
-	return &RouteController{db: db}
+func NewRouteController(db *gorm.DB, routingEngine services.RoutingEngine, elevationService *services.ElevationService, notificationController *NotificationController) *RouteController {
+	difficultyService := services.NewDifficultyService(db, elevationService, routingEngine)
+	return &RouteController{
+		db:                     db,
+		routingEngine:          routingEngine,
+		elevationService:       elevationService,
+		spatialService:         services.NewSpatialService(db),
+		loopGenerator:          services.NewLoopGenerator(routingEngine),
+		optimizer:              services.NewWaypointOptimizer(routingEngine),
+		revisionService:        services.NewRouteRevisionService(db),
+		collaborationService:   services.NewRouteCollaborationService(db),
+		poiService:             services.NewPOIService(db),
+		difficultyService:      difficultyService,
+		sharingService:         services.NewRouteSharingService(db, difficultyService),
+		notificationController: notificationController,
+	}
 }
 
 type CreateRouteRequest struct {
@@ -34,6 +68,7 @@ type CreateRouteRequest struct {
 	EstimatedTime      int                     `json:"estimated_time"` // in seconds
 	AvoidHighways      bool                    `json:"avoid_highways"`
 	PreferWindingRoads bool                    `json:"prefer_winding_roads"`
+	Version            int                     `json:"version"` // version an update is based on, see routeVersion
 }
 
 type RouteWaypointRequestV struct {
@@ -42,6 +77,7 @@ type RouteWaypointRequestV struct {
 	Latitude    float64 `json:"latitude" binding:"required"`
 	Longitude   float64 `json:"longitude" binding:"required"`
 	Order       int     `json:"order" binding:"required"`
+	POIID       *string `json:"poi_id"` // the place the waypoint stops at
 }
 
 // GetRoutes returns user's personal routes with pagination and filtering
@@ -63,12 +99,29 @@ func (rc *RouteController) GetRoutes(c *gin.Context) {
 	if difficulty := c.Query("difficulty"); difficulty != "" {
 		query = query.Where("difficulty = ?", difficulty)
 	}
+	if difficulty := c.Query("computed_difficulty"); difficulty != "" {
+		query = query.Where("computed_difficulty = ?", difficulty)
+	}
+
+	query = filterByTwistiness(c, query)
+
+	spatialFilter, err := services.ParseSpatialFilter(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query = rc.spatialService.FilterRoutes(query, models.RouteGeoCellOwnerRoute, spatialFilter)
 
 	// Get total count
 	query.Model(&models.Route{}).Count(&total)
 
+	order := "created_at DESC"
+	if c.Query("sort") == "twistiness" {
+		order = "twistiness_score DESC, created_at DESC"
+	}
+
 	// Get paginated results
-	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&routes).Error; err != nil {
+	if err := query.Order(order).Offset(offset).Limit(limit).Find(&routes).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch routes"})
 		return
 	}
@@ -95,6 +148,9 @@ func (rc *RouteController) CreateRoute(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !rc.validWaypointPOIs(c, req.Waypoints) {
+		return
+	}
 
 	// Calculate total distance if not provided
 	totalDistance := req.TotalDistance
@@ -108,6 +164,9 @@ func (rc *RouteController) CreateRoute(c *gin.Context) {
 		estimatedTime = int(totalDistance * 60) // Rough estimate: 1 minute per km
 	}
 
+	points := rc.requestPoints(req)
+	totalElevation := rc.totalElevation(req.TotalElevation, points)
+
 	// Create route settings
 	routeSettings := map[string]interface{}{
 		"avoid_highways":       req.AvoidHighways,
@@ -127,15 +186,19 @@ func (rc *RouteController) CreateRoute(c *gin.Context) {
 		Name:           req.Name,
 		Description:    req.Description,
 		TotalDistance:  totalDistance,
-		TotalElevation: req.TotalElevation,
+		TotalElevation: totalElevation,
 		EstimatedTime:  estimatedTime,
 		Difficulty:     req.Difficulty,
 		Tags:           models.StringSlice(req.Tags),
 		IsPublic:       req.IsPublic,
-		RouteGeometry:  rc.convertGeometryToJSONData(req.RouteGeometry), // ⭐ JAVÍTOTT: Új metódus
+		RouteGeometry:  requestGeometry(req.RouteGeometry),
 		RouteSettings:  models.JSONData(routeSettings),
 	}
 
+	curvature := services.AnalyzeCurvature(points)
+	route.Curvature = &curvature
+	route.TwistinessScore = curvature.Score
+
 	if err := rc.db.Create(&route).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create route"})
 		return
@@ -150,6 +213,7 @@ func (rc *RouteController) CreateRoute(c *gin.Context) {
 			Latitude:    wp.Latitude,
 			Longitude:   wp.Longitude,
 			Order:       wp.Order,
+			POIID:       wp.POIID,
 		}
 		if err := rc.db.Create(&waypoint).Error; err != nil {
 			// Log error but continue
@@ -157,6 +221,14 @@ func (rc *RouteController) CreateRoute(c *gin.Context) {
 		}
 	}
 
+	if err := rc.spatialService.IndexRoute(models.RouteGeoCellOwnerRoute, route.ID, points); err != nil {
+		fmt.Printf("Warning: Could not index route location: %v\n", err)
+	}
+	if err := rc.difficultyService.ClassifyRoute(route.ID); err != nil {
+		fmt.Printf("Warning: Could not classify route difficulty: %v\n", err)
+	}
+	rc.recordRevision(route.ID, userID, models.RouteRevisionCreated)
+
 	// Load the complete route with waypoints
 	rc.db.Preload("Waypoints").First(&route, "id = ?", route.ID)
 
@@ -169,13 +241,13 @@ func (rc *RouteController) GetRoute(c *gin.Context) {
 	routeID := c.Param("id")
 
 	var route models.Route
-	if err := rc.db.Preload("Waypoints").Preload("User").First(&route, "id = ?", routeID).Error; err != nil {
+	if err := rc.db.Preload("Waypoints.POI").Preload("User").Preload("Collaborators.User").First(&route, "id = ?", routeID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
 		return
 	}
 
-	// Check if user owns this route or if it's public
-	if route.UserID != userID && !route.IsPublic {
+	// Check if user owns this route, plans it together with the owner, or if it's public
+	if !route.IsAccessibleBy(userID) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
@@ -189,14 +261,13 @@ func (rc *RouteController) GetRoute(c *gin.Context) {
 	c.JSON(http.StatusOK, route)
 }
 
-// UpdateRoute updates an existing route (owner only)
+// UpdateRoute updates an existing route (owner and editors)
 func (rc *RouteController) UpdateRoute(c *gin.Context) {
 	userID := c.GetString("user_id")
 	routeID := c.Param("id")
 
-	var route models.Route
-	if err := rc.db.First(&route, "id = ? AND user_id = ?", routeID, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found or access denied"})
+	route, ok := rc.editableRoute(c, routeID)
+	if !ok {
 		return
 	}
 
@@ -206,6 +277,24 @@ func (rc *RouteController) UpdateRoute(c *gin.Context) {
 		return
 	}
 
+	expectedVersion, ok := routeVersion(c, route, req.Version)
+	if !ok {
+		return
+	}
+	if !rc.validWaypointPOIs(c, req.Waypoints) {
+		return
+	}
+
+	// Only the owner decides who can see the route
+	isPublic := route.IsPublic
+	if route.UserID == userID {
+		isPublic = req.IsPublic
+	}
+
+	if err := rc.revisionService.EnsureBaseline(routeID); err != nil {
+		fmt.Printf("Warning: Could not save route baseline revision: %v\n", err)
+	}
+
 	// Calculate total distance if not provided
 	totalDistance := req.TotalDistance
 	if totalDistance == 0 {
@@ -231,51 +320,816 @@ func (rc *RouteController) UpdateRoute(c *gin.Context) {
 		}
 	}
 
+	points := rc.requestPoints(req)
+	curvature := services.AnalyzeCurvature(points)
+
 	// Update route
 	updates := map[string]interface{}{
-		"name":            req.Name,
-		"description":     req.Description,
-		"total_distance":  totalDistance,
-		"total_elevation": req.TotalElevation,
-		"estimated_time":  estimatedTime,
-		"difficulty":      req.Difficulty,
-		"tags":            models.StringSlice(req.Tags),
-		"is_public":       req.IsPublic,
-		"route_geometry":  rc.convertGeometryToJSONData(req.RouteGeometry), // ⭐ JAVÍTOTT: Új metódus
-		"route_settings":  models.JSONData(routeSettings),
+		"name":             req.Name,
+		"description":      req.Description,
+		"total_distance":   totalDistance,
+		"total_elevation":  rc.totalElevation(req.TotalElevation, points),
+		"estimated_time":   estimatedTime,
+		"difficulty":       req.Difficulty,
+		"tags":             models.StringSlice(req.Tags),
+		"is_public":        isPublic,
+		"route_geometry":   requestGeometry(req.RouteGeometry),
+		"route_settings":   models.JSONData(routeSettings),
+		"curvature":        &curvature,
+		"twistiness_score": curvature.Score,
 	}
 
-	if err := rc.db.Model(&route).Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update route"})
+	err := rc.db.Transaction(func(tx *gorm.DB) error {
+		if err := services.ClaimVersion(tx, routeID, expectedVersion); err != nil {
+			return err
+		}
+		if err := tx.Model(route).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		// Delete old waypoints and create new ones
+		if err := tx.Where("route_id = ?", routeID).Delete(&models.RouteWaypoint{}).Error; err != nil {
+			return err
+		}
+		for _, wp := range req.Waypoints {
+			waypoint := models.RouteWaypoint{
+				RouteID:     routeID,
+				Name:        wp.Name,
+				Description: wp.Description,
+				Latitude:    wp.Latitude,
+				Longitude:   wp.Longitude,
+				Order:       wp.Order,
+				POIID:       wp.POIID,
+			}
+			if err := tx.Create(&waypoint).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		rc.respondRouteChangeError(c, routeID, err, "Failed to update route")
 		return
 	}
 
-	// Delete old waypoints and create new ones
-	rc.db.Where("route_id = ?", routeID).Delete(&models.RouteWaypoint{})
-	for _, wp := range req.Waypoints {
-		waypoint := models.RouteWaypoint{
-			RouteID:     routeID,
-			Name:        wp.Name,
-			Description: wp.Description,
-			Latitude:    wp.Latitude,
-			Longitude:   wp.Longitude,
-			Order:       wp.Order,
-		}
-		rc.db.Create(&waypoint)
+	if err := rc.spatialService.IndexRoute(models.RouteGeoCellOwnerRoute, routeID, points); err != nil {
+		fmt.Printf("Warning: Could not index route location: %v\n", err)
 	}
+	if err := rc.difficultyService.ClassifyRoute(routeID); err != nil {
+		fmt.Printf("Warning: Could not classify route difficulty: %v\n", err)
+	}
+	// Publishing is left to POST and DELETE /routes/:id/publish
+	rc.syncSharedRoute(routeID)
+	rc.recordRevision(routeID, userID, models.RouteRevisionUpdated)
+	rc.notifyCollaborators(routeID, userID)
 
 	// Return updated route
-	rc.db.Preload("Waypoints").First(&route, "id = ?", routeID)
+	rc.db.Preload("Waypoints").Preload("Collaborators").First(route, "id = ?", routeID)
 	c.JSON(http.StatusOK, route)
 }
 
-// ⭐ ÚJ METÓDUS: Route geometry konvertálása JSONData formátumba
-func (rc *RouteController) convertGeometryToJSONData(geometry []map[string]float64) models.JSONData {
-	result := make(models.JSONData)
-	for i, point := range geometry {
-		result[fmt.Sprintf("%d", i)] = point
+// editableRoute loads a route the current user may change, answering 404 or 403 otherwise
+func (rc *RouteController) editableRoute(c *gin.Context, routeID string) (*models.Route, bool) {
+	route, err := rc.collaborationService.LoadRoute(routeID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		return nil, false
 	}
-	return result
+	if !route.CanBeEditedBy(c.GetString("user_id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, false
+	}
+	return route, true
+}
+
+// routeVersion returns the version a change is based on. It may be left out
+// while the owner plans alone; once friends plan the route too it is required,
+// so one rider's edit cannot silently overwrite another's.
+func routeVersion(c *gin.Context, route *models.Route, sent int) (int, bool) {
+	if sent == 0 && len(route.Collaborators) > 0 {
+		c.JSON(http.StatusPreconditionRequired, gin.H{
+			"error":   "version is required for routes planned together",
+			"version": route.Version,
+		})
+		return 0, false
+	}
+	return sent, true
+}
+
+// respondRouteChangeError answers a failed route change, with the current
+// version when someone else changed the route first
+func (rc *RouteController) respondRouteChangeError(c *gin.Context, routeID string, err error, message string) {
+	if errors.Is(err, services.ErrRouteVersionConflict) {
+		var current models.Route
+		rc.db.Select("id", "version").First(&current, "id = ?", routeID)
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "version": current.Version})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
+
+// notifyCollaborators tells the owner and collaborators that a route changed
+// (don't fail the request if notifications fail)
+func (rc *RouteController) notifyCollaborators(routeID, userID string) {
+	for _, memberID := range rc.collaborationService.MemberIDs(routeID, userID) {
+		if err := rc.notificationController.CreateRouteUpdateNotification(userID, memberID, routeID); err != nil {
+			fmt.Printf("Failed to create route update notification: %v\n", err)
+		}
+	}
+}
+
+// validWaypointPOIs checks the POIs the waypoints stop at, answering 400 for unknown ones
+func (rc *RouteController) validWaypointPOIs(c *gin.Context, waypoints []RouteWaypointRequestV) bool {
+	var ids []string
+	for i, wp := range waypoints {
+		if wp.POIID != nil && *wp.POIID == "" {
+			waypoints[i].POIID = nil
+		} else if wp.POIID != nil {
+			ids = append(ids, *wp.POIID)
+		}
+	}
+	if err := rc.poiService.ValidateIDs(c.GetString("user_id"), ids); err != nil {
+		if errors.Is(err, services.ErrPOINotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown point of interest on a waypoint"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check points of interest"})
+		}
+		return false
+	}
+	return true
+}
+
+// requestGeometry keeps the submitted geometry points in their order
+func requestGeometry(points []map[string]float64) models.Geometry {
+	geometry := make(models.Geometry, 0, len(points))
+	for _, point := range points {
+		p := models.GeometryPoint{Latitude: point["latitude"], Longitude: point["longitude"]}
+		if elevation, ok := point["elevation"]; ok {
+			p.Elevation = &elevation
+		}
+		geometry = append(geometry, p)
+	}
+	return geometry
+}
+
+// requestPoints returns the submitted geometry, or the waypoints when no geometry is sent
+func (rc *RouteController) requestPoints(req CreateRouteRequest) []models.LatLng {
+	points := requestGeometry(req.RouteGeometry).LatLngs()
+
+	if len(points) < 2 {
+		waypoints := append([]RouteWaypointRequestV(nil), req.Waypoints...)
+		sort.SliceStable(waypoints, func(i, j int) bool { return waypoints[i].Order < waypoints[j].Order })
+
+		points = points[:0]
+		for _, wp := range waypoints {
+			points = append(points, models.LatLng{Latitude: wp.Latitude, Longitude: wp.Longitude})
+		}
+	}
+
+	return points
+}
+
+// totalElevation keeps the climbing sent by the client, or measures it on the
+// elevation tiles when the client has no altitude data
+func (rc *RouteController) totalElevation(sent float64, points []models.LatLng) float64 {
+	if sent != 0 {
+		return sent
+	}
+
+	profile, err := rc.elevationService.Profile(points)
+	if err != nil {
+		return 0
+	}
+	return profile.TotalAscent
+}
+
+// GetRouteElevation returns the elevation profile of a route
+func (rc *RouteController) GetRouteElevation(c *gin.Context) {
+	userID := c.GetString("user_id")
+	routeID := c.Param("id")
+
+	var route models.Route
+	if err := rc.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).Preload("Collaborators").First(&route, "id = ?", routeID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		return
+	}
+
+	if !route.IsAccessibleBy(userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	points := route.GetRouteGeometryAsLatLng()
+	if len(points) < 2 {
+		points = route.GetWaypointsAsLatLng()
+	}
+	respondElevationProfile(c, rc.elevationService, points)
+}
+
+// ProfileElevation returns the elevation profile of a submitted path
+func (rc *RouteController) ProfileElevation(c *gin.Context) {
+	var req struct {
+		Points []models.LatLng `json:"points" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondElevationProfile(c, rc.elevationService, req.Points)
+}
+
+func respondElevationProfile(c *gin.Context, elevationService *services.ElevationService, points []models.LatLng) {
+	profile, err := elevationService.Profile(points)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrElevationUnavailable):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// ImportRoute creates a route from an uploaded GPX, KML or GeoJSON file (form
+// field "file"); the format comes from ?format= or the file extension
+func (rc *RouteController) ImportRoute(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	upload, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file provided"})
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		format, _ = services.RouteFileFormat(upload.Filename)
+	}
+	if !services.IsValidRouteFileFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only .gpx, .kml and .geojson files are supported"})
+		return
+	}
+
+	src, err := upload.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer src.Close()
+
+	file, err := services.ParseRouteFile(src, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := c.PostForm("name")
+	if name == "" {
+		name = file.Name
+	}
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(upload.Filename), filepath.Ext(upload.Filename))
+	}
+
+	points := file.Track.LatLngs()
+	totalDistance := math.Round(services.PathLengthKm(points)*100) / 100
+	totalElevation := services.GeometryAscent(file.Track)
+	if totalElevation == 0 {
+		totalElevation = rc.totalElevation(0, points)
+	}
+	curvature := services.AnalyzeCurvature(points)
+
+	route := models.Route{
+		ID:              uuid.New().String(),
+		UserID:          userID,
+		Name:            name,
+		Description:     file.Description,
+		TotalDistance:   totalDistance,
+		TotalElevation:  totalElevation,
+		EstimatedTime:   int(totalDistance * 60),
+		Tags:            models.StringSlice{},
+		RouteGeometry:   file.Track,
+		RouteSettings:   models.JSONData{"profile": "driving", "imported_from": format},
+		Curvature:       &curvature,
+		TwistinessScore: curvature.Score,
+	}
+
+	err = rc.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&route).Error; err != nil {
+			return err
+		}
+		for i, wp := range file.Waypoints {
+			waypoint := models.RouteWaypoint{
+				RouteID:     route.ID,
+				Name:        wp.Name,
+				Description: wp.Description,
+				Latitude:    wp.Latitude,
+				Longitude:   wp.Longitude,
+				Order:       i + 1,
+			}
+			if err := tx.Create(&waypoint).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import route"})
+		return
+	}
+
+	if err := rc.spatialService.IndexRoute(models.RouteGeoCellOwnerRoute, route.ID, points); err != nil {
+		fmt.Printf("Warning: Could not index route location: %v\n", err)
+	}
+	if err := rc.difficultyService.ClassifyRoute(route.ID); err != nil {
+		fmt.Printf("Warning: Could not classify route difficulty: %v\n", err)
+	}
+	rc.recordRevision(route.ID, userID, models.RouteRevisionImported)
+
+	rc.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).First(&route, "id = ?", route.ID)
+
+	c.JSON(http.StatusCreated, route)
+}
+
+// ExportRoute downloads a route as GPX, KML or GeoJSON (?format=, default gpx)
+func (rc *RouteController) ExportRoute(c *gin.Context) {
+	userID := c.GetString("user_id")
+	routeID := c.Param("id")
+
+	format, ok := routeFileFormat(c)
+	if !ok {
+		return
+	}
+
+	var route models.Route
+	if err := rc.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).Preload("Collaborators").First(&route, "id = ?", routeID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		return
+	}
+
+	if !route.IsAccessibleBy(userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	sendRouteFile(c, format, services.RouteFileFromRoute(&route))
+}
+
+// routeFileFormat reads the ?format= export parameter, answering 400 when it is not supported
+func routeFileFormat(c *gin.Context) (string, bool) {
+	format := strings.ToLower(c.DefaultQuery("format", services.RouteFileGPX))
+	if !services.IsValidRouteFileFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be gpx, kml or geojson"})
+		return "", false
+	}
+	return format, true
+}
+
+// sendRouteFile writes a route file as an attachment
+func sendRouteFile(c *gin.Context, format string, file *services.RouteFile) {
+	var buf bytes.Buffer
+	if err := services.WriteRouteFile(&buf, format, file); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write route file"})
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": services.RouteFileName(file.Name, format)}))
+	c.Data(http.StatusOK, services.RouteFileContentType(format), buf.Bytes())
+}
+
+// recordRevision saves the current state of a route as a new revision
+func (rc *RouteController) recordRevision(routeID, userID, action string) {
+	if _, err := rc.revisionService.Record(routeID, userID, action); err != nil {
+		fmt.Printf("Warning: Could not save route revision: %v\n", err)
+	}
+}
+
+// revisionRoute loads a route for its revision endpoints, answering 404 or 403 when it cannot be used
+func (rc *RouteController) revisionRoute(c *gin.Context, edit bool) (*models.Route, bool) {
+	userID := c.GetString("user_id")
+
+	var route models.Route
+	if err := rc.db.Preload("Collaborators").First(&route, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		return nil, false
+	}
+
+	allowed := route.IsAccessibleBy(userID)
+	if edit {
+		allowed = route.CanBeEditedBy(userID)
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, false
+	}
+	return &route, true
+}
+
+// revisionNumber reads the :number path parameter
+func revisionNumber(c *gin.Context) (int, bool) {
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil || number < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return 0, false
+	}
+	return number, true
+}
+
+// respondRevisionError maps revision service errors to HTTP statuses
+func respondRevisionError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrRevisionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load route revisions"})
+}
+
+// GetRouteRevisions lists the revisions of a route, newest first
+func (rc *RouteController) GetRouteRevisions(c *gin.Context) {
+	route, ok := rc.revisionRoute(c, false)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	revisions, total, err := rc.revisionService.List(route.ID, limit, (page-1)*limit)
+	if err != nil {
+		respondRevisionError(c, err)
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+	c.JSON(http.StatusOK, gin.H{
+		"revisions":   revisions,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"has_more":    page < totalPages,
+		"total_pages": totalPages,
+	})
+}
+
+// GetRouteRevision returns one revision of a route with its geometry and waypoints
+func (rc *RouteController) GetRouteRevision(c *gin.Context) {
+	route, ok := rc.revisionRoute(c, false)
+	if !ok {
+		return
+	}
+	number, ok := revisionNumber(c)
+	if !ok {
+		return
+	}
+
+	revision, err := rc.revisionService.Get(route.ID, number)
+	if err != nil {
+		respondRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+// DiffRouteRevision compares a revision with the one before it, or with ?against=
+func (rc *RouteController) DiffRouteRevision(c *gin.Context) {
+	route, ok := rc.revisionRoute(c, false)
+	if !ok {
+		return
+	}
+	number, ok := revisionNumber(c)
+	if !ok {
+		return
+	}
+
+	against := number - 1
+	if value := c.Query("against"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+			return
+		}
+		against = parsed
+	}
+
+	diff, err := rc.revisionService.Diff(route.ID, against, number)
+	if err != nil {
+		respondRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// RevertRoute restores a route to an earlier revision (owner and editors)
+func (rc *RouteController) RevertRoute(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	route, ok := rc.revisionRoute(c, true)
+	if !ok {
+		return
+	}
+	number, ok := revisionNumber(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Version int `json:"version"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	expectedVersion, ok := routeVersion(c, route, req.Version)
+	if !ok {
+		return
+	}
+
+	revision, err := rc.revisionService.Revert(route.ID, number, userID, expectedVersion)
+	if err != nil {
+		if errors.Is(err, services.ErrRevisionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		rc.respondRouteChangeError(c, route.ID, err, "Failed to revert route")
+		return
+	}
+	rc.notifyCollaborators(route.ID, userID)
+	if err := rc.difficultyService.ClassifyRoute(route.ID); err != nil {
+		fmt.Printf("Warning: Could not classify route difficulty: %v\n", err)
+	}
+	rc.syncSharedRoute(route.ID)
+
+	rc.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).First(route, "id = ?", route.ID)
+
+	if err := rc.spatialService.IndexRoute(models.RouteGeoCellOwnerRoute, route.ID, services.RoutePath(route)); err != nil {
+		fmt.Printf("Warning: Could not index route location: %v\n", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"route":    route,
+		"revision": revision,
+	})
+}
+
+// GetRouteCollaborators lists the friends planning a route with its owner
+func (rc *RouteController) GetRouteCollaborators(c *gin.Context) {
+	route, err := rc.collaborationService.LoadRoute(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		return
+	}
+	if !route.IsAccessibleBy(c.GetString("user_id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	collaborators, err := rc.collaborationService.List(route.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch collaborators"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"owner_id":      route.UserID,
+		"collaborators": collaborators,
+	})
+}
+
+// InviteRouteCollaborator invites a friend as editor or viewer, or changes
+// the role of a collaborator (owner only)
+func (rc *RouteController) InviteRouteCollaborator(c *gin.Context) {
+	userID := c.GetString("user_id")
+	routeID := c.Param("id")
+
+	var req struct {
+		UserID string `json:"user_id" binding:"required"`
+		Role   string `json:"role" binding:"required"` // editor, viewer
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	collaborator, created, err := rc.collaborationService.Invite(userID, routeID, req.UserID, req.Role)
+	if err != nil {
+		respondCollaborationError(c, err, "Failed to invite collaborator")
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+		if err := rc.notificationController.CreateRouteInviteNotification(userID, req.UserID, routeID); err != nil {
+			fmt.Printf("Failed to create route invite notification: %v\n", err)
+		}
+	}
+
+	c.JSON(status, collaborator)
+}
+
+// RemoveRouteCollaborator removes a collaborator (owner), or leaves a route (the collaborator)
+func (rc *RouteController) RemoveRouteCollaborator(c *gin.Context) {
+	if err := rc.collaborationService.Remove(c.GetString("user_id"), c.Param("id"), c.Param("user_id")); err != nil {
+		respondCollaborationError(c, err, "Failed to remove collaborator")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Collaborator removed"})
+}
+
+// GetSharedWithMeRoutes returns the routes friends invited the user to plan
+func (rc *RouteController) GetSharedWithMeRoutes(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	routes, total, err := rc.collaborationService.SharedWith(c.GetString("user_id"), limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch routes"})
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+	c.JSON(http.StatusOK, gin.H{
+		"routes":      routes,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"has_more":    page < totalPages,
+		"total_pages": totalPages,
+	})
+}
+
+// respondCollaborationError maps collaboration service errors to HTTP statuses
+func respondCollaborationError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrRouteNotFound), errors.Is(err, services.ErrCollaboratorNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotRouteOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCollaboratorNotFriend), errors.Is(err, services.ErrInvalidCollaboratorRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// PublishRoute publishes a route as a shared route, or refreshes the shared
+// route it is published as (owner only). Later edits of the route are
+// mirrored into the shared route until it is unpublished.
+func (rc *RouteController) PublishRoute(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req struct {
+		ImageUrls []string `json:"image_urls"` // left out to keep the current images
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var publisher models.User
+	if err := rc.db.First(&publisher, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	shared, created, err := rc.sharingService.Publish(userID, c.Param("id"), services.PublishInput{
+		ImageUrls:     req.ImageUrls,
+		CreatorName:   publisher.Name,
+		CreatorAvatar: getInitials(publisher.Name),
+	})
+	if err != nil {
+		respondSharingError(c, err, "Failed to publish route")
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, shared)
+}
+
+// UnpublishRoute removes the shared route a route is published as and makes
+// the route private (owner only)
+func (rc *RouteController) UnpublishRoute(c *gin.Context) {
+	if err := rc.sharingService.Unpublish(c.GetString("user_id"), c.Param("id")); err != nil {
+		respondSharingError(c, err, "Failed to unpublish route")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Route unpublished successfully"})
+}
+
+// syncSharedRoute mirrors a changed route into the shared route it is
+// published as (don't fail the request if that fails)
+func (rc *RouteController) syncSharedRoute(routeID string) {
+	if err := rc.sharingService.Sync(routeID); err != nil {
+		fmt.Printf("Warning: Could not sync shared route: %v\n", err)
+	}
+}
+
+func respondSharingError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrRouteNotFound), errors.Is(err, services.ErrSharedRouteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotRoutePublisher):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSharedRouteSynced), errors.Is(err, services.ErrRouteNotPublished):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrRouteGeometryMissing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// SetWaypointPOI attaches a POI to a waypoint of a route, or detaches it when
+// poi_id is empty (owner and editors)
+func (rc *RouteController) SetWaypointPOI(c *gin.Context) {
+	userID := c.GetString("user_id")
+	routeID := c.Param("id")
+
+	route, ok := rc.editableRoute(c, routeID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		POIID   string `json:"poi_id"`
+		Version int    `json:"version"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	expectedVersion, ok := routeVersion(c, route, req.Version)
+	if !ok {
+		return
+	}
+
+	var waypoint models.RouteWaypoint
+	if err := rc.db.First(&waypoint, "id = ? AND route_id = ?", c.Param("waypoint_id"), routeID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Waypoint not found"})
+		return
+	}
+
+	var poiID *string
+	if req.POIID != "" {
+		if _, err := rc.poiService.Get(req.POIID, userID); err != nil {
+			respondPOIError(c, err, "Failed to attach point of interest")
+			return
+		}
+		poiID = &req.POIID
+	}
+
+	if err := rc.revisionService.EnsureBaseline(routeID); err != nil {
+		fmt.Printf("Warning: Could not save route baseline revision: %v\n", err)
+	}
+
+	err := rc.db.Transaction(func(tx *gorm.DB) error {
+		if err := services.ClaimVersion(tx, routeID, expectedVersion); err != nil {
+			return err
+		}
+		return tx.Model(&waypoint).Update("poi_id", poiID).Error
+	})
+	if err != nil {
+		rc.respondRouteChangeError(c, routeID, err, "Failed to update waypoint")
+		return
+	}
+
+	rc.recordRevision(routeID, userID, models.RouteRevisionUpdated)
+	rc.notifyCollaborators(routeID, userID)
+
+	rc.db.Preload("POI").First(&waypoint, "id = ?", waypoint.ID)
+	c.JSON(http.StatusOK, waypoint)
 }
 
 // GetSavedRoutes returns routes that the user has saved (their own routes)
@@ -418,13 +1272,15 @@ func (rc *RouteController) GetRecommendations(c *gin.Context) {
 	c.JSON(http.StatusOK, routes)
 }
 
-// PlanRoute - Mock implementation for route planning (would use Mapbox API in production)
+// PlanRoute plans a route through the waypoints with the configured routing engine
 func (rc *RouteController) PlanRoute(c *gin.Context) {
 	var req struct {
 		Waypoints     []RouteWaypointRequestV `json:"waypoints" binding:"required"`
 		AvoidHighways bool                    `json:"avoid_highways"`
 		PreferWinding bool                    `json:"prefer_winding"`
 		Profile       string                  `json:"profile"`
+		Optimize      string                  `json:"optimize"` // fixed_ends or round_trip to reorder the stops
+		Language      string                  `json:"language"` // instruction language, defaults to Accept-Language
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -432,29 +1288,225 @@ func (rc *RouteController) PlanRoute(c *gin.Context) {
 		return
 	}
 
-	// In a real implementation, this would call Mapbox API
-	totalDistance := rc.calculateTotalDistance(req.Waypoints)
+	language := services.AcceptedInstructionLanguage(c.GetHeader("Accept-Language"))
+	if req.Language != "" {
+		var ok bool
+		if language, ok = services.InstructionLanguage(req.Language); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Unsupported language, use one of: " + strings.Join(services.InstructionLanguages(), ", "),
+			})
+			return
+		}
+	}
 
-	// Mock geometry - in production this would come from Mapbox
-	geometry := make([]map[string]float64, 0, len(req.Waypoints))
+	planRequest := models.RoutePlanRequest{
+		Waypoints:     make([]models.LatLng, 0, len(req.Waypoints)),
+		AvoidHighways: req.AvoidHighways,
+		PreferWinding: req.PreferWinding,
+		Profile:       req.Profile,
+	}
 	for _, wp := range req.Waypoints {
-		geometry = append(geometry, map[string]float64{
-			"latitude":  wp.Latitude,
-			"longitude": wp.Longitude,
-		})
+		planRequest.Waypoints = append(planRequest.Waypoints, models.LatLng{Latitude: wp.Latitude, Longitude: wp.Longitude})
+	}
+
+	var optimization *models.WaypointOptimization
+	if req.Optimize != "" {
+		var err error
+		optimization, planRequest.Waypoints, err = rc.optimizer.Optimize(c.Request.Context(), planRequest, req.Optimize)
+		if err != nil {
+			respondRoutingError(c, err)
+			return
+		}
 	}
 
-	response := gin.H{
-		"geometry": geometry,
-		"distance": totalDistance * 1000, // in meters
-		"duration": totalDistance * 60,   // rough estimate in seconds
-		"summary":  "Planned route",
-		"steps":    []gin.H{}, // Would contain turn-by-turn instructions
+	plan, err := rc.routingEngine.Route(c.Request.Context(), planRequest)
+	if err != nil {
+		respondRoutingError(c, err)
+		return
 	}
 
+	// Cached plans are shared between requests, so annotate a copy
+	response := *plan
+	curvature := services.AnalyzeCurvature(plan.Geometry)
+	response.Curvature = &curvature
+	response.Optimization = optimization
+	response.Steps = services.LocalizeInstructions(plan.Steps, language)
+
 	c.JSON(http.StatusOK, response)
 }
 
+// OptimizeRoute reorders the stops of a saved route for the shortest ride,
+// renumbers its waypoints and replans its geometry
+func (rc *RouteController) OptimizeRoute(c *gin.Context) {
+	userID := c.GetString("user_id")
+	routeID := c.Param("id")
+
+	var req struct {
+		Mode    string `json:"mode"` // fixed_ends (default) or round_trip
+		Version int    `json:"version"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = services.WaypointOrderFixedEnds
+	}
+
+	var route models.Route
+	if err := rc.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).Preload("Collaborators").First(&route, "id = ?", routeID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		return
+	}
+
+	if !route.CanBeEditedBy(userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	expectedVersion, ok := routeVersion(c, &route, req.Version)
+	if !ok {
+		return
+	}
+
+	planRequest := models.RoutePlanRequest{
+		Waypoints:     route.GetWaypointsAsLatLng(),
+		AvoidHighways: route.GetAvoidHighways(),
+		PreferWinding: route.GetPreferWindingRoads(),
+	}
+
+	optimization, waypoints, err := rc.optimizer.Optimize(c.Request.Context(), planRequest, req.Mode)
+	if err != nil {
+		respondRoutingError(c, err)
+		return
+	}
+
+	planRequest.Waypoints = waypoints
+	plan, err := rc.routingEngine.Route(c.Request.Context(), planRequest)
+	if err != nil {
+		respondRoutingError(c, err)
+		return
+	}
+
+	if err := rc.revisionService.EnsureBaseline(routeID); err != nil {
+		fmt.Printf("Warning: Could not save route baseline revision: %v\n", err)
+	}
+
+	curvature := services.AnalyzeCurvature(plan.Geometry)
+	err = rc.db.Transaction(func(tx *gorm.DB) error {
+		if err := services.ClaimVersion(tx, routeID, expectedVersion); err != nil {
+			return err
+		}
+
+		// A round trip's closing waypoint is left out of the order and stays last
+		for position, index := range optimization.Order {
+			if err := tx.Model(&models.RouteWaypoint{}).Where("id = ?", route.Waypoints[index].ID).
+				UpdateColumn("order", position+1).Error; err != nil {
+				return err
+			}
+		}
+		if closing := len(optimization.Order); closing < len(route.Waypoints) {
+			if err := tx.Model(&models.RouteWaypoint{}).Where("id = ?", route.Waypoints[closing].ID).
+				UpdateColumn("order", closing+1).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&route).Updates(map[string]interface{}{
+			"route_geometry":   models.GeometryFromLatLngs(plan.Geometry),
+			"total_distance":   math.Round(plan.Distance/10) / 100,
+			"estimated_time":   int(plan.Duration),
+			"curvature":        &curvature,
+			"twistiness_score": curvature.Score,
+		}).Error
+	})
+	if err != nil {
+		rc.respondRouteChangeError(c, routeID, err, "Failed to save optimized route")
+		return
+	}
+
+	if err := rc.spatialService.IndexRoute(models.RouteGeoCellOwnerRoute, routeID, plan.Geometry); err != nil {
+		fmt.Printf("Warning: Could not index route location: %v\n", err)
+	}
+	if err := rc.difficultyService.ClassifyRoute(routeID); err != nil {
+		fmt.Printf("Warning: Could not classify route difficulty: %v\n", err)
+	}
+	rc.syncSharedRoute(routeID)
+	rc.recordRevision(routeID, userID, models.RouteRevisionOptimized)
+	rc.notifyCollaborators(routeID, userID)
+
+	rc.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).First(&route, "id = ?", routeID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"route":        route,
+		"optimization": optimization,
+	})
+}
+
+// respondRoutingError maps routing engine errors to HTTP statuses
+func respondRoutingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRoutingUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoRouteFound), errors.Is(err, services.ErrNoRoadNearby):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Route planning timed out"})
+	case errors.Is(err, services.ErrMapboxRequestFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Routing service is unavailable, please try again later"})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// GenerateLoops suggests round trips of about the requested distance from a
+// start point; a candidate can be saved with POST /routes/
+func (rc *RouteController) GenerateLoops(c *gin.Context) {
+	var req struct {
+		Start         models.LatLng `json:"start" binding:"required"`
+		Distance      float64       `json:"distance" binding:"required"` // km
+		Direction     string        `json:"direction"`                   // n, ne, ..., a bearing in degrees, or any
+		PreferWinding bool          `json:"prefer_winding"`
+		AvoidHighways bool          `json:"avoid_highways"`
+		Profile       string        `json:"profile"`
+		Candidates    int           `json:"candidates"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bearing, err := services.ParseLoopDirection(req.Direction)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loops, err := rc.loopGenerator.Generate(c.Request.Context(), services.LoopRequest{
+		Start:         req.Start,
+		DistanceKm:    req.Distance,
+		Bearing:       bearing,
+		PreferWinding: req.PreferWinding,
+		AvoidHighways: req.AvoidHighways,
+		Profile:       req.Profile,
+		Candidates:    req.Candidates,
+	})
+	if err != nil {
+		respondRoutingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"target_distance": req.Distance,
+		"candidates":      loops,
+	})
+}
+
 // CalculateMetrics calculates distance and time between two points
 func (rc *RouteController) CalculateMetrics(c *gin.Context) {
 	var req struct {
@@ -483,15 +1535,6 @@ type LatLng struct {
 	Longitude float64 `json:"longitude"`
 }
 
-// ⭐ DEPRECATED: Az eredeti convertToJSONData függvény
-func convertToJSONData(geometry []map[string]float64) models.JSONData {
-	result := make(models.JSONData)
-	for i, point := range geometry {
-		result[fmt.Sprintf("%d", i)] = point
-	}
-	return result
-}
-
 func (rc *RouteController) calculateTotalDistance(waypoints []RouteWaypointRequestV) float64 {
 	if len(waypoints) < 2 {
 		return 0
@@ -2,12 +2,15 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"io"
 	"math"
 	"motocosmos-api/models"
+	"motocosmos-api/services"
 	"net/http"
 	"strconv"
 	"strings"
@@ -16,12 +19,21 @@ import (
 type SharedRouteController struct {
 	db                     *gorm.DB
 	notificationController *NotificationController
+	elevationService       *services.ElevationService
+	spatialService         *services.SpatialService
+	difficultyService      *services.DifficultyService
+	sharingService         *services.RouteSharingService
 }
 
-func NewSharedRouteController(db *gorm.DB, notificationController *NotificationController) *SharedRouteController {
+func NewSharedRouteController(db *gorm.DB, notificationController *NotificationController, elevationService *services.ElevationService, routingEngine services.RoutingEngine) *SharedRouteController {
+	difficultyService := services.NewDifficultyService(db, elevationService, routingEngine)
 	return &SharedRouteController{
 		db:                     db,
 		notificationController: notificationController,
+		elevationService:       elevationService,
+		spatialService:         services.NewSpatialService(db),
+		difficultyService:      difficultyService,
+		sharingService:         services.NewRouteSharingService(db, difficultyService),
 	}
 }
 
@@ -48,7 +60,7 @@ func (src *SharedRouteController) GetSharedRoutes(c *gin.Context) {
 	var total int64
 
 	// Build query
-	query := src.db.Preload("Creator").Order("created_at DESC")
+	query := src.db.Preload("Creator").Order(sharedRouteOrder(c.Query("sort")))
 
 	// Apply filters
 	if search := c.Query("search"); search != "" {
@@ -60,11 +72,23 @@ func (src *SharedRouteController) GetSharedRoutes(c *gin.Context) {
 	if difficulty := c.Query("difficulty"); difficulty != "" {
 		query = query.Where("difficulty = ?", difficulty)
 	}
+	if difficulty := c.Query("computed_difficulty"); difficulty != "" {
+		query = query.Where("computed_difficulty = ?", difficulty)
+	}
 
 	if tag := c.Query("tag"); tag != "" {
 		query = query.Where("JSON_CONTAINS(tags, ?)", fmt.Sprintf(`"%s"`, tag))
 	}
 
+	query = filterByTwistiness(c, query)
+
+	spatialFilter, err := services.ParseSpatialFilter(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query = src.spatialService.FilterRoutes(query, models.RouteGeoCellOwnerSharedRoute, spatialFilter)
+
 	// Get total count
 	query.Model(&models.SharedRoute{}).Count(&total)
 
@@ -139,15 +163,7 @@ func (src *SharedRouteController) CreateSharedRoute(c *gin.Context) {
 		return
 	}
 
-	// Convert RoutePoints to JSONData
-	routePointsJSON := make(models.JSONData)
-	for i, point := range req.RoutePoints {
-		routePointsJSON[fmt.Sprintf("%d", i)] = map[string]interface{}{
-			"latitude":  point.Latitude,
-			"longitude": point.Longitude,
-			"elevation": point.Elevation,
-		}
-	}
+	totalElevation := src.enrichElevation(req.RoutePoints, req.TotalElevation)
 
 	// Create shared route
 	route := models.SharedRoute{
@@ -158,19 +174,30 @@ func (src *SharedRouteController) CreateSharedRoute(c *gin.Context) {
 		CreatorName:       creator.Name,
 		CreatorAvatar:     getInitials(creator.Name),
 		ImageUrls:         models.StringSlice(req.ImageUrls),
-		RoutePoints:       routePointsJSON,
+		RoutePoints:       models.Geometry(req.RoutePoints),
 		TotalDistance:     req.TotalDistance,
-		TotalElevation:    req.TotalElevation,
+		TotalElevation:    totalElevation,
 		EstimatedDuration: req.EstimatedDuration,
 		Difficulty:        req.Difficulty,
 		Tags:              models.StringSlice(req.Tags),
 	}
 
+	curvature := services.AnalyzeCurvature(models.Geometry(req.RoutePoints).LatLngs())
+	route.Curvature = &curvature
+	route.TwistinessScore = curvature.Score
+
 	if err := src.db.Create(&route).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create shared route"})
 		return
 	}
 
+	if err := src.spatialService.IndexRoute(models.RouteGeoCellOwnerSharedRoute, route.ID, route.GetRoutePointsAsLatLng()); err != nil {
+		fmt.Printf("Warning: Could not index shared route location: %v\n", err)
+	}
+	if err := src.difficultyService.ClassifySharedRoute(route.ID); err != nil {
+		fmt.Printf("Warning: Could not classify shared route difficulty: %v\n", err)
+	}
+
 	// Load the complete route with creator info
 	src.db.Preload("Creator").First(&route, "id = ?", route.ID)
 	route.Creator.Password = ""
@@ -189,32 +216,33 @@ func (src *SharedRouteController) UpdateSharedRoute(c *gin.Context) {
 		return
 	}
 
+	if route.SourceRouteID != nil {
+		respondSharingError(c, services.ErrSharedRouteSynced, "Failed to update shared route")
+		return
+	}
+
 	var req CreateSharedRouteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Convert RoutePoints to JSONData
-	routePointsJSON := make(models.JSONData)
-	for i, point := range req.RoutePoints {
-		routePointsJSON[fmt.Sprintf("%d", i)] = map[string]interface{}{
-			"latitude":  point.Latitude,
-			"longitude": point.Longitude,
-			"elevation": point.Elevation,
-		}
-	}
+	totalElevation := src.enrichElevation(req.RoutePoints, req.TotalElevation)
+
+	curvature := services.AnalyzeCurvature(models.Geometry(req.RoutePoints).LatLngs())
 
 	updates := map[string]interface{}{
 		"title":              req.Title,
 		"description":        req.Description,
 		"image_urls":         models.StringSlice(req.ImageUrls),
-		"route_points":       routePointsJSON,
+		"route_points":       models.Geometry(req.RoutePoints),
 		"total_distance":     req.TotalDistance,
-		"total_elevation":    req.TotalElevation,
+		"total_elevation":    totalElevation,
 		"estimated_duration": req.EstimatedDuration,
 		"difficulty":         req.Difficulty,
 		"tags":               models.StringSlice(req.Tags),
+		"curvature":          &curvature,
+		"twistiness_score":   curvature.Score,
 	}
 
 	if err := src.db.Model(&route).Updates(updates).Error; err != nil {
@@ -222,6 +250,13 @@ func (src *SharedRouteController) UpdateSharedRoute(c *gin.Context) {
 		return
 	}
 
+	if err := src.spatialService.IndexRoute(models.RouteGeoCellOwnerSharedRoute, routeID, models.Geometry(req.RoutePoints).LatLngs()); err != nil {
+		fmt.Printf("Warning: Could not index shared route location: %v\n", err)
+	}
+	if err := src.difficultyService.ClassifySharedRoute(routeID); err != nil {
+		fmt.Printf("Warning: Could not classify shared route difficulty: %v\n", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Shared route updated successfully"})
 }
 
@@ -236,12 +271,8 @@ func (src *SharedRouteController) DeleteSharedRoute(c *gin.Context) {
 		return
 	}
 
-	// Delete likes and bookmarks first
-	src.db.Where("route_id = ?", routeID).Delete(&models.SharedRouteLike{})
-	src.db.Where("route_id = ?", routeID).Delete(&models.SharedRouteBookmark{})
-
-	// Delete the route
-	if err := src.db.Delete(&route).Error; err != nil {
+	// Removes likes, bookmarks and attached POIs too, and unpublishes the personal route it came from
+	if err := src.sharingService.Remove(&route); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete shared route"})
 		return
 	}
@@ -270,8 +301,10 @@ func (src *SharedRouteController) LikeSharedRoute(c *gin.Context) {
 			return
 		}
 
-		// Update likes count
-		src.db.Model(&route).UpdateColumn("likes_count", gorm.Expr("likes_count - ?", 1))
+		// Update likes count, of the route and of its lineage
+		if err := src.sharingService.CountLike(&route, -1); err != nil {
+			fmt.Printf("Warning: Could not update like counts: %v\n", err)
+		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"message":  "Route unliked successfully",
@@ -291,8 +324,10 @@ func (src *SharedRouteController) LikeSharedRoute(c *gin.Context) {
 		return
 	}
 
-	// Update likes count
-	src.db.Model(&route).UpdateColumn("likes_count", gorm.Expr("likes_count + ?", 1))
+	// Update likes count, of the route and of its lineage
+	if err := src.sharingService.CountLike(&route, 1); err != nil {
+		fmt.Printf("Warning: Could not update like counts: %v\n", err)
+	}
 
 	// Create notification for route like
 	if err := src.notificationController.CreateLikeNotification(userID, route.CreatorID, routeID); err != nil {
@@ -351,26 +386,75 @@ func (src *SharedRouteController) BookmarkSharedRoute(c *gin.Context) {
 	})
 }
 
-// DownloadSharedRoute increments download count for a route
+// DownloadSharedRoute counts a download and returns the route as GPX, KML or GeoJSON (?format=, default gpx)
 func (src *SharedRouteController) DownloadSharedRoute(c *gin.Context) {
 	routeID := c.Param("id")
 
+	format, ok := routeFileFormat(c)
+	if !ok {
+		return
+	}
+
 	var route models.SharedRoute
 	if err := src.db.First(&route, "id = ?", routeID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Shared route not found"})
 		return
 	}
 
-	// Update downloads count
-	src.db.Model(&route).UpdateColumn("downloads_count", gorm.Expr("downloads_count + ?", 1))
+	// Update downloads count, of the route and of its lineage
+	if err := src.sharingService.CountDownload(&route); err != nil {
+		fmt.Printf("Warning: Could not update download counts: %v\n", err)
+	}
 
-	// In the future, this would redirect to a route detail/navigation screen
-	// For now, just return success with route data
-	c.JSON(http.StatusOK, gin.H{
-		"message":      "Route download initiated",
-		"route_id":     routeID,
-		"redirect_url": fmt.Sprintf("/routes/%s/navigate", routeID), // Future navigation screen
-	})
+	sendRouteFile(c, format, services.RouteFileFromSharedRoute(&route))
+}
+
+// ForkSharedRoute copies a shared route into a new personal route of the
+// user, crediting the shared route it came from
+func (src *SharedRouteController) ForkSharedRoute(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" binding:"max=255"` // defaults to the shared route's title
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	route, err := src.sharingService.Fork(c.GetString("user_id"), c.Param("id"), req.Name)
+	if err != nil {
+		respondSharingError(c, err, "Failed to fork shared route")
+		return
+	}
+
+	c.JSON(http.StatusCreated, route)
+}
+
+// GetSharedRouteLineage returns the routes a shared route was forked from,
+// the shared routes forked from it, and the likes and downloads of the lineage
+func (src *SharedRouteController) GetSharedRouteLineage(c *gin.Context) {
+	lineage, err := src.sharingService.Lineage(c.Param("id"))
+	if err != nil {
+		respondSharingError(c, err, "Failed to fetch route lineage")
+		return
+	}
+
+	c.JSON(http.StatusOK, lineage)
+}
+
+// ExportSharedRoute returns a shared route as GPX, KML or GeoJSON without counting a download
+func (src *SharedRouteController) ExportSharedRoute(c *gin.Context) {
+	format, ok := routeFileFormat(c)
+	if !ok {
+		return
+	}
+
+	var route models.SharedRoute
+	if err := src.db.First(&route, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shared route not found"})
+		return
+	}
+
+	sendRouteFile(c, format, services.RouteFileFromSharedRoute(&route))
 }
 
 // GetBookmarkedRoutes returns user's bookmarked routes
@@ -440,7 +524,15 @@ func (src *SharedRouteController) SearchSharedRoutes(c *gin.Context) {
 	dbQuery := src.db.Preload("Creator").Where(
 		"title LIKE ? OR description LIKE ? OR creator_name LIKE ? OR JSON_SEARCH(tags, 'one', ?) IS NOT NULL",
 		searchPattern, searchPattern, searchPattern, query,
-	).Order("created_at DESC")
+	).Order(sharedRouteOrder(c.Query("sort")))
+	dbQuery = filterByTwistiness(c, dbQuery)
+
+	spatialFilter, err := services.ParseSpatialFilter(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dbQuery = src.spatialService.FilterRoutes(dbQuery, models.RouteGeoCellOwnerSharedRoute, spatialFilter)
 
 	// Get total count
 	dbQuery.Model(&models.SharedRoute{}).Count(&total)
@@ -582,6 +674,61 @@ func (src *SharedRouteController) getUserInteractions(userID, routeID string) mo
 }
 
 // Helper function to get initials from name
+// enrichElevation fills in point elevations missing from the request from the
+// elevation tiles, and measures the total climbing when it was not sent
+func (src *SharedRouteController) enrichElevation(points []models.SharedRoutePoint, totalElevation float64) float64 {
+	for i := range points {
+		if points[i].Elevation != nil {
+			continue
+		}
+		if elevation, ok := src.elevationService.Elevation(points[i].Latitude, points[i].Longitude); ok {
+			rounded := math.Round(elevation*10) / 10
+			points[i].Elevation = &rounded
+		}
+	}
+
+	if totalElevation == 0 {
+		if profile, err := src.elevationService.Profile(models.Geometry(points).LatLngs()); err == nil {
+			totalElevation = profile.TotalAscent
+		}
+	}
+	return totalElevation
+}
+
+// GetSharedRouteElevation returns the elevation profile of a shared route
+func (src *SharedRouteController) GetSharedRouteElevation(c *gin.Context) {
+	var route models.SharedRoute
+	if err := src.db.First(&route, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shared route not found"})
+		return
+	}
+
+	respondElevationProfile(c, src.elevationService, route.GetRoutePointsAsLatLng())
+}
+
+// sharedRouteOrder maps the sort query parameter to an ORDER BY clause
+func sharedRouteOrder(sort string) string {
+	switch sort {
+	case "twistiness":
+		return "twistiness_score DESC, created_at DESC"
+	case "popular":
+		return "likes_count DESC, downloads_count DESC, created_at DESC"
+	default:
+		return "created_at DESC"
+	}
+}
+
+// filterByTwistiness applies the min_twistiness and max_twistiness query parameters
+func filterByTwistiness(c *gin.Context, query *gorm.DB) *gorm.DB {
+	if value, err := strconv.ParseFloat(c.Query("min_twistiness"), 64); err == nil {
+		query = query.Where("twistiness_score >= ?", value)
+	}
+	if value, err := strconv.ParseFloat(c.Query("max_twistiness"), 64); err == nil {
+		query = query.Where("twistiness_score <= ?", value)
+	}
+	return query
+}
+
 func getInitials(name string) string {
 	words := strings.Fields(name)
 	if len(words) == 0 {
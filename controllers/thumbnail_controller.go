@@ -0,0 +1,47 @@
+// File: /controllers/thumbnail_controller.go
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"motocosmos-api/services"
+)
+
+type ThumbnailController struct {
+	storage *services.StorageService
+}
+
+func NewThumbnailController(storage *services.StorageService) *ThumbnailController {
+	return &ThumbnailController{
+		storage: storage,
+	}
+}
+
+// GetThumbnail streams a map thumbnail from MinIO. Thumbnail names change with
+// the track, so they can be cached for good.
+func (tc *ThumbnailController) GetThumbnail(c *gin.Context) {
+	kind := c.Param("kind")
+	file := c.Param("file")
+	if !services.IsThumbnailKind(kind) || path.Base(file) != file || !strings.HasSuffix(file, ".png") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Thumbnail not found"})
+		return
+	}
+
+	obj, _, err := tc.storage.GetObject(c.Request.Context(), services.ThumbnailObjectName(kind, file))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Thumbnail not found"})
+		return
+	}
+	defer obj.Close()
+
+	c.Header("Content-Type", "image/png")
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	if _, err := io.Copy(c.Writer, obj); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read thumbnail"})
+		return
+	}
+}
@@ -0,0 +1,160 @@
+// File: /controllers/toll_controller.go
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+	"motocosmos-api/services"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type TollController struct {
+	db          *gorm.DB
+	tollService *services.TollService
+}
+
+func NewTollController(db *gorm.DB) *TollController {
+	return &TollController{
+		db:          db,
+		tollService: services.NewTollService(db),
+	}
+}
+
+// GetTollRates returns the vignettes and tolls valid today (?country=AT&vehicle_class=motorcycle)
+func (tc *TollController) GetTollRates(c *gin.Context) {
+	vehicleClass := c.DefaultQuery("vehicle_class", models.VehicleClassMotorcycle)
+
+	var countries []string
+	if country := strings.ToUpper(c.Query("country")); country != "" {
+		countries = append(countries, country)
+	}
+
+	rates, err := tc.tollService.RatesValidAt(vehicleClass, countries, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch toll rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// Admin endpoints
+
+// ListTollRates returns all stored rates including expired ones
+func (tc *TollController) ListTollRates(c *gin.Context) {
+	query := tc.db.Model(&models.TollRate{})
+	if country := c.Query("country"); country != "" {
+		query = query.Where("country_code = ?", strings.ToUpper(country))
+	}
+	if vehicleClass := c.Query("vehicle_class"); vehicleClass != "" {
+		query = query.Where("vehicle_class = ?", vehicleClass)
+	}
+
+	var rates []models.TollRate
+	if err := query.Order("country_code ASC, valid_from DESC").Find(&rates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch toll rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// CreateTollRate stores a rate; a rate with the same country, class, kind, name and start date is replaced
+func (tc *TollController) CreateTollRate(c *gin.Context) {
+	var req services.TollRateInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rate, created, err := tc.tollService.Save(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, rate)
+}
+
+// ExpireTollRate ends the validity of a rate, e.g. when a new price takes effect
+func (tc *TollController) ExpireTollRate(c *gin.Context) {
+	var rate models.TollRate
+	if err := tc.db.First(&rate, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Toll rate not found"})
+		return
+	}
+
+	var req struct {
+		ValidTo string `json:"valid_to" binding:"required"` // YYYY-MM-DD
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	validTo, err := time.Parse("2006-01-02", req.ValidTo)
+	if err != nil || !validTo.After(rate.ValidFrom) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "valid_to must be a date after valid_from"})
+		return
+	}
+
+	if err := tc.db.Model(&rate).Update("valid_to", validTo).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update toll rate"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+// DeleteTollRate removes a rate
+func (tc *TollController) DeleteTollRate(c *gin.Context) {
+	result := tc.db.Where("id = ?", c.Param("id")).Delete(&models.TollRate{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete toll rate"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Toll rate not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Toll rate deleted successfully"})
+}
+
+// ImportTollRates bulk imports rates from an uploaded CSV or JSON file (form field "file")
+func (tc *TollController) ImportTollRates(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file provided"})
+		return
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Filename)), ".")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only .csv and .json files are supported"})
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer src.Close()
+
+	result, err := tc.tollService.Import(src, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,262 @@
+// File: /controllers/trip_controller.go
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"math"
+	"motocosmos-api/models"
+	"motocosmos-api/services"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type TripController struct {
+	db          *gorm.DB
+	tripService *services.TripService
+}
+
+func NewTripController(db *gorm.DB, routingEngine services.RoutingEngine, elevationService *services.ElevationService) *TripController {
+	return &TripController{
+		db:          db,
+		tripService: services.NewTripService(db, services.NewDifficultyService(db, elevationService, routingEngine)),
+	}
+}
+
+type TripDayRequest struct {
+	RouteID                string   `json:"route_id"` // empty for a rest day
+	Date                   string   `json:"date"`     // YYYY-MM-DD, defaults to the start date plus the day
+	Title                  string   `json:"title" binding:"max=255"`
+	Notes                  string   `json:"notes"`
+	Distance               float64  `json:"distance" binding:"gte=0"`    // km, defaults to the route distance
+	RidingTime             int      `json:"riding_time" binding:"gte=0"` // seconds, defaults to the route estimate
+	AccommodationName      string   `json:"accommodation_name" binding:"max=255"`
+	AccommodationAddress   string   `json:"accommodation_address" binding:"max=500"`
+	AccommodationLatitude  *float64 `json:"accommodation_latitude" binding:"omitempty,gte=-90,lte=90"`
+	AccommodationLongitude *float64 `json:"accommodation_longitude" binding:"omitempty,gte=-180,lte=180"`
+	AccommodationCost      float64  `json:"accommodation_cost" binding:"gte=0"` // in the trip currency
+	AccommodationURL       string   `json:"accommodation_url" binding:"max=500"`
+}
+
+type TripRequest struct {
+	Name        string           `json:"name" binding:"required,max=255"`
+	Description string           `json:"description"`
+	StartDate   string           `json:"start_date"` // YYYY-MM-DD
+	Currency    string           `json:"currency"`   // defaults to the preferred currency
+	Days        []TripDayRequest `json:"days" binding:"dive"`
+}
+
+type SplitRouteRequest struct {
+	RouteID          string  `json:"route_id" binding:"required"`
+	Name             string  `json:"name" binding:"max=255"` // defaults to the route name
+	StartDate        string  `json:"start_date"`             // YYYY-MM-DD
+	Currency         string  `json:"currency"`
+	MaxDailyDistance float64 `json:"max_daily_distance" binding:"gte=0"` // km
+	MaxDailyTime     int     `json:"max_daily_time" binding:"gte=0"`     // riding seconds
+}
+
+// GetTrips returns the user's trips with their days
+func (tc *TripController) GetTrips(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	trips, total, err := tc.tripService.List(c.GetString("user_id"), limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trips"})
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+	c.JSON(http.StatusOK, gin.H{
+		"trips":       trips,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"has_more":    page < totalPages,
+		"total_pages": totalPages,
+	})
+}
+
+// GetTrip returns a trip with its days and their routes
+func (tc *TripController) GetTrip(c *gin.Context) {
+	trip, err := tc.tripService.Get(c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		tc.respondError(c, err, "Failed to fetch trip")
+		return
+	}
+
+	c.JSON(http.StatusOK, trip)
+}
+
+// CreateTrip creates a trip from day legs in order
+func (tc *TripController) CreateTrip(c *gin.Context) {
+	input, ok := tc.bindTrip(c)
+	if !ok {
+		return
+	}
+
+	trip, err := tc.tripService.Create(c.GetString("user_id"), input)
+	if err != nil {
+		tc.respondError(c, err, "Failed to create trip")
+		return
+	}
+
+	c.JSON(http.StatusCreated, trip)
+}
+
+// UpdateTrip replaces a trip's details and days
+func (tc *TripController) UpdateTrip(c *gin.Context) {
+	input, ok := tc.bindTrip(c)
+	if !ok {
+		return
+	}
+
+	trip, err := tc.tripService.Update(c.GetString("user_id"), c.Param("id"), input)
+	if err != nil {
+		tc.respondError(c, err, "Failed to update trip")
+		return
+	}
+
+	c.JSON(http.StatusOK, trip)
+}
+
+// DeleteTrip deletes a trip; the routes of its days are kept
+func (tc *TripController) DeleteTrip(c *gin.Context) {
+	if err := tc.tripService.Delete(c.GetString("user_id"), c.Param("id")); err != nil {
+		tc.respondError(c, err, "Failed to delete trip")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Trip deleted successfully"})
+}
+
+// SplitRoute creates a trip by splitting a long route into days of a maximum distance or riding time
+func (tc *TripController) SplitRoute(c *gin.Context) {
+	var req SplitRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	startDate, err := parseTripDate(req.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date, expected YYYY-MM-DD"})
+		return
+	}
+
+	trip, err := tc.tripService.SplitRoute(c.GetString("user_id"), services.TripSplitInput{
+		RouteID:          req.RouteID,
+		Name:             req.Name,
+		StartDate:        startDate,
+		Currency:         req.Currency,
+		MaxDailyDistance: req.MaxDailyDistance,
+		MaxDailyTime:     req.MaxDailyTime,
+	})
+	if err != nil {
+		tc.respondError(c, err, "Failed to split route")
+		return
+	}
+
+	c.JSON(http.StatusCreated, trip)
+}
+
+// GetTripBudget estimates fuel, tolls and accommodation for the whole trip
+func (tc *TripController) GetTripBudget(c *gin.Context) {
+	vehicleClass := strings.ToLower(c.Query("vehicle_class"))
+	if vehicleClass != "" && vehicleClass != models.VehicleClassMotorcycle && vehicleClass != models.VehicleClassCar {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle class"})
+		return
+	}
+
+	budget, err := tc.tripService.Budget(c.GetString("user_id"), c.Param("id"), services.TripBudgetInput{
+		MotorcycleID: c.Query("motorcycle_id"),
+		FuelGrade:    c.Query("fuel_grade"),
+		IncludeTolls: c.DefaultQuery("include_tolls", "true") == "true",
+		VehicleClass: vehicleClass,
+	})
+	if err != nil {
+		tc.respondError(c, err, "Failed to estimate trip budget")
+		return
+	}
+
+	c.JSON(http.StatusOK, budget)
+}
+
+// bindTrip binds a trip request and parses its dates
+func (tc *TripController) bindTrip(c *gin.Context) (services.TripPlanInput, bool) {
+	var req TripRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return services.TripPlanInput{}, false
+	}
+
+	startDate, err := parseTripDate(req.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date, expected YYYY-MM-DD"})
+		return services.TripPlanInput{}, false
+	}
+
+	input := services.TripPlanInput{
+		Name:        req.Name,
+		Description: req.Description,
+		StartDate:   startDate,
+		Currency:    req.Currency,
+		Days:        make([]services.TripDayInput, len(req.Days)),
+	}
+	for i, day := range req.Days {
+		date, err := parseTripDate(day.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid date of day %d, expected YYYY-MM-DD", i+1)})
+			return services.TripPlanInput{}, false
+		}
+		input.Days[i] = services.TripDayInput{
+			RouteID:                day.RouteID,
+			Date:                   date,
+			Title:                  day.Title,
+			Notes:                  day.Notes,
+			Distance:               day.Distance,
+			RidingTime:             day.RidingTime,
+			AccommodationName:      day.AccommodationName,
+			AccommodationAddress:   day.AccommodationAddress,
+			AccommodationLatitude:  day.AccommodationLatitude,
+			AccommodationLongitude: day.AccommodationLongitude,
+			AccommodationCost:      day.AccommodationCost,
+			AccommodationURL:       day.AccommodationURL,
+		}
+	}
+
+	return input, true
+}
+
+// parseTripDate parses an optional YYYY-MM-DD date
+func parseTripDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+func (tc *TripController) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrTripNotFound), errors.Is(err, services.ErrRouteNotFound), errors.Is(err, services.ErrMotorcycleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidTrip), errors.Is(err, services.ErrUnsupportedCurrency), errors.Is(err, services.ErrRouteGeometryMissing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
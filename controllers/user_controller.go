@@ -8,6 +8,7 @@ import (
 	"motocosmos-api/models"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 type UserController struct {
@@ -26,7 +27,7 @@ func (uc *UserController) GetProfile(c *gin.Context) {
 	userID := c.GetString("user_id")
 
 	var user models.User
-	if err := uc.db.Preload("Motorcycles").First(&user, "id = ?", userID).Error; err != nil {
+	if err := uc.db.Preload("Motorcycles").Preload("Motorcycles.Images").First(&user, "id = ?", userID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
@@ -39,9 +40,10 @@ func (uc *UserController) UpdateProfile(c *gin.Context) {
 	userID := c.GetString("user_id")
 
 	var req struct {
-		Name   string  `json:"name"`
-		Handle string  `json:"handle"`
-		Avatar *string `json:"avatar"`
+		Name              string  `json:"name"`
+		Handle            string  `json:"handle"`
+		Avatar            *string `json:"avatar"`
+		PreferredCurrency string  `json:"preferred_currency"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -71,6 +73,14 @@ func (uc *UserController) UpdateProfile(c *gin.Context) {
 	if req.Avatar != nil {
 		updates["avatar"] = req.Avatar
 	}
+	if req.PreferredCurrency != "" {
+		currency := strings.ToUpper(req.PreferredCurrency)
+		if !models.IsSupportedCurrency(currency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+			return
+		}
+		updates["preferred_currency"] = currency
+	}
 
 	if err := uc.db.Model(&user).Updates(updates).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
@@ -291,7 +301,7 @@ func (uc *UserController) GetUserByHandle(c *gin.Context) {
 	currentUserID := c.GetString("user_id")
 
 	var user models.User
-	if err := uc.db.Preload("Motorcycles").Where("handle = ?", handle).First(&user).Error; err != nil {
+	if err := uc.db.Preload("Motorcycles").Preload("Motorcycles.Images").Where("handle = ?", handle).First(&user).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
@@ -315,6 +325,83 @@ func (uc *UserController) GetUserByHandle(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// GetGarage returns the public garage of a user: bikes with galleries, mods and ride totals
+func (uc *UserController) GetGarage(c *gin.Context) {
+	targetUserID := c.Param("user_id")
+	if targetUserID == "" || targetUserID == "me" {
+		targetUserID = c.GetString("user_id")
+	}
+
+	var user models.User
+	if err := uc.db.First(&user, "id = ?", targetUserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var motorcycles []models.Motorcycle
+	if err := uc.db.Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Preload("Mods", func(db *gorm.DB) *gorm.DB {
+		return db.Order("installed_at DESC, created_at DESC")
+	}).Where("user_id = ?", targetUserID).Order("created_at ASC").Find(&motorcycles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch garage"})
+		return
+	}
+
+	// Aggregate completed rides per motorcycle
+	var rideStats []struct {
+		MotorcycleID  string
+		RidesCount    int64
+		TotalDistance float64
+		TotalDuration int64
+		MaxSpeed      float64
+	}
+	uc.db.Model(&models.RideRecord{}).
+		Select("motorcycle_id, COUNT(*) as rides_count, COALESCE(SUM(distance), 0) as total_distance, COALESCE(SUM(duration), 0) as total_duration, COALESCE(MAX(max_speed), 0) as max_speed").
+		Where("user_id = ? AND is_completed = ?", targetUserID, true).
+		Group("motorcycle_id").
+		Scan(&rideStats)
+
+	statsByMotorcycle := make(map[string]models.MotorcycleRideStats, len(rideStats))
+	for _, stat := range rideStats {
+		statsByMotorcycle[stat.MotorcycleID] = models.MotorcycleRideStats{
+			RidesCount:    stat.RidesCount,
+			TotalDistance: stat.TotalDistance,
+			TotalDuration: stat.TotalDuration,
+			MaxSpeed:      stat.MaxSpeed,
+		}
+	}
+
+	response := models.GarageResponse{
+		User: models.NotificationUser{
+			ID:     user.ID,
+			Name:   user.Name,
+			Handle: user.Handle,
+			Avatar: user.Avatar,
+		},
+		Motorcycles: make([]models.GarageMotorcycle, 0, len(motorcycles)),
+	}
+
+	for _, motorcycle := range motorcycles {
+		stats := statsByMotorcycle[motorcycle.ID]
+
+		response.Totals.RidesCount += stats.RidesCount
+		response.Totals.TotalDistance += stats.TotalDistance
+		response.Totals.TotalDuration += stats.TotalDuration
+		if stats.MaxSpeed > response.Totals.MaxSpeed {
+			response.Totals.MaxSpeed = stats.MaxSpeed
+		}
+		response.ModsCount += len(motorcycle.Mods)
+
+		response.Motorcycles = append(response.Motorcycles, models.GarageMotorcycle{
+			Motorcycle: motorcycle,
+			RideStats:  stats,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 // Helper function to generate unique handle
 func (uc *UserController) GenerateUniqueHandle(baseName string) string {
 	baseHandle := models.GenerateHandleFromName(baseName)
@@ -26,6 +26,9 @@ func Migrate(db *gorm.DB) error {
 	err := db.AutoMigrate(
 		&models.User{},
 		&models.Motorcycle{},
+		&models.MotorcycleImage{},
+		&models.MotorcycleMod{},
+		&models.FuelLog{},
 		&models.Route{},
 		&models.RouteWaypoint{},
 		&models.SavedRoute{},
@@ -41,11 +44,29 @@ func Migrate(db *gorm.DB) error {
 		&models.LocationVisibilitySettings{},      // ← ÚJ
 		&models.LocationVisibilityAllowed{},       // ← ÚJ
 		&models.TripCalculation{},
+		&models.FuelPrice{},
+		&models.ExchangeRate{},
+		&models.PointOfInterest{},
+		&models.TollRate{},
+		&models.ExpenseGroup{},
+		&models.ExpenseGroupMember{},
+		&models.Expense{},
+		&models.ExpenseShare{},
+		&models.ExpenseSettlement{},
 		&models.Notification{},
 		&models.Comment{},
 		&models.SharedRoute{},
 		&models.SharedRouteLike{},
 		&models.SharedRouteBookmark{},
+		&models.RouteGeoCell{},
+		&models.RouteRevision{},
+		&models.RouteCollaborator{},
+		&models.Trip{},
+		&models.TripDay{},
+		&models.SharedRoutePOI{},
+		&models.HazardReport{},
+		&models.HazardConfirmation{},
+		&models.HazardAlert{},
 		  &models.FriendRequest{},
         &models.Friendship{},   
 	)
@@ -0,0 +1,133 @@
+// File: /database/geometry_migration.go
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"motocosmos-api/models"
+)
+
+// GeometryMigrationResult reports the rows checked in one geometry column
+type GeometryMigrationResult struct {
+	Table    string   `json:"table"`
+	Column   string   `json:"column"`
+	Rows     int      `json:"rows"`
+	Migrated int      `json:"migrated"`
+	Ordered  int      `json:"ordered"` // already stored as an array
+	Failed   int      `json:"failed"`
+	Errors   []string `json:"errors"`
+}
+
+// MigrateGeometry rewrites route geometries stored as objects keyed by point
+// index into ordered JSON arrays. Each row is rewritten in its own
+// transaction and read back; the rewrite is rolled back unless the stored
+// array holds the same points in the same order. Rows already stored as
+// arrays are only checked, so the migration can be run repeatedly.
+func MigrateGeometry(db *gorm.DB) ([]GeometryMigrationResult, error) {
+	columns := []struct{ table, column string }{
+		{"routes", "route_geometry"},
+		{"shared_routes", "route_points"},
+	}
+
+	results := make([]GeometryMigrationResult, 0, len(columns))
+	for _, target := range columns {
+		result, err := migrateGeometryColumn(db, target.table, target.column)
+		if err != nil {
+			return results, fmt.Errorf("failed to migrate %s.%s: %w", target.table, target.column, err)
+		}
+		results = append(results, *result)
+	}
+	return results, nil
+}
+
+func migrateGeometryColumn(db *gorm.DB, table, column string) (*GeometryMigrationResult, error) {
+	result := &GeometryMigrationResult{Table: table, Column: column, Errors: []string{}}
+
+	var ids []string
+	if err := db.Table(table).Where(column+" IS NOT NULL").Order("id").Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		result.Rows++
+		migrated := false
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			raw, err := readGeometryColumn(tx.Clauses(clause.Locking{Strength: "UPDATE"}), table, column, id)
+			if err != nil {
+				return err
+			}
+
+			geometry, err := models.ParseGeometry(raw)
+			if err != nil {
+				return err
+			}
+			if !models.IsLegacyGeometry(raw) {
+				return nil
+			}
+
+			if err := tx.Table(table).Where("id = ?", id).UpdateColumn(column, geometry).Error; err != nil {
+				return err
+			}
+
+			stored, err := readGeometryColumn(tx, table, column, id)
+			if err != nil {
+				return err
+			}
+			if models.IsLegacyGeometry(stored) {
+				return errors.New("geometry is still stored as an object")
+			}
+			check, err := models.ParseGeometry(stored)
+			if err != nil {
+				return fmt.Errorf("rewritten geometry is unreadable: %w", err)
+			}
+			if !sameGeometry(geometry, check) {
+				return errors.New("rewritten geometry does not match the original points")
+			}
+
+			migrated = true
+			return nil
+		})
+
+		switch {
+		case err != nil:
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Sprintf("%s %s: %v", table, id, err))
+		case migrated:
+			result.Migrated++
+		default:
+			result.Ordered++
+		}
+	}
+
+	return result, nil
+}
+
+func readGeometryColumn(db *gorm.DB, table, column, id string) ([]byte, error) {
+	var raw []byte
+	if err := db.Table(table).Select(column).Where("id = ?", id).Row().Scan(&raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func sameGeometry(a, b models.Geometry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Latitude != b[i].Latitude || a[i].Longitude != b[i].Longitude {
+			return false
+		}
+		if (a[i].Elevation == nil) != (b[i].Elevation == nil) {
+			return false
+		}
+		if a[i].Elevation != nil && *a[i].Elevation != *b[i].Elevation {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+// File: /jobs/hazard_cleanup_job.go
+package jobs
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"motocosmos-api/services"
+	"time"
+)
+
+// HazardCleanupJob periodically removes expired and disputed hazard reports
+type HazardCleanupJob struct {
+	db            *gorm.DB
+	hazardService *services.HazardService
+	ticker        *time.Ticker
+	done          chan bool
+}
+
+// NewHazardCleanupJob creates a new hazard cleanup job
+func NewHazardCleanupJob(db *gorm.DB, interval time.Duration) *HazardCleanupJob {
+	return &HazardCleanupJob{
+		db:            db,
+		hazardService: services.NewHazardService(db),
+		ticker:        time.NewTicker(interval),
+		done:          make(chan bool),
+	}
+}
+
+// Start begins the cleanup job
+func (j *HazardCleanupJob) Start() {
+	fmt.Println("Hazard cleanup job started")
+
+	go func() {
+		// Run immediately on start
+		j.cleanup()
+
+		// Then run on schedule
+		for {
+			select {
+			case <-j.ticker.C:
+				j.cleanup()
+			case <-j.done:
+				fmt.Println("Hazard cleanup job stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the cleanup job
+func (j *HazardCleanupJob) Stop() {
+	j.ticker.Stop()
+	j.done <- true
+}
+
+// cleanup performs the actual cleanup
+func (j *HazardCleanupJob) cleanup() {
+	removed, err := j.hazardService.CleanupExpired()
+	if err != nil {
+		fmt.Printf("Error during hazard cleanup: %v\n", err)
+		return
+	}
+
+	if removed > 0 {
+		fmt.Printf("Hazard cleanup removed %d expired or disputed reports\n", removed)
+	}
+}
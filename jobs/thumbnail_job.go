@@ -0,0 +1,75 @@
+// File: /jobs/thumbnail_job.go
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"motocosmos-api/services"
+	"time"
+)
+
+// thumbnailBatchSize is how many rows of each kind are rendered per run
+const thumbnailBatchSize = 50
+
+// ThumbnailJob periodically renders the map thumbnails of new and changed
+// routes, shared routes and rides
+type ThumbnailJob struct {
+	db               *gorm.DB
+	thumbnailService *services.ThumbnailService
+	ticker           *time.Ticker
+	done             chan bool
+}
+
+// NewThumbnailJob creates a new thumbnail job
+func NewThumbnailJob(db *gorm.DB, storage *services.StorageService, tilesPath string, interval time.Duration) *ThumbnailJob {
+	return &ThumbnailJob{
+		db:               db,
+		thumbnailService: services.NewThumbnailService(db, storage, tilesPath),
+		ticker:           time.NewTicker(interval),
+		done:             make(chan bool),
+	}
+}
+
+// Start begins the thumbnail job
+func (j *ThumbnailJob) Start() {
+	fmt.Println("Thumbnail job started")
+
+	go func() {
+		// Run immediately on start
+		j.render()
+
+		// Then run on schedule
+		for {
+			select {
+			case <-j.ticker.C:
+				j.render()
+			case <-j.done:
+				fmt.Println("Thumbnail job stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the thumbnail job
+func (j *ThumbnailJob) Stop() {
+	j.ticker.Stop()
+	j.done <- true
+}
+
+// render brings a batch of outdated thumbnails up to date
+func (j *ThumbnailJob) render() {
+	result, err := j.thumbnailService.RenderPending(context.Background(), thumbnailBatchSize)
+	for _, failure := range result.Failures {
+		fmt.Printf("Error during thumbnail rendering of %s %s: %v\n", failure.Kind, failure.ID, failure.Error)
+	}
+	if err != nil {
+		fmt.Printf("Error during thumbnail rendering: %v\n", err)
+		return
+	}
+
+	if result.Rendered > 0 {
+		fmt.Printf("Thumbnail job updated %d thumbnails\n", result.Rendered)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"motocosmos-api/database"
 	"motocosmos-api/routes"
 	"motocosmos-api/jobs"
+	"motocosmos-api/services"
 	"time"
 )
 
@@ -18,6 +19,26 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	// Commands that don't need the database
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import-osm":
+			if len(os.Args) < 3 {
+				log.Fatalf("Usage: %s import-osm <extract.osm.pbf>", os.Args[0])
+			}
+			fmt.Printf("Building road graph from %s...\n", os.Args[2])
+			graph, stats, err := services.BuildRoadGraph(os.Args[2])
+			if err != nil {
+				log.Fatalf("OSM import failed: %v", err)
+			}
+			if err := graph.Save(cfg.RoutingGraphPath); err != nil {
+				log.Fatalf("Failed to save road graph: %v", err)
+			}
+			fmt.Printf("Road graph saved to %s: %d ways, %d nodes, %d edges\n", cfg.RoutingGraphPath, stats.Ways, stats.Nodes, stats.Edges)
+			return
+		}
+	}
+
 	// Initialize database
 	db, err := database.Initialize(cfg.DatabaseURL)
 	if err != nil {
@@ -41,6 +62,112 @@ func main() {
 			}
 			fmt.Println("Database seeded successfully!")
 			return
+		case "migrate-geometry":
+			fmt.Println("Rewriting route geometries as ordered arrays...")
+			results, err := database.MigrateGeometry(db)
+			for _, result := range results {
+				for _, rowErr := range result.Errors {
+					fmt.Printf("Failed %s\n", rowErr)
+				}
+				fmt.Printf("%s.%s: %d rows, %d migrated, %d already ordered, %d failed\n",
+					result.Table, result.Column, result.Rows, result.Migrated, result.Ordered, result.Failed)
+			}
+			if err != nil {
+				log.Fatalf("Geometry migration failed: %v", err)
+			}
+			return
+		case "import-fuel-prices":
+			if len(os.Args) < 3 {
+				log.Fatalf("Usage: %s import-fuel-prices <file.csv|file.json>", os.Args[0])
+			}
+			fmt.Printf("Importing fuel prices from %s...\n", os.Args[2])
+			result, err := services.NewFuelPriceService(db).ImportFile(os.Args[2])
+			if err != nil {
+				log.Fatalf("Fuel price import failed: %v", err)
+			}
+			for _, rowErr := range result.Errors {
+				fmt.Printf("Skipped %s\n", rowErr)
+			}
+			fmt.Printf("Fuel prices imported: %d new, %d updated, %d skipped\n", result.Imported, result.Updated, result.Skipped)
+			return
+		case "import-exchange-rates":
+			if len(os.Args) < 3 {
+				log.Fatalf("Usage: %s import-exchange-rates <file.csv|file.json>", os.Args[0])
+			}
+			fmt.Printf("Importing exchange rates from %s...\n", os.Args[2])
+			result, err := services.NewCurrencyService(db).ImportFile(os.Args[2])
+			if err != nil {
+				log.Fatalf("Exchange rate import failed: %v", err)
+			}
+			for _, rowErr := range result.Errors {
+				fmt.Printf("Skipped %s\n", rowErr)
+			}
+			fmt.Printf("Exchange rates imported: %d new, %d updated, %d skipped\n", result.Imported, result.Updated, result.Skipped)
+			return
+		case "import-pois":
+			if len(os.Args) < 3 {
+				log.Fatalf("Usage: %s import-pois <file.csv|file.json>", os.Args[0])
+			}
+			fmt.Printf("Importing points of interest from %s...\n", os.Args[2])
+			result, err := services.NewPOIService(db).ImportFile(os.Args[2])
+			if err != nil {
+				log.Fatalf("POI import failed: %v", err)
+			}
+			for _, rowErr := range result.Errors {
+				fmt.Printf("Skipped %s\n", rowErr)
+			}
+			fmt.Printf("POIs imported: %d new, %d updated, %d skipped\n", result.Imported, result.Updated, result.Skipped)
+			return
+		case "import-toll-rates":
+			if len(os.Args) < 3 {
+				log.Fatalf("Usage: %s import-toll-rates <file.csv|file.json>", os.Args[0])
+			}
+			fmt.Printf("Importing toll and vignette rates from %s...\n", os.Args[2])
+			result, err := services.NewTollService(db).ImportFile(os.Args[2])
+			if err != nil {
+				log.Fatalf("Toll rate import failed: %v", err)
+			}
+			for _, rowErr := range result.Errors {
+				fmt.Printf("Skipped %s\n", rowErr)
+			}
+			fmt.Printf("Toll rates imported: %d new, %d updated, %d skipped\n", result.Imported, result.Updated, result.Skipped)
+			return
+		case "score-curvature":
+			fmt.Println("Scoring the curvature of routes and shared routes...")
+			result, err := services.NewCurvatureService(db).Backfill()
+			if err != nil {
+				log.Fatalf("Curvature scoring failed: %v", err)
+			}
+			fmt.Printf("Curvature scored: %d routes, %d shared routes\n", result.Routes, result.SharedRoutes)
+			return
+		case "classify-difficulty":
+			fmt.Println("Classifying the difficulty of routes and shared routes...")
+			routingEngine, err := services.NewRoutingEngine(cfg)
+			if err != nil {
+				log.Fatalf("Failed to initialize routing engine: %v", err)
+			}
+			result, err := services.NewDifficultyService(db, services.NewElevationService(cfg.ElevationDataPath), routingEngine).Backfill()
+			if err != nil {
+				log.Fatalf("Difficulty classification failed: %v", err)
+			}
+			fmt.Printf("Difficulty classified: %d routes, %d shared routes\n", result.Routes, result.SharedRoutes)
+			return
+		case "count-lineage":
+			fmt.Println("Counting forks and lineage likes and downloads of shared routes...")
+			count, err := services.NewRouteSharingService(db, services.NewDifficultyService(db, nil, nil)).RecountLineage()
+			if err != nil {
+				log.Fatalf("Lineage counting failed: %v", err)
+			}
+			fmt.Printf("Lineage counted for %d shared routes\n", count)
+			return
+		case "index-spatial":
+			fmt.Println("Indexing route, shared route and event locations...")
+			result, err := services.NewSpatialService(db).Reindex()
+			if err != nil {
+				log.Fatalf("Spatial indexing failed: %v", err)
+			}
+			fmt.Printf("Spatial index built: %d routes, %d shared routes, %d events\n", result.Routes, result.SharedRoutes, result.Events)
+			return
 		}
 	}
 
@@ -58,8 +185,14 @@ func main() {
 	// Setup CORS
 	router.Use(routes.SetupCORS())
 
+	// Object storage is shared by the routes and the thumbnail job
+	storageService, err := services.NewStorageService()
+	if err != nil {
+		log.Fatalf("Failed to initialize object storage: %v", err)
+	}
+
 	// Setup routes
-	routes.SetupRoutes(router, db, cfg.JWTSecret)
+	routes.SetupRoutes(router, db, cfg.JWTSecret, storageService)
 
 	// Start server
 	fmt.Printf("🚀 MotoCosmos API Server starting on port %s\n", cfg.Port)
@@ -69,6 +202,12 @@ func main() {
 	cleanupJob := jobs.NewLocationCleanupJob(db, 5*time.Minute)
 	cleanupJob.Start()
     defer cleanupJob.Stop()
+	hazardCleanupJob := jobs.NewHazardCleanupJob(db, 10*time.Minute)
+	hazardCleanupJob.Start()
+	defer hazardCleanupJob.Stop()
+	thumbnailJob := jobs.NewThumbnailJob(db, storageService, cfg.MapTilesPath, time.Minute)
+	thumbnailJob.Start()
+	defer thumbnailJob.Stop()
 	if err := router.Run(":" + cfg.Port); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
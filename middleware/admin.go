@@ -0,0 +1,26 @@
+// File: /middleware/admin.go
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+	"net/http"
+)
+
+// AdminMiddleware allows the request only for users flagged as admin.
+// It must run after AuthMiddleware.
+func AdminMiddleware(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+
+		var user models.User
+		if err := db.Select("id", "is_admin").First(&user, "id = ?", userID).Error; err != nil || !user.IsAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -151,6 +151,16 @@ func ValidateJSON() gin.HandlerFunc {
 			"/posts/upload-images",
 			"/shared-routes/upload-image",
 			"/users/upload-avatar",
+			"/fuel-prices/import",    // CSV/JSON file upload
+			"/exchange-rates/import", // CSV/JSON file upload
+			"/pois/import",           // CSV/JSON file upload
+			"/toll-rates/import",     // CSV/JSON file upload
+			"/routes/import",         // GPX/KML/GeoJSON file upload
+		}
+
+		// Routes with path parameters are matched on their registered pattern
+		skipRoutes := []string{
+			"/api/v1/motorcycles/:id/images", // Motorcycle gallery uploads
 		}
 
 		// Check if current path should skip JSON validation
@@ -160,6 +170,12 @@ func ValidateJSON() gin.HandlerFunc {
 				return
 			}
 		}
+		for _, route := range skipRoutes {
+			if c.FullPath() == route {
+				c.Next()
+				return
+			}
+		}
 
 		// Skip validation for GET, DELETE, and OPTIONS requests
 		if c.Request.Method == "GET" || c.Request.Method == "DELETE" || c.Request.Method == "OPTIONS" {
@@ -14,7 +14,24 @@ type TripCalculation struct {
 	AverageFuelConsumption float64   `json:"average_fuel_consumption" gorm:"not null"`
 	OtherCosts             float64   `json:"other_costs" gorm:"default:0"`
 	TotalCost              float64   `json:"total_cost" gorm:"not null"`
+	Currency               string    `json:"currency" gorm:"size:3;default:'EUR'"` // Currency of all money values above
 	CreatedAt              time.Time `json:"created_at"`
 
-	User User `json:"user" gorm:"foreignKey:UserID"`
+	// Optional links when the calculation was made from a saved route and bike
+	RouteID           *string     `json:"route_id" gorm:"size:191;index"`
+	SharedRouteID     *string     `json:"shared_route_id" gorm:"size:191;index"`
+	MotorcycleID      *string     `json:"motorcycle_id" gorm:"size:191"`
+	ConsumptionSource string      `json:"consumption_source" gorm:"size:20"` // manual, spec, fuel_log, default
+	Countries         StringSlice `json:"countries" gorm:"type:json"`
+
+	// Vignettes and tolls included in OtherCosts
+	TollCosts float64       `json:"toll_costs" gorm:"default:0"`
+	TollItems TollCostItems `json:"toll_items" gorm:"type:json"`
+
+	// Total cost in the requesting user's preferred currency, filled on read
+	DisplayTotalCost *Money `json:"display_total_cost,omitempty" gorm:"-"`
+
+	User        User         `json:"user" gorm:"foreignKey:UserID"`
+	Route       *Route       `json:"route,omitempty" gorm:"foreignKey:RouteID"`
+	SharedRoute *SharedRoute `json:"shared_route,omitempty" gorm:"foreignKey:SharedRouteID"`
 }
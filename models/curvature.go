@@ -0,0 +1,37 @@
+// File: /models/curvature.go
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// CurvatureStats describes how winding a road geometry is
+type CurvatureStats struct {
+	Distance           float64 `json:"distance"`              // km analysed
+	TotalHeadingChange float64 `json:"total_heading_change"`  // degrees
+	HeadingChangePerKm float64 `json:"heading_change_per_km"` // degrees per km
+	TightBends         int     `json:"tight_bends"`           // radius below 50 m
+	MediumBends        int     `json:"medium_bends"`          // radius 50-150 m
+	SweepingBends      int     `json:"sweeping_bends"`        // radius 150-400 m
+	Score              float64 `json:"score"`                 // twistiness from 0 (straight) to 100
+}
+
+func (c CurvatureStats) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *CurvatureStats) Scan(value interface{}) error {
+	if value == nil {
+		*c = CurvatureStats{}
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, c)
+}
@@ -0,0 +1,51 @@
+// File: /models/difficulty.go
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// Difficulty levels, shared by the creator's choice and the computed one
+const (
+	DifficultyEasy   = "Easy"
+	DifficultyMedium = "Medium"
+	DifficultyHard   = "Hard"
+)
+
+// Road type groups used by DifficultyFactors.RoadTypes
+const (
+	RoadTypeMotorway = "motorway" // motorways and their links
+	RoadTypeMain     = "main"     // trunk and primary roads
+	RoadTypeMinor    = "minor"    // secondary, tertiary and unclassified roads
+	RoadTypeNarrow   = "narrow"   // residential, service and other small roads
+)
+
+// DifficultyFactors are the measurements a computed difficulty is based on
+type DifficultyFactors struct {
+	Distance      float64            `json:"distance"`             // km
+	ElevationGain float64            `json:"elevation_gain"`       // m
+	MaxGradient   float64            `json:"max_gradient"`         // steepest climb, %, 0 when unknown
+	Curvature     float64            `json:"curvature"`            // twistiness 0-100
+	RoadTypes     map[string]float64 `json:"road_types,omitempty"` // share of the distance per road type, empty when unknown
+	Score         float64            `json:"score"`                // 0 (easiest) to 100
+}
+
+func (d DifficultyFactors) Value() (driver.Value, error) {
+	return json.Marshal(d)
+}
+
+func (d *DifficultyFactors) Scan(value interface{}) error {
+	if value == nil {
+		*d = DifficultyFactors{}
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, d)
+}
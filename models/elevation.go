@@ -0,0 +1,23 @@
+// File: /models/elevation.go
+package models
+
+// ElevationSample is one point of an elevation profile
+type ElevationSample struct {
+	Distance  float64 `json:"distance"` // km from the start
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Elevation float64 `json:"elevation"` // m
+}
+
+// ElevationProfile describes the heights along a route, sampled from DEM tiles
+type ElevationProfile struct {
+	Samples            []ElevationSample `json:"samples"`
+	Distance           float64           `json:"distance"`             // km
+	TotalAscent        float64           `json:"total_ascent"`         // m
+	TotalDescent       float64           `json:"total_descent"`        // m
+	MinElevation       float64           `json:"min_elevation"`        // m
+	MaxElevation       float64           `json:"max_elevation"`        // m
+	MaxGradient        float64           `json:"max_gradient"`         // steepest climb, %
+	MaxDescentGradient float64           `json:"max_descent_gradient"` // steepest descent, %
+	Coverage           float64           `json:"coverage"`             // share of the samples covered by DEM tiles, 0-1
+}
@@ -0,0 +1,41 @@
+// File: /models/exchange_rate.go
+package models
+
+import (
+	"time"
+)
+
+// DefaultCurrency is the currency all internal reference prices are kept in
+const DefaultCurrency = "EUR"
+
+// SupportedCurrencies lists the currencies users can pay and display amounts in
+var SupportedCurrencies = []string{"EUR", "HUF", "CZK", "PLN", "CHF", "RON", "BGN", "DKK", "SEK", "NOK", "GBP", "USD"}
+
+// IsSupportedCurrency reports whether code is one of the supported ISO 4217 currency codes
+func IsSupportedCurrency(code string) bool {
+	for _, c := range SupportedCurrencies {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// ExchangeRate stores how many units of Currency one unit of BaseCurrency is
+// worth on a given date. Older rows are kept as history.
+type ExchangeRate struct {
+	ID            string    `json:"id" gorm:"primaryKey;size:191"`
+	BaseCurrency  string    `json:"base_currency" gorm:"not null;size:3;default:'EUR';index:idx_exchange_rates_lookup"`
+	Currency      string    `json:"currency" gorm:"not null;size:3;index:idx_exchange_rates_lookup"`
+	Rate          float64   `json:"rate" gorm:"not null"`
+	EffectiveDate time.Time `json:"effective_date" gorm:"not null;index:idx_exchange_rates_lookup"`
+	Source        string    `json:"source" gorm:"size:100"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Money is an amount together with its currency
+type Money struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+}
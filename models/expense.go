@@ -0,0 +1,153 @@
+// File: /models/expense.go
+package models
+
+import (
+	"time"
+)
+
+// Expense categories
+const (
+	ExpenseCategoryFuel          = "fuel"
+	ExpenseCategoryAccommodation = "accommodation"
+	ExpenseCategoryFerry         = "ferry"
+	ExpenseCategoryFood          = "food"
+	ExpenseCategoryToll          = "toll"
+	ExpenseCategoryOther         = "other"
+)
+
+var ExpenseCategories = []string{
+	ExpenseCategoryFuel,
+	ExpenseCategoryAccommodation,
+	ExpenseCategoryFerry,
+	ExpenseCategoryFood,
+	ExpenseCategoryToll,
+	ExpenseCategoryOther,
+}
+
+// IsValidExpenseCategory reports whether a category is known
+func IsValidExpenseCategory(category string) bool {
+	for _, c := range ExpenseCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Expense split types
+const (
+	ExpenseSplitEqual    = "equal"    // amount divided evenly among the shares
+	ExpenseSplitWeighted = "weighted" // amount divided in proportion to each share's weight
+)
+
+// ExpenseGroup is a shared ledger for a group ride, either linked to a
+// community event or created for a group of friends
+type ExpenseGroup struct {
+	ID          string    `json:"id" gorm:"primaryKey;size:191"`
+	Name        string    `json:"name" gorm:"not null;size:255"`
+	EventID     *string   `json:"event_id" gorm:"size:191;index"`
+	CreatedByID string    `json:"created_by_id" gorm:"not null;size:191"`
+	Currency    string    `json:"currency" gorm:"size:3;default:'EUR'"` // balances are reported in this currency
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	CreatedBy User                 `json:"created_by" gorm:"foreignKey:CreatedByID"`
+	Event     *CommunityEvent      `json:"event,omitempty" gorm:"foreignKey:EventID"`
+	Members   []ExpenseGroupMember `json:"members" gorm:"foreignKey:GroupID"`
+}
+
+// HasMember reports whether a user belongs to the group (Members must be loaded)
+func (g *ExpenseGroup) HasMember(userID string) bool {
+	for _, member := range g.Members {
+		if member.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+type ExpenseGroupMember struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	GroupID   string    `json:"group_id" gorm:"not null;size:191;uniqueIndex:idx_expense_group_member"`
+	UserID    string    `json:"user_id" gorm:"not null;size:191;uniqueIndex:idx_expense_group_member"`
+	CreatedAt time.Time `json:"created_at"`
+
+	User User `json:"user" gorm:"foreignKey:UserID"`
+}
+
+// Expense is a single payment made by one member on behalf of others
+type Expense struct {
+	ID          string    `json:"id" gorm:"primaryKey;size:191"`
+	GroupID     string    `json:"group_id" gorm:"not null;size:191;index"`
+	PaidByID    string    `json:"paid_by_id" gorm:"not null;size:191"`
+	CreatedByID string    `json:"created_by_id" gorm:"not null;size:191"`
+	Description string    `json:"description" gorm:"not null;size:255"`
+	Category    string    `json:"category" gorm:"not null;size:50;default:'other'"`
+	Amount      float64   `json:"amount" gorm:"not null"`
+	Currency    string    `json:"currency" gorm:"size:3;default:'EUR'"`
+	SplitType   string    `json:"split_type" gorm:"not null;size:20;default:'equal'"`
+	SpentAt     time.Time `json:"spent_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	PaidBy User           `json:"paid_by" gorm:"foreignKey:PaidByID"`
+	Shares []ExpenseShare `json:"shares" gorm:"foreignKey:ExpenseID"`
+}
+
+// ExpenseShare is the part of an expense a member owes, in the expense currency
+type ExpenseShare struct {
+	ID        uint    `json:"id" gorm:"primaryKey"`
+	ExpenseID string  `json:"expense_id" gorm:"not null;size:191;index"`
+	UserID    string  `json:"user_id" gorm:"not null;size:191"`
+	Weight    float64 `json:"weight" gorm:"default:1"`
+	Amount    float64 `json:"amount" gorm:"not null"`
+
+	User User `json:"user" gorm:"foreignKey:UserID"`
+}
+
+// ExpenseSettlement records money paid back from one member to another
+type ExpenseSettlement struct {
+	ID         string    `json:"id" gorm:"primaryKey;size:191"`
+	GroupID    string    `json:"group_id" gorm:"not null;size:191;index"`
+	FromUserID string    `json:"from_user_id" gorm:"not null;size:191"`
+	ToUserID   string    `json:"to_user_id" gorm:"not null;size:191"`
+	Amount     float64   `json:"amount" gorm:"not null"`
+	Currency   string    `json:"currency" gorm:"size:3;default:'EUR'"`
+	Note       string    `json:"note" gorm:"size:255"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	FromUser User `json:"from_user" gorm:"foreignKey:FromUserID"`
+	ToUser   User `json:"to_user" gorm:"foreignKey:ToUserID"`
+}
+
+// MemberBalance is a member's position in the group currency. A positive
+// balance means the member is owed money, a negative one that they owe.
+type MemberBalance struct {
+	UserID  string  `json:"user_id"`
+	Name    string  `json:"name"`
+	Handle  string  `json:"handle"`
+	Paid    float64 `json:"paid"`    // expenses paid for the group
+	Share   float64 `json:"share"`   // own part of all expenses
+	Settled float64 `json:"settled"` // settlements paid minus settlements received
+	Balance float64 `json:"balance"`
+}
+
+// SettlementSuggestion is a transfer that helps settle the group
+type SettlementSuggestion struct {
+	FromUserID string  `json:"from_user_id"`
+	FromName   string  `json:"from_name"`
+	ToUserID   string  `json:"to_user_id"`
+	ToName     string  `json:"to_name"`
+	Amount     float64 `json:"amount"`
+	Currency   string  `json:"currency"`
+}
+
+// ExpenseGroupBalances is the ledger overview shown to every member
+type ExpenseGroupBalances struct {
+	GroupID     string                 `json:"group_id"`
+	Currency    string                 `json:"currency"`
+	TotalSpent  float64                `json:"total_spent"`
+	ByCategory  map[string]float64     `json:"by_category"`
+	Balances    []MemberBalance        `json:"balances"`
+	Suggestions []SettlementSuggestion `json:"suggestions"`
+}
@@ -0,0 +1,64 @@
+// File: /models/fuel_price.go
+package models
+
+import (
+	"time"
+)
+
+// Fuel grades tracked in the price database
+const (
+	FuelGrade95     = "95"
+	FuelGrade98     = "98"
+	FuelGradeDiesel = "diesel"
+)
+
+// FuelGrades lists all supported fuel grades
+var FuelGrades = []string{FuelGrade95, FuelGrade98, FuelGradeDiesel}
+
+// IsValidFuelGrade reports whether grade is one of the supported fuel grades
+func IsValidFuelGrade(grade string) bool {
+	for _, g := range FuelGrades {
+		if g == grade {
+			return true
+		}
+	}
+	return false
+}
+
+// FuelPrice is a fuel price valid from a given date. Older rows are kept as
+// price history; the row with the latest EffectiveFrom is the current price.
+type FuelPrice struct {
+	ID            string    `json:"id" gorm:"primaryKey;size:191"`
+	CountryCode   string    `json:"country_code" gorm:"not null;size:2;index:idx_fuel_prices_lookup"` // ISO 3166-1 alpha-2
+	Region        string    `json:"region" gorm:"size:100;index:idx_fuel_prices_lookup"`              // Empty for the national average
+	Grade         string    `json:"grade" gorm:"not null;size:20;index:idx_fuel_prices_lookup"`       // 95, 98, diesel
+	Price         float64   `json:"price" gorm:"not null"`                                            // per liter
+	Currency      string    `json:"currency" gorm:"size:3;default:'EUR'"`
+	EffectiveFrom time.Time `json:"effective_from" gorm:"not null;index:idx_fuel_prices_lookup"`
+	Source        string    `json:"source" gorm:"size:100"` // manual, import, ...
+	CreatedByID   *string   `json:"created_by_id" gorm:"size:191"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// FuelPricePoint is a single price in a trend series
+type FuelPricePoint struct {
+	Price         float64   `json:"price"`
+	EffectiveFrom time.Time `json:"effective_from"`
+}
+
+// FuelPriceTrend describes how a fuel price developed over a period
+type FuelPriceTrend struct {
+	CountryCode   string           `json:"country_code"`
+	Region        string           `json:"region"`
+	Grade         string           `json:"grade"`
+	Currency      string           `json:"currency"`
+	CurrentPrice  float64          `json:"current_price"`
+	PreviousPrice float64          `json:"previous_price"` // price at the start of the period
+	Change        float64          `json:"change"`
+	ChangePercent float64          `json:"change_percent"`
+	MinPrice      float64          `json:"min_price"`
+	MaxPrice      float64          `json:"max_price"`
+	Direction     string           `json:"direction"` // up, down, stable
+	Points        []FuelPricePoint `json:"points"`
+}
@@ -0,0 +1,124 @@
+// File: /models/geometry.go
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// GeometryPoint is one point of a route geometry with optional elevation
+type GeometryPoint struct {
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Elevation *float64 `json:"elevation,omitempty"`
+}
+
+// Geometry is an ordered list of points, stored as a JSON array. Rows written
+// before it existed hold an object keyed by point index ("0", "1", ...);
+// Scan still reads those in index order until migrate-geometry rewrites them.
+type Geometry []GeometryPoint
+
+func (g Geometry) Value() (driver.Value, error) {
+	if g == nil {
+		return "[]", nil
+	}
+	return json.Marshal(g)
+}
+
+func (g *Geometry) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*g = Geometry{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("type assertion to []byte failed")
+	}
+
+	parsed, err := ParseGeometry(data)
+	if err != nil {
+		return err
+	}
+	*g = parsed
+	return nil
+}
+
+// ParseGeometry decodes a JSON array of points, or the legacy object keyed by point index
+func ParseGeometry(data []byte) (Geometry, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return Geometry{}, nil
+	}
+	if data[0] == '{' {
+		return parseIndexedGeometry(data)
+	}
+
+	var geometry Geometry
+	if err := json.Unmarshal(data, &geometry); err != nil {
+		return nil, err
+	}
+	if geometry == nil {
+		geometry = Geometry{}
+	}
+	return geometry, nil
+}
+
+// IsLegacyGeometry reports whether stored geometry uses the index-keyed object form
+func IsLegacyGeometry(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '{'
+}
+
+func parseIndexedGeometry(data []byte) (Geometry, error) {
+	var indexed map[string]json.RawMessage
+	if err := json.Unmarshal(data, &indexed); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(indexed))
+	indexes := make(map[string]int, len(indexed))
+	for key := range indexed {
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 {
+			return nil, fmt.Errorf("geometry key %q is not a point index", key)
+		}
+		keys = append(keys, key)
+		indexes[key] = index
+	}
+	sort.Slice(keys, func(i, j int) bool { return indexes[keys[i]] < indexes[keys[j]] })
+
+	geometry := make(Geometry, len(keys))
+	for i, key := range keys {
+		if err := json.Unmarshal(indexed[key], &geometry[i]); err != nil {
+			return nil, fmt.Errorf("geometry point %s: %w", key, err)
+		}
+	}
+	return geometry, nil
+}
+
+// LatLngs returns the coordinates of the points in order
+func (g Geometry) LatLngs() []LatLng {
+	points := make([]LatLng, len(g))
+	for i, p := range g {
+		points[i] = LatLng{Latitude: p.Latitude, Longitude: p.Longitude}
+	}
+	return points
+}
+
+// GeometryFromLatLngs builds a geometry from coordinates
+func GeometryFromLatLngs(points []LatLng) Geometry {
+	geometry := make(Geometry, len(points))
+	for i, p := range points {
+		geometry[i] = GeometryPoint{Latitude: p.Latitude, Longitude: p.Longitude}
+	}
+	return geometry
+}
@@ -0,0 +1,87 @@
+// File: /models/hazard.go
+package models
+
+import "time"
+
+// Hazard types
+const (
+	HazardTypeGravel    = "gravel"
+	HazardTypeOil       = "oil"
+	HazardTypePothole   = "pothole"
+	HazardTypeRoadworks = "roadworks"
+	HazardTypePolice    = "police"
+	HazardTypeAnimal    = "animal"
+	HazardTypeAccident  = "accident"
+	HazardTypeOther     = "other"
+)
+
+// HazardLifetimes is how long a report of each type stays active without
+// confirmations. Every confirmation keeps it active this long again.
+var HazardLifetimes = map[string]time.Duration{
+	HazardTypeGravel:    48 * time.Hour,
+	HazardTypeOil:       12 * time.Hour,
+	HazardTypePothole:   30 * 24 * time.Hour,
+	HazardTypeRoadworks: 14 * 24 * time.Hour,
+	HazardTypePolice:    2 * time.Hour,
+	HazardTypeAnimal:    time.Hour,
+	HazardTypeAccident:  3 * time.Hour,
+	HazardTypeOther:     24 * time.Hour,
+}
+
+// HazardDisputeVotes is how many riders must deny a hazard before it counts
+// as disputed, provided they outnumber the ones confirming it
+const HazardDisputeVotes = 3
+
+// HazardReport is a road hazard reported by a rider at a location
+type HazardReport struct {
+	ID          string    `json:"id" gorm:"primaryKey;size:191"`
+	ReporterID  string    `json:"reporter_id" gorm:"not null;size:191;index"`
+	Type        string    `json:"type" gorm:"not null;size:20"`
+	Description string    `json:"description" gorm:"size:500"`
+	Latitude    float64   `json:"latitude" gorm:"not null;index:idx_hazard_reports_location"`
+	Longitude   float64   `json:"longitude" gorm:"not null;index:idx_hazard_reports_location"`
+	ExpiresAt   time.Time `json:"expires_at" gorm:"not null;index"`
+	UpVotes     int       `json:"up_votes" gorm:"default:0"`   // riders confirming it is still there
+	DownVotes   int       `json:"down_votes" gorm:"default:0"` // riders saying it is gone
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	Reporter User `json:"-" gorm:"foreignKey:ReporterID"`
+}
+
+// IsDisputed reports whether enough riders said the hazard is not there
+func (h *HazardReport) IsDisputed() bool {
+	return h.DownVotes >= HazardDisputeVotes && h.DownVotes > h.UpVotes
+}
+
+// IsActive reports whether the hazard should still be shown to riders
+func (h *HazardReport) IsActive() bool {
+	return time.Now().Before(h.ExpiresAt) && !h.IsDisputed()
+}
+
+// HazardConfirmation is one rider's vote on whether a hazard is still there
+type HazardConfirmation struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	HazardID   string    `json:"hazard_id" gorm:"not null;size:191;uniqueIndex:idx_hazard_confirmations_user"`
+	UserID     string    `json:"user_id" gorm:"not null;size:191;uniqueIndex:idx_hazard_confirmations_user"`
+	StillThere bool      `json:"still_there"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// HazardAlert records that a rider was warned about a hazard during a ride,
+// so the warning is not repeated with every position update
+type HazardAlert struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	HazardID  string    `json:"hazard_id" gorm:"not null;size:191;uniqueIndex:idx_hazard_alerts_ride"`
+	RideID    string    `json:"ride_id" gorm:"not null;size:191;uniqueIndex:idx_hazard_alerts_ride"`
+	UserID    string    `json:"user_id" gorm:"not null;size:191"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NearbyHazard is an active hazard with its distance from the rider
+type NearbyHazard struct {
+	HazardReport
+	Distance      float64  `json:"distance"`                 // km in a straight line
+	DistanceAhead *float64 `json:"distance_ahead,omitempty"` // km along the planned route
+}
@@ -0,0 +1,27 @@
+// File: /models/loop.go
+package models
+
+// LoopWaypoint is a stop of a generated loop, in the shape POST /routes/ accepts
+type LoopWaypoint struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Order     int     `json:"order"`
+}
+
+// LoopCandidate is a generated round trip. Its waypoints, geometry, distance
+// and time can be sent as they are to POST /routes/ to save it as a Route.
+type LoopCandidate struct {
+	Name              string          `json:"name"`
+	Bearing           float64         `json:"bearing"` // direction the loop heads out in, degrees
+	Waypoints         []LoopWaypoint  `json:"waypoints"`
+	RouteGeometry     []LatLng        `json:"route_geometry"`
+	TotalDistance     float64         `json:"total_distance"`     // km
+	EstimatedTime     int             `json:"estimated_time"`     // seconds
+	DistanceDeviation float64         `json:"distance_deviation"` // share of the target distance missed
+	Overlap           float64         `json:"overlap"`            // share of the loop ridden twice
+	TwistinessScore   float64         `json:"twistiness_score"`
+	Curvature         *CurvatureStats `json:"curvature"`
+	Score             float64         `json:"score"` // 0-100, candidates are sorted by it
+	Engine            string          `json:"engine"`
+}
@@ -11,9 +11,81 @@ type Motorcycle struct {
 	Brand     string    `json:"brand" gorm:"not null;size:100"`
 	Model     string    `json:"model" gorm:"not null;size:100"`
 	Year      string    `json:"year" gorm:"not null;size:4"`
-	ImageURL  string    `json:"image_url" gorm:"size:500"`
+	ImageURL  string    `json:"image_url" gorm:"size:500"` // Cover image, mirrors the primary gallery image
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
-	User User `json:"user" gorm:"foreignKey:UserID"`
+	// Spec values used by the trip calculator
+	FuelConsumption float64 `json:"fuel_consumption"` // L/100km as per manufacturer or owner
+	TankCapacity    float64 `json:"tank_capacity"`    // liters
+
+	User   User              `json:"user" gorm:"foreignKey:UserID"`
+	Images []MotorcycleImage `json:"images" gorm:"foreignKey:MotorcycleID"`
+	Mods   []MotorcycleMod   `json:"mods" gorm:"foreignKey:MotorcycleID"`
+}
+
+// MotorcycleImage is a single picture in a motorcycle's gallery
+type MotorcycleImage struct {
+	ID           string    `json:"id" gorm:"primaryKey;size:191"`
+	MotorcycleID string    `json:"motorcycle_id" gorm:"not null;size:191;index"`
+	UserID       string    `json:"user_id" gorm:"not null;size:191"`
+	URL          string    `json:"url" gorm:"not null;size:500"`
+	ObjectName   string    `json:"-" gorm:"not null;size:500"` // MinIO object path
+	Position     int       `json:"position" gorm:"default:0"`
+	IsPrimary    bool      `json:"is_primary" gorm:"default:false"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// MotorcycleMod is an aftermarket modification installed on a motorcycle
+type MotorcycleMod struct {
+	ID           string     `json:"id" gorm:"primaryKey;size:191"`
+	MotorcycleID string     `json:"motorcycle_id" gorm:"not null;size:191;index"`
+	UserID       string     `json:"user_id" gorm:"not null;size:191"`
+	Name         string     `json:"name" gorm:"not null;size:255"`
+	Category     string     `json:"category" gorm:"size:50"` // exhaust, suspension, luggage, protection, electronics, other
+	Brand        string     `json:"brand" gorm:"size:100"`
+	Description  string     `json:"description" gorm:"type:text"`
+	Cost         float64    `json:"cost"`
+	InstalledAt  *time.Time `json:"installed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// FuelLog is a single refuelling entry of a motorcycle
+type FuelLog struct {
+	ID            string    `json:"id" gorm:"primaryKey;size:191"`
+	MotorcycleID  string    `json:"motorcycle_id" gorm:"not null;size:191;index"`
+	UserID        string    `json:"user_id" gorm:"not null;size:191"`
+	Liters        float64   `json:"liters" gorm:"not null"`
+	PricePerLiter float64   `json:"price_per_liter"`
+	TotalCost     float64   `json:"total_cost"`
+	Currency      string    `json:"currency" gorm:"size:3;default:'EUR'"` // Currency of the price and total cost
+	Odometer      float64   `json:"odometer"`                             // km
+	IsFullTank    bool      `json:"is_full_tank" gorm:"default:true"`
+	Country       string    `json:"country" gorm:"size:2"` // ISO 3166-1 alpha-2
+	FilledAt      time.Time `json:"filled_at" gorm:"not null"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// MotorcycleRideStats aggregates completed rides recorded with a motorcycle
+type MotorcycleRideStats struct {
+	RidesCount    int64   `json:"rides_count"`
+	TotalDistance float64 `json:"total_distance"` // km
+	TotalDuration int64   `json:"total_duration"` // seconds
+	MaxSpeed      float64 `json:"max_speed"`      // km/h
+}
+
+// GarageMotorcycle represents a motorcycle on a user's public garage page
+type GarageMotorcycle struct {
+	Motorcycle
+	User      *User               `json:"user,omitempty"` // Shadows Motorcycle.User, the owner is GarageResponse.User
+	RideStats MotorcycleRideStats `json:"ride_stats"`
+}
+
+// GarageResponse represents the public garage view of a user's profile
+type GarageResponse struct {
+	User        NotificationUser    `json:"user"`
+	Motorcycles []GarageMotorcycle  `json:"motorcycles"`
+	Totals      MotorcycleRideStats `json:"totals"`
+	ModsCount   int                 `json:"mods_count"`
 }
@@ -14,6 +14,10 @@ const (
 	NotificationTypeComment     NotificationType = "comment"
 	NotificationTypeCommentLike NotificationType = "comment_like"
 	NotificationTypeShare       NotificationType = "share"
+	NotificationTypeExpense     NotificationType = "expense"
+	NotificationTypeRouteInvite NotificationType = "route_invite"
+	NotificationTypeRouteUpdate NotificationType = "route_update"
+	NotificationTypeHazardAlert NotificationType = "hazard_alert"
 )
 
 type Notification struct {
@@ -23,6 +27,7 @@ type Notification struct {
 	TargetUserID string           `json:"target_user_id" gorm:"not null;size:191"` // Who receives the notification
 	PostID       *string          `json:"post_id" gorm:"size:191"`                 // Optional: related post
 	CommentID    *string          `json:"comment_id" gorm:"size:191"`              // Optional: related comment
+	ReferenceID  *string          `json:"reference_id" gorm:"size:191"`            // Optional: related entity, e.g. expense group
 	IsRead       bool             `json:"is_read" gorm:"default:false"`
 	CreatedAt    time.Time        `json:"created_at"`
 	UpdatedAt    time.Time        `json:"updated_at"`
@@ -39,6 +44,7 @@ type NotificationResponse struct {
 	Type      NotificationType  `json:"type"`
 	ActorUser NotificationUser  `json:"actor_user"`
 	Post      *NotificationPost `json:"post,omitempty"`
+	Reference *string           `json:"reference_id,omitempty"`
 	IsRead    bool              `json:"is_read"`
 	CreatedAt time.Time         `json:"created_at"`
 	Message   string            `json:"message"`
@@ -81,6 +87,7 @@ type CreateNotificationParams struct {
 	TargetUserID string           `json:"target_user_id"`
 	PostID       *string          `json:"post_id,omitempty"`
 	CommentID    *string          `json:"comment_id,omitempty"`
+	ReferenceID  *string          `json:"reference_id,omitempty"`
 }
 
 // GetNotificationMessage returns a human-readable message for the notification
@@ -96,6 +103,14 @@ func (n *Notification) GetNotificationMessage() string {
 		return "liked your comment"
 	case NotificationTypeShare:
 		return "shared your post"
+	case NotificationTypeExpense:
+		return "added an expense to your group"
+	case NotificationTypeRouteInvite:
+		return "invited you to plan a route together"
+	case NotificationTypeRouteUpdate:
+		return "changed a route you plan together"
+	case NotificationTypeHazardAlert:
+		return "reported a hazard ahead of you"
 	default:
 		return "interacted with your content"
 	}
@@ -147,6 +162,7 @@ func (n *Notification) ToResponse() NotificationResponse {
 	response := NotificationResponse{
 		ID:        n.ID,
 		Type:      n.Type,
+		Reference: n.ReferenceID,
 		IsRead:    n.IsRead,
 		CreatedAt: n.CreatedAt,
 		Message:   n.GetNotificationMessage(),
@@ -164,6 +180,8 @@ func (n *Notification) ToResponse() NotificationResponse {
 		imageURL := ""
 		if n.Post.ImageUrls != nil && len(n.Post.ImageUrls) > 0 {
 			imageURL = n.Post.ImageUrls[0]
+		} else if n.Post.ThumbnailURL != "" {
+			imageURL = n.Post.ThumbnailURL // no photo, show the route instead
 		}
 		response.Post = &NotificationPost{
 			ID:       n.Post.ID,
@@ -0,0 +1,142 @@
+// File: /models/poi.go
+package models
+
+import (
+	"time"
+)
+
+// POI categories
+const (
+	POICategoryFuel      = "fuel"
+	POICategoryCafe      = "cafe"
+	POICategoryHangout   = "hangout" // biker meeting points and motorcycle friendly places
+	POICategoryViewpoint = "viewpoint"
+	POICategoryMechanic  = "mechanic"
+	POICategoryCampsite  = "campsite"
+	POICategoryPass      = "pass" // mountain passes, Elevation is the summit height
+)
+
+// POICategories lists every supported category
+var POICategories = []string{
+	POICategoryFuel,
+	POICategoryCafe,
+	POICategoryHangout,
+	POICategoryViewpoint,
+	POICategoryMechanic,
+	POICategoryCampsite,
+	POICategoryPass,
+}
+
+// POI review states. Imported POIs are approved right away, suggestions by
+// users wait for an admin.
+const (
+	POIStatusApproved = "approved"
+	POIStatusPending  = "pending"
+	POIStatusRejected = "rejected"
+)
+
+// IsValidPOICategory checks a category against POICategories
+func IsValidPOICategory(category string) bool {
+	for _, c := range POICategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// PointOfInterest is a place riders care about, imported from an external
+// dataset (OSM extract, CSV) or suggested by users, and kept locally so
+// lookups work offline
+type PointOfInterest struct {
+	ID            string    `json:"id" gorm:"primaryKey;size:191"`
+	Name          string    `json:"name" gorm:"not null;size:255"`
+	Category      string    `json:"category" gorm:"not null;size:50;index:idx_pois_category_location"`
+	Latitude      float64   `json:"latitude" gorm:"not null;index:idx_pois_category_location"`
+	Longitude     float64   `json:"longitude" gorm:"not null;index:idx_pois_category_location"`
+	Brand         string    `json:"brand" gorm:"size:100"`
+	Address       string    `json:"address" gorm:"size:500"`
+	CountryCode   string    `json:"country_code" gorm:"size:2"`
+	Source        string    `json:"source" gorm:"size:50"`             // csv, osm, user, ...
+	ExternalID    string    `json:"external_id" gorm:"size:191;index"` // ID in the source dataset, used to de-duplicate imports
+	Description   string    `json:"description" gorm:"type:text"`
+	Website       string    `json:"website" gorm:"size:500"`
+	Phone         string    `json:"phone" gorm:"size:50"`
+	OpeningHours  string    `json:"opening_hours" gorm:"size:255"` // OSM opening_hours syntax
+	Elevation     *float64  `json:"elevation"`                     // m
+	Status        string    `json:"status" gorm:"size:20;default:'approved';index"`
+	SuggestedByID *string   `json:"suggested_by_id,omitempty" gorm:"size:191;index"` // user who suggested it
+	ReviewNote    string    `json:"review_note,omitempty" gorm:"size:500"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// RoutePOI is a POI near a route
+type RoutePOI struct {
+	PointOfInterest
+	Detour            float64 `json:"detour"`              // km from the route
+	DistanceFromStart float64 `json:"distance_from_start"` // km along the route
+}
+
+// SharedRoutePOI attaches a POI to a shared route, e.g. the café the
+// route is meant to end at
+type SharedRoutePOI struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	SharedRouteID string    `json:"shared_route_id" gorm:"not null;size:191;uniqueIndex:idx_shared_route_pois"`
+	POIID         string    `json:"poi_id" gorm:"column:poi_id;not null;size:191;uniqueIndex:idx_shared_route_pois"`
+	AddedByID     string    `json:"added_by_id" gorm:"not null;size:191"`
+	Note          string    `json:"note" gorm:"size:500"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	POI PointOfInterest `json:"poi" gorm:"foreignKey:POIID"`
+}
+
+// NearbyPOI is a POI together with its distance from a reference point
+type NearbyPOI struct {
+	PointOfInterest
+	Distance float64 `json:"distance"` // km
+}
+
+// FuelStation is a fuel POI suggested near a planned fuel stop
+type FuelStation struct {
+	PointOfInterest
+	Detour            float64 `json:"detour"`              // km from the route
+	DistanceFromStart float64 `json:"distance_from_start"` // km along the route
+}
+
+// FuelStop is a point along a route where refuelling is required
+type FuelStop struct {
+	Number               int           `json:"number"`
+	Latitude             float64       `json:"latitude"`
+	Longitude            float64       `json:"longitude"`
+	DistanceFromStart    float64       `json:"distance_from_start"`    // km
+	DistanceFromPrevious float64       `json:"distance_from_previous"` // km since the previous stop or the start
+	FuelToAdd            float64       `json:"fuel_to_add"`            // liters to fill the tank
+	Station              *FuelStation  `json:"station"`                // nil when no station was found in range
+	Alternatives         []FuelStation `json:"alternatives"`
+}
+
+// FuelGapWarning flags a leg that is longer than the motorcycle can ride
+type FuelGapWarning struct {
+	FromKm   float64 `json:"from_km"`
+	ToKm     float64 `json:"to_km"`
+	Distance float64 `json:"distance"` // km
+	Range    float64 `json:"range"`    // usable range in km
+	Severity string  `json:"severity"` // reserve (eats into the reserve), critical (exceeds a full tank)
+	Message  string  `json:"message"`
+}
+
+// FuelStopPlan is the result of planning fuel stops along a route
+type FuelStopPlan struct {
+	RouteID         string           `json:"route_id"`
+	MotorcycleID    *string          `json:"motorcycle_id,omitempty"`
+	TotalDistance   float64          `json:"total_distance"`   // km
+	TankCapacity    float64          `json:"tank_capacity"`    // liters
+	FuelConsumption float64          `json:"fuel_consumption"` // L/100km
+	ReservePercent  float64          `json:"reserve_percent"`
+	FullRange       float64          `json:"full_range"`   // km on a full tank
+	UsableRange     float64          `json:"usable_range"` // km before touching the reserve
+	TotalFuel       float64          `json:"total_fuel"`   // liters for the whole route
+	Stops           []FuelStop       `json:"stops"`
+	Warnings        []FuelGapWarning `json:"warnings"`
+}
@@ -17,6 +17,8 @@ type Post struct {
 	LikesCount    int         `json:"likes_count" gorm:"default:0"`
 	CommentsCount int         `json:"comments_count" gorm:"default:0"`
 	SharesCount   int         `json:"shares_count" gorm:"default:0"`
+	RouteID       *string     `json:"route_id" gorm:"size:191;index"` // optional route the post is about
+	ThumbnailURL  string      `json:"thumbnail_url" gorm:"size:500"`  // the route's thumbnail, kept in sync by ThumbnailService
 	CreatedAt     time.Time   `json:"created_at"`
 	UpdatedAt     time.Time   `json:"updated_at"`
 
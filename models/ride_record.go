@@ -6,22 +6,27 @@ import (
 )
 
 type RideRecord struct {
-	ID             string      `json:"id" gorm:"primaryKey"`
-	UserID         string      `json:"user_id" gorm:"not null"`
-	MotorcycleID   string      `json:"motorcycle_id" gorm:"not null"`
-	MotorcycleName string      `json:"motorcycle_name" gorm:"not null"`
-	StartTime      time.Time   `json:"start_time" gorm:"not null"`
-	EndTime        *time.Time  `json:"end_time"`
-	Duration       int         `json:"duration"`        // in seconds
-	Distance       float64     `json:"distance"`        // in km
-	MaxSpeed       float64     `json:"max_speed"`       // in km/h
-	AverageSpeed   float64     `json:"average_speed"`   // in km/h
-	MaxAltitude    float64     `json:"max_altitude"`    // in meters
-	TotalElevation float64     `json:"total_elevation"` // in meters
-	PhotoUrls      StringSlice `json:"photo_urls" gorm:"type:json"`
-	IsCompleted    bool        `json:"is_completed" gorm:"default:false"`
-	CreatedAt      time.Time   `json:"created_at"`
-	UpdatedAt      time.Time   `json:"updated_at"`
+	ID                string      `json:"id" gorm:"primaryKey"`
+	UserID            string      `json:"user_id" gorm:"not null"`
+	MotorcycleID      string      `json:"motorcycle_id" gorm:"not null"`
+	MotorcycleName    string      `json:"motorcycle_name" gorm:"not null"`
+	RouteID           *string     `json:"route_id" gorm:"size:191"` // planned route, used to warn about hazards ahead
+	StartTime         time.Time   `json:"start_time" gorm:"not null"`
+	EndTime           *time.Time  `json:"end_time"`
+	Duration          int         `json:"duration"`        // in seconds
+	Distance          float64     `json:"distance"`        // in km
+	MaxSpeed          float64     `json:"max_speed"`       // in km/h
+	AverageSpeed      float64     `json:"average_speed"`   // in km/h
+	MaxAltitude       float64     `json:"max_altitude"`    // in meters
+	TotalElevation    float64     `json:"total_elevation"` // in meters
+	PhotoUrls         StringSlice `json:"photo_urls" gorm:"type:json"`
+	IsCompleted       bool        `json:"is_completed" gorm:"default:false"`
+	ThumbnailURL      string      `json:"thumbnail_url" gorm:"size:500"` // static map of the track, rendered once the ride is completed
+	ThumbnailAt       *time.Time  `json:"-" gorm:"index"`                // UpdatedAt the thumbnail was rendered for
+	ThumbnailFailures int         `json:"-"`                             // failed renderings since the last thumbnail
+	ThumbnailRetryAt  *time.Time  `json:"-" gorm:"index"`                // failed thumbnails are not rendered again before
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
 
 	User        User         `json:"user" gorm:"foreignKey:UserID"`
 	Motorcycle  Motorcycle   `json:"motorcycle" gorm:"foreignKey:MotorcycleID"`
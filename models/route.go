@@ -10,25 +10,39 @@ import (
 
 // Route represents a user's personal route (saved from route planning)
 type Route struct {
-	ID             string      `json:"id" gorm:"primaryKey;size:191"`
-	UserID         string      `json:"user_id" gorm:"not null;size:191"`
-	Name           string      `json:"name" gorm:"not null;size:255"`
-	Description    string      `json:"description" gorm:"type:text"`
-	TotalDistance  float64     `json:"total_distance"`  // km
-	TotalElevation float64     `json:"total_elevation"` // m
-	EstimatedTime  int         `json:"estimated_time"`  // in seconds
-	Difficulty     string      `json:"difficulty" gorm:"size:50"`
-	Tags           StringSlice `json:"tags" gorm:"type:json"`
-	IsPublic       bool        `json:"is_public" gorm:"default:false"`
-	TimesUsed      int         `json:"times_used" gorm:"default:0"`
-	RouteGeometry  JSONData    `json:"route_geometry" gorm:"type:json"` // Detailed route points
-	RouteSettings  JSONData    `json:"route_settings" gorm:"type:json"` // Route planning settings
-	CreatedAt      time.Time   `json:"created_at"`
-	UpdatedAt      time.Time   `json:"updated_at"`
+	ID                 string             `json:"id" gorm:"primaryKey;size:191"`
+	UserID             string             `json:"user_id" gorm:"not null;size:191"`
+	Name               string             `json:"name" gorm:"not null;size:255"`
+	Description        string             `json:"description" gorm:"type:text"`
+	TotalDistance      float64            `json:"total_distance"`                           // km
+	TotalElevation     float64            `json:"total_elevation"`                          // m
+	EstimatedTime      int                `json:"estimated_time"`                           // in seconds
+	Difficulty         string             `json:"difficulty" gorm:"size:50"`                // chosen by the creator
+	ComputedDifficulty string             `json:"computed_difficulty" gorm:"size:50;index"` // see DifficultyFactors
+	DifficultyFactors  *DifficultyFactors `json:"difficulty_factors" gorm:"type:json"`
+	Tags               StringSlice        `json:"tags" gorm:"type:json"`
+	IsPublic           bool               `json:"is_public" gorm:"default:false"`
+	TimesUsed          int                `json:"times_used" gorm:"default:0"`
+	RouteGeometry      Geometry           `json:"route_geometry" gorm:"type:json"`         // Detailed route points
+	RouteSettings      JSONData           `json:"route_settings" gorm:"type:json"`         // Route planning settings
+	TwistinessScore    float64            `json:"twistiness_score" gorm:"default:0;index"` // 0-100, see Curvature
+	Curvature          *CurvatureStats    `json:"curvature" gorm:"type:json"`
+	StartLatitude      float64            `json:"start_latitude"`
+	StartLongitude     float64            `json:"start_longitude"`
+	StartGeohash       string             `json:"-" gorm:"size:12;index"`               // see RouteGeoCell for the whole path
+	Version            int                `json:"version" gorm:"not null;default:1"`    // raised on every change, see CanBeEditedBy
+	ForkedFromID       *string            `json:"forked_from_id" gorm:"size:191;index"` // shared route this route was forked from
+	ThumbnailURL       string             `json:"thumbnail_url" gorm:"size:500"`        // static map of the route, see ThumbnailService
+	ThumbnailAt        *time.Time         `json:"-" gorm:"index"`                       // UpdatedAt the thumbnail was rendered for
+	ThumbnailFailures  int                `json:"-"`                                    // failed renderings since the last thumbnail
+	ThumbnailRetryAt   *time.Time         `json:"-" gorm:"index"`                       // failed thumbnails are not rendered again before
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
 
 	// Relationships
-	User      User            `json:"user" gorm:"foreignKey:UserID"`
-	Waypoints []RouteWaypoint `json:"waypoints" gorm:"foreignKey:RouteID"`
+	User          User                `json:"user" gorm:"foreignKey:UserID"`
+	Waypoints     []RouteWaypoint     `json:"waypoints" gorm:"foreignKey:RouteID"`
+	Collaborators []RouteCollaborator `json:"collaborators,omitempty" gorm:"foreignKey:RouteID"`
 }
 
 // RouteWaypoint represents a waypoint in a user's route
@@ -40,8 +54,10 @@ type RouteWaypoint struct {
 	Latitude    float64 `json:"latitude" gorm:"not null"`
 	Longitude   float64 `json:"longitude" gorm:"not null"`
 	Order       int     `json:"order" gorm:"not null"`
+	POIID       *string `json:"poi_id" gorm:"column:poi_id;size:191;index"` // the place the waypoint stops at
 
-	Route Route `json:"route" gorm:"foreignKey:RouteID"`
+	Route Route            `json:"route" gorm:"foreignKey:RouteID"`
+	POI   *PointOfInterest `json:"poi,omitempty" gorm:"foreignKey:POIID"`
 }
 
 // SavedRoute represents a bookmark to another user's route
@@ -94,19 +110,50 @@ type RoutePlanRequest struct {
 
 // RoutePlanResponse represents a route planning response
 type RoutePlanResponse struct {
-	Geometry []LatLng           `json:"geometry"`
-	Distance float64            `json:"distance"` // in meters
-	Duration float64            `json:"duration"` // in seconds
-	Summary  string             `json:"summary"`
-	Steps    []RouteInstruction `json:"steps"`
+	Geometry  []LatLng           `json:"geometry"`
+	Distance  float64            `json:"distance"` // in meters
+	Duration  float64            `json:"duration"` // in seconds
+	Summary   string             `json:"summary"`
+	Steps     []RouteInstruction `json:"steps"`
+	Engine    string             `json:"engine"` // routing engine that produced the route
+	Curvature *CurvatureStats    `json:"curvature,omitempty"`
+
+	Optimization *WaypointOptimization `json:"optimization,omitempty"`
+}
+
+// WaypointOptimization reports how the stops of a route were reordered
+type WaypointOptimization struct {
+	Mode              string  `json:"mode"`               // fixed_ends or round_trip
+	Order             []int   `json:"order"`              // submitted waypoint indexes in the optimized order
+	OriginalDistance  float64 `json:"original_distance"`  // road distance of the submitted order, in meters
+	OptimizedDistance float64 `json:"optimized_distance"` // in meters
+	DistanceSaved     float64 `json:"distance_saved"`     // in meters
+	Exact             bool    `json:"exact"`              // false when the order comes from a heuristic
 }
 
-// RouteInstruction represents a turn-by-turn instruction
+// Maneuver types of a RouteInstruction
+const (
+	ManeuverDepart     = "depart"
+	ManeuverTurn       = "turn"
+	ManeuverContinue   = "continue" // straight on where the road changes its name or at a junction
+	ManeuverRoundabout = "roundabout"
+	ManeuverArrive     = "arrive"
+)
+
+// RouteInstruction represents a turn-by-turn instruction. Distance and
+// Duration cover the stretch from the maneuver to the next one.
 type RouteInstruction struct {
 	Instruction string  `json:"instruction"`
+	Voice       string  `json:"voice"` // spoken variant, announcing the distance to the maneuver
 	Distance    float64 `json:"distance"`
 	Duration    float64 `json:"duration"`
 	Maneuver    string  `json:"maneuver"`
+	Modifier    string  `json:"modifier,omitempty"`  // straight, slight/sharp left/right, left, right or uturn
+	Direction   string  `json:"direction,omitempty"` // compass direction of a depart: north, northeast, ...
+	Exit        int     `json:"exit,omitempty"`      // roundabout exit to take
+	Road        string  `json:"road,omitempty"`      // road followed after the maneuver
+	Waypoint    int     `json:"waypoint,omitempty"`  // intermediate waypoint reached by an arrive
+	Location    *LatLng `json:"location,omitempty"`
 }
 
 // LatLng represents a latitude/longitude coordinate
@@ -177,14 +224,32 @@ func (r *Route) GetDifficultyLevel() int {
 	}
 }
 
-// IsAccessibleBy checks if a route is accessible by a given user
+// RoleOf returns the user's role on the route: owner, editor, viewer, or
+// empty for everyone else. Collaborators must be preloaded.
+func (r *Route) RoleOf(userID string) string {
+	if r.UserID == userID {
+		return RouteRoleOwner
+	}
+	for _, collaborator := range r.Collaborators {
+		if collaborator.UserID == userID {
+			return collaborator.Role
+		}
+	}
+	return ""
+}
+
+// IsAccessibleBy checks if a route is accessible by a given user: its owner,
+// a collaborator, or anyone when it is public. Collaborators must be preloaded.
 func (r *Route) IsAccessibleBy(userID string) bool {
-	return r.UserID == userID || r.IsPublic
+	return r.IsPublic || r.RoleOf(userID) != ""
 }
 
-// CanBeEditedBy checks if a route can be edited by a given user
+// CanBeEditedBy checks if a route can be edited by a given user: its owner
+// or an editor. Collaborators must be preloaded. Concurrent edits are
+// detected with Version.
 func (r *Route) CanBeEditedBy(userID string) bool {
-	return r.UserID == userID
+	role := r.RoleOf(userID)
+	return role == RouteRoleOwner || role == RouteRoleEditor
 }
 
 // IncrementUsage increments the times used counter
@@ -194,20 +259,7 @@ func (r *Route) IncrementUsage() {
 
 // GetRouteGeometryAsLatLng converts route geometry to LatLng slice
 func (r *Route) GetRouteGeometryAsLatLng() []LatLng {
-	var points []LatLng
-	for _, point := range r.RouteGeometry {
-		if pointMap, ok := point.(map[string]interface{}); ok {
-			if lat, ok := pointMap["latitude"].(float64); ok {
-				if lng, ok := pointMap["longitude"].(float64); ok {
-					points = append(points, LatLng{
-						Latitude:  lat,
-						Longitude: lng,
-					})
-				}
-			}
-		}
-	}
-	return points
+	return r.RouteGeometry.LatLngs()
 }
 
 // GetWaypointsAsLatLng converts waypoints to LatLng slice
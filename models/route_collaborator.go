@@ -0,0 +1,29 @@
+// File: /models/route_collaborator.go
+package models
+
+import "time"
+
+// Route roles; the owner's role is implied by Route.UserID
+const (
+	RouteRoleOwner  = "owner"
+	RouteRoleEditor = "editor" // may change waypoints, geometry, settings and metadata
+	RouteRoleViewer = "viewer" // may open, export and follow the route
+)
+
+// RouteCollaborator is a friend the owner invited to plan a route together
+type RouteCollaborator struct {
+	ID          string    `json:"id" gorm:"primaryKey;size:191"`
+	RouteID     string    `json:"route_id" gorm:"not null;size:191;uniqueIndex:idx_route_collaborators_user"`
+	UserID      string    `json:"user_id" gorm:"not null;size:191;uniqueIndex:idx_route_collaborators_user;index"`
+	Role        string    `json:"role" gorm:"not null;size:20"` // editor, viewer
+	InvitedByID string    `json:"invited_by_id" gorm:"not null;size:191"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	User User `json:"user" gorm:"foreignKey:UserID"`
+}
+
+// IsValidRouteRole reports whether a collaborator can be given the role
+func IsValidRouteRole(role string) bool {
+	return role == RouteRoleEditor || role == RouteRoleViewer
+}
@@ -0,0 +1,109 @@
+// File: /models/route_revision.go
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Route revision actions
+const (
+	RouteRevisionBaseline  = "baseline" // state of a route saved before revisions were kept
+	RouteRevisionCreated   = "created"
+	RouteRevisionUpdated   = "updated"
+	RouteRevisionImported  = "imported"
+	RouteRevisionOptimized = "optimized"
+	RouteRevisionReverted  = "reverted"
+	RouteRevisionForked    = "forked"
+)
+
+// RouteRevision is a snapshot of a route after a change: its metadata,
+// settings, geometry and waypoints. Revisions are numbered from 1 per route.
+type RouteRevision struct {
+	ID             string            `json:"id" gorm:"primaryKey;size:191"`
+	RouteID        string            `json:"route_id" gorm:"not null;size:191;uniqueIndex:idx_route_revisions_number"`
+	Number         int               `json:"number" gorm:"not null;uniqueIndex:idx_route_revisions_number"`
+	AuthorID       string            `json:"author_id" gorm:"not null;size:191"`
+	Action         string            `json:"action" gorm:"not null;size:20"`
+	RestoredFrom   *int              `json:"restored_from,omitempty"` // revision number a revert went back to
+	Name           string            `json:"name" gorm:"size:255"`
+	Description    string            `json:"description" gorm:"type:text"`
+	Difficulty     string            `json:"difficulty" gorm:"size:50"`
+	Tags           StringSlice       `json:"tags" gorm:"type:json"`
+	IsPublic       bool              `json:"is_public"`
+	TotalDistance  float64           `json:"total_distance"`  // km
+	TotalElevation float64           `json:"total_elevation"` // m
+	EstimatedTime  int               `json:"estimated_time"`  // in seconds
+	RouteGeometry  Geometry          `json:"route_geometry,omitempty" gorm:"type:json"`
+	RouteSettings  JSONData          `json:"route_settings" gorm:"type:json"`
+	Waypoints      RevisionWaypoints `json:"waypoints" gorm:"type:json"`
+	CreatedAt      time.Time         `json:"created_at"`
+
+	Author User `json:"author" gorm:"foreignKey:AuthorID"`
+}
+
+// RevisionWaypoint is a route waypoint as it was in a revision
+type RevisionWaypoint struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Order       int     `json:"order"`
+	POIID       *string `json:"poi_id,omitempty"`
+}
+
+// RevisionWaypoints is stored as a JSON column
+type RevisionWaypoints []RevisionWaypoint
+
+func (w RevisionWaypoints) Value() (driver.Value, error) {
+	if w == nil {
+		return "[]", nil
+	}
+	return json.Marshal(w)
+}
+
+func (w *RevisionWaypoints) Scan(value interface{}) error {
+	if value == nil {
+		*w = RevisionWaypoints{}
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, w)
+}
+
+// RouteRevisionDiff compares two revisions of a route
+type RouteRevisionDiff struct {
+	From            int                `json:"from"` // revision numbers
+	To              int                `json:"to"`
+	ChangedFields   []string           `json:"changed_fields"`
+	Added           []RevisionWaypoint `json:"added_waypoints"`
+	Removed         []RevisionWaypoint `json:"removed_waypoints"`
+	Moved           []WaypointMove     `json:"moved_waypoints"`
+	Reordered       []WaypointReorder  `json:"reordered_waypoints"`
+	GeometryChanged bool               `json:"geometry_changed"`
+	DistanceChange  float64            `json:"distance_change"`  // km
+	ElevationChange float64            `json:"elevation_change"` // m
+	TimeChange      int                `json:"time_change"`      // seconds
+}
+
+// WaypointMove is a waypoint whose position changed between revisions
+type WaypointMove struct {
+	Name     string  `json:"name"`
+	From     LatLng  `json:"from"`
+	To       LatLng  `json:"to"`
+	Distance float64 `json:"distance"` // meters
+}
+
+// WaypointReorder is a waypoint whose place in the route changed between revisions
+type WaypointReorder struct {
+	Name      string `json:"name"`
+	FromOrder int    `json:"from_order"`
+	ToOrder   int    `json:"to_order"`
+}
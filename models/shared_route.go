@@ -6,32 +6,44 @@ import (
 )
 
 // SharedRoutePoint represents a point in a shared route with optional elevation
-type SharedRoutePoint struct {
-	Latitude  float64  `json:"latitude"`
-	Longitude float64  `json:"longitude"`
-	Elevation *float64 `json:"elevation,omitempty"`
-}
+type SharedRoutePoint = GeometryPoint
 
 // SharedRoute represents a publicly shared route that users can explore
 type SharedRoute struct {
-	ID                string      `json:"id" gorm:"primaryKey;size:191"`
-	Title             string      `json:"title" gorm:"not null;size:255"`
-	Description       string      `json:"description" gorm:"type:text"`
-	CreatorID         string      `json:"creator_id" gorm:"not null;size:191"`
-	CreatorName       string      `json:"creator_name" gorm:"not null;size:255"`
-	CreatorAvatar     string      `json:"creator_avatar" gorm:"size:255"`
-	ImageUrls         StringSlice `json:"image_urls" gorm:"type:json"`
-	RoutePoints       JSONData    `json:"route_points" gorm:"type:json"` // Array of SharedRoutePoint
-	TotalDistance     float64     `json:"total_distance"`                // km
-	TotalElevation    float64     `json:"total_elevation"`               // m
-	EstimatedDuration int         `json:"estimated_duration"`            // seconds
-	Difficulty        string      `json:"difficulty" gorm:"size:50"`     // Easy, Medium, Hard
-	Tags              StringSlice `json:"tags" gorm:"type:json"`
-	LikesCount        int         `json:"likes_count" gorm:"default:0"`
-	CommentsCount     int         `json:"comments_count" gorm:"default:0"`
-	DownloadsCount    int         `json:"downloads_count" gorm:"default:0"`
-	CreatedAt         time.Time   `json:"created_at"`
-	UpdatedAt         time.Time   `json:"updated_at"`
+	ID                    string             `json:"id" gorm:"primaryKey;size:191"`
+	Title                 string             `json:"title" gorm:"not null;size:255"`
+	Description           string             `json:"description" gorm:"type:text"`
+	CreatorID             string             `json:"creator_id" gorm:"not null;size:191"`
+	CreatorName           string             `json:"creator_name" gorm:"not null;size:255"`
+	CreatorAvatar         string             `json:"creator_avatar" gorm:"size:255"`
+	ImageUrls             StringSlice        `json:"image_urls" gorm:"type:json"`
+	RoutePoints           Geometry           `json:"route_points" gorm:"type:json"`            // Ordered route points
+	TotalDistance         float64            `json:"total_distance"`                           // km
+	TotalElevation        float64            `json:"total_elevation"`                          // m
+	EstimatedDuration     int                `json:"estimated_duration"`                       // seconds
+	Difficulty            string             `json:"difficulty" gorm:"size:50"`                // Easy, Medium, Hard, chosen by the creator
+	ComputedDifficulty    string             `json:"computed_difficulty" gorm:"size:50;index"` // see DifficultyFactors
+	DifficultyFactors     *DifficultyFactors `json:"difficulty_factors" gorm:"type:json"`
+	Tags                  StringSlice        `json:"tags" gorm:"type:json"`
+	LikesCount            int                `json:"likes_count" gorm:"default:0"`
+	CommentsCount         int                `json:"comments_count" gorm:"default:0"`
+	DownloadsCount        int                `json:"downloads_count" gorm:"default:0"`
+	TwistinessScore       float64            `json:"twistiness_score" gorm:"default:0;index"` // 0-100, see Curvature
+	Curvature             *CurvatureStats    `json:"curvature" gorm:"type:json"`
+	StartLatitude         float64            `json:"start_latitude"`
+	StartLongitude        float64            `json:"start_longitude"`
+	StartGeohash          string             `json:"-" gorm:"size:12;index"`                      // see RouteGeoCell for the whole path
+	SourceRouteID         *string            `json:"source_route_id" gorm:"size:191;uniqueIndex"` // personal route it is published from and kept in sync with
+	ForkedFromID          *string            `json:"forked_from_id" gorm:"size:191;index"`        // shared route its source route was forked from
+	ForksCount            int                `json:"forks_count" gorm:"default:0"`
+	LineageLikesCount     int                `json:"lineage_likes_count" gorm:"default:0"`     // likes of the route and of all its forks
+	LineageDownloadsCount int                `json:"lineage_downloads_count" gorm:"default:0"` // downloads of the route and of all its forks
+	ThumbnailURL          string             `json:"thumbnail_url" gorm:"size:500"`            // static map of the route, see ThumbnailService
+	ThumbnailAt           *time.Time         `json:"-" gorm:"index"`                           // UpdatedAt the thumbnail was rendered for
+	ThumbnailFailures     int                `json:"-"`                                        // failed renderings since the last thumbnail
+	ThumbnailRetryAt      *time.Time         `json:"-" gorm:"index"`                           // failed thumbnails are not rendered again before
+	CreatedAt             time.Time          `json:"created_at"`
+	UpdatedAt             time.Time          `json:"updated_at"`
 
 	// Relationships
 	Creator   User                  `json:"creator" gorm:"foreignKey:CreatorID"`
@@ -101,3 +113,8 @@ type SharedRouteStats struct {
 	TotalDownloads int64     `json:"total_downloads"`
 	PopularTags    []TagInfo `json:"popular_tags"`
 }
+
+// GetRoutePointsAsLatLng converts the stored route points to LatLng slice
+func (sr *SharedRoute) GetRoutePointsAsLatLng() []LatLng {
+	return sr.RoutePoints.LatLngs()
+}
@@ -0,0 +1,21 @@
+// File: /models/spatial.go
+package models
+
+// Owners of route geohash cells
+const (
+	RouteGeoCellOwnerRoute       = "route"
+	RouteGeoCellOwnerSharedRoute = "shared_route"
+)
+
+// RouteGeoCell is a geohash cell a route or shared route passes through.
+// Bounding box queries match cells by geohash prefix and then by their bounds.
+type RouteGeoCell struct {
+	ID           uint    `json:"id" gorm:"primaryKey"`
+	OwnerType    string  `json:"owner_type" gorm:"not null;size:20;index:idx_route_geo_cells_owner"`
+	OwnerID      string  `json:"owner_id" gorm:"not null;size:191;index:idx_route_geo_cells_owner"`
+	Cell         string  `json:"cell" gorm:"not null;size:12;index"`
+	MinLatitude  float64 `json:"min_latitude"`
+	MaxLatitude  float64 `json:"max_latitude"`
+	MinLongitude float64 `json:"min_longitude"`
+	MaxLongitude float64 `json:"max_longitude"`
+}
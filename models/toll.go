@@ -0,0 +1,81 @@
+// File: /models/toll.go
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Toll kinds
+const (
+	TollKindVignette = "vignette" // time based permit for a country's motorway network
+	TollKindToll     = "toll"     // single passage of a tolled road section, tunnel or pass
+)
+
+// Vehicle classes
+const (
+	VehicleClassMotorcycle = "motorcycle"
+	VehicleClassCar        = "car"
+)
+
+// TollRate is a vignette or toll price for a vehicle class, valid for a period.
+// Section tolls carry a location and apply when a route passes within RadiusKm.
+type TollRate struct {
+	ID           string     `json:"id" gorm:"primaryKey;size:191"`
+	CountryCode  string     `json:"country_code" gorm:"not null;size:2;index:idx_toll_rates_lookup"`
+	VehicleClass string     `json:"vehicle_class" gorm:"not null;size:20;default:'motorcycle';index:idx_toll_rates_lookup"`
+	Kind         string     `json:"kind" gorm:"not null;size:20"` // vignette, toll
+	Name         string     `json:"name" gorm:"not null;size:255"`
+	Price        float64    `json:"price" gorm:"not null"`
+	Currency     string     `json:"currency" gorm:"size:3;default:'EUR'"`
+	ValidityDays int        `json:"validity_days"` // how long a vignette is valid, 0 for tolls
+	ValidFrom    time.Time  `json:"valid_from" gorm:"not null"`
+	ValidTo      *time.Time `json:"valid_to"` // nil while the rate is current
+	Latitude     *float64   `json:"latitude"`
+	Longitude    *float64   `json:"longitude"`
+	RadiusKm     float64    `json:"radius_km"`
+	Source       string     `json:"source" gorm:"size:100"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// IsValidAt reports whether the rate applies on the given date
+func (t *TollRate) IsValidAt(at time.Time) bool {
+	return !at.Before(t.ValidFrom) && (t.ValidTo == nil || at.Before(*t.ValidTo))
+}
+
+// TollCostItem is a single line in a trip's toll and vignette breakdown
+type TollCostItem struct {
+	TollRateID   string  `json:"toll_rate_id"`
+	CountryCode  string  `json:"country_code"`
+	Kind         string  `json:"kind"`
+	Name         string  `json:"name"`
+	Quantity     int     `json:"quantity"` // vignettes needed to cover the trip
+	ValidityDays int     `json:"validity_days"`
+	UnitPrice    float64 `json:"unit_price"`
+	Amount       float64 `json:"amount"`
+	Currency     string  `json:"currency"`
+}
+
+// TollCostItems is stored as a JSON column
+type TollCostItems []TollCostItem
+
+func (t TollCostItems) Value() (driver.Value, error) {
+	return json.Marshal(t)
+}
+
+func (t *TollCostItems) Scan(value interface{}) error {
+	if value == nil {
+		*t = TollCostItems{}
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, t)
+}
@@ -0,0 +1,71 @@
+// File: /models/trip.go
+package models
+
+import "time"
+
+// Trip is a multi-day tour: day legs in order, each riding a Route and
+// ending at an overnight stop
+type Trip struct {
+	ID            string     `json:"id" gorm:"primaryKey;size:191"`
+	UserID        string     `json:"user_id" gorm:"not null;size:191;index"`
+	Name          string     `json:"name" gorm:"not null;size:255"`
+	Description   string     `json:"description" gorm:"type:text"`
+	StartDate     *time.Time `json:"start_date" gorm:"type:date"`
+	EndDate       *time.Time `json:"end_date" gorm:"type:date"`
+	Currency      string     `json:"currency" gorm:"size:3;default:'EUR'"` // accommodation costs and budgets are in this currency
+	TotalDistance float64    `json:"total_distance"`                       // km, sum of the day legs
+	TotalTime     int        `json:"total_time"`                           // riding seconds, sum of the day legs
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	User User      `json:"-" gorm:"foreignKey:UserID"`
+	Days []TripDay `json:"days" gorm:"foreignKey:TripID"`
+}
+
+// TripDay is one day leg of a trip. The accommodation is where the rider
+// sleeps after the leg; the last day usually has none.
+type TripDay struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	TripID     string     `json:"trip_id" gorm:"not null;size:191;index"`
+	DayNumber  int        `json:"day_number" gorm:"not null"` // 1-based order of the legs
+	Date       *time.Time `json:"date" gorm:"type:date"`
+	RouteID    *string    `json:"route_id" gorm:"size:191;index"` // nil for a rest day
+	Title      string     `json:"title" gorm:"size:255"`
+	Notes      string     `json:"notes" gorm:"type:text"`
+	Distance   float64    `json:"distance"`    // km
+	RidingTime int        `json:"riding_time"` // seconds
+
+	AccommodationName      string   `json:"accommodation_name" gorm:"size:255"`
+	AccommodationAddress   string   `json:"accommodation_address" gorm:"size:500"`
+	AccommodationLatitude  *float64 `json:"accommodation_latitude"`
+	AccommodationLongitude *float64 `json:"accommodation_longitude"`
+	AccommodationCost      float64  `json:"accommodation_cost"` // in the trip currency
+	AccommodationURL       string   `json:"accommodation_url" gorm:"size:500"`
+
+	Route *Route `json:"route,omitempty" gorm:"foreignKey:RouteID"`
+}
+
+// TripBudget is the estimated cost of a trip from the trip cost calculator,
+// with fuel per day, tolls and vignettes for the whole trip and accommodation
+type TripBudget struct {
+	TripID            string          `json:"trip_id"`
+	Currency          string          `json:"currency"`
+	TotalDistance     float64         `json:"total_distance"` // km
+	FuelNeeded        float64         `json:"fuel_needed_liters"`
+	FuelCost          float64         `json:"fuel_cost"`
+	TollCosts         float64         `json:"toll_costs"`
+	TollItems         TollCostItems   `json:"toll_items"`
+	AccommodationCost float64         `json:"accommodation_cost"`
+	TotalCost         float64         `json:"total_cost"`
+	Days              []TripDayBudget `json:"days"`
+}
+
+// TripDayBudget is the cost of one day leg; tolls are only known for the whole trip
+type TripDayBudget struct {
+	DayNumber         int     `json:"day_number"`
+	Distance          float64 `json:"distance"` // km
+	FuelNeeded        float64 `json:"fuel_needed_liters"`
+	FuelCost          float64 `json:"fuel_cost"`
+	AccommodationCost float64 `json:"accommodation_cost"`
+	TotalCost         float64 `json:"total_cost"`
+}
@@ -7,20 +7,22 @@ import (
 )
 
 type User struct {
-	ID             string    `json:"id" gorm:"primaryKey;size:191"`
-	Name           string    `json:"name" gorm:"not null;size:255"`
-	Handle         string    `json:"handle" gorm:"uniqueIndex;not null;size:50"` // Added for @username functionality
-	Email          string    `json:"email" gorm:"uniqueIndex;not null;size:255"`
-	Password       string    `json:"-" gorm:"not null;size:255"`
-	EmailVerified  bool      `json:"email_verified" gorm:"default:false"`
-	Avatar         *string   `json:"avatar" gorm:"size:500"`
-	FollowersCount int       `json:"followers_count" gorm:"default:0"`
-	FollowingCount int       `json:"following_count" gorm:"default:0"`
-	RidesCount     int       `json:"rides_count" gorm:"default:0"`
-	TotalTime      string    `json:"total_time" gorm:"default:'0h 0m';size:50"`
-	TotalDistance  string    `json:"total_distance" gorm:"default:'0 km';size:50"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID                string    `json:"id" gorm:"primaryKey;size:191"`
+	Name              string    `json:"name" gorm:"not null;size:255"`
+	Handle            string    `json:"handle" gorm:"uniqueIndex;not null;size:50"` // Added for @username functionality
+	Email             string    `json:"email" gorm:"uniqueIndex;not null;size:255"`
+	Password          string    `json:"-" gorm:"not null;size:255"`
+	EmailVerified     bool      `json:"email_verified" gorm:"default:false"`
+	IsAdmin           bool      `json:"is_admin" gorm:"default:false"`
+	PreferredCurrency string    `json:"preferred_currency" gorm:"size:3;default:'EUR'"` // Display currency for money values
+	Avatar            *string   `json:"avatar" gorm:"size:500"`
+	FollowersCount    int       `json:"followers_count" gorm:"default:0"`
+	FollowingCount    int       `json:"following_count" gorm:"default:0"`
+	RidesCount        int       `json:"rides_count" gorm:"default:0"`
+	TotalTime         string    `json:"total_time" gorm:"default:'0h 0m';size:50"`
+	TotalDistance     string    `json:"total_distance" gorm:"default:'0 km';size:50"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
 
 	// Relationships
 	Motorcycles   []Motorcycle     `json:"motorcycles" gorm:"foreignKey:UserID"`
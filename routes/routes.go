@@ -2,6 +2,7 @@
 package routes
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"motocosmos-api/config"
@@ -10,21 +11,39 @@ import (
 	"motocosmos-api/services"
 )
 
-func SetupRoutes(router *gin.Engine, db *gorm.DB, jwtSecret string) {
+func SetupRoutes(router *gin.Engine, db *gorm.DB, jwtSecret string, storageService *services.StorageService) {
 	cfg := config.Load()
 	emailService := services.NewEmailService(cfg)
+	routingEngine, err := services.NewRoutingEngine(cfg)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize routing engine: %v", err))
+	}
+	elevationService := services.NewElevationService(cfg.ElevationDataPath)
 
 	// Initialize controllers in proper order - NotificationController first
 	notificationController := controllers.NewNotificationController(db)
 	authController := controllers.NewAuthController(db, jwtSecret, emailService)
 	userController := controllers.NewUserController(db, notificationController)
-	postController := controllers.NewPostController(db, notificationController)
+	postController := controllers.NewPostController(db, notificationController, storageService)
 	commentController := controllers.NewCommentController(db, notificationController)
-	sharedRouteController := controllers.NewSharedRouteController(db, notificationController)
-	routeController := controllers.NewRouteController(db) // NEW: Personal routes controller
+	sharedRouteController := controllers.NewSharedRouteController(db, notificationController, elevationService, routingEngine)
+	routeController := controllers.NewRouteController(db, routingEngine, elevationService, notificationController) // NEW: Personal routes controller
 	socialAuthController := controllers.NewSocialAuthController(db, jwtSecret)
-	locatorController := controllers.NewLocatorController(db)
+	locatorController := controllers.NewLocatorController(db, notificationController)
 	friendController := controllers.NewFriendController(db, notificationController)
+	motorcycleController := controllers.NewMotorcycleController(db, storageService)
+	calculatorController := controllers.NewCalculatorController(db)
+	fuelPriceController := controllers.NewFuelPriceController(db)
+	currencyController := controllers.NewCurrencyController(db)
+	fuelStopController := controllers.NewFuelStopController(db)
+	tollController := controllers.NewTollController(db)
+	expenseController := controllers.NewExpenseController(db, notificationController)
+	eventController := controllers.NewEventController(db)
+	tripController := controllers.NewTripController(db, routingEngine, elevationService)
+	poiController := controllers.NewPOIController(db)
+	hazardController := controllers.NewHazardController(db, notificationController)
+	rideController := controllers.NewRideController(db, elevationService, notificationController)
+	thumbnailController := controllers.NewThumbnailController(storageService)
 
 	router.Static("/uploads", "./uploads")
 
@@ -76,6 +95,7 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, jwtSecret string) {
 		users.GET("/following-status/:user_id", userController.GetFollowingStatus) // Check if following a user
 		users.GET("/search", userController.SearchUsers)                           // Search users by name/handle
 		users.GET("/handle/:handle", userController.GetUserByHandle)               // Get user by handle
+		users.GET("/garage/:user_id", userController.GetGarage)                    // Public garage: bikes, ride totals, mods
 	}
 
 	friends := protected.Group("/friends")
@@ -127,6 +147,8 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, jwtSecret string) {
 	}
 
 	v1.GET("/posts/images/:user_id/:file", postController.GetImage)
+	v1.GET("/motorcycles/images/:user_id/:file", motorcycleController.GetImage)
+	v1.GET("/thumbnails/:kind/:file", thumbnailController.GetThumbnail) // Static route maps, see thumbnail_url
 
 
 	// NEW: Shared Routes - Public exploration of community routes
@@ -140,9 +162,18 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, jwtSecret string) {
 		sharedRoutes.DELETE("/:id", sharedRouteController.DeleteSharedRoute) // Delete shared route (creator only)
 
 		// Interaction endpoints
-		sharedRoutes.POST("/:id/like", sharedRouteController.LikeSharedRoute)         // Toggle like on shared route
-		sharedRoutes.POST("/:id/bookmark", sharedRouteController.BookmarkSharedRoute) // Toggle bookmark on shared route
-		sharedRoutes.POST("/:id/download", sharedRouteController.DownloadSharedRoute) // Download/navigate to route
+		sharedRoutes.POST("/:id/like", sharedRouteController.LikeSharedRoute)             // Toggle like on shared route
+		sharedRoutes.POST("/:id/bookmark", sharedRouteController.BookmarkSharedRoute)     // Toggle bookmark on shared route
+		sharedRoutes.POST("/:id/download", sharedRouteController.DownloadSharedRoute)     // Download/navigate to route
+		sharedRoutes.GET("/:id/elevation", sharedRouteController.GetSharedRouteElevation) // Elevation profile from DEM tiles
+		sharedRoutes.GET("/:id/export", sharedRouteController.ExportSharedRoute)          // GPX/KML/GeoJSON file
+		sharedRoutes.GET("/:id/pois", poiController.GetSharedRoutePOIs)                   // Attached points of interest
+		sharedRoutes.POST("/:id/pois", poiController.AttachSharedRoutePOI)                // Attach a POI (creator only)
+		sharedRoutes.DELETE("/:id/pois/:poi_id", poiController.DetachSharedRoutePOI)      // Detach a POI (creator only)
+
+		// Forks and attribution
+		sharedRoutes.POST("/:id/fork", sharedRouteController.ForkSharedRoute)         // Copy into a personal route
+		sharedRoutes.GET("/:id/lineage", sharedRouteController.GetSharedRouteLineage) // Forked from, forks and lineage counts
 
 		// Collection endpoints
 		sharedRoutes.GET("/bookmarked", sharedRouteController.GetBookmarkedRoutes) // Get user's bookmarked routes
@@ -173,11 +204,30 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, jwtSecret string) {
 		routes.GET("/saved", routeController.GetSavedRoutes) // Get user's saved routes (alias for GET /)
 		routes.GET("/:id", routeController.GetRoute)         // Get single route by ID
 		routes.PUT("/:id", routeController.UpdateRoute)      // Update route (owner only)
-	
 
 		// Route planning endpoints
-		routes.POST("/plan", routeController.PlanRoute)                     // Plan a route using waypoints
+		routes.POST("/plan", routeController.PlanRoute)                     // Plan a route with the routing engine
 		routes.POST("/calculate-metrics", routeController.CalculateMetrics) // Calculate distance/time between points
+		routes.POST("/elevation", routeController.ProfileElevation)         // Elevation profile of a path from DEM tiles
+		routes.POST("/loops", routeController.GenerateLoops)                // Round trip suggestions from a start point
+		routes.POST("/:id/optimize", routeController.OptimizeRoute)         // Reorder the stops for the shortest ride
+
+		// Revision history
+		routes.GET("/:id/revisions", routeController.GetRouteRevisions)              // List revisions, newest first
+		routes.GET("/:id/revisions/:number", routeController.GetRouteRevision)       // Single revision with geometry
+		routes.GET("/:id/revisions/:number/diff", routeController.DiffRouteRevision) // Changes against the previous revision
+		routes.POST("/:id/revisions/:number/revert", routeController.RevertRoute)    // Restore a revision (owner and editors)
+
+		// Planning together with friends
+		routes.GET("/shared-with-me", routeController.GetSharedWithMeRoutes)                  // Routes the user was invited to
+		routes.GET("/:id/collaborators", routeController.GetRouteCollaborators)               // Owner and invited friends
+		routes.POST("/:id/collaborators", routeController.InviteRouteCollaborator)            // Invite a friend or change their role (owner only)
+		routes.DELETE("/:id/collaborators/:user_id", routeController.RemoveRouteCollaborator) // Remove a collaborator or leave the route
+		routes.PUT("/:id/waypoints/:waypoint_id/poi", routeController.SetWaypointPOI)         // Attach or detach a POI
+
+		// Publishing to the community
+		routes.POST("/:id/publish", routeController.PublishRoute)     // Publish as a shared route, kept in sync (owner only)
+		routes.DELETE("/:id/publish", routeController.UnpublishRoute) // Remove the shared route (owner only)
 
 		// Route recommendations and discovery
 		routes.GET("/recommendations", routeController.GetRecommendations) // Get recommended public routes
@@ -186,27 +236,80 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, jwtSecret string) {
 		routes.POST("/:id/bookmark", routeController.BookmarkRoute)     // Bookmark a public route
 		routes.DELETE("/:id/bookmark", routeController.UnbookmarkRoute) // Remove bookmark
 		routes.GET("/bookmarked", routeController.GetBookmarkedRoutes)  // Get bookmarked routes
+
+		// Trip planning
+		routes.POST("/:id/fuel-stops", fuelStopController.PlanFuelStops) // Plan fuel stops for a motorcycle
+		routes.GET("/:id/elevation", routeController.GetRouteElevation)  // Elevation profile from DEM tiles
+
+		// GPX/KML/GeoJSON files
+		routes.POST("/import", routeController.ImportRoute)    // Multipart upload (field "file")
+		routes.GET("/:id/export", routeController.ExportRoute) // ?format=gpx|kml|geojson
 	}
 
-	// Motorcycle routes (if implemented)
+	// Motorcycle routes - the user's garage
 	motorcycles := protected.Group("/motorcycles")
 	{
-		// Add motorcycle endpoints here when implemented
-		_ = motorcycles // Prevent unused variable error
+		motorcycles.GET("/", motorcycleController.GetMotorcycles)
+		motorcycles.POST("/", motorcycleController.CreateMotorcycle)
+		motorcycles.PUT("/:id", motorcycleController.UpdateMotorcycle)
+		motorcycles.DELETE("/:id", motorcycleController.DeleteMotorcycle)
+
+		// Image gallery
+		motorcycles.POST("/:id/images", motorcycleController.UploadImages)                     // Upload one or more images
+		motorcycles.DELETE("/:id/images/:image_id", motorcycleController.DeleteImage)          // Delete image
+		motorcycles.PUT("/:id/images/:image_id/primary", motorcycleController.SetPrimaryImage) // Set cover image
+
+		// Mods list
+		motorcycles.GET("/:id/mods", motorcycleController.GetMods)
+		motorcycles.POST("/:id/mods", motorcycleController.AddMod)
+		motorcycles.PUT("/:id/mods/:mod_id", motorcycleController.UpdateMod)
+		motorcycles.DELETE("/:id/mods/:mod_id", motorcycleController.DeleteMod)
+
+		// Fuel log
+		motorcycles.GET("/:id/fuel-logs", motorcycleController.GetFuelLogs)
+		motorcycles.POST("/:id/fuel-logs", motorcycleController.AddFuelLog)
+		motorcycles.DELETE("/:id/fuel-logs/:log_id", motorcycleController.DeleteFuelLog)
 	}
 
-	// Event routes (if implemented)
+	// Event routes
 	events := protected.Group("/events")
 	{
-		// Add event endpoints here when implemented
-		_ = events // Prevent unused variable error
+		events.GET("/", eventController.GetEvents) // Upcoming events (?lat=&lng=&radius=&bbox=&from=&to=&when=)
+		events.POST("/", eventController.CreateEvent)
+		events.GET("/joined", eventController.GetJoinedEvents)
+		events.GET("/created", eventController.GetCreatedEvents)
+		events.GET("/search", eventController.SearchEvents)
+		events.GET("/:id", eventController.GetEvent)
+		events.PUT("/:id", eventController.UpdateEvent)    // Organizer only
+		events.DELETE("/:id", eventController.DeleteEvent) // Organizer only
+		events.POST("/:id/join", eventController.JoinEvent)
+		events.POST("/:id/leave", eventController.LeaveEvent)
+		events.POST("/:id/like", eventController.LikeEvent)
+		events.DELETE("/:id/like", eventController.UnlikeEvent)
 	}
 
-	// Ride recording routes (if implemented)
+	// Ride recording routes
 	rides := protected.Group("/rides")
 	{
-		// Add ride recording endpoints here when implemented
-		_ = rides // Prevent unused variable error
+		rides.GET("/", rideController.GetRides)
+		rides.POST("/", rideController.StartRide) // Optionally on a planned route
+		rides.GET("/:id", rideController.GetRide)
+		rides.POST("/:id/pause", rideController.PauseRide)
+		rides.POST("/:id/resume", rideController.ResumeRide)
+		rides.POST("/:id/stop", rideController.StopRide)
+		rides.POST("/:id/share", rideController.ShareRide)
+		rides.POST("/:id/points", rideController.AddRoutePoint) // Warns about hazards ahead
+	}
+
+	// Road hazard reports
+	hazards := protected.Group("/hazards")
+	{
+		hazards.GET("/types", hazardController.GetHazardTypes)
+		hazards.GET("/nearby", hazardController.GetNearbyHazards) // ?lat=&lng=&radius=&type=
+		hazards.POST("/", hazardController.ReportHazard)
+		hazards.GET("/:id", hazardController.GetHazard)
+		hazards.POST("/:id/confirm", hazardController.ConfirmHazard) // Still there or gone
+		hazards.DELETE("/:id", hazardController.DeleteHazard)        // Reporter only
 	}
 
 	// Location routes (if implemented)
@@ -216,11 +319,91 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, jwtSecret string) {
 		_ = locations // Prevent unused variable error
 	}
 
-	// Trip calculator routes (if implemented)
+	// Trip calculator routes
 	calculator := protected.Group("/calculator")
 	{
-		// Add calculator endpoints here when implemented
-		_ = calculator // Prevent unused variable error
+		calculator.POST("/calculate", calculatorController.CalculateTrip)            // Manual values or route + motorcycle
+		calculator.POST("/save", calculatorController.SaveCalculation)               // Calculate and store in history
+		calculator.GET("/history", calculatorController.GetHistory)                  // Saved calculations
+		calculator.DELETE("/history", calculatorController.ClearHistory)             // Clear saved calculations
+		calculator.GET("/fuel-prices", calculatorController.GetFuelPrices)           // Reference fuel prices per country
+		calculator.GET("/fuel-consumption", calculatorController.GetFuelConsumption) // Typical consumption values
+
+		// Fuel price database
+		calculator.GET("/fuel-prices/latest", fuelPriceController.GetLatestPrices)  // Current prices incl. regions
+		calculator.GET("/fuel-prices/history", fuelPriceController.GetPriceHistory) // Price history of a country
+		calculator.GET("/fuel-prices/trend", fuelPriceController.GetPriceTrend)     // Price trend of a country
+		calculator.GET("/toll-rates", tollController.GetTollRates)                  // Current vignettes and tolls
+	}
+
+	// Group expense ledgers
+	expenses := protected.Group("/expenses")
+	{
+		expenses.GET("/groups", expenseController.GetGroups)
+		expenses.POST("/groups", expenseController.CreateGroup) // From an event or a list of friends
+		expenses.GET("/groups/:id", expenseController.GetGroup)
+		expenses.POST("/groups/:id/members", expenseController.AddMember)
+		expenses.DELETE("/groups/:id/members/:user_id", expenseController.RemoveMember)
+		expenses.GET("/groups/:id/expenses", expenseController.GetExpenses)
+		expenses.POST("/groups/:id/expenses", expenseController.AddExpense) // Notifies the other members
+		expenses.DELETE("/groups/:id/expenses/:expense_id", expenseController.DeleteExpense)
+		expenses.GET("/groups/:id/balances", expenseController.GetBalances) // Balances and settle-up suggestions
+		expenses.GET("/groups/:id/settlements", expenseController.GetSettlements)
+		expenses.POST("/groups/:id/settlements", expenseController.RecordSettlement)
+	}
+
+	// Points of interest
+	pois := protected.Group("/pois")
+	{
+		pois.GET("/categories", poiController.GetCategories)
+		pois.GET("/nearby", poiController.GetNearbyPOIs)           // Near me (?lat=&lng=&radius=&category=)
+		pois.POST("/along-route", poiController.GetPOIsAlongRoute) // Within a distance of a route or geometry
+		pois.POST("/suggestions", poiController.SuggestPOI)        // Reviewed by an admin
+		pois.GET("/:id", poiController.GetPOI)
+	}
+
+	// Multi-day trips
+	trips := protected.Group("/trips")
+	{
+		trips.GET("/", tripController.GetTrips)
+		trips.POST("/", tripController.CreateTrip)
+		trips.POST("/split", tripController.SplitRoute) // Split a long route into day legs
+		trips.GET("/:id", tripController.GetTrip)
+		trips.PUT("/:id", tripController.UpdateTrip) // Replaces the days
+		trips.DELETE("/:id", tripController.DeleteTrip)
+		trips.GET("/:id/budget", tripController.GetTripBudget) // Fuel, tolls and accommodation
+	}
+
+	// Admin routes
+	admin := protected.Group("/admin")
+	admin.Use(middleware.AdminMiddleware(db))
+	{
+		admin.GET("/fuel-prices", fuelPriceController.ListPrices)
+		admin.POST("/fuel-prices", fuelPriceController.CreatePrice)
+		admin.POST("/fuel-prices/import", fuelPriceController.ImportPrices) // CSV or JSON upload
+		admin.PUT("/fuel-prices/:id", fuelPriceController.UpdatePrice)
+		admin.DELETE("/fuel-prices/:id", fuelPriceController.DeletePrice)
+
+		admin.GET("/exchange-rates", currencyController.ListExchangeRates)
+		admin.POST("/exchange-rates", currencyController.CreateExchangeRate)
+		admin.POST("/exchange-rates/import", currencyController.ImportExchangeRates) // CSV or JSON upload
+
+		admin.POST("/pois/import", fuelStopController.ImportPOIs) // CSV, JSON or OSM XML upload
+		admin.GET("/pois/suggestions", poiController.GetSuggestions)
+		admin.POST("/pois/:id/review", poiController.ReviewSuggestion)
+
+		admin.GET("/toll-rates", tollController.ListTollRates)
+		admin.POST("/toll-rates", tollController.CreateTollRate)
+		admin.POST("/toll-rates/import", tollController.ImportTollRates) // CSV or JSON upload
+		admin.PUT("/toll-rates/:id/expire", tollController.ExpireTollRate)
+		admin.DELETE("/toll-rates/:id", tollController.DeleteTollRate)
+	}
+
+	// Currency routes
+	currencies := protected.Group("/currencies")
+	{
+		currencies.GET("/", currencyController.GetCurrencies)  // Supported currencies and current rates
+		currencies.GET("/convert", currencyController.Convert) // Convert an amount
 	}
 
 	// Health check endpoint (public)
@@ -256,6 +439,7 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, jwtSecret string) {
 					"GET /users/following":                 "Get users being followed",
 					"GET /users/search":                    "Search users",
 					"GET /users/handle/:handle":            "Get user by handle",
+					"GET /users/garage/:user_id":           "Get a user's public garage",
 				},
 				"notifications": gin.H{
 					"GET /notifications/":         "Get paginated notifications",
@@ -266,7 +450,7 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, jwtSecret string) {
 				},
 				"posts": gin.H{
 					"GET /posts/":                 "Get all posts",
-					"POST /posts/":                "Create a new post",
+					"POST /posts/":                "Create a new post; route_id links a route whose map thumbnail stands in when there are no images",
 					"GET /posts/feed":             "Get personalized feed",
 					"GET /posts/:id":              "Get single post",
 					"PUT /posts/:id":              "Update post",
@@ -280,32 +464,171 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, jwtSecret string) {
 					"GET /posts/bookmarked":       "Get bookmarked posts",
 				},
 				"shared-routes": gin.H{
-					"GET /shared-routes/":              "Get all shared routes with filtering",
-					"POST /shared-routes/":             "Create a new shared route",
-					"GET /shared-routes/:id":           "Get single shared route",
-					"PUT /shared-routes/:id":           "Update shared route (creator only)",
-					"DELETE /shared-routes/:id":        "Delete shared route (creator only)",
-					"POST /shared-routes/:id/like":     "Toggle like on shared route",
-					"POST /shared-routes/:id/bookmark": "Toggle bookmark on shared route",
-					"POST /shared-routes/:id/download": "Download/navigate to shared route",
-					"GET /shared-routes/bookmarked":    "Get user's bookmarked routes",
-					"GET /shared-routes/search":        "Search shared routes",
-					"GET /shared-routes/tags/popular":  "Get popular tags",
-					"GET /shared-routes/stats":         "Get shared route statistics",
+					"GET /shared-routes/":                    "Get all shared routes with filtering (?difficulty=&computed_difficulty=Easy|Medium|Hard, min_twistiness=&max_twistiness=&sort=newest|popular|twistiness, lat=&lng=&radius=km starting nearby, bbox=west,south,east,north passing through)",
+					"POST /shared-routes/":                   "Create a new shared route",
+					"GET /shared-routes/:id":                 "Get single shared route",
+					"PUT /shared-routes/:id":                 "Update shared route (creator only; routes published from a personal route are edited through that route)",
+					"DELETE /shared-routes/:id":              "Delete shared route (creator only); its forks are credited to the route it was forked from",
+					"POST /shared-routes/:id/like":           "Toggle like on shared route",
+					"POST /shared-routes/:id/bookmark":       "Toggle bookmark on shared route",
+					"POST /shared-routes/:id/download":       "Download shared route as a file and count it (?format=gpx|kml|geojson)",
+					"GET /shared-routes/:id/export":          "Export shared route as a file (?format=gpx|kml|geojson)",
+					"GET /shared-routes/:id/elevation":       "Get the elevation profile (ascent, descent, max gradient)",
+					"GET /shared-routes/:id/pois":            "Get the points of interest attached to a shared route",
+					"POST /shared-routes/:id/pois":           "Attach a point of interest with a note (creator only; poi_id, note)",
+					"DELETE /shared-routes/:id/pois/:poi_id": "Detach a point of interest (creator only)",
+					"POST /shared-routes/:id/fork":           "Fork a shared route into a personal route that credits it (name)",
+					"GET /shared-routes/:id/lineage":         "Get the routes a shared route was forked from, its forks, and the likes and downloads of its whole lineage",
+					"GET /shared-routes/bookmarked":          "Get user's bookmarked routes",
+					"GET /shared-routes/search":              "Search shared routes (?q=&min_twistiness=&max_twistiness=&sort=&lat=&lng=&radius=&bbox=)",
+					"GET /shared-routes/tags/popular":        "Get popular tags",
+					"GET /shared-routes/stats":               "Get shared route statistics",
+				},
+				"events": gin.H{
+					"GET /events/":            "Get upcoming events (?search=&difficulty=&computed_difficulty=&available_only=, lat=&lng=&radius=km, bbox=west,south,east,north, from=&to=, when=this_weekend|next_weekend)",
+					"POST /events/":           "Create an event",
+					"GET /events/joined":      "Get events the user joined",
+					"GET /events/created":     "Get events the user organizes",
+					"GET /events/search":      "Search upcoming events (?q=)",
+					"GET /events/:id":         "Get single event",
+					"PUT /events/:id":         "Update event (organizer only)",
+					"DELETE /events/:id":      "Delete event (organizer only)",
+					"POST /events/:id/join":   "Join an event",
+					"POST /events/:id/leave":  "Leave an event",
+					"POST /events/:id/like":   "Like an event",
+					"DELETE /events/:id/like": "Unlike an event",
+				},
+				"motorcycles": gin.H{
+					"GET /motorcycles/":                             "Get user's motorcycles",
+					"POST /motorcycles/":                            "Add a motorcycle",
+					"PUT /motorcycles/:id":                          "Update motorcycle",
+					"DELETE /motorcycles/:id":                       "Delete motorcycle",
+					"POST /motorcycles/:id/images":                  "Upload gallery images",
+					"DELETE /motorcycles/:id/images/:image_id":      "Delete gallery image",
+					"PUT /motorcycles/:id/images/:image_id/primary": "Set cover image",
+					"GET /motorcycles/:id/mods":                     "Get mods list",
+					"POST /motorcycles/:id/mods":                    "Add a mod",
+					"PUT /motorcycles/:id/mods/:mod_id":             "Update a mod",
+					"DELETE /motorcycles/:id/mods/:mod_id":          "Delete a mod",
+					"GET /motorcycles/:id/fuel-logs":                "Get fuel log and calculated consumption",
+					"POST /motorcycles/:id/fuel-logs":               "Add a refuelling",
+					"DELETE /motorcycles/:id/fuel-logs/:log_id":     "Delete a refuelling",
+				},
+				"calculator": gin.H{
+					"POST /calculator/calculate":          "Calculate trip cost (manual values or route_id/shared_route_id + motorcycle_id)",
+					"POST /calculator/save":               "Calculate and save trip cost",
+					"GET /calculator/history":             "Get saved calculations",
+					"DELETE /calculator/history":          "Clear saved calculations",
+					"GET /calculator/fuel-prices":         "Get current national fuel prices per country (?grade=95|98|diesel)",
+					"GET /calculator/fuel-consumption":    "Get typical fuel consumption values",
+					"GET /calculator/fuel-prices/latest":  "Get current fuel prices incl. regional prices",
+					"GET /calculator/fuel-prices/history": "Get fuel price history (?country=&grade=&region=&days=)",
+					"GET /calculator/fuel-prices/trend":   "Get fuel price trend (?country=&grade=&region=&days=)",
+					"GET /calculator/toll-rates":          "Get current vignette and toll rates (?country=&vehicle_class=)",
+				},
+				"expenses": gin.H{
+					"GET /expenses/groups":                             "Get the user's expense groups",
+					"POST /expenses/groups":                            "Create an expense group from an event or friends",
+					"GET /expenses/groups/:id":                         "Get expense group with members",
+					"POST /expenses/groups/:id/members":                "Add a friend or event participant",
+					"DELETE /expenses/groups/:id/members/:user_id":     "Remove a member without expenses",
+					"GET /expenses/groups/:id/expenses":                "Get group expenses",
+					"POST /expenses/groups/:id/expenses":               "Add an expense (equal or weighted split)",
+					"DELETE /expenses/groups/:id/expenses/:expense_id": "Delete an expense",
+					"GET /expenses/groups/:id/balances":                "Get balances and settle-up suggestions (?currency=)",
+					"GET /expenses/groups/:id/settlements":             "Get recorded settlements",
+					"POST /expenses/groups/:id/settlements":            "Record a settlement between members",
+				},
+				"rides": gin.H{
+					"GET /rides/":            "Get the user's recorded rides",
+					"POST /rides/":           "Start a ride (motorcycle_id, optional route_id to be warned about hazards ahead on it)",
+					"GET /rides/:id":         "Get a ride with its track",
+					"POST /rides/:id/pause":  "Pause the active ride",
+					"POST /rides/:id/resume": "Resume the active ride",
+					"POST /rides/:id/stop":   "Stop the active ride and calculate its statistics",
+					"POST /rides/:id/share":  "Share a completed ride",
+					"POST /rides/:id/points": "Add a track point; hazards ahead are sent as notifications",
+				},
+				"hazards": gin.H{
+					"GET /hazards/types":        "Get the hazard types and how long reports stay active (seconds)",
+					"GET /hazards/nearby":       "Get active hazards near a position (?lat=&lng=&radius=km&type=gravel,oil)",
+					"POST /hazards/":            "Report a hazard (type, latitude, longitude, description); confirms an active one of the same type nearby",
+					"GET /hazards/:id":          "Get a hazard report",
+					"POST /hazards/:id/confirm": "Confirm a hazard is still there or gone (still_there); confirmations extend it, denials dispute it",
+					"DELETE /hazards/:id":       "Delete a hazard you reported",
+				},
+				"pois": gin.H{
+					"GET /pois/categories":   "Get the point of interest categories (fuel, cafe, hangout, viewpoint, mechanic, campsite, pass)",
+					"GET /pois/nearby":       "Get points of interest near a position (?lat=&lng=&radius=km&category=fuel,cafe&limit=)",
+					"POST /pois/along-route": "Get points of interest within radius km of a route (route_id, shared_route_id or points; categories)",
+					"POST /pois/suggestions": "Suggest a new point of interest, visible to others once approved",
+					"GET /pois/:id":          "Get a point of interest",
+				},
+				"trips": gin.H{
+					"GET /trips":            "Get the user's multi-day trips",
+					"POST /trips":           "Create a trip from day legs with routes, dates and accommodation",
+					"POST /trips/split":     "Split a route into days (route_id, max_daily_distance km and/or max_daily_time s)",
+					"GET /trips/:id":        "Get a trip with its days and routes",
+					"PUT /trips/:id":        "Update a trip and replace its days",
+					"DELETE /trips/:id":     "Delete a trip (routes are kept)",
+					"GET /trips/:id/budget": "Get the trip budget (?motorcycle_id=&fuel_grade=&include_tolls=&vehicle_class=)",
+				},
+				"admin": gin.H{
+					"GET /admin/fuel-prices":            "List stored fuel prices",
+					"POST /admin/fuel-prices":           "Add a fuel price",
+					"POST /admin/fuel-prices/import":    "Bulk import fuel prices from CSV/JSON",
+					"PUT /admin/fuel-prices/:id":        "Correct a fuel price",
+					"DELETE /admin/fuel-prices/:id":     "Delete a fuel price",
+					"GET /admin/exchange-rates":         "List stored exchange rates",
+					"POST /admin/exchange-rates":        "Add an exchange rate",
+					"POST /admin/exchange-rates/import": "Bulk import exchange rates from CSV/JSON",
+					"POST /admin/pois/import":           "Bulk import points of interest from CSV/JSON or an OSM XML extract (categories from OSM tags)",
+					"GET /admin/pois/suggestions":       "List points of interest suggested by users (?status=pending|approved|rejected)",
+					"POST /admin/pois/:id/review":       "Approve or reject a suggested point of interest (approve, note)",
+					"GET /admin/toll-rates":             "List all toll and vignette rates",
+					"POST /admin/toll-rates":            "Add a toll or vignette rate",
+					"POST /admin/toll-rates/import":     "Bulk import toll and vignette rates from CSV/JSON",
+					"PUT /admin/toll-rates/:id/expire":  "End the validity of a rate",
+					"DELETE /admin/toll-rates/:id":      "Delete a rate",
+				},
+				"thumbnails": gin.H{
+					"GET /thumbnails/:kind/:file": "Static map PNG of a route, shared route or completed ride (kind=routes|shared-routes|rides), linked as thumbnail_url and rendered in the background after changes",
+				},
+				"currencies": gin.H{
+					"GET /currencies/":        "Get supported currencies, preferred currency and rates (?base=)",
+					"GET /currencies/convert": "Convert an amount (?amount=&from=&to=)",
 				},
 				"routes": gin.H{
-					"GET /routes/":                   "Get user's personal routes with filtering",
-					"POST /routes/":                  "Create/save a new route",
-					"GET /routes/saved":              "Get user's saved routes",
-					"GET /routes/:id":                "Get single route by ID",
-					"PUT /routes/:id":                "Update route (owner only)",
-					"DELETE /routes/:id":             "Delete route (owner only)",
-					"POST /routes/plan":              "Plan a route using waypoints",
-					"POST /routes/calculate-metrics": "Calculate distance/time between points",
-					"GET /routes/recommendations":    "Get recommended public routes",
-					"POST /routes/:id/bookmark":      "Bookmark a public route",
-					"DELETE /routes/:id/bookmark":    "Remove bookmark",
-					"GET /routes/bookmarked":         "Get bookmarked routes",
+					"GET /routes/":                               "Get user's personal routes with filtering (?difficulty=&computed_difficulty=Easy|Medium|Hard, min_twistiness=&max_twistiness=&sort=twistiness, lat=&lng=&radius=km starting nearby, bbox=west,south,east,north passing through)",
+					"POST /routes/":                              "Create/save a new route",
+					"GET /routes/saved":                          "Get user's saved routes",
+					"GET /routes/:id":                            "Get single route by ID",
+					"PUT /routes/:id":                            "Update route (owner and editors); send the loaded version, required once friends plan the route, 409 when someone else changed it first",
+					"DELETE /routes/:id":                         "Delete route (owner only)",
+					"POST /routes/plan":                          "Plan a route through waypoints (avoid_highways, prefer_winding, profile=motorcycle) with its curvature; optimize=fixed_ends|round_trip reorders the stops over road distances; steps carry turn-by-turn instruction and voice texts in language=en|de|hu (default from Accept-Language)",
+					"POST /routes/calculate-metrics":             "Calculate distance/time between points",
+					"POST /routes/loops":                         "Suggest round trips (start, distance km, direction, prefer_winding, avoid_highways, candidates); save one with POST /routes/",
+					"POST /routes/:id/optimize":                  "Reorder a route's stops for the shortest ride (mode=fixed_ends|round_trip), renumber its waypoints and report the distance saved",
+					"GET /routes/:id/revisions":                  "List a route's revisions, newest first (author, action, waypoints, distance)",
+					"GET /routes/:id/revisions/:number":          "Get one revision of a route with its geometry and waypoints",
+					"GET /routes/:id/revisions/:number/diff":     "Diff a revision against the previous one or ?against= (added, removed, moved and reordered waypoints, distance change)",
+					"POST /routes/:id/revisions/:number/revert":  "Restore a route to a revision (owner and editors, version); the restored state is saved as a new revision",
+					"GET /routes/shared-with-me":                 "Get routes friends invited you to plan (paginated)",
+					"GET /routes/:id/collaborators":              "List a route's collaborators and their roles",
+					"POST /routes/:id/collaborators":             "Invite a friend to a route as editor or viewer, or change their role (owner only; user_id, role)",
+					"DELETE /routes/:id/collaborators/:user_id":  "Remove a collaborator (owner) or leave a route (the collaborator)",
+					"PUT /routes/:id/waypoints/:waypoint_id/poi": "Attach a point of interest to a waypoint, or detach it with an empty poi_id (poi_id, version)",
+					"POST /routes/:id/publish":                   "Publish a route as a shared route, or refresh it (owner only; image_urls); later edits are mirrored until it is unpublished",
+					"DELETE /routes/:id/publish":                 "Unpublish a route and make it private (owner only)",
+					"POST /routes/elevation":                     "Get the elevation profile of a path (points)",
+					"GET /routes/:id/elevation":                  "Get the elevation profile of a route (ascent, descent, max gradient)",
+					"POST /routes/import":                        "Import a route from a GPX (rte/trk), KML or GeoJSON file (multipart field file, ?format=)",
+					"GET /routes/:id/export":                     "Export a route as a file (?format=gpx|kml|geojson)",
+					"GET /routes/recommendations":                "Get recommended public routes",
+					"POST /routes/:id/bookmark":                  "Bookmark a public route",
+					"DELETE /routes/:id/bookmark":                "Remove bookmark",
+					"GET /routes/bookmarked":                     "Get bookmarked routes",
+					"POST /routes/:id/fuel-stops":                "Plan fuel stops for a motorcycle along the route",
 				},
 			},
 		})
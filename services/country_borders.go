@@ -0,0 +1,310 @@
+// File: /services/country_borders.go
+package services
+
+// borderLines are simplified borders and coastlines as {lat, lng} points,
+// accurate to a few kilometers. A border between two known countries is
+// stored once and shared by both outlines, so neighbours never overlap.
+// Pieces are named after the countries they separate, alphabetically, or
+// after the country and the coast or unsupported neighbours they face.
+var borderLines = map[string][][2]float64{
+	// Austria
+	"AT-CH north": {{47.52, 9.62}, {47.43, 9.65}, {47.35, 9.62}, {47.27, 9.53}},
+	"AT-CH south": {{47.06, 9.61}, {47.05, 9.75}, {46.96, 9.90}, {46.85, 10.10}, {46.90, 10.25}, {46.97, 10.40}, {46.93, 10.50}, {46.85, 10.47}},
+	"AT-CZ": {
+		{48.77, 13.84}, {48.70, 14.00}, {48.58, 14.30}, {48.61, 14.70}, {48.78, 14.97}, {49.01, 15.00}, {48.96, 15.35},
+		{48.91, 15.65}, {48.84, 15.95}, {48.78, 16.10}, {48.73, 16.40}, {48.78, 16.62}, {48.62, 16.94},
+	},
+	"AT-DE": {
+		{47.52, 9.62}, {47.54, 9.73}, {47.58, 9.82}, {47.55, 9.97}, {47.45, 10.10}, {47.30, 10.20}, {47.39, 10.43},
+		{47.52, 10.45}, {47.57, 10.60}, {47.55, 10.75}, {47.47, 10.92}, {47.40, 11.00}, {47.39, 11.25}, {47.45, 11.40},
+		{47.53, 11.55}, {47.60, 11.75}, {47.58, 12.00}, {47.62, 12.18}, {47.70, 12.43}, {47.61, 12.57}, {47.67, 12.78},
+		{47.57, 12.80}, {47.46, 12.92}, {47.55, 13.06}, {47.70, 13.08}, {47.77, 12.98}, {47.84, 12.995}, {47.95, 12.93},
+		{48.06, 12.78}, {48.16, 12.85}, {48.262, 13.03}, {48.33, 13.30}, {48.47, 13.44}, {48.56, 13.45}, {48.58, 13.51},
+		{48.52, 13.72}, {48.77, 13.84},
+	},
+	"AT-HU": {
+		{48.01, 17.16}, {47.93, 17.11}, {47.76, 17.05}, {47.68, 16.90}, {47.66, 16.75}, {47.73, 16.65}, {47.72, 16.52},
+		{47.66, 16.45}, {47.52, 16.66}, {47.42, 16.48}, {47.30, 16.47}, {47.05, 16.47}, {46.95, 16.30}, {46.87, 16.11},
+	},
+	"AT-IT": {
+		{46.52, 13.71}, {46.60, 13.40}, {46.63, 12.90}, {46.68, 12.45}, {46.74, 12.37}, {46.85, 12.20}, {47.07, 12.20},
+		{46.98, 11.75}, {47.00, 11.51}, {46.90, 11.10}, {46.78, 10.78}, {46.84, 10.51}, {46.85, 10.47},
+	},
+	"AT-LI": {{47.27, 9.53}, {47.22, 9.59}, {47.10, 9.64}, {47.06, 9.61}},
+	"AT-SI": {
+		{46.87, 16.11}, {46.68, 16.00}, {46.70, 15.64}, {46.64, 15.45}, {46.65, 15.07}, {46.62, 14.97}, {46.52, 14.80},
+		{46.41, 14.55}, {46.44, 14.25}, {46.48, 13.95}, {46.52, 13.71},
+	},
+	"AT-SK": {{48.62, 16.94}, {48.45, 16.86}, {48.28, 16.90}, {48.17, 16.98}, {48.12, 17.06}, {48.01, 17.16}},
+
+	// Bosnia and Herzegovina
+	"BA-HR": {
+		{44.87, 19.05}, {45.15, 18.45}, {45.15, 18.00}, {45.10, 17.50}, {45.27, 16.90}, {45.22, 16.35}, {45.10, 15.95},
+		{44.80, 15.75}, {44.55, 16.05}, {44.20, 16.20}, {43.95, 16.60}, {43.60, 17.05}, {43.30, 17.40}, {43.08, 17.70},
+		{42.95, 17.58},
+	},
+	"BA coast":    {{42.95, 17.58}, {42.90, 17.70}},
+	"BA-HR south": {{42.90, 17.70}, {42.85, 17.85}, {42.70, 18.10}, {42.55, 18.45}},
+	"BA-ME":       {{42.55, 18.45}, {42.62, 18.55}, {42.90, 18.50}, {43.10, 18.65}, {43.30, 18.95}, {43.52, 19.23}},
+	"BA-RS":       {{43.52, 19.23}, {43.78, 19.35}, {44.00, 19.55}, {44.40, 19.12}, {44.75, 19.38}, {44.89, 19.35}, {44.87, 19.05}},
+
+	// Belgium
+	"BE-DE": {{50.13, 6.14}, {50.33, 6.40}, {50.50, 6.20}, {50.65, 6.20}, {50.755, 6.02}},
+	"BE-FR": {
+		{51.09, 2.55}, {50.95, 2.60}, {50.78, 2.90}, {50.70, 3.15}, {50.52, 3.30}, {50.45, 3.60}, {50.33, 4.00},
+		{50.10, 4.15}, {49.97, 4.45}, {50.17, 4.82}, {49.80, 4.90}, {49.78, 5.15}, {49.55, 5.45}, {49.55, 5.82},
+	},
+	"BE-LU": {{49.55, 5.82}, {49.70, 5.90}, {49.87, 5.75}, {50.05, 5.95}, {50.13, 6.14}},
+	"BE-NL": {
+		{50.755, 6.02}, {50.76, 5.72}, {50.83, 5.64}, {51.00, 5.77}, {51.10, 5.82}, {51.17, 5.85}, {51.25, 5.55},
+		{51.35, 5.20}, {51.43, 4.95}, {51.45, 4.75}, {51.44, 4.40}, {51.37, 4.25}, {51.27, 4.20}, {51.25, 3.95},
+		{51.28, 3.60}, {51.37, 3.36},
+	},
+	"BE coast": {{51.37, 3.36}, {51.22, 2.90}, {51.09, 2.55}},
+
+	// Bulgaria
+	"BG-GR":    {{41.72, 26.36}, {41.55, 26.10}, {41.40, 25.30}, {41.55, 24.60}, {41.40, 23.30}, {41.37, 22.95}},
+	"BG-MK":    {{41.37, 22.95}, {41.70, 22.95}, {42.05, 22.80}, {42.32, 22.36}},
+	"BG-RS":    {{44.22, 22.68}, {43.80, 22.45}, {43.45, 22.75}, {43.05, 22.95}, {42.85, 22.50}, {42.32, 22.36}},
+	"BG-TR":    {{41.98, 28.03}, {41.95, 27.50}, {41.72, 26.36}},
+	"BG coast": {{43.74, 28.58}, {43.20, 27.95}, {42.70, 27.75}, {42.50, 27.50}, {42.10, 27.90}, {41.98, 28.03}},
+
+	// Switzerland and Liechtenstein
+	"CH-DE": {
+		{47.52, 9.62}, {47.60, 9.40}, {47.653, 9.18}, {47.69, 9.00}, {47.66, 8.85}, {47.78, 8.70}, {47.70, 8.50},
+		{47.59, 8.40}, {47.61, 8.22}, {47.56, 7.95}, {47.555, 7.79}, {47.59, 7.59},
+	},
+	"CH-FR": {
+		{45.92, 7.04}, {46.10, 6.85}, {46.39, 6.80}, {46.45, 6.50}, {46.30, 6.24}, {46.19, 6.22}, {46.12, 6.10},
+		{46.15, 5.96}, {46.25, 5.97}, {46.35, 6.12}, {46.48, 6.10}, {46.72, 6.32}, {46.78, 6.46}, {46.92, 6.46},
+		{47.06, 6.70}, {47.25, 6.95}, {47.45, 6.92}, {47.50, 7.13}, {47.43, 7.45}, {47.50, 7.52}, {47.59, 7.59},
+	},
+	"CH-IT": {
+		{46.85, 10.47}, {46.63, 10.49}, {46.53, 10.25}, {46.23, 10.15}, {46.40, 9.95}, {46.30, 9.45}, {46.50, 9.30},
+		{46.15, 9.05}, {45.83, 9.03}, {46.10, 8.70}, {46.26, 8.43}, {46.15, 8.10}, {45.93, 7.87}, {45.98, 7.66},
+		{45.87, 7.17}, {45.92, 7.04},
+	},
+	"CH-LI": {{47.27, 9.53}, {47.14, 9.49}, {47.06, 9.50}, {47.06, 9.61}},
+
+	// Czech Republic
+	"CZ-DE": {
+		{50.87, 14.82}, {51.00, 14.60}, {51.05, 14.30}, {50.87, 14.20}, {50.75, 13.80}, {50.50, 13.25}, {50.40, 12.95},
+		{50.27, 12.40}, {50.32, 12.15}, {50.26, 12.09}, {50.18, 12.20}, {50.10, 12.22}, {50.00, 12.45}, {49.65, 12.53},
+		{49.45, 12.65}, {49.33, 12.87}, {49.15, 13.15}, {48.95, 13.50}, {48.77, 13.84},
+	},
+	"CZ-PL": {
+		{50.87, 14.82}, {51.02, 15.00}, {50.80, 15.30}, {50.74, 15.74}, {50.62, 16.05}, {50.65, 16.35}, {50.44, 16.22},
+		{50.35, 16.35}, {50.15, 16.66}, {50.20, 16.95}, {50.40, 17.05}, {50.30, 17.30}, {50.30, 17.70}, {50.10, 17.75},
+		{50.00, 18.00}, {49.93, 18.20}, {49.93, 18.55}, {49.75, 18.62}, {49.52, 18.85},
+	},
+	"CZ-SK": {{48.62, 16.94}, {48.85, 17.18}, {49.00, 17.60}, {49.15, 18.10}, {49.40, 18.35}, {49.52, 18.85}},
+
+	// Germany
+	"DE-DK": {{55.05, 8.40}, {54.92, 8.70}, {54.84, 9.35}, {54.83, 9.62}},
+	"DE-FR": {
+		{47.59, 7.59}, {47.80, 7.55}, {48.10, 7.58}, {48.30, 7.73}, {48.58, 7.80}, {48.97, 8.22}, {49.05, 7.95},
+		{49.12, 7.50}, {49.08, 7.10}, {49.20, 7.00}, {49.21, 6.93}, {49.47, 6.37},
+	},
+	"DE-LU": {{49.47, 6.37}, {49.71, 6.50}, {49.81, 6.42}, {50.00, 6.13}, {50.13, 6.14}},
+	"DE-NL": {
+		{50.755, 6.02}, {50.90, 6.08}, {51.05, 5.88}, {51.20, 6.08}, {51.37, 6.23}, {51.55, 6.10}, {51.85, 5.98},
+		{51.80, 6.20}, {51.90, 6.42}, {51.85, 6.80}, {52.12, 7.05}, {52.43, 7.05}, {52.65, 6.78}, {53.00, 7.20},
+		{53.30, 7.15}, {53.45, 6.98}, {53.60, 6.60},
+	},
+	"DE-PL": {
+		{53.93, 14.22}, {53.45, 14.41}, {53.10, 14.38}, {52.60, 14.62}, {52.35, 14.555}, {51.95, 14.72}, {51.50, 14.73},
+		{51.15, 14.99}, {50.87, 14.82},
+	},
+	"DE Baltic coast": {
+		{54.83, 9.62}, {54.68, 10.05}, {54.45, 10.20}, {54.50, 11.25}, {53.98, 10.88}, {54.02, 11.55}, {54.20, 12.10},
+		{54.45, 12.50}, {54.70, 13.40}, {54.20, 13.85}, {53.93, 14.22},
+	},
+	"DE North Sea coast": {{53.60, 6.60}, {53.75, 7.90}, {53.90, 8.60}, {54.35, 8.55}, {54.75, 8.27}, {55.05, 8.40}},
+
+	// Denmark
+	"DK coast": {
+		{54.83, 9.62}, {55.05, 9.50}, {55.50, 9.75}, {56.15, 10.25}, {56.45, 10.95}, {57.75, 10.60}, {57.12, 8.60},
+		{56.70, 8.15}, {55.50, 8.08}, {55.05, 8.40},
+	},
+	"Zealand": {
+		{56.12, 12.31}, {56.03, 12.62}, {55.68, 12.65}, {55.30, 12.45}, {55.00, 11.90}, {55.33, 11.12}, {55.68, 11.05},
+		{55.95, 11.40}, {55.97, 11.85},
+	},
+	"Funen": {{55.51, 9.73}, {55.60, 10.30}, {55.45, 10.75}, {55.06, 10.65}, {55.00, 10.30}, {55.27, 9.88}},
+
+	// Spain
+	"ES-FR": {
+		{42.43, 3.17}, {42.46, 2.86}, {42.42, 2.20}, {42.44, 1.94}, {42.60, 1.45}, {42.85, 0.80}, {42.70, 0.10},
+		{42.80, -0.53}, {43.05, -1.20}, {43.25, -1.45}, {43.38, -1.78},
+	},
+	"ES-PT": {
+		{41.87, -8.87}, {42.05, -8.62}, {42.13, -8.20}, {41.88, -7.20}, {41.98, -6.55}, {41.55, -6.20}, {41.00, -6.90},
+		{40.60, -6.80}, {40.20, -7.00}, {39.65, -7.05}, {39.00, -7.00}, {38.20, -6.95}, {37.55, -7.45}, {37.17, -7.40},
+	},
+	"ES north coast": {
+		{43.38, -1.78}, {43.32, -1.98}, {43.40, -3.00}, {43.47, -3.80}, {43.57, -5.70}, {43.55, -7.05}, {43.75, -7.70},
+		{43.37, -8.40}, {42.90, -9.27}, {42.23, -8.80}, {41.87, -8.87},
+	},
+	"ES south coast": {
+		{37.17, -7.40}, {37.20, -6.95}, {36.50, -6.28}, {36.01, -5.61}, {36.13, -5.35}, {36.70, -4.42}, {36.83, -2.46},
+		{36.72, -2.19}, {37.58, -0.98}, {38.33, -0.48}, {38.75, 0.20}, {39.45, -0.32}, {39.97, 0.05}, {40.70, 0.90},
+		{41.10, 1.25}, {41.38, 2.18}, {41.70, 2.90}, {42.32, 3.32}, {42.43, 3.17},
+	},
+	"Mallorca": {{39.95, 3.10}, {39.70, 3.45}, {39.30, 3.10}, {39.50, 2.45}, {39.90, 2.80}},
+
+	// France
+	"FR-IT": {
+		{43.78, 7.53}, {44.10, 7.70}, {44.15, 7.45}, {44.35, 6.90}, {44.70, 6.98}, {44.93, 6.72}, {45.25, 6.90},
+		{45.45, 7.10}, {45.68, 6.88}, {45.83, 6.85}, {45.92, 7.04},
+	},
+	"FR-LU": {{49.55, 5.82}, {49.47, 6.00}, {49.45, 6.20}, {49.47, 6.37}},
+	"FR Atlantic coast": {
+		{43.38, -1.78}, {43.48, -1.56}, {44.65, -1.25}, {45.60, -1.25}, {46.15, -1.20}, {46.80, -2.10}, {47.27, -2.30},
+		{47.75, -3.70}, {48.00, -4.70}, {48.40, -4.78}, {48.70, -4.10}, {48.65, -2.02}, {48.65, -1.55}, {49.70, -1.95},
+		{49.65, -1.30}, {49.35, -0.60}, {49.50, 0.10}, {49.93, 1.08}, {50.20, 1.55}, {50.73, 1.60}, {50.97, 1.85},
+		{51.05, 2.38}, {51.09, 2.55},
+	},
+	"FR Mediterranean coast": {
+		{43.78, 7.53}, {43.68, 7.25}, {43.53, 7.00}, {43.20, 6.65}, {43.05, 5.95}, {43.20, 5.35}, {43.40, 4.60},
+		{43.40, 3.70}, {43.00, 3.05}, {42.43, 3.17},
+	},
+	"Corsica": {{43.00, 9.40}, {42.70, 9.45}, {41.95, 9.40}, {41.38, 9.20}, {41.60, 8.78}, {41.92, 8.60}, {42.40, 8.55}, {42.65, 8.95}},
+
+	// Greece
+	"GR-TR": {{41.72, 26.36}, {41.30, 26.60}, {40.85, 26.03}},
+	"GR coast": {
+		{40.85, 26.03}, {40.93, 24.41}, {40.70, 23.80}, {40.00, 23.95}, {40.60, 22.95}, {40.27, 22.60}, {39.35, 22.95},
+		{38.85, 22.60}, {38.40, 23.60}, {37.95, 23.65}, {37.65, 24.02}, {37.95, 23.15}, {37.57, 22.80}, {36.45, 23.10},
+		{36.39, 22.48}, {37.03, 22.11}, {36.80, 21.70}, {37.65, 21.30}, {38.25, 21.73}, {38.37, 21.43}, {38.95, 20.75},
+		{39.50, 20.25}, {39.65, 20.00},
+	},
+	"GR north": {{39.65, 20.00}, {39.92, 20.35}, {40.40, 20.80}, {40.86, 20.98}, {41.00, 21.60}, {41.13, 22.52}, {41.37, 22.95}},
+	"Crete":    {{35.55, 23.55}, {35.30, 26.30}, {35.00, 26.10}, {34.95, 24.75}, {35.25, 23.55}},
+
+	// Croatia
+	"HR-HU": {{46.48, 16.55}, {46.30, 16.90}, {46.00, 17.30}, {45.80, 17.70}, {45.75, 18.30}, {45.90, 18.60}, {45.90, 18.90}},
+	"HR-ME": {{42.55, 18.45}, {42.42, 18.52}},
+	"HR-RS": {{45.90, 18.90}, {45.55, 19.00}, {45.25, 19.43}, {45.10, 19.15}, {44.87, 19.05}},
+	"HR-SI": {
+		{46.48, 16.55}, {46.40, 16.28}, {46.25, 15.90}, {46.22, 15.65}, {45.95, 15.70}, {45.85, 15.70}, {45.70, 15.40},
+		{45.55, 15.28}, {45.48, 15.00}, {45.50, 14.65}, {45.57, 14.50}, {45.48, 14.20}, {45.50, 13.95}, {45.47, 13.60},
+		{45.48, 13.50},
+	},
+	"HR coast": {
+		{42.95, 17.58}, {43.05, 17.40}, {43.30, 17.00}, {43.50, 16.40}, {43.73, 15.85}, {44.12, 15.20}, {44.55, 14.90},
+		{45.00, 14.88}, {45.33, 14.42}, {45.10, 14.20}, {44.85, 13.85}, {45.08, 13.62}, {45.43, 13.50}, {45.48, 13.50},
+	},
+	"HR south coast": {{42.42, 18.52}, {42.65, 18.07}, {42.78, 17.55}, {42.95, 17.25}, {42.90, 17.70}},
+
+	// Hungary
+	"HU-RO": {{48.00, 22.88}, {47.75, 22.60}, {47.55, 22.05}, {47.05, 21.80}, {46.65, 21.45}, {46.30, 21.20}, {46.12, 20.26}},
+	"HU-RS": {{46.12, 20.26}, {46.17, 19.70}, {45.95, 19.00}, {45.90, 18.90}},
+	"HU-SI": {{46.87, 16.11}, {46.85, 16.35}, {46.65, 16.40}, {46.48, 16.55}},
+	"HU-SK": {
+		{48.01, 17.16}, {47.87, 17.50}, {47.75, 18.00}, {47.76, 18.50}, {47.82, 18.87}, {48.07, 18.95}, {48.08, 19.30},
+		{48.20, 19.65}, {48.25, 20.15}, {48.55, 20.55}, {48.55, 21.00}, {48.55, 21.40}, {48.42, 21.66}, {48.55, 22.00},
+		{48.40, 22.15},
+	},
+	"HU-UA": {{48.40, 22.15}, {48.12, 22.60}, {48.00, 22.88}},
+
+	// Italy
+	"IT-SI": {
+		{46.52, 13.71}, {46.38, 13.60}, {46.22, 13.40}, {46.08, 13.65}, {45.95, 13.635}, {45.85, 13.60}, {45.70, 13.86},
+		{45.62, 13.86}, {45.60, 13.70},
+	},
+	"IT coast": {
+		{45.60, 13.70}, {45.66, 13.74}, {45.78, 13.55}, {45.65, 13.10}, {45.45, 12.40}, {45.00, 12.50}, {44.50, 12.28},
+		{44.05, 12.60}, {43.60, 13.50}, {42.90, 13.90}, {42.40, 14.25}, {41.95, 15.00}, {41.90, 16.15}, {41.45, 15.95},
+		{41.10, 16.90}, {40.65, 17.95}, {40.15, 18.50}, {39.80, 18.35}, {40.30, 17.80}, {40.47, 17.20}, {40.10, 16.65},
+		{39.40, 17.15}, {38.90, 16.60}, {38.45, 16.57}, {37.92, 16.05}, {38.10, 15.62}, {38.68, 15.90}, {39.55, 15.80},
+		{40.00, 15.60}, {40.65, 14.75}, {40.83, 14.20}, {41.25, 13.60}, {41.45, 12.60}, {41.75, 12.25}, {42.40, 11.20},
+		{42.95, 10.50}, {43.55, 10.30}, {44.05, 10.00}, {44.40, 8.90}, {44.10, 8.20}, {43.78, 7.53},
+	},
+	"Sicily": {
+		{38.27, 15.65}, {37.50, 15.10}, {36.68, 15.10}, {37.06, 14.25}, {37.28, 13.55}, {37.65, 12.58}, {38.02, 12.48},
+		{38.20, 13.10}, {38.04, 14.02}, {38.27, 15.24},
+	},
+	"Sardinia": {
+		{41.25, 9.23}, {40.95, 9.70}, {40.20, 9.70}, {39.15, 9.57}, {38.90, 8.80}, {39.10, 8.40}, {39.90, 8.40},
+		{40.55, 8.20}, {40.95, 8.20}, {40.91, 8.71},
+	},
+
+	// Montenegro
+	"ME-RS":    {{42.83, 20.35}, {43.00, 20.15}, {43.20, 19.80}, {43.40, 19.55}, {43.52, 19.23}},
+	"ME coast": {{42.42, 18.52}, {42.28, 18.84}, {42.10, 19.09}, {41.93, 19.22}, {41.86, 19.36}},
+	"ME south": {{41.86, 19.36}, {42.15, 19.40}, {42.50, 19.75}, {42.55, 20.07}, {42.83, 20.35}},
+
+	// Netherlands
+	"NL coast": {
+		{53.60, 6.60}, {53.50, 6.20}, {53.45, 5.60}, {53.18, 4.85}, {52.96, 4.72}, {52.46, 4.58}, {51.98, 4.12},
+		{51.80, 3.90}, {51.55, 3.45}, {51.37, 3.36},
+	},
+
+	// Poland
+	"PL-SK": {
+		{49.52, 18.85}, {49.45, 19.35}, {49.20, 19.75}, {49.18, 20.10}, {49.40, 20.40}, {49.42, 21.00}, {49.40, 21.60},
+		{49.18, 22.30}, {49.09, 22.56},
+	},
+	"PL east": {
+		{49.09, 22.56}, {49.40, 22.70}, {49.78, 22.95}, {50.30, 23.70}, {50.80, 24.10}, {51.55, 23.60}, {52.10, 23.65},
+		{52.65, 23.90}, {53.20, 23.90}, {53.90, 23.50}, {54.36, 22.79}, {54.40, 21.00}, {54.45, 19.62},
+	},
+	"PL coast": {{54.45, 19.62}, {54.35, 18.70}, {54.80, 18.40}, {54.76, 17.55}, {54.50, 16.40}, {54.18, 15.58}, {53.93, 14.22}},
+
+	// Portugal
+	"PT coast": {
+		{41.87, -8.87}, {41.15, -8.68}, {40.64, -8.75}, {40.15, -8.87}, {39.36, -9.40}, {38.78, -9.50}, {38.45, -8.95},
+		{37.95, -8.87}, {37.02, -8.99}, {37.10, -8.67}, {37.01, -7.93}, {37.17, -7.40},
+	},
+
+	// Romania
+	"RO-BG": {{43.74, 28.58}, {43.98, 28.00}, {44.12, 27.27}, {43.87, 25.97}, {43.68, 24.85}, {43.75, 23.70}, {44.00, 22.90}, {44.22, 22.68}},
+	"RO-RS": {{46.12, 20.26}, {45.90, 20.70}, {45.50, 20.95}, {45.20, 21.45}, {44.80, 21.40}, {44.70, 22.00}, {44.60, 22.55}, {44.22, 22.68}},
+	"RO north": {
+		{48.00, 22.88}, {47.95, 23.90}, {47.72, 24.60}, {47.95, 25.10}, {47.95, 26.10}, {48.25, 26.63}, {47.70, 27.30},
+		{47.15, 27.85}, {46.50, 28.20}, {45.47, 28.20}, {45.35, 29.00}, {45.20, 29.65},
+	},
+	"RO coast": {{45.20, 29.65}, {44.70, 29.00}, {44.17, 28.66}, {43.74, 28.58}},
+
+	// Serbia, without Kosovo
+	"RS south": {{42.32, 22.36}, {42.30, 21.60}, {42.65, 21.75}, {43.00, 21.40}, {43.25, 20.85}, {43.00, 20.60}, {42.83, 20.35}},
+
+	// Slovenia
+	"SI coast": {{45.48, 13.50}, {45.53, 13.57}, {45.54, 13.72}, {45.60, 13.70}},
+
+	// Slovakia
+	"SK-UA": {{49.09, 22.56}, {48.85, 22.40}, {48.60, 22.25}, {48.40, 22.15}},
+}
+
+// countryOutlines lists the rings of each country as border pieces in order;
+// a leading "-" walks a piece backwards. Each piece starts where the previous
+// one ends and the last one leads back to the start of the first.
+var countryOutlines = map[string][][]string{
+	"AT": {{"AT-DE", "AT-CZ", "AT-SK", "AT-HU", "AT-SI", "AT-IT", "-AT-CH south", "-AT-LI", "-AT-CH north"}},
+	"BA": {{"BA-HR", "BA coast", "BA-HR south", "BA-ME", "BA-RS"}},
+	"BE": {{"BE-DE", "BE-NL", "BE coast", "BE-FR", "BE-LU"}},
+	"BG": {{"-RO-BG", "BG coast", "BG-TR", "BG-GR", "BG-MK", "-BG-RS"}},
+	"CH": {{"-CH-DE", "AT-CH north", "CH-LI", "AT-CH south", "CH-IT", "CH-FR"}},
+	"CZ": {{"CZ-PL", "-CZ-SK", "-AT-CZ", "-CZ-DE"}},
+	"DE": {{
+		"DE-DK", "DE Baltic coast", "DE-PL", "CZ-DE", "-AT-DE", "CH-DE", "DE-FR", "DE-LU", "BE-DE", "DE-NL",
+		"DE North Sea coast",
+	}},
+	"DK": {{"DE-DK", "DK coast"}, {"Zealand"}, {"Funen"}},
+	"ES": {{"ES-FR", "ES north coast", "ES-PT", "ES south coast"}, {"Mallorca"}},
+	"FR": {{"BE-FR", "FR-LU", "-DE-FR", "-CH-FR", "-FR-IT", "FR Mediterranean coast", "ES-FR", "FR Atlantic coast"}, {"Corsica"}},
+	"GR": {{"GR-TR", "GR coast", "GR north", "-BG-GR"}, {"Crete"}},
+	"HR": {{"HR-HU", "HR-RS", "BA-HR", "HR coast", "-HR-SI"}, {"BA-HR south", "HR-ME", "HR south coast"}},
+	"HU": {{"HU-SK", "HU-UA", "HU-RO", "HU-RS", "-HR-HU", "-HU-SI", "-AT-HU"}},
+	"IT": {{"-CH-IT", "-AT-IT", "IT-SI", "IT coast", "FR-IT"}, {"Sicily"}, {"Sardinia"}},
+	"LI": {{"-CH-LI", "AT-LI"}},
+	"LU": {{"DE-LU", "-BE-LU", "FR-LU"}},
+	"ME": {{"HR-ME", "ME coast", "ME south", "ME-RS", "-BA-ME"}},
+	"NL": {{"DE-NL", "NL coast", "-BE-NL"}},
+	"PL": {{"PL coast", "DE-PL", "CZ-PL", "PL-SK", "PL east"}},
+	"PT": {{"-ES-PT", "PT coast"}},
+	"RO": {{"RO north", "RO coast", "RO-BG", "-RO-RS", "-HU-RO"}},
+	"RS": {{"RO-RS", "BG-RS", "RS south", "ME-RS", "BA-RS", "-HR-RS", "-HU-RS"}},
+	"SI": {{"-AT-SI", "HU-SI", "HR-SI", "SI coast", "-IT-SI"}},
+	"SK": {{"CZ-SK", "PL-SK", "SK-UA", "-HU-SK", "-AT-SK"}},
+}
@@ -0,0 +1,313 @@
+// File: /services/currency_service.go
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+)
+
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// DefaultExchangeRates are fallback rates (units per 1 EUR) used until rates
+// have been imported into the exchange rate table
+var DefaultExchangeRates = map[string]float64{
+	"EUR": 1,
+	"HUF": 395,
+	"CZK": 25.0,
+	"PLN": 4.30,
+	"CHF": 0.94,
+	"RON": 4.97,
+	"BGN": 1.956,
+	"DKK": 7.46,
+	"SEK": 11.3,
+	"NOK": 11.6,
+	"GBP": 0.85,
+	"USD": 1.08,
+}
+
+// ExchangeRateInput is a single rate row coming from the API or an import file
+type ExchangeRateInput struct {
+	BaseCurrency  string  `json:"base_currency"`
+	Currency      string  `json:"currency"`
+	Rate          float64 `json:"rate"`
+	EffectiveDate string  `json:"effective_date"` // YYYY-MM-DD or RFC3339
+	Source        string  `json:"source"`
+}
+
+// ExchangeRateImportResult summarizes a bulk import
+type ExchangeRateImportResult struct {
+	Imported int      `json:"imported"`
+	Updated  int      `json:"updated"`
+	Skipped  int      `json:"skipped"`
+	Errors   []string `json:"errors"`
+}
+
+type CurrencyService struct {
+	db *gorm.DB
+}
+
+func NewCurrencyService(db *gorm.DB) *CurrencyService {
+	return &CurrencyService{db: db}
+}
+
+// Rate returns how many units of `to` one unit of `from` is worth
+func (s *CurrencyService) Rate(from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	fromPerEUR, err := s.perEUR(from)
+	if err != nil {
+		return 0, err
+	}
+	toPerEUR, err := s.perEUR(to)
+	if err != nil {
+		return 0, err
+	}
+
+	return toPerEUR / fromPerEUR, nil
+}
+
+// Convert converts an amount between two currencies
+func (s *CurrencyService) Convert(amount float64, from, to string) (float64, error) {
+	rate, err := s.Rate(from, to)
+	if err != nil {
+		return 0, err
+	}
+	return amount * rate, nil
+}
+
+// Rates returns the current rate of every supported currency against base
+func (s *CurrencyService) Rates(base string) (map[string]float64, error) {
+	rates := make(map[string]float64, len(models.SupportedCurrencies))
+	for _, currency := range models.SupportedCurrencies {
+		rate, err := s.Rate(base, currency)
+		if err != nil {
+			return nil, err
+		}
+		rates[currency] = rate
+	}
+	return rates, nil
+}
+
+// PreferredCurrency returns the display currency of a user, defaulting to EUR
+func (s *CurrencyService) PreferredCurrency(userID string) string {
+	var user models.User
+	if err := s.db.Select("id", "preferred_currency").First(&user, "id = ?", userID).Error; err != nil || user.PreferredCurrency == "" {
+		return models.DefaultCurrency
+	}
+	return user.PreferredCurrency
+}
+
+// perEUR returns the latest known units of currency per 1 EUR
+func (s *CurrencyService) perEUR(currency string) (float64, error) {
+	if !models.IsSupportedCurrency(currency) {
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+	if currency == models.DefaultCurrency {
+		return 1, nil
+	}
+
+	var rate models.ExchangeRate
+	err := s.db.Where("((base_currency = ? AND currency = ?) OR (base_currency = ? AND currency = ?)) AND effective_date <= ?",
+		models.DefaultCurrency, currency, currency, models.DefaultCurrency, time.Now()).
+		Order("effective_date DESC").First(&rate).Error
+	if err == nil && rate.Rate > 0 {
+		if rate.BaseCurrency == models.DefaultCurrency {
+			return rate.Rate, nil
+		}
+		return 1 / rate.Rate, nil
+	}
+
+	return DefaultExchangeRates[currency], nil
+}
+
+// Save validates an input row and stores it. A row for the same currency pair
+// and date replaces the existing rate; the returned bool reports whether a
+// new row was created.
+func (s *CurrencyService) Save(input ExchangeRateInput) (*models.ExchangeRate, bool, error) {
+	base := strings.ToUpper(strings.TrimSpace(input.BaseCurrency))
+	if base == "" {
+		base = models.DefaultCurrency
+	}
+	currency := strings.ToUpper(strings.TrimSpace(input.Currency))
+
+	if !models.IsSupportedCurrency(base) || !models.IsSupportedCurrency(currency) {
+		return nil, false, fmt.Errorf("%w: %s/%s", ErrUnsupportedCurrency, base, currency)
+	}
+	if base == currency {
+		return nil, false, errors.New("base and quote currency must differ")
+	}
+	if input.Rate <= 0 || math.IsInf(input.Rate, 0) {
+		return nil, false, errors.New("rate must be positive")
+	}
+
+	effectiveDate := time.Now().Truncate(24 * time.Hour)
+	if input.EffectiveDate != "" {
+		parsed, err := parseImportDate(input.EffectiveDate)
+		if err != nil {
+			return nil, false, err
+		}
+		effectiveDate = parsed
+	}
+
+	source := input.Source
+	if source == "" {
+		source = "manual"
+	}
+
+	var existing models.ExchangeRate
+	err := s.db.Where("base_currency = ? AND currency = ? AND effective_date = ?", base, currency, effectiveDate).First(&existing).Error
+	if err == nil {
+		if err := s.db.Model(&existing).Updates(map[string]interface{}{"rate": input.Rate, "source": source}).Error; err != nil {
+			return nil, false, err
+		}
+		return &existing, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	rate := &models.ExchangeRate{
+		ID:            uuid.New().String(),
+		BaseCurrency:  base,
+		Currency:      currency,
+		Rate:          input.Rate,
+		EffectiveDate: effectiveDate,
+		Source:        source,
+	}
+	if err := s.db.Create(rate).Error; err != nil {
+		return nil, false, err
+	}
+	return rate, true, nil
+}
+
+// Import stores rates from a CSV or JSON document. The CSV needs a header row
+// with currency and rate columns and optionally base_currency, effective_date
+// and source; the base currency defaults to EUR.
+func (s *CurrencyService) Import(r io.Reader, format string) (*ExchangeRateImportResult, error) {
+	var rows []ExchangeRateInput
+	var err error
+
+	switch strings.ToLower(format) {
+	case "csv":
+		rows, err = parseExchangeRateCSV(r)
+	case "json":
+		err = json.NewDecoder(r).Decode(&rows)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", format, err)
+	}
+
+	result := &ExchangeRateImportResult{Errors: []string{}}
+	for i, row := range rows {
+		if row.Source == "" {
+			row.Source = "import"
+		}
+		_, created, err := s.Save(row)
+		switch {
+		case err != nil:
+			result.Skipped++
+			result.Errors = append(result.Errors, fmt.Sprintf("row %d: %v", i+1, err))
+		case created:
+			result.Imported++
+		default:
+			result.Updated++
+		}
+	}
+
+	return result, nil
+}
+
+// ImportFile imports a CSV or JSON file, detecting the format from its extension
+func (s *CurrencyService) ImportFile(path string) (*ExchangeRateImportResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	return s.Import(file, format)
+}
+
+func parseImportDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", value)
+}
+
+func parseExchangeRateCSV(r io.Reader) ([]ExchangeRateInput, error) {
+	records, field, err := readImportCSV(r, "currency", "rate")
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]ExchangeRateInput, 0, len(records))
+	for _, record := range records {
+		// Invalid rates are rejected row by row during the import
+		rate, _ := strconv.ParseFloat(strings.Replace(field(record, "rate"), ",", ".", 1), 64)
+		rows = append(rows, ExchangeRateInput{
+			BaseCurrency:  field(record, "base_currency"),
+			Currency:      field(record, "currency"),
+			Rate:          rate,
+			EffectiveDate: field(record, "effective_date"),
+			Source:        field(record, "source"),
+		})
+	}
+
+	return rows, nil
+}
+
+// readImportCSV reads a CSV document with a header row and returns the data
+// records together with a lookup for fields by column name
+func readImportCSV(r io.Reader, required ...string) ([][]string, func(record []string, name string) string, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, errors.New("empty file")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range required {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, fmt.Errorf("missing %q column", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	return records[1:], field, nil
+}
@@ -0,0 +1,192 @@
+// File: /services/curvature_service.go
+package services
+
+import (
+	"math"
+
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+)
+
+const (
+	// curvatureMinSegmentKm merges points closer than this to suppress GPS jitter
+	curvatureMinSegmentKm = 0.01
+	// curvatureNoiseDegrees is the smallest heading change counted as turning
+	curvatureNoiseDegrees = 1.0
+	// bendMinAngle is the smallest total turn counted as a bend
+	bendMinAngle = 30.0
+
+	tightBendRadius    = 50.0  // m
+	mediumBendRadius   = 150.0 // m
+	sweepingBendRadius = 400.0 // m
+)
+
+// AnalyzeCurvature measures the heading change along a path and counts its
+// bends by radius. Each bend is a run of turns in the same direction; its
+// radius is estimated from its length and total angle.
+func AnalyzeCurvature(points []models.LatLng) models.CurvatureStats {
+	var stats models.CurvatureStats
+
+	// Drop points too close to the previous one
+	kept := make([]models.LatLng, 0, len(points))
+	for _, p := range points {
+		if len(kept) == 0 || HaversineKm(kept[len(kept)-1].Latitude, kept[len(kept)-1].Longitude, p.Latitude, p.Longitude) >= curvatureMinSegmentKm {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) < 2 {
+		return stats
+	}
+
+	lengths := make([]float64, len(kept)-1) // km
+	headings := make([]float64, len(kept)-1)
+	for i := 1; i < len(kept); i++ {
+		lengths[i-1] = HaversineKm(kept[i-1].Latitude, kept[i-1].Longitude, kept[i].Latitude, kept[i].Longitude)
+		headings[i-1] = bearingDegrees(kept[i-1], kept[i])
+		stats.Distance += lengths[i-1]
+	}
+
+	var bendAngle, bendLength float64
+	bendSign := 0
+	closeBend := func() {
+		if bendAngle >= bendMinAngle {
+			radius := bendLength * 1000 / (bendAngle * math.Pi / 180)
+			switch {
+			case radius < tightBendRadius:
+				stats.TightBends++
+			case radius < mediumBendRadius:
+				stats.MediumBends++
+			case radius < sweepingBendRadius:
+				stats.SweepingBends++
+			}
+		}
+		bendAngle, bendLength, bendSign = 0, 0, 0
+	}
+
+	for i := 1; i < len(headings); i++ {
+		delta := math.Mod(headings[i]-headings[i-1]+540, 360) - 180
+		turn := math.Abs(delta)
+		if turn < curvatureNoiseDegrees {
+			closeBend()
+			continue
+		}
+		stats.TotalHeadingChange += turn
+
+		sign := 1
+		if delta < 0 {
+			sign = -1
+		}
+		if sign != bendSign {
+			closeBend()
+			bendSign = sign
+		}
+		bendAngle += turn
+		bendLength += (lengths[i-1] + lengths[i]) / 2
+	}
+	closeBend()
+
+	stats.HeadingChangePerKm = stats.TotalHeadingChange / stats.Distance
+	stats.Score = TwistinessScore(stats)
+
+	stats.Distance = roundToDecimal(stats.Distance, 2)
+	stats.TotalHeadingChange = roundToDecimal(stats.TotalHeadingChange, 1)
+	stats.HeadingChangePerKm = roundToDecimal(stats.HeadingChangePerKm, 1)
+	return stats
+}
+
+// TwistinessScore condenses curvature into 0-100. Heading change per km and
+// bends per km (tight bends weigh most) saturate towards 100, so a motorway
+// scores near 0, a typical country road around 30 and an alpine pass near 100.
+func TwistinessScore(stats models.CurvatureStats) float64 {
+	if stats.Distance <= 0 {
+		return 0
+	}
+
+	weightedBends := float64(3*stats.TightBends+2*stats.MediumBends+stats.SweepingBends) / stats.Distance
+	intensity := stats.HeadingChangePerKm/150 + weightedBends/3
+	return roundToDecimal(100*(1-math.Exp(-intensity)), 1)
+}
+
+// bearingDegrees returns the initial bearing from a to b, 0-360 degrees
+func bearingDegrees(a, b models.LatLng) float64 {
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	y := math.Sin(dLng) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLng)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+// RoutePath returns a route's geometry, or its waypoints when no geometry is stored
+func RoutePath(route *models.Route) []models.LatLng {
+	points := route.GetRouteGeometryAsLatLng()
+	if len(points) < 2 {
+		points = route.GetWaypointsAsLatLng()
+	}
+	return points
+}
+
+// RouteCurvature analyses the path of a route
+func RouteCurvature(route *models.Route) models.CurvatureStats {
+	return AnalyzeCurvature(RoutePath(route))
+}
+
+type CurvatureService struct {
+	db *gorm.DB
+}
+
+func NewCurvatureService(db *gorm.DB) *CurvatureService {
+	return &CurvatureService{db: db}
+}
+
+// CurvatureBackfillResult counts the rows scored by Backfill
+type CurvatureBackfillResult struct {
+	Routes       int `json:"routes"`
+	SharedRoutes int `json:"shared_routes"`
+}
+
+// Backfill (re)computes the curvature of all routes and shared routes
+func (s *CurvatureService) Backfill() (*CurvatureBackfillResult, error) {
+	result := &CurvatureBackfillResult{}
+
+	var routes []models.Route
+	err := s.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).FindInBatches(&routes, 200, func(tx *gorm.DB, batch int) error {
+		for i := range routes {
+			stats := RouteCurvature(&routes[i])
+			if err := s.db.Model(&routes[i]).UpdateColumns(map[string]interface{}{
+				"curvature":        stats,
+				"twistiness_score": stats.Score,
+			}).Error; err != nil {
+				return err
+			}
+			result.Routes++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var sharedRoutes []models.SharedRoute
+	err = s.db.FindInBatches(&sharedRoutes, 200, func(tx *gorm.DB, batch int) error {
+		for i := range sharedRoutes {
+			stats := AnalyzeCurvature(sharedRoutes[i].GetRoutePointsAsLatLng())
+			if err := s.db.Model(&sharedRoutes[i]).UpdateColumns(map[string]interface{}{
+				"curvature":        stats,
+				"twistiness_score": stats.Score,
+			}).Error; err != nil {
+				return err
+			}
+			result.SharedRoutes++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
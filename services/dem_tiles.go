@@ -0,0 +1,508 @@
+// File: /services/dem_tiles.go
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// demVoid marks samples without data
+const demVoid = math.MinInt16
+
+// demTile is a grid of elevation samples in meters. Sample (0, 0) is the
+// north-west one; rows run south and columns east.
+type demTile struct {
+	north, west      float64 // coordinates of sample (0, 0)
+	latStep, lngStep float64 // degrees between samples
+	width, height    int
+	data             []int16
+}
+
+func (t *demTile) contains(lat, lng float64) bool {
+	row := (t.north - lat) / t.latStep
+	col := (lng - t.west) / t.lngStep
+	return row >= 0 && col >= 0 && row <= float64(t.height-1) && col <= float64(t.width-1)
+}
+
+// elevation interpolates bilinearly between the four surrounding samples,
+// ignoring void samples
+func (t *demTile) elevation(lat, lng float64) (float64, bool) {
+	if !t.contains(lat, lng) {
+		return 0, false
+	}
+
+	row := (t.north - lat) / t.latStep
+	col := (lng - t.west) / t.lngStep
+	r0, c0 := int(row), int(col)
+	r1, c1 := min(r0+1, t.height-1), min(c0+1, t.width-1)
+	fr, fc := row-float64(r0), col-float64(c0)
+
+	var sum, weights float64
+	for _, s := range []struct {
+		r, c int
+		w    float64
+	}{
+		{r0, c0, (1 - fr) * (1 - fc)},
+		{r0, c1, (1 - fr) * fc},
+		{r1, c0, fr * (1 - fc)},
+		{r1, c1, fr * fc},
+	} {
+		value := t.data[s.r*t.width+s.c]
+		if value == demVoid || s.w == 0 {
+			continue
+		}
+		sum += float64(value) * s.w
+		weights += s.w
+	}
+	if weights == 0 {
+		return 0, false
+	}
+	return sum / weights, true
+}
+
+// parseHGTName returns the south-west corner of an SRTM tile from its file
+// name, like N47E019.hgt or N47E019.SRTMGL1.hgt.zip
+func parseHGTName(path string) (lat, lng int, ok bool) {
+	name := strings.ToUpper(filepath.Base(path))
+	if len(name) < 7 {
+		return 0, 0, false
+	}
+
+	lat, errLat := strconv.Atoi(name[1:3])
+	lng, errLng := strconv.Atoi(name[4:7])
+	if errLat != nil || errLng != nil {
+		return 0, 0, false
+	}
+	switch name[0] {
+	case 'N':
+	case 'S':
+		lat = -lat
+	default:
+		return 0, 0, false
+	}
+	switch name[3] {
+	case 'E':
+	case 'W':
+		lng = -lng
+	default:
+		return 0, 0, false
+	}
+	return lat, lng, true
+}
+
+// loadHGT reads an SRTM tile: a square grid of big-endian int16 samples
+// covering one degree, 1201 (3") or 3601 (1") samples wide
+func loadHGT(path string) (*demTile, error) {
+	lat, lng, ok := parseHGTName(path)
+	if !ok {
+		return nil, fmt.Errorf("%s is not named like N47E019.hgt", filepath.Base(path))
+	}
+
+	var raw []byte
+	var err error
+	if strings.HasSuffix(strings.ToLower(path), ".zip") {
+		raw, err = readHGTZip(path)
+	} else {
+		raw, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	size := int(math.Sqrt(float64(len(raw) / 2)))
+	if size < 2 || size*size*2 != len(raw) {
+		return nil, fmt.Errorf("%s has an unexpected size of %d bytes", filepath.Base(path), len(raw))
+	}
+
+	tile := &demTile{
+		north:   float64(lat + 1),
+		west:    float64(lng),
+		latStep: 1 / float64(size-1),
+		lngStep: 1 / float64(size-1),
+		width:   size,
+		height:  size,
+		data:    make([]int16, size*size),
+	}
+	for i := range tile.data {
+		tile.data[i] = int16(binary.BigEndian.Uint16(raw[i*2:]))
+	}
+	return tile, nil
+}
+
+func readHGTZip(path string) ([]byte, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	for _, file := range archive.File {
+		if !strings.HasSuffix(strings.ToLower(file.Name), ".hgt") {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("%s contains no .hgt file", filepath.Base(path))
+}
+
+// TIFF tags used to read single band GeoTIFF elevation models
+const (
+	tiffImageWidth      = 256
+	tiffImageLength     = 257
+	tiffBitsPerSample   = 258
+	tiffCompression     = 259
+	tiffStripOffsets    = 273
+	tiffSamplesPerPixel = 277
+	tiffRowsPerStrip    = 278
+	tiffStripByteCounts = 279
+	tiffPredictor       = 317
+	tiffTileWidth       = 322
+	tiffTileLength      = 323
+	tiffTileOffsets     = 324
+	tiffTileByteCounts  = 325
+	tiffSampleFormat    = 339
+	geoPixelScale       = 33550
+	geoTiepoint         = 33922
+	geoKeyDirectory     = 34735
+	gdalNoData          = 42113
+
+	geoKeyRasterType      = 1025
+	geoKeyProjectedCSType = 3072
+	geoRasterPixelIsPoint = 2
+)
+
+// geoTIFFInfo is the layout of a GeoTIFF elevation model, read from its header
+type geoTIFFInfo struct {
+	path   string
+	order  binary.ByteOrder
+	bounds demTile // grid geometry without data
+
+	bits, format, compression, predictor int
+	blockWidth, blockHeight              int // strip or tile size
+	offsets, counts                      []uint64
+	noData                               *float64
+}
+
+func (g *geoTIFFInfo) contains(lat, lng float64) bool {
+	return g.bounds.contains(lat, lng)
+}
+
+// readGeoTIFFInfo parses the first image of a GeoTIFF in geographic
+// coordinates. Uncompressed and deflate images with 16 bit integer or 32 bit
+// samples are supported.
+func readGeoTIFFInfo(path string) (*geoTIFFInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return nil, err
+	}
+
+	info := &geoTIFFInfo{path: path, compression: 1, predictor: 1, format: 1}
+	switch string(header[:2]) {
+	case "II":
+		info.order = binary.LittleEndian
+	case "MM":
+		info.order = binary.BigEndian
+	default:
+		return nil, errors.New("not a TIFF file")
+	}
+	if info.order.Uint16(header[2:]) != 42 {
+		return nil, errors.New("BigTIFF and other TIFF variants are not supported")
+	}
+
+	entries, err := readTIFFDirectory(file, info.order, int64(info.order.Uint32(header[4:])))
+	if err != nil {
+		return nil, err
+	}
+
+	number := func(tag uint16) (int, bool) {
+		if values := entries[tag]; len(values) > 0 {
+			return int(values[0]), true
+		}
+		return 0, false
+	}
+
+	var width, height int
+	var ok bool
+	if width, ok = number(tiffImageWidth); !ok {
+		return nil, errors.New("missing image width")
+	}
+	if height, ok = number(tiffImageLength); !ok {
+		return nil, errors.New("missing image length")
+	}
+	if samples, ok := number(tiffSamplesPerPixel); ok && samples != 1 {
+		return nil, fmt.Errorf("%d samples per pixel, expected a single band", samples)
+	}
+	info.bits, _ = number(tiffBitsPerSample)
+	if value, ok := number(tiffSampleFormat); ok {
+		info.format = value
+	}
+	if value, ok := number(tiffCompression); ok {
+		info.compression = value
+	}
+	if value, ok := number(tiffPredictor); ok {
+		info.predictor = value
+	}
+
+	switch {
+	case info.bits == 16 && (info.format == 1 || info.format == 2):
+	case info.bits == 32 && (info.format == 2 || info.format == 3):
+	default:
+		return nil, fmt.Errorf("unsupported sample type: %d bit, format %d", info.bits, info.format)
+	}
+	switch info.compression {
+	case 1, 8, 32946: // none, deflate, old-style deflate
+	default:
+		return nil, fmt.Errorf("unsupported compression %d", info.compression)
+	}
+
+	if tileWidth, ok := number(tiffTileWidth); ok {
+		info.blockWidth = tileWidth
+		info.blockHeight, _ = number(tiffTileLength)
+		info.offsets, info.counts = toUint64(entries[tiffTileOffsets]), toUint64(entries[tiffTileByteCounts])
+	} else {
+		info.blockWidth = width
+		info.blockHeight = height
+		if rows, ok := number(tiffRowsPerStrip); ok && rows < height {
+			info.blockHeight = rows
+		}
+		info.offsets, info.counts = toUint64(entries[tiffStripOffsets]), toUint64(entries[tiffStripByteCounts])
+	}
+	if info.blockWidth <= 0 || info.blockHeight <= 0 || len(info.offsets) == 0 || len(info.offsets) != len(info.counts) {
+		return nil, errors.New("invalid strip or tile layout")
+	}
+
+	scale, tiepoint := entries[geoPixelScale], entries[geoTiepoint]
+	if len(scale) < 2 || len(tiepoint) < 6 {
+		return nil, errors.New("missing GeoTIFF pixel scale or tie point")
+	}
+
+	// The tie point refers to the corner of a pixel unless the raster is PixelIsPoint
+	pixelIsPoint := false
+	keys := entries[geoKeyDirectory]
+	for i := 4; i+3 < len(keys); i += 4 {
+		switch uint16(keys[i]) {
+		case geoKeyRasterType:
+			pixelIsPoint = keys[i+3] == geoRasterPixelIsPoint
+		case geoKeyProjectedCSType:
+			return nil, errors.New("projected elevation models are not supported, use geographic (EPSG:4326) coordinates")
+		}
+	}
+
+	info.bounds = demTile{
+		west:    tiepoint[3] - tiepoint[0]*scale[0],
+		north:   tiepoint[4] + tiepoint[1]*scale[1],
+		lngStep: scale[0],
+		latStep: scale[1],
+		width:   width,
+		height:  height,
+	}
+	if !pixelIsPoint {
+		info.bounds.west += scale[0] / 2
+		info.bounds.north -= scale[1] / 2
+	}
+
+	if values, ok := entries[gdalNoData]; ok && len(values) > 0 {
+		noData := values[0]
+		info.noData = &noData
+	}
+	return info, nil
+}
+
+// readTIFFDirectory reads the numeric values of an image file directory.
+// ASCII values are parsed as a single number, which is how GDAL stores NoData.
+func readTIFFDirectory(r io.ReaderAt, order binary.ByteOrder, offset int64) (map[uint16][]float64, error) {
+	countBytes := make([]byte, 2)
+	if _, err := r.ReadAt(countBytes, offset); err != nil {
+		return nil, err
+	}
+	count := int(order.Uint16(countBytes))
+
+	raw := make([]byte, count*12)
+	if _, err := r.ReadAt(raw, offset+2); err != nil {
+		return nil, err
+	}
+
+	typeSizes := map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 6: 1, 8: 2, 9: 4, 11: 4, 12: 8}
+	entries := make(map[uint16][]float64, count)
+	for i := 0; i < count; i++ {
+		entry := raw[i*12 : i*12+12]
+		tag, kind, n := order.Uint16(entry), order.Uint16(entry[2:]), int(order.Uint32(entry[4:]))
+		size, known := typeSizes[kind]
+		if !known || n <= 0 || n > 1<<24 {
+			continue
+		}
+
+		data := entry[8:12]
+		if size*n > 4 {
+			data = make([]byte, size*n)
+			if _, err := r.ReadAt(data, int64(order.Uint32(entry[8:]))); err != nil {
+				return nil, err
+			}
+		} else {
+			data = data[:size*n]
+		}
+
+		if kind == 2 {
+			text := strings.TrimSpace(strings.TrimRight(string(data), "\x00"))
+			if value, err := strconv.ParseFloat(text, 64); err == nil {
+				entries[tag] = []float64{value}
+			}
+			continue
+		}
+
+		values := make([]float64, n)
+		for j := range values {
+			b := data[j*size:]
+			switch kind {
+			case 1:
+				values[j] = float64(b[0])
+			case 6:
+				values[j] = float64(int8(b[0]))
+			case 3:
+				values[j] = float64(order.Uint16(b))
+			case 8:
+				values[j] = float64(int16(order.Uint16(b)))
+			case 4:
+				values[j] = float64(order.Uint32(b))
+			case 9:
+				values[j] = float64(int32(order.Uint32(b)))
+			case 11:
+				values[j] = float64(math.Float32frombits(order.Uint32(b)))
+			case 12:
+				values[j] = math.Float64frombits(order.Uint64(b))
+			}
+		}
+		entries[tag] = values
+	}
+	return entries, nil
+}
+
+func toUint64(values []float64) []uint64 {
+	result := make([]uint64, len(values))
+	for i, v := range values {
+		result[i] = uint64(v)
+	}
+	return result
+}
+
+// loadGeoTIFF decodes the samples of a GeoTIFF described by readGeoTIFFInfo
+func loadGeoTIFF(info *geoTIFFInfo) (*demTile, error) {
+	file, err := os.Open(info.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	tile := info.bounds
+	tile.data = make([]int16, tile.width*tile.height)
+	bytesPerSample := info.bits / 8
+	blocksAcross := (tile.width + info.blockWidth - 1) / info.blockWidth
+
+	for block := range info.offsets {
+		raw := make([]byte, info.counts[block])
+		if _, err := file.ReadAt(raw, int64(info.offsets[block])); err != nil {
+			return nil, fmt.Errorf("block %d: %w", block, err)
+		}
+
+		if info.compression != 1 {
+			zr, err := zlib.NewReader(bytes.NewReader(raw))
+			if err != nil {
+				return nil, fmt.Errorf("block %d: %w", block, err)
+			}
+			raw, err = io.ReadAll(zr)
+			zr.Close()
+			if err != nil {
+				return nil, fmt.Errorf("block %d: %w", block, err)
+			}
+		}
+
+		rowBytes := info.blockWidth * bytesPerSample
+		rows := len(raw) / rowBytes
+		for r := 0; r < rows; r++ {
+			undoTIFFPredictor(raw[r*rowBytes:(r+1)*rowBytes], info, bytesPerSample)
+		}
+
+		top := (block / blocksAcross) * info.blockHeight
+		left := (block % blocksAcross) * info.blockWidth
+		for r := 0; r < rows && top+r < tile.height; r++ {
+			for c := 0; c < info.blockWidth && left+c < tile.width; c++ {
+				tile.data[(top+r)*tile.width+left+c] = tiffSample(raw[(r*info.blockWidth+c)*bytesPerSample:], info)
+			}
+		}
+	}
+	return &tile, nil
+}
+
+// undoTIFFPredictor reverses horizontal (2) or floating point (3) differencing of one row
+func undoTIFFPredictor(row []byte, info *geoTIFFInfo, bytesPerSample int) {
+	switch info.predictor {
+	case 2:
+		samples := len(row) / bytesPerSample
+		for i := 1; i < samples; i++ {
+			prev, cur := row[(i-1)*bytesPerSample:], row[i*bytesPerSample:]
+			if bytesPerSample == 2 {
+				info.order.PutUint16(cur, info.order.Uint16(cur)+info.order.Uint16(prev))
+			} else {
+				info.order.PutUint32(cur, info.order.Uint32(cur)+info.order.Uint32(prev))
+			}
+		}
+	case 3:
+		// Bytes are differenced, then stored most significant byte plane first
+		for i := 1; i < len(row); i++ {
+			row[i] += row[i-1]
+		}
+		samples := len(row) / bytesPerSample
+		planes := append([]byte(nil), row...)
+		for i := 0; i < samples; i++ {
+			for b := 0; b < bytesPerSample; b++ {
+				value := planes[b*samples+i]
+				if info.order == binary.LittleEndian {
+					row[i*bytesPerSample+bytesPerSample-1-b] = value
+				} else {
+					row[i*bytesPerSample+b] = value
+				}
+			}
+		}
+	}
+}
+
+// tiffSample converts one sample to whole meters, mapping NoData to demVoid
+func tiffSample(b []byte, info *geoTIFFInfo) int16 {
+	var value float64
+	switch {
+	case info.bits == 16 && info.format == 1:
+		value = float64(info.order.Uint16(b))
+	case info.bits == 16:
+		value = float64(int16(info.order.Uint16(b)))
+	case info.format == 3:
+		value = float64(math.Float32frombits(info.order.Uint32(b)))
+	default:
+		value = float64(int32(info.order.Uint32(b)))
+	}
+
+	if math.IsNaN(value) || (info.noData != nil && value == *info.noData) || value <= demVoid || value > math.MaxInt16 {
+		return demVoid
+	}
+	return int16(math.Round(value))
+}
@@ -0,0 +1,364 @@
+// File: /services/difficulty_service.go
+package services
+
+import (
+	"errors"
+	"math"
+
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+)
+
+const (
+	// The measurements that earn a factor its full weight
+	difficultyFullDistanceKm   = 400.0
+	difficultyFullGainMeters   = 4000.0
+	difficultyEasyGradient     = 4.0 // %, climbs up to this add nothing
+	difficultyFullGradient     = 16.0
+	difficultyMediumScore      = 30.0
+	difficultyHardScore        = 55.0
+	difficultyRoadSampleKm     = 0.5 // spacing of the road type samples
+	difficultyMaxRoadSamples   = 400
+	difficultyRoadSnapKm       = 0.05 // samples further from a road node are left out
+	difficultyMinRoadCoverage  = 0.25 // share of the samples that must match a road
+	difficultyBearingLookahead = 0.05 // km ahead used for the direction of travel
+)
+
+// difficultyWeights is how much each factor contributes to the score. Factors
+// that cannot be measured are left out and the others scaled up.
+var difficultyWeights = struct {
+	distance, gain, gradient, curvature, roads float64
+}{20, 25, 20, 20, 15}
+
+// roadTypeDemand is how demanding riding each road type is, 0-1
+var roadTypeDemand = map[string]float64{
+	models.RoadTypeMotorway: 0,
+	models.RoadTypeMain:     0.25,
+	models.RoadTypeMinor:    0.6,
+	models.RoadTypeNarrow:   1,
+}
+
+// DifficultyInput is what a difficulty is computed from
+type DifficultyInput struct {
+	Geometry      models.Geometry // the path, with elevations when known
+	Distance      float64         // km by road, 0 to measure the path
+	ElevationGain float64         // m, 0 to derive it from the elevations
+}
+
+// DifficultyService classifies routes, shared routes and events from their
+// geometry, independently of the difficulty their creator picked
+type DifficultyService struct {
+	db               *gorm.DB
+	elevationService *ElevationService
+	routingEngine    RoutingEngine
+}
+
+// NewDifficultyService creates the classifier. Without an elevation service
+// gradients come from the geometry's own elevations, and road types are only
+// known when the routing engine is the OSM router.
+func NewDifficultyService(db *gorm.DB, elevationService *ElevationService, routingEngine RoutingEngine) *DifficultyService {
+	return &DifficultyService{
+		db:               db,
+		elevationService: elevationService,
+		routingEngine:    routingEngine,
+	}
+}
+
+// Classify computes the difficulty of a path; it is empty when the path has
+// fewer than two points
+func (s *DifficultyService) Classify(input DifficultyInput) (string, *models.DifficultyFactors) {
+	points := input.Geometry.LatLngs()
+	if len(points) < 2 {
+		return "", nil
+	}
+
+	factors := &models.DifficultyFactors{
+		Distance:      input.Distance,
+		ElevationGain: input.ElevationGain,
+		Curvature:     AnalyzeCurvature(points).Score,
+	}
+	if factors.Distance <= 0 {
+		factors.Distance = PathLengthKm(points)
+	}
+
+	profile := s.elevationProfile(input.Geometry, points)
+	if profile != nil {
+		factors.MaxGradient = profile.MaxGradient
+		if factors.ElevationGain <= 0 {
+			factors.ElevationGain = profile.TotalAscent
+		}
+	}
+	factors.RoadTypes = s.roadTypes(points)
+
+	weights := difficultyWeights
+	var score, total float64
+	add := func(weight, value float64) {
+		score += weight * math.Max(0, math.Min(1, value))
+		total += weight
+	}
+	add(weights.distance, factors.Distance/difficultyFullDistanceKm)
+	add(weights.gain, factors.ElevationGain/difficultyFullGainMeters)
+	add(weights.curvature, factors.Curvature/100)
+	if profile != nil {
+		add(weights.gradient, (factors.MaxGradient-difficultyEasyGradient)/(difficultyFullGradient-difficultyEasyGradient))
+	}
+	if len(factors.RoadTypes) > 0 {
+		var demand float64
+		for roadType, share := range factors.RoadTypes {
+			demand += share * roadTypeDemand[roadType]
+		}
+		add(weights.roads, demand)
+	}
+
+	factors.Distance = roundToDecimal(factors.Distance, 1)
+	factors.ElevationGain = math.Round(factors.ElevationGain)
+	factors.Score = roundToDecimal(100*score/total, 1)
+	return DifficultyForScore(factors.Score), factors
+}
+
+// DifficultyForScore maps a difficulty score to Easy, Medium or Hard
+func DifficultyForScore(score float64) string {
+	switch {
+	case score >= difficultyHardScore:
+		return models.DifficultyHard
+	case score >= difficultyMediumScore:
+		return models.DifficultyMedium
+	default:
+		return models.DifficultyEasy
+	}
+}
+
+// elevationProfile summarizes the geometry's own elevations when every point
+// has one, and samples the DEM tiles otherwise; nil when neither is available
+func (s *DifficultyService) elevationProfile(geometry models.Geometry, points []models.LatLng) *models.ElevationProfile {
+	cumulative := CumulativeDistancesKm(points)
+	samples := make([]models.ElevationSample, 0, len(geometry))
+	for i, p := range geometry {
+		if p.Elevation == nil {
+			break
+		}
+		samples = append(samples, models.ElevationSample{
+			Distance:  cumulative[i],
+			Latitude:  p.Latitude,
+			Longitude: p.Longitude,
+			Elevation: *p.Elevation,
+		})
+	}
+	if len(samples) == len(geometry) {
+		profile := &models.ElevationProfile{Samples: samples, Distance: cumulative[len(cumulative)-1]}
+		summarizeElevation(profile)
+		return profile
+	}
+
+	if s.elevationService == nil {
+		return nil
+	}
+	profile, err := s.elevationService.Profile(points)
+	if err != nil || len(profile.Samples) < 2 {
+		return nil
+	}
+	return profile
+}
+
+// roadTypes samples the path against the road graph and returns the share of
+// each road type, or nil without a graph or when too little of the path is on it
+func (s *DifficultyService) roadTypes(points []models.LatLng) map[string]float64 {
+	router, ok := s.routingEngine.(*OSMRouter)
+	if !ok {
+		return nil
+	}
+	graph, err := router.Graph()
+	if err != nil {
+		return nil
+	}
+
+	cumulative := CumulativeDistancesKm(points)
+	length := cumulative[len(cumulative)-1]
+	spacing := math.Max(difficultyRoadSampleKm, length/difficultyMaxRoadSamples)
+
+	counts := make(map[string]int)
+	var samples, matched int
+	for km := 0.0; km <= length; km += spacing {
+		samples++
+		point := PointAlongPath(points, cumulative, km)
+		ahead := PointAlongPath(points, cumulative, math.Min(length, km+difficultyBearingLookahead))
+		if ahead == point {
+			ahead = PointAlongPath(points, cumulative, math.Max(0, km-difficultyBearingLookahead))
+		}
+
+		node := graph.Nearest(point.Latitude, point.Longitude, difficultyRoadSnapKm, nil)
+		if node < 0 {
+			continue
+		}
+		edge := alignedEdge(graph, node, bearingDegrees(point, ahead))
+		if edge < 0 {
+			continue
+		}
+		counts[RoadTypeOf(graph.EdgeClass[edge])]++
+		matched++
+	}
+	if samples == 0 || float64(matched)/float64(samples) < difficultyMinRoadCoverage {
+		return nil
+	}
+
+	shares := make(map[string]float64, len(counts))
+	for roadType, count := range counts {
+		shares[roadType] = roundToDecimal(float64(count)/float64(matched), 3)
+	}
+	return shares
+}
+
+// alignedEdge returns the outgoing edge of a node that runs closest to a
+// bearing in either direction, or -1 when the node has no edges
+func alignedEdge(graph *RoadGraph, node int32, bearing float64) int32 {
+	best, bestDiff := int32(-1), math.MaxFloat64
+	from := models.LatLng{Latitude: graph.Lat[node], Longitude: graph.Lng[node]}
+	for e := graph.EdgeStart[node]; e < graph.EdgeStart[node+1]; e++ {
+		to := graph.EdgeTo[e]
+		edgeBearing := bearingDegrees(from, models.LatLng{Latitude: graph.Lat[to], Longitude: graph.Lng[to]})
+		diff := math.Mod(math.Abs(edgeBearing-bearing), 180)
+		diff = math.Min(diff, 180-diff)
+		if diff < bestDiff {
+			best, bestDiff = e, diff
+		}
+	}
+	return best
+}
+
+// RoadTypeOf groups a road graph class into one of the difficulty road types
+func RoadTypeOf(class uint8) string {
+	switch class {
+	case RoadClassMotorway, RoadClassMotorwayLink:
+		return models.RoadTypeMotorway
+	case RoadClassTrunk, RoadClassTrunkLink, RoadClassPrimary, RoadClassPrimaryLink:
+		return models.RoadTypeMain
+	case RoadClassSecondary, RoadClassSecondaryLink, RoadClassTertiary, RoadClassTertiaryLink, RoadClassUnclassified:
+		return models.RoadTypeMinor
+	default:
+		return models.RoadTypeNarrow
+	}
+}
+
+// ClassifyRoute recomputes a route's difficulty from its stored geometry and
+// passes it on to the events riding the route
+func (s *DifficultyService) ClassifyRoute(routeID string) error {
+	var route models.Route
+	if err := s.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).First(&route, "id = ?", routeID).Error; err != nil {
+		return err
+	}
+	return s.saveRoute(&route)
+}
+
+func (s *DifficultyService) saveRoute(route *models.Route) error {
+	geometry := route.RouteGeometry
+	if len(geometry) < 2 {
+		geometry = models.GeometryFromLatLngs(RoutePath(route))
+	}
+	difficulty, factors := s.Classify(DifficultyInput{
+		Geometry:      geometry,
+		Distance:      route.TotalDistance,
+		ElevationGain: route.TotalElevation,
+	})
+
+	updates := map[string]interface{}{
+		"computed_difficulty": difficulty,
+		"difficulty_factors":  factors,
+	}
+	if err := s.db.Model(&models.Route{}).Where("id = ?", route.ID).UpdateColumns(updates).Error; err != nil {
+		return err
+	}
+	return s.db.Model(&models.CommunityEvent{}).Where("route_id = ?", route.ID).UpdateColumns(updates).Error
+}
+
+// ClassifySharedRoute recomputes a shared route's difficulty from its stored points
+func (s *DifficultyService) ClassifySharedRoute(sharedRouteID string) error {
+	var route models.SharedRoute
+	if err := s.db.First(&route, "id = ?", sharedRouteID).Error; err != nil {
+		return err
+	}
+	return s.saveSharedRoute(&route)
+}
+
+func (s *DifficultyService) saveSharedRoute(route *models.SharedRoute) error {
+	difficulty, factors := s.Classify(DifficultyInput{
+		Geometry:      route.RoutePoints,
+		Distance:      route.TotalDistance,
+		ElevationGain: route.TotalElevation,
+	})
+	return s.db.Model(&models.SharedRoute{}).Where("id = ?", route.ID).UpdateColumns(map[string]interface{}{
+		"computed_difficulty": difficulty,
+		"difficulty_factors":  factors,
+	}).Error
+}
+
+// ClassifyEvent copies the computed difficulty of an event's route; events
+// without a route have none
+func (s *DifficultyService) ClassifyEvent(eventID string) error {
+	var event models.CommunityEvent
+	if err := s.db.First(&event, "id = ?", eventID).Error; err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"computed_difficulty": "",
+		"difficulty_factors":  nil,
+	}
+	if event.RouteID != nil {
+		var route models.Route
+		err := s.db.Select("id", "computed_difficulty", "difficulty_factors").First(&route, "id = ?", *event.RouteID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			updates["computed_difficulty"] = route.ComputedDifficulty
+			updates["difficulty_factors"] = route.DifficultyFactors
+		}
+	}
+	return s.db.Model(&event).UpdateColumns(updates).Error
+}
+
+// DifficultyBackfillResult counts the rows classified by Backfill
+type DifficultyBackfillResult struct {
+	Routes       int `json:"routes"`
+	SharedRoutes int `json:"shared_routes"`
+}
+
+// Backfill (re)computes the difficulty of all routes and shared routes, and
+// with the routes that of their events
+func (s *DifficultyService) Backfill() (*DifficultyBackfillResult, error) {
+	result := &DifficultyBackfillResult{}
+
+	var routes []models.Route
+	err := s.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).FindInBatches(&routes, 200, func(tx *gorm.DB, batch int) error {
+		for i := range routes {
+			if err := s.saveRoute(&routes[i]); err != nil {
+				return err
+			}
+			result.Routes++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var sharedRoutes []models.SharedRoute
+	err = s.db.FindInBatches(&sharedRoutes, 200, func(tx *gorm.DB, batch int) error {
+		for i := range sharedRoutes {
+			if err := s.saveSharedRoute(&sharedRoutes[i]); err != nil {
+				return err
+			}
+			result.SharedRoutes++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
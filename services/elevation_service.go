@@ -0,0 +1,245 @@
+// File: /services/elevation_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"motocosmos-api/models"
+)
+
+const (
+	// elevationSampleSpacingKm is about one SRTM 1" cell
+	elevationSampleSpacingKm = 0.03
+	// maxElevationSamples bounds the profile size; long routes are sampled more sparsely
+	maxElevationSamples = 2000
+	// elevationNoiseMeters is the height change ignored when summing ascent and descent
+	elevationNoiseMeters = 3.0
+	// gradientWindowKm is the distance gradients are measured over
+	gradientWindowKm = 0.1
+	// demCacheTiles is how many decoded tiles are kept in memory
+	demCacheTiles = 12
+)
+
+var (
+	ErrElevationUnavailable = errors.New("no elevation data covers this area")
+	ErrTooFewPoints         = errors.New("at least two points are required")
+)
+
+// ElevationService samples heights from SRTM (.hgt, .hgt.zip) and GeoTIFF
+// elevation tiles in a directory. The directory is indexed on first use and
+// decoded tiles are cached, so new tiles need a restart.
+type ElevationService struct {
+	dir string
+
+	mu      sync.Mutex
+	indexed bool
+	hgt     map[[2]int]string // south-west corner to file
+	tiffs   []*geoTIFFInfo
+	loaded  map[string]*demTile
+	failed  map[string]bool
+	recent  []string // loaded tile paths, oldest first
+}
+
+func NewElevationService(dir string) *ElevationService {
+	return &ElevationService{
+		dir:    dir,
+		hgt:    make(map[[2]int]string),
+		loaded: make(map[string]*demTile),
+		failed: make(map[string]bool),
+	}
+}
+
+// Elevation returns the height in meters at a coordinate
+func (s *ElevationService) Elevation(lat, lng float64) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tile := s.tileFor(lat, lng)
+	if tile == nil {
+		return 0, false
+	}
+	return tile.elevation(lat, lng)
+}
+
+// Profile samples the heights along a path at regular intervals and sums up
+// the climbing. Points without DEM coverage are left out of the samples.
+func (s *ElevationService) Profile(points []models.LatLng) (*models.ElevationProfile, error) {
+	if len(points) < 2 {
+		return nil, ErrTooFewPoints
+	}
+
+	cumulative := CumulativeDistancesKm(points)
+	length := cumulative[len(cumulative)-1]
+	spacing := math.Max(elevationSampleSpacingKm, length/maxElevationSamples)
+	count := int(length/spacing) + 1
+
+	profile := &models.ElevationProfile{
+		Samples:  make([]models.ElevationSample, 0, count+1),
+		Distance: roundToDecimal(length, 2),
+	}
+
+	segment := 1
+	sample := func(km float64) {
+		for segment < len(points)-1 && cumulative[segment] < km {
+			segment++
+		}
+		point := points[segment]
+		if span := cumulative[segment] - cumulative[segment-1]; span > 0 {
+			t := math.Max(0, math.Min(1, (km-cumulative[segment-1])/span))
+			point = models.LatLng{
+				Latitude:  points[segment-1].Latitude + t*(points[segment].Latitude-points[segment-1].Latitude),
+				Longitude: points[segment-1].Longitude + t*(points[segment].Longitude-points[segment-1].Longitude),
+			}
+		}
+
+		if elevation, ok := s.Elevation(point.Latitude, point.Longitude); ok {
+			profile.Samples = append(profile.Samples, models.ElevationSample{
+				Distance:  roundToDecimal(km, 3),
+				Latitude:  point.Latitude,
+				Longitude: point.Longitude,
+				Elevation: roundToDecimal(elevation, 1),
+			})
+		}
+	}
+
+	total := 0
+	for i := 0; i < count; i++ {
+		sample(float64(i) * spacing)
+		total++
+	}
+	if float64(count-1)*spacing < length {
+		sample(length)
+		total++
+	}
+
+	if len(profile.Samples) == 0 {
+		return nil, ErrElevationUnavailable
+	}
+	profile.Coverage = roundToDecimal(float64(len(profile.Samples))/float64(total), 3)
+
+	summarizeElevation(profile)
+	return profile, nil
+}
+
+// summarizeElevation fills in the totals and extremes of a profile's samples
+func summarizeElevation(profile *models.ElevationProfile) {
+	samples := profile.Samples
+	profile.MinElevation, profile.MaxElevation = samples[0].Elevation, samples[0].Elevation
+
+	// Only count changes beyond the noise threshold so DEM jitter does not add up
+	reference := samples[0].Elevation
+	var ascent, descent float64
+	for _, s := range samples {
+		profile.MinElevation = math.Min(profile.MinElevation, s.Elevation)
+		profile.MaxElevation = math.Max(profile.MaxElevation, s.Elevation)
+
+		switch diff := s.Elevation - reference; {
+		case diff >= elevationNoiseMeters:
+			ascent += diff
+			reference = s.Elevation
+		case -diff >= elevationNoiseMeters:
+			descent -= diff
+			reference = s.Elevation
+		}
+	}
+	profile.TotalAscent = math.Round(ascent)
+	profile.TotalDescent = math.Round(descent)
+
+	var maxClimb, maxDrop float64
+	j := 0
+	for i := range samples {
+		for j < len(samples) && samples[j].Distance-samples[i].Distance < gradientWindowKm {
+			j++
+		}
+		if j == len(samples) {
+			break
+		}
+		grade := (samples[j].Elevation - samples[i].Elevation) / ((samples[j].Distance - samples[i].Distance) * 1000) * 100
+		maxClimb = math.Max(maxClimb, grade)
+		maxDrop = math.Max(maxDrop, -grade)
+	}
+	profile.MaxGradient = roundToDecimal(maxClimb, 1)
+	profile.MaxDescentGradient = roundToDecimal(maxDrop, 1)
+}
+
+// tileFor returns the decoded tile covering a coordinate; s.mu must be held
+func (s *ElevationService) tileFor(lat, lng float64) *demTile {
+	if !s.indexed {
+		s.index()
+	}
+
+	if path, ok := s.hgt[[2]int{int(math.Floor(lat)), int(math.Floor(lng))}]; ok {
+		if tile := s.load(path, func() (*demTile, error) { return loadHGT(path) }); tile != nil {
+			return tile
+		}
+	}
+	for _, info := range s.tiffs {
+		if info.contains(lat, lng) {
+			if tile := s.load(info.path, func() (*demTile, error) { return loadGeoTIFF(info) }); tile != nil {
+				return tile
+			}
+		}
+	}
+	return nil
+}
+
+// load decodes a tile once and keeps the most recently loaded ones
+func (s *ElevationService) load(path string, decode func() (*demTile, error)) *demTile {
+	if tile, ok := s.loaded[path]; ok {
+		return tile
+	}
+	if s.failed[path] {
+		return nil
+	}
+
+	tile, err := decode()
+	if err != nil {
+		fmt.Printf("Failed to load elevation tile %s: %v\n", path, err)
+		s.failed[path] = true
+		return nil
+	}
+
+	if len(s.recent) >= demCacheTiles {
+		delete(s.loaded, s.recent[0])
+		s.recent = s.recent[1:]
+	}
+	s.loaded[path] = tile
+	s.recent = append(s.recent, path)
+	return tile
+}
+
+// index finds the tiles in the data directory; s.mu must be held
+func (s *ElevationService) index() {
+	s.indexed = true
+
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		name := strings.ToLower(d.Name())
+		switch {
+		case strings.HasSuffix(name, ".hgt") || strings.HasSuffix(name, ".hgt.zip"):
+			if lat, lng, ok := parseHGTName(path); ok {
+				s.hgt[[2]int{lat, lng}] = path
+			}
+		case strings.HasSuffix(name, ".tif") || strings.HasSuffix(name, ".tiff"):
+			info, err := readGeoTIFFInfo(path)
+			if err != nil {
+				fmt.Printf("Skipping elevation tile %s: %v\n", path, err)
+				return nil
+			}
+			s.tiffs = append(s.tiffs, info)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Elevation data %s not available: %v\n", s.dir, err)
+	}
+}
@@ -0,0 +1,696 @@
+// File: /services/expense_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+)
+
+var (
+	ErrExpenseGroupNotFound = errors.New("expense group not found")
+	ErrExpenseNotFound      = errors.New("expense not found")
+	ErrEventNotFound        = errors.New("event not found")
+	ErrNotExpenseMember     = errors.New("user is not a member of the expense group")
+	ErrNotFriend            = errors.New("members must be friends or participants of the event")
+	ErrMemberHasExpenses    = errors.New("member still has expenses or settlements in the group")
+	ErrInvalidExpense       = errors.New("invalid expense")
+)
+
+// ExpenseGroupInput creates a ledger from an event's participants or a list of friends
+type ExpenseGroupInput struct {
+	Name      string
+	EventID   string
+	MemberIDs []string
+	Currency  string
+}
+
+// ExpenseShareInput is a member taking part in an expense; Weight is only used for weighted splits
+type ExpenseShareInput struct {
+	UserID string  `json:"user_id" binding:"required"`
+	Weight float64 `json:"weight"`
+}
+
+// ExpenseInput describes a payment; without shares it is split equally among all members
+type ExpenseInput struct {
+	PaidByID    string
+	Description string
+	Category    string
+	Amount      float64
+	Currency    string
+	SplitType   string
+	Shares      []ExpenseShareInput
+	SpentAt     *time.Time
+}
+
+// SettlementInput records a transfer between two members
+type SettlementInput struct {
+	FromUserID string
+	ToUserID   string
+	Amount     float64
+	Currency   string
+	Note       string
+}
+
+type ExpenseService struct {
+	db              *gorm.DB
+	currencyService *CurrencyService
+}
+
+func NewExpenseService(db *gorm.DB) *ExpenseService {
+	return &ExpenseService{
+		db:              db,
+		currencyService: NewCurrencyService(db),
+	}
+}
+
+// CreateGroup creates a ledger. For an event the organizer and all participants
+// become members; otherwise the given members must be friends of the creator.
+func (s *ExpenseService) CreateGroup(userID string, input ExpenseGroupInput) (*models.ExpenseGroup, error) {
+	currency := strings.ToUpper(strings.TrimSpace(input.Currency))
+	if currency == "" {
+		currency = s.currencyService.PreferredCurrency(userID)
+	}
+	if !models.IsSupportedCurrency(currency) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+
+	group := &models.ExpenseGroup{
+		ID:          uuid.New().String(),
+		Name:        strings.TrimSpace(input.Name),
+		CreatedByID: userID,
+		Currency:    currency,
+	}
+
+	memberIDs := []string{userID}
+	if input.EventID != "" {
+		var event models.CommunityEvent
+		if err := s.db.Preload("Participants").First(&event, "id = ?", input.EventID).Error; err != nil {
+			return nil, ErrEventNotFound
+		}
+
+		memberIDs = append(memberIDs, event.OrganizerID)
+		for _, participant := range event.Participants {
+			memberIDs = append(memberIDs, participant.UserID)
+		}
+		if !containsString(memberIDs[1:], userID) {
+			return nil, ErrNotExpenseMember
+		}
+
+		group.EventID = &event.ID
+		if group.Name == "" {
+			group.Name = event.Title
+		}
+	} else {
+		for _, memberID := range input.MemberIDs {
+			if memberID != userID && !s.areFriends(userID, memberID) {
+				return nil, ErrNotFriend
+			}
+		}
+		memberIDs = append(memberIDs, input.MemberIDs...)
+	}
+
+	if group.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidExpense)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(group).Error; err != nil {
+			return err
+		}
+		for _, memberID := range uniqueStrings(memberIDs) {
+			member := models.ExpenseGroupMember{GroupID: group.ID, UserID: memberID}
+			if err := tx.Create(&member).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetGroup(userID, group.ID)
+}
+
+// GetGroup loads a group with its members, visible to members only
+func (s *ExpenseService) GetGroup(userID, groupID string) (*models.ExpenseGroup, error) {
+	var group models.ExpenseGroup
+	if err := s.db.Preload("Members.User").Preload("CreatedBy").
+		First(&group, "id = ?", groupID).Error; err != nil || !group.HasMember(userID) {
+		return nil, ErrExpenseGroupNotFound
+	}
+	return &group, nil
+}
+
+// ListGroups returns the groups a user belongs to, newest first
+func (s *ExpenseService) ListGroups(userID string) ([]models.ExpenseGroup, error) {
+	var groups []models.ExpenseGroup
+	err := s.db.Preload("Members.User").
+		Where("id IN (?)", s.db.Model(&models.ExpenseGroupMember{}).Select("group_id").Where("user_id = ?", userID)).
+		Order("created_at DESC").Find(&groups).Error
+	return groups, err
+}
+
+// AddMember adds a friend of the requesting member, or a participant of the group's event
+func (s *ExpenseService) AddMember(userID, groupID, memberID string) (*models.ExpenseGroup, error) {
+	group, err := s.GetGroup(userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group.HasMember(memberID) {
+		return group, nil
+	}
+
+	allowed := s.areFriends(userID, memberID)
+	if !allowed && group.EventID != nil {
+		var count int64
+		s.db.Model(&models.EventParticipant{}).Where("event_id = ? AND user_id = ?", *group.EventID, memberID).Count(&count)
+		allowed = count > 0
+	}
+	if !allowed {
+		return nil, ErrNotFriend
+	}
+
+	if err := s.db.Create(&models.ExpenseGroupMember{GroupID: groupID, UserID: memberID}).Error; err != nil {
+		return nil, err
+	}
+	return s.GetGroup(userID, groupID)
+}
+
+// RemoveMember removes a member who neither paid, shares an expense nor took part in a settlement.
+// Members can remove themselves; the group creator can remove anyone.
+func (s *ExpenseService) RemoveMember(userID, groupID, memberID string) error {
+	group, err := s.GetGroup(userID, groupID)
+	if err != nil {
+		return err
+	}
+	if !group.HasMember(memberID) {
+		return ErrNotExpenseMember
+	}
+	if userID != memberID && userID != group.CreatedByID {
+		return ErrNotExpenseMember
+	}
+
+	var involved int64
+	s.db.Model(&models.Expense{}).Where("group_id = ? AND paid_by_id = ?", groupID, memberID).Count(&involved)
+	if involved == 0 {
+		s.db.Model(&models.ExpenseShare{}).
+			Where("user_id = ? AND expense_id IN (?)", memberID, s.db.Model(&models.Expense{}).Select("id").Where("group_id = ?", groupID)).
+			Count(&involved)
+	}
+	if involved == 0 {
+		s.db.Model(&models.ExpenseSettlement{}).
+			Where("group_id = ? AND (from_user_id = ? OR to_user_id = ?)", groupID, memberID, memberID).
+			Count(&involved)
+	}
+	if involved > 0 {
+		return ErrMemberHasExpenses
+	}
+
+	return s.db.Where("group_id = ? AND user_id = ?", groupID, memberID).Delete(&models.ExpenseGroupMember{}).Error
+}
+
+// AddExpense stores a payment and splits it into shares
+func (s *ExpenseService) AddExpense(userID, groupID string, input ExpenseInput) (*models.Expense, error) {
+	group, err := s.GetGroup(userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	expense := &models.Expense{
+		ID:          uuid.New().String(),
+		GroupID:     groupID,
+		PaidByID:    input.PaidByID,
+		CreatedByID: userID,
+		Description: strings.TrimSpace(input.Description),
+		Category:    strings.ToLower(strings.TrimSpace(input.Category)),
+		Amount:      roundToDecimal(input.Amount, 2),
+		Currency:    strings.ToUpper(strings.TrimSpace(input.Currency)),
+		SplitType:   strings.ToLower(strings.TrimSpace(input.SplitType)),
+		SpentAt:     time.Now(),
+	}
+	if expense.PaidByID == "" {
+		expense.PaidByID = userID
+	}
+	if expense.Category == "" {
+		expense.Category = models.ExpenseCategoryOther
+	}
+	if expense.Currency == "" {
+		expense.Currency = group.Currency
+	}
+	if expense.SplitType == "" {
+		expense.SplitType = models.ExpenseSplitEqual
+	}
+	if input.SpentAt != nil {
+		expense.SpentAt = *input.SpentAt
+	}
+
+	switch {
+	case expense.Description == "":
+		return nil, fmt.Errorf("%w: description is required", ErrInvalidExpense)
+	case expense.Amount <= 0:
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidExpense)
+	case !models.IsValidExpenseCategory(expense.Category):
+		return nil, fmt.Errorf("%w: unknown category %q", ErrInvalidExpense, expense.Category)
+	case !models.IsSupportedCurrency(expense.Currency):
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, expense.Currency)
+	case !group.HasMember(expense.PaidByID):
+		return nil, fmt.Errorf("%w: payer is not a member", ErrInvalidExpense)
+	}
+
+	shares := input.Shares
+	if len(shares) == 0 {
+		for _, member := range group.Members {
+			shares = append(shares, ExpenseShareInput{UserID: member.UserID, Weight: 1})
+		}
+	}
+	expense.Shares, err = splitExpense(expense.Amount, expense.SplitType, shares, group)
+	if err != nil {
+		return nil, err
+	}
+
+	// Shares are created together with the expense
+	if err := s.db.Create(expense).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetExpense(userID, groupID, expense.ID)
+}
+
+// GetExpense loads an expense with its payer and shares
+func (s *ExpenseService) GetExpense(userID, groupID, expenseID string) (*models.Expense, error) {
+	if _, err := s.GetGroup(userID, groupID); err != nil {
+		return nil, err
+	}
+
+	var expense models.Expense
+	if err := s.db.Preload("PaidBy").Preload("Shares.User").
+		First(&expense, "id = ? AND group_id = ?", expenseID, groupID).Error; err != nil {
+		return nil, ErrExpenseNotFound
+	}
+	return &expense, nil
+}
+
+// ListExpenses returns the group's expenses, most recent first
+func (s *ExpenseService) ListExpenses(userID, groupID string) ([]models.Expense, error) {
+	if _, err := s.GetGroup(userID, groupID); err != nil {
+		return nil, err
+	}
+
+	var expenses []models.Expense
+	err := s.db.Preload("PaidBy").Preload("Shares.User").
+		Where("group_id = ?", groupID).Order("spent_at DESC, created_at DESC").Find(&expenses).Error
+	return expenses, err
+}
+
+// DeleteExpense removes an expense; only its creator, its payer or the group creator may do so
+func (s *ExpenseService) DeleteExpense(userID, groupID, expenseID string) error {
+	group, err := s.GetGroup(userID, groupID)
+	if err != nil {
+		return err
+	}
+
+	var expense models.Expense
+	if err := s.db.First(&expense, "id = ? AND group_id = ?", expenseID, groupID).Error; err != nil {
+		return ErrExpenseNotFound
+	}
+	if userID != expense.CreatedByID && userID != expense.PaidByID && userID != group.CreatedByID {
+		return ErrExpenseNotFound
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expense_id = ?", expense.ID).Delete(&models.ExpenseShare{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&expense).Error
+	})
+}
+
+// RecordSettlement stores a transfer between members; either side may record it
+func (s *ExpenseService) RecordSettlement(userID, groupID string, input SettlementInput) (*models.ExpenseSettlement, error) {
+	group, err := s.GetGroup(userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	settlement := &models.ExpenseSettlement{
+		ID:         uuid.New().String(),
+		GroupID:    groupID,
+		FromUserID: input.FromUserID,
+		ToUserID:   input.ToUserID,
+		Amount:     roundToDecimal(input.Amount, 2),
+		Currency:   strings.ToUpper(strings.TrimSpace(input.Currency)),
+		Note:       strings.TrimSpace(input.Note),
+	}
+	if settlement.Currency == "" {
+		settlement.Currency = group.Currency
+	}
+
+	switch {
+	case settlement.Amount <= 0:
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidExpense)
+	case !models.IsSupportedCurrency(settlement.Currency):
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, settlement.Currency)
+	case settlement.FromUserID == settlement.ToUserID:
+		return nil, fmt.Errorf("%w: cannot settle with yourself", ErrInvalidExpense)
+	case !group.HasMember(settlement.FromUserID) || !group.HasMember(settlement.ToUserID):
+		return nil, ErrNotExpenseMember
+	case userID != settlement.FromUserID && userID != settlement.ToUserID:
+		return nil, fmt.Errorf("%w: only the payer or the receiver can record a settlement", ErrInvalidExpense)
+	}
+
+	if err := s.db.Create(settlement).Error; err != nil {
+		return nil, err
+	}
+	return settlement, nil
+}
+
+// ListSettlements returns the recorded transfers of a group
+func (s *ExpenseService) ListSettlements(userID, groupID string) ([]models.ExpenseSettlement, error) {
+	if _, err := s.GetGroup(userID, groupID); err != nil {
+		return nil, err
+	}
+
+	var settlements []models.ExpenseSettlement
+	err := s.db.Preload("FromUser").Preload("ToUser").
+		Where("group_id = ?", groupID).Order("created_at DESC").Find(&settlements).Error
+	return settlements, err
+}
+
+// Balances computes every member's position in the group currency (or the
+// requested one) together with the transfers needed to settle up
+func (s *ExpenseService) Balances(userID, groupID, currency string) (*models.ExpenseGroupBalances, error) {
+	group, err := s.GetGroup(userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		currency = group.Currency
+	}
+	if !models.IsSupportedCurrency(currency) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+
+	var expenses []models.Expense
+	if err := s.db.Preload("Shares").Where("group_id = ?", groupID).Find(&expenses).Error; err != nil {
+		return nil, err
+	}
+	var settlements []models.ExpenseSettlement
+	if err := s.db.Where("group_id = ?", groupID).Find(&settlements).Error; err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]*models.MemberBalance, len(group.Members))
+	order := make([]string, 0, len(group.Members))
+	balanceOf := func(id string) *models.MemberBalance {
+		if balance, ok := balances[id]; ok {
+			return balance
+		}
+		// Former members keep their balance until it is settled
+		balances[id] = &models.MemberBalance{UserID: id}
+		order = append(order, id)
+		return balances[id]
+	}
+	for _, member := range group.Members {
+		balance := balanceOf(member.UserID)
+		balance.Name = member.User.Name
+		balance.Handle = member.User.Handle
+	}
+
+	result := &models.ExpenseGroupBalances{
+		GroupID:     group.ID,
+		Currency:    currency,
+		ByCategory:  map[string]float64{},
+		Balances:    []models.MemberBalance{},
+		Suggestions: []models.SettlementSuggestion{},
+	}
+
+	for _, expense := range expenses {
+		rate, err := s.currencyService.Rate(expense.Currency, currency)
+		if err != nil {
+			return nil, err
+		}
+		amount := expense.Amount * rate
+		result.TotalSpent += amount
+		result.ByCategory[expense.Category] += amount
+		balanceOf(expense.PaidByID).Paid += amount
+		for _, share := range expense.Shares {
+			balanceOf(share.UserID).Share += share.Amount * rate
+		}
+	}
+
+	for _, settlement := range settlements {
+		amount, err := s.currencyService.Convert(settlement.Amount, settlement.Currency, currency)
+		if err != nil {
+			return nil, err
+		}
+		balanceOf(settlement.FromUserID).Settled += amount
+		balanceOf(settlement.ToUserID).Settled -= amount
+	}
+
+	for _, id := range order {
+		balance := balances[id]
+		balance.Paid = roundToDecimal(balance.Paid, 2)
+		balance.Share = roundToDecimal(balance.Share, 2)
+		balance.Settled = roundToDecimal(balance.Settled, 2)
+		balance.Balance = roundToDecimal(balance.Paid-balance.Share+balance.Settled, 2)
+		result.Balances = append(result.Balances, *balance)
+	}
+	result.TotalSpent = roundToDecimal(result.TotalSpent, 2)
+	for category, amount := range result.ByCategory {
+		result.ByCategory[category] = roundToDecimal(amount, 2)
+	}
+
+	result.Suggestions = SettleUp(result.Balances, currency)
+	return result, nil
+}
+
+// settleUpExactMembers is the most members with open balances for which
+// SettleUp searches for the fewest transfers
+const settleUpExactMembers = 14
+
+// settlementPosition is the open balance of a member in cents, positive when
+// the member is owed money
+type settlementPosition struct {
+	member models.MemberBalance
+	cents  int64
+}
+
+// SettleUp suggests transfers that bring every balance to zero. Members are
+// split into as many subgroups as possible whose balances cancel out, and each
+// subgroup of k members is settled with k-1 transfers, which gives the fewest
+// transfers possible. The search is exponential, so in groups with more than
+// settleUpExactMembers open balances the whole group is settled at once; that
+// needs at most n-1 transfers but may need more than the minimum.
+func SettleUp(balances []models.MemberBalance, currency string) []models.SettlementSuggestion {
+	var positions []settlementPosition
+	var total int64
+	for _, balance := range balances {
+		if cents := int64(math.Round(balance.Balance * 100)); cents != 0 {
+			positions = append(positions, settlementPosition{balance, cents})
+			total += cents
+		}
+	}
+
+	suggestions := []models.SettlementSuggestion{}
+	if len(positions) > settleUpExactMembers || total != 0 {
+		return append(suggestions, settleGreedily(positions, currency)...)
+	}
+	for _, group := range zeroSumGroups(positions) {
+		suggestions = append(suggestions, settleGreedily(group, currency)...)
+	}
+	return suggestions
+}
+
+// zeroSumGroups partitions positions that add up to zero into the largest
+// number of groups that each add up to zero
+func zeroSumGroups(positions []settlementPosition) [][]settlementPosition {
+	n := len(positions)
+	full := 1<<n - 1
+
+	// groups[mask] is the most zero-sum groups the members in mask can be
+	// split into, counting the rest of the mask as one group when it
+	// doesn't add up to zero
+	sum := make([]int64, full+1)
+	groups := make([]int, full+1)
+	for mask := 1; mask <= full; mask++ {
+		low := bits.TrailingZeros(uint(mask))
+		sum[mask] = sum[mask&(mask-1)] + positions[low].cents
+		for i := 0; i < n; i++ {
+			if mask&(1<<i) != 0 {
+				groups[mask] = max(groups[mask], groups[mask&^(1<<i)])
+			}
+		}
+		if sum[mask] == 0 {
+			groups[mask]++
+		}
+	}
+
+	// Remove members one by one along the best choices; every mask that adds
+	// up to zero on the way closes a group
+	var result [][]settlementPosition
+	var group []settlementPosition
+	for mask := full; mask != 0; {
+		bonus := 0
+		if sum[mask] == 0 {
+			bonus = 1
+			if len(group) > 0 {
+				result = append(result, group)
+				group = nil
+			}
+		}
+		for i := 0; i < n; i++ {
+			if mask&(1<<i) != 0 && groups[mask&^(1<<i)]+bonus == groups[mask] {
+				group = append(group, positions[i])
+				mask &^= 1 << i
+				break
+			}
+		}
+	}
+	return append(result, group)
+}
+
+// settleGreedily lets the largest debtor repeatedly pay the largest creditor.
+// Each transfer clears at least one member, so n members need at most n-1
+// transfers.
+func settleGreedily(positions []settlementPosition, currency string) []models.SettlementSuggestion {
+	var creditors, debtors []settlementPosition
+	for _, position := range positions {
+		switch {
+		case position.cents > 0:
+			creditors = append(creditors, position)
+		case position.cents < 0:
+			debtors = append(debtors, settlementPosition{position.member, -position.cents})
+		}
+	}
+
+	var suggestions []models.SettlementSuggestion
+	for len(creditors) > 0 && len(debtors) > 0 {
+		sort.Slice(creditors, func(i, j int) bool { return creditors[i].cents > creditors[j].cents })
+		sort.Slice(debtors, func(i, j int) bool { return debtors[i].cents > debtors[j].cents })
+
+		creditor, debtor := &creditors[0], &debtors[0]
+		cents := creditor.cents
+		if debtor.cents < cents {
+			cents = debtor.cents
+		}
+
+		suggestions = append(suggestions, models.SettlementSuggestion{
+			FromUserID: debtor.member.UserID,
+			FromName:   debtor.member.Name,
+			ToUserID:   creditor.member.UserID,
+			ToName:     creditor.member.Name,
+			Amount:     float64(cents) / 100,
+			Currency:   currency,
+		})
+
+		creditor.cents -= cents
+		debtor.cents -= cents
+		if creditor.cents == 0 {
+			creditors = creditors[1:]
+		}
+		if debtor.cents == 0 {
+			debtors = debtors[1:]
+		}
+	}
+
+	return suggestions
+}
+
+// MemberIDs returns the user IDs of a group's members
+func (s *ExpenseService) MemberIDs(groupID string) []string {
+	var ids []string
+	s.db.Model(&models.ExpenseGroupMember{}).Where("group_id = ?", groupID).Pluck("user_id", &ids)
+	return ids
+}
+
+// splitExpense divides an amount into shares in cents; rounding leftovers go
+// to the first shares so the shares always add up to the amount
+func splitExpense(amount float64, splitType string, inputs []ExpenseShareInput, group *models.ExpenseGroup) ([]models.ExpenseShare, error) {
+	if splitType != models.ExpenseSplitEqual && splitType != models.ExpenseSplitWeighted {
+		return nil, fmt.Errorf("%w: split_type must be equal or weighted", ErrInvalidExpense)
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%w: at least one share is required", ErrInvalidExpense)
+	}
+
+	seen := make(map[string]bool, len(inputs))
+	var totalWeight float64
+	for i := range inputs {
+		if !group.HasMember(inputs[i].UserID) {
+			return nil, fmt.Errorf("%w: %s", ErrNotExpenseMember, inputs[i].UserID)
+		}
+		if seen[inputs[i].UserID] {
+			return nil, fmt.Errorf("%w: duplicate share for %s", ErrInvalidExpense, inputs[i].UserID)
+		}
+		seen[inputs[i].UserID] = true
+
+		if splitType == models.ExpenseSplitEqual {
+			inputs[i].Weight = 1
+		} else if inputs[i].Weight <= 0 {
+			return nil, fmt.Errorf("%w: weights must be positive", ErrInvalidExpense)
+		}
+		totalWeight += inputs[i].Weight
+	}
+
+	totalCents := int64(math.Round(amount * 100))
+	shares := make([]models.ExpenseShare, len(inputs))
+	var assigned int64
+	for i, input := range inputs {
+		cents := int64(math.Floor(float64(totalCents) * input.Weight / totalWeight))
+		shares[i] = models.ExpenseShare{UserID: input.UserID, Weight: input.Weight, Amount: float64(cents)}
+		assigned += cents
+	}
+	for i := 0; assigned < totalCents; i = (i + 1) % len(shares) {
+		shares[i].Amount++
+		assigned++
+	}
+	for i := range shares {
+		shares[i].Amount /= 100
+	}
+
+	return shares, nil
+}
+
+func (s *ExpenseService) areFriends(user1ID, user2ID string) bool {
+	if user1ID > user2ID {
+		user1ID, user2ID = user2ID, user1ID
+	}
+
+	var count int64
+	s.db.Model(&models.Friendship{}).Where("user1_id = ? AND user2_id = ?", user1ID, user2ID).Count(&count)
+	return count > 0
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		unique = append(unique, value)
+	}
+	return unique
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"motocosmos-api/models"
+)
+
+func TestSettleUp(t *testing.T) {
+	tests := []struct {
+		name      string
+		balances  []float64
+		transfers int
+	}{
+		{"settled", []float64{0, 0, 0}, 0},
+		{"one debtor", []float64{30, -10, -20}, 2},
+		{"pairs", []float64{25, -25, 10, -10}, 2},
+		// The largest debtor paying the largest creditor needs 5 transfers
+		{"cancelling subgroups", []float64{6, 4, 1, -5, -5, -1}, 4},
+		{"cents", []float64{10.01, -3.34, -3.34, -3.33}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balances := make([]models.MemberBalance, len(tt.balances))
+			for i, balance := range tt.balances {
+				balances[i] = models.MemberBalance{UserID: string(rune('a' + i)), Balance: balance}
+			}
+
+			suggestions := SettleUp(balances, "EUR")
+			if len(suggestions) != tt.transfers {
+				t.Errorf("got %d transfers, want %d: %+v", len(suggestions), tt.transfers, suggestions)
+			}
+
+			remaining := make(map[string]float64)
+			for _, balance := range balances {
+				remaining[balance.UserID] = balance.Balance
+			}
+			for _, s := range suggestions {
+				if s.Amount <= 0 {
+					t.Errorf("transfer of %v from %s to %s", s.Amount, s.FromUserID, s.ToUserID)
+				}
+				remaining[s.FromUserID] += s.Amount
+				remaining[s.ToUserID] -= s.Amount
+			}
+			for id, balance := range remaining {
+				if math.Abs(balance) > 0.001 {
+					t.Errorf("%s keeps a balance of %v", id, balance)
+				}
+			}
+		})
+	}
+}
+
+func TestSettleUpLargeGroup(t *testing.T) {
+	// Too many members for the exact search; everyone still ends up settled
+	var balances []models.MemberBalance
+	for i := 0; i < settleUpExactMembers+2; i++ {
+		amount := float64(i + 1)
+		if i%2 == 1 {
+			amount = -float64(i)
+		}
+		balances = append(balances, models.MemberBalance{UserID: string(rune('a' + i)), Balance: amount})
+	}
+
+	suggestions := SettleUp(balances, "EUR")
+	if len(suggestions) > len(balances)-1 {
+		t.Errorf("got %d transfers for %d members", len(suggestions), len(balances))
+	}
+}
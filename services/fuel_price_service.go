@@ -0,0 +1,363 @@
+// File: /services/fuel_price_service.go
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+)
+
+var ErrFuelPriceNotFound = errors.New("fuel price not found")
+
+// FuelPriceInput is a single price row coming from the API or an import file
+type FuelPriceInput struct {
+	CountryCode   string  `json:"country_code"`
+	Region        string  `json:"region"`
+	Grade         string  `json:"grade"`
+	Price         float64 `json:"price"`
+	Currency      string  `json:"currency"`
+	EffectiveFrom string  `json:"effective_from"` // YYYY-MM-DD or RFC3339
+	Source        string  `json:"source"`
+}
+
+// FuelPriceImportResult summarizes a bulk import
+type FuelPriceImportResult struct {
+	Imported int      `json:"imported"`
+	Updated  int      `json:"updated"`
+	Skipped  int      `json:"skipped"`
+	Errors   []string `json:"errors"`
+}
+
+type FuelPriceService struct {
+	db *gorm.DB
+}
+
+func NewFuelPriceService(db *gorm.DB) *FuelPriceService {
+	return &FuelPriceService{db: db}
+}
+
+// LatestPrice returns the price currently in effect for a country, region and
+// grade. A missing regional price falls back to the national price.
+func (s *FuelPriceService) LatestPrice(countryCode, region, grade string) (*models.FuelPrice, error) {
+	regions := []string{region}
+	if region != "" {
+		regions = append(regions, "")
+	}
+
+	for _, r := range regions {
+		var price models.FuelPrice
+		err := s.db.Where("country_code = ? AND region = ? AND grade = ? AND effective_from <= ?", countryCode, r, grade, time.Now()).
+			Order("effective_from DESC").First(&price).Error
+		if err == nil {
+			return &price, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	return nil, ErrFuelPriceNotFound
+}
+
+// LatestPrices returns the price currently in effect for every country and
+// region of a grade
+func (s *FuelPriceService) LatestPrices(grade string) ([]models.FuelPrice, error) {
+	var prices []models.FuelPrice
+	if err := s.db.Where("grade = ? AND effective_from <= ?", grade, time.Now()).
+		Order("country_code ASC, region ASC, effective_from DESC").Find(&prices).Error; err != nil {
+		return nil, err
+	}
+
+	latest := make([]models.FuelPrice, 0, len(prices))
+	seen := make(map[string]bool)
+	for _, price := range prices {
+		key := price.CountryCode + "|" + price.Region
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		latest = append(latest, price)
+	}
+
+	return latest, nil
+}
+
+// NationalPrices returns the current national price in EUR per country code
+// for a grade, filling countries without stored prices from DefaultFuelPrices
+func (s *FuelPriceService) NationalPrices(grade string) map[string]float64 {
+	currencyService := NewCurrencyService(s.db)
+
+	result := make(map[string]float64)
+	if grade == models.FuelGrade95 {
+		for code, price := range DefaultFuelPrices {
+			result[code] = price
+		}
+	}
+
+	if prices, err := s.LatestPrices(grade); err == nil {
+		for _, price := range prices {
+			if price.Region != "" {
+				continue
+			}
+			if eur, err := currencyService.Convert(price.Price, price.Currency, models.DefaultCurrency); err == nil {
+				result[price.CountryCode] = eur
+			}
+		}
+	}
+
+	return result
+}
+
+// History returns all prices of a country, region and grade since the given time, oldest first
+func (s *FuelPriceService) History(countryCode, region, grade string, since time.Time) ([]models.FuelPrice, error) {
+	var prices []models.FuelPrice
+	err := s.db.Where("country_code = ? AND region = ? AND grade = ? AND effective_from >= ? AND effective_from <= ?",
+		countryCode, region, grade, since, time.Now()).
+		Order("effective_from ASC").Find(&prices).Error
+	return prices, err
+}
+
+// Trend describes how a price developed over the last days
+func (s *FuelPriceService) Trend(countryCode, region, grade string, days int) (*models.FuelPriceTrend, error) {
+	since := time.Now().AddDate(0, 0, -days)
+
+	history, err := s.History(countryCode, region, grade, since)
+	if err != nil {
+		return nil, err
+	}
+
+	// The price in effect at the start of the period is the baseline
+	var baseline models.FuelPrice
+	hasBaseline := s.db.Where("country_code = ? AND region = ? AND grade = ? AND effective_from < ?", countryCode, region, grade, since).
+		Order("effective_from DESC").First(&baseline).Error == nil
+
+	if len(history) == 0 && !hasBaseline {
+		return nil, ErrFuelPriceNotFound
+	}
+
+	points := make([]models.FuelPricePoint, 0, len(history)+1)
+	if hasBaseline {
+		points = append(points, models.FuelPricePoint{Price: baseline.Price, EffectiveFrom: since})
+	}
+	for _, price := range history {
+		points = append(points, models.FuelPricePoint{Price: price.Price, EffectiveFrom: price.EffectiveFrom})
+	}
+
+	trend := &models.FuelPriceTrend{
+		CountryCode:   countryCode,
+		Region:        region,
+		Grade:         grade,
+		Currency:      models.DefaultCurrency,
+		PreviousPrice: points[0].Price,
+		CurrentPrice:  points[len(points)-1].Price,
+		MinPrice:      points[0].Price,
+		MaxPrice:      points[0].Price,
+		Points:        points,
+	}
+	if len(history) > 0 {
+		trend.Currency = history[len(history)-1].Currency
+	} else {
+		trend.Currency = baseline.Currency
+	}
+
+	for _, point := range points {
+		trend.MinPrice = math.Min(trend.MinPrice, point.Price)
+		trend.MaxPrice = math.Max(trend.MaxPrice, point.Price)
+	}
+
+	trend.Change = trend.CurrentPrice - trend.PreviousPrice
+	if trend.PreviousPrice > 0 {
+		trend.ChangePercent = trend.Change / trend.PreviousPrice * 100
+	}
+	switch {
+	case trend.ChangePercent > 0.5:
+		trend.Direction = "up"
+	case trend.ChangePercent < -0.5:
+		trend.Direction = "down"
+	default:
+		trend.Direction = "stable"
+	}
+
+	return trend, nil
+}
+
+// Save validates an input row and stores it. A row for the same country,
+// region, grade and date replaces the existing price; the returned bool
+// reports whether a new row was created.
+func (s *FuelPriceService) Save(input FuelPriceInput, createdByID *string) (*models.FuelPrice, bool, error) {
+	price, err := s.buildPrice(input)
+	if err != nil {
+		return nil, false, err
+	}
+	price.CreatedByID = createdByID
+
+	var existing models.FuelPrice
+	err = s.db.Where("country_code = ? AND region = ? AND grade = ? AND effective_from = ?",
+		price.CountryCode, price.Region, price.Grade, price.EffectiveFrom).First(&existing).Error
+	if err == nil {
+		if err := s.db.Model(&existing).Updates(map[string]interface{}{
+			"price":    price.Price,
+			"currency": price.Currency,
+			"source":   price.Source,
+		}).Error; err != nil {
+			return nil, false, err
+		}
+		return &existing, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	if err := s.db.Create(price).Error; err != nil {
+		return nil, false, err
+	}
+	return price, true, nil
+}
+
+// Import stores rows from a CSV or JSON document. The CSV needs a header row
+// with country_code (or country), grade, price and optionally region,
+// currency, effective_from and source columns.
+func (s *FuelPriceService) Import(r io.Reader, format string, createdByID *string) (*FuelPriceImportResult, error) {
+	var rows []FuelPriceInput
+	var err error
+
+	switch strings.ToLower(format) {
+	case "csv":
+		rows, err = parseFuelPriceCSV(r)
+	case "json":
+		err = json.NewDecoder(r).Decode(&rows)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", format, err)
+	}
+
+	result := &FuelPriceImportResult{Errors: []string{}}
+	for i, row := range rows {
+		if row.Source == "" {
+			row.Source = "import"
+		}
+		_, created, err := s.Save(row, createdByID)
+		switch {
+		case err != nil:
+			result.Skipped++
+			result.Errors = append(result.Errors, fmt.Sprintf("row %d: %v", i+1, err))
+		case created:
+			result.Imported++
+		default:
+			result.Updated++
+		}
+	}
+
+	return result, nil
+}
+
+// ImportFile imports a CSV or JSON file, detecting the format from its extension
+func (s *FuelPriceService) ImportFile(path string) (*FuelPriceImportResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	return s.Import(file, format, nil)
+}
+
+func (s *FuelPriceService) buildPrice(input FuelPriceInput) (*models.FuelPrice, error) {
+	country, ok := CountryByCode(strings.TrimSpace(input.CountryCode))
+	if !ok {
+		country, ok = CountryByCode(strings.ToUpper(strings.TrimSpace(input.CountryCode)))
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown country %q", input.CountryCode)
+	}
+
+	grade := strings.ToLower(strings.TrimSpace(input.Grade))
+	if !models.IsValidFuelGrade(grade) {
+		return nil, fmt.Errorf("invalid fuel grade %q, must be one of %s", input.Grade, strings.Join(models.FuelGrades, ", "))
+	}
+
+	if input.Price <= 0 {
+		return nil, fmt.Errorf("price must be positive")
+	}
+
+	effectiveFrom := time.Now().Truncate(24 * time.Hour)
+	if input.EffectiveFrom != "" {
+		parsed, err := parseImportDate(input.EffectiveFrom)
+		if err != nil {
+			return nil, err
+		}
+		effectiveFrom = parsed
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(input.Currency))
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	if !models.IsSupportedCurrency(currency) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+
+	// Sanity check against the 10 EUR/L ceiling the calculator uses
+	if priceEUR, err := NewCurrencyService(s.db).Convert(input.Price, currency, models.DefaultCurrency); err != nil || priceEUR > 10 {
+		return nil, fmt.Errorf("price cannot exceed 10 EUR/L or its equivalent")
+	}
+
+	source := input.Source
+	if source == "" {
+		source = "manual"
+	}
+
+	return &models.FuelPrice{
+		ID:            uuid.New().String(),
+		CountryCode:   country.Code,
+		Region:        strings.TrimSpace(input.Region),
+		Grade:         grade,
+		Price:         input.Price,
+		Currency:      currency,
+		EffectiveFrom: effectiveFrom,
+		Source:        source,
+	}, nil
+}
+
+func parseFuelPriceCSV(r io.Reader) ([]FuelPriceInput, error) {
+	records, field, err := readImportCSV(r, "grade", "price")
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]FuelPriceInput, 0, len(records))
+	for _, record := range records {
+		country := field(record, "country_code")
+		if country == "" {
+			country = field(record, "country")
+		}
+
+		// Invalid prices are rejected row by row during the import
+		price, _ := strconv.ParseFloat(strings.Replace(field(record, "price"), ",", ".", 1), 64)
+		rows = append(rows, FuelPriceInput{
+			CountryCode:   country,
+			Region:        field(record, "region"),
+			Grade:         field(record, "grade"),
+			Price:         price,
+			Currency:      field(record, "currency"),
+			EffectiveFrom: field(record, "effective_from"),
+			Source:        field(record, "source"),
+		})
+	}
+
+	return rows, nil
+}
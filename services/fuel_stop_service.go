@@ -0,0 +1,262 @@
+// File: /services/fuel_stop_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+)
+
+var (
+	ErrRouteGeometryMissing = errors.New("route has no geometry or waypoints to plan along")
+	ErrTankCapacityMissing  = errors.New("tank capacity is required, set it on the motorcycle or in the request")
+)
+
+const (
+	defaultFuelReservePercent   = 15.0
+	defaultFuelStationRadiusKm  = 5.0
+	maxFuelStationAlternatives  = 3
+	fuelStationAlternativeShare = 0.25 // alternatives lie within this share of the usable range before the chosen station
+)
+
+// FuelStopInput describes the motorcycle and preferences used for planning.
+// Zero values are taken from the motorcycle or the defaults.
+type FuelStopInput struct {
+	RouteID          string
+	MotorcycleID     string
+	TankCapacity     float64  // liters
+	FuelConsumption  float64  // L/100km
+	ReservePercent   *float64 // share of the tank kept as reserve
+	StartFuelPercent *float64 // tank level at the start, defaults to full
+	SearchRadius     float64  // km around the route to look for stations
+}
+
+type FuelStopService struct {
+	db              *gorm.DB
+	poiService      *POIService
+	tripCostService *TripCostService
+}
+
+func NewFuelStopService(db *gorm.DB) *FuelStopService {
+	return &FuelStopService{
+		db:              db,
+		poiService:      NewPOIService(db),
+		tripCostService: NewTripCostService(db),
+	}
+}
+
+// Plan computes where refuelling is required along a route and suggests
+// stations from the local POI dataset near those points
+func (s *FuelStopService) Plan(userID string, input FuelStopInput) (*models.FuelStopPlan, error) {
+	var route models.Route
+	if err := s.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).Preload("Collaborators").First(&route, "id = ?", input.RouteID).Error; err != nil || !route.IsAccessibleBy(userID) {
+		return nil, ErrRouteNotFound
+	}
+
+	points := route.GetRouteGeometryAsLatLng()
+	if len(points) < 2 {
+		points = route.GetWaypointsAsLatLng()
+	}
+	if len(points) < 2 {
+		return nil, ErrRouteGeometryMissing
+	}
+
+	plan := &models.FuelStopPlan{
+		RouteID:         route.ID,
+		TankCapacity:    input.TankCapacity,
+		FuelConsumption: input.FuelConsumption,
+		ReservePercent:  defaultFuelReservePercent,
+		Stops:           []models.FuelStop{},
+		Warnings:        []models.FuelGapWarning{},
+	}
+	if input.ReservePercent != nil {
+		plan.ReservePercent = math.Max(0, math.Min(90, *input.ReservePercent))
+	}
+
+	if input.MotorcycleID != "" {
+		var motorcycle models.Motorcycle
+		if err := s.db.First(&motorcycle, "id = ? AND user_id = ?", input.MotorcycleID, userID).Error; err != nil {
+			return nil, ErrMotorcycleNotFound
+		}
+		plan.MotorcycleID = &motorcycle.ID
+
+		if plan.TankCapacity == 0 {
+			plan.TankCapacity = motorcycle.TankCapacity
+		}
+		if plan.FuelConsumption == 0 {
+			plan.FuelConsumption, _ = s.tripCostService.MotorcycleConsumption(&motorcycle)
+		}
+	}
+	if plan.TankCapacity <= 0 {
+		return nil, ErrTankCapacityMissing
+	}
+	if plan.FuelConsumption <= 0 {
+		plan.FuelConsumption = defaultMotorcycleConsumption
+	}
+
+	startFuel := 1.0
+	if input.StartFuelPercent != nil {
+		startFuel = math.Max(0, math.Min(100, *input.StartFuelPercent)) / 100
+	}
+
+	radius := input.SearchRadius
+	if radius <= 0 {
+		radius = defaultFuelStationRadiusKm
+	}
+
+	// Geometry is usually denser than the road network's curves allow for, so
+	// stretch path distances to the route's stored road distance when known
+	cumulative := CumulativeDistancesKm(points)
+	pathLength := cumulative[len(cumulative)-1]
+	scale := 1.0
+	if route.TotalDistance > pathLength && pathLength > 0 {
+		scale = route.TotalDistance / pathLength
+	}
+
+	plan.TotalDistance = pathLength * scale
+	plan.FullRange = plan.TankCapacity / plan.FuelConsumption * 100
+	plan.UsableRange = plan.FullRange * (1 - plan.ReservePercent/100)
+	plan.TotalFuel = plan.TotalDistance * plan.FuelConsumption / 100
+
+	stations, err := s.stationsAlongPath(points, cumulative, scale, radius)
+	if err != nil {
+		return nil, err
+	}
+
+	s.placeStops(plan, points, cumulative, scale, stations, startFuel)
+
+	return plan, nil
+}
+
+// stationsAlongPath returns the fuel stations within radius of the path, ordered by their position along it
+func (s *FuelStopService) stationsAlongPath(points []models.LatLng, cumulative []float64, scale, radius float64) ([]models.FuelStation, error) {
+	minLat, maxLat, minLng, maxLng := PathBounds(points)
+	minLat, _, minLng, _ = BoundingBox(minLat, minLng, radius)
+	_, maxLat, _, maxLng = BoundingBox(maxLat, maxLng, radius)
+
+	pois, err := s.poiService.InBounds(minLat, maxLat, minLng, maxLng, models.POICategoryFuel)
+	if err != nil {
+		return nil, err
+	}
+
+	stations := make([]models.FuelStation, 0, len(pois))
+	for _, poi := range pois {
+		along, offset := ProjectOntoPath(points, cumulative, poi.Latitude, poi.Longitude)
+		if offset > radius {
+			continue
+		}
+		stations = append(stations, models.FuelStation{
+			PointOfInterest:   poi,
+			Detour:            offset,
+			DistanceFromStart: along * scale,
+		})
+	}
+
+	sort.Slice(stations, func(i, j int) bool {
+		return stations[i].DistanceFromStart < stations[j].DistanceFromStart
+	})
+
+	return stations, nil
+}
+
+// placeStops greedily refuels at the last station reachable before the
+// reserve, which keeps the number of stops minimal
+func (s *FuelStopService) placeStops(plan *models.FuelStopPlan, points []models.LatLng, cumulative []float64, scale float64, stations []models.FuelStation, startFuel float64) {
+	position := 0.0
+	tankLevel := startFuel // share of the tank at the current position
+
+	for {
+		reach := position + plan.FullRange*(tankLevel-plan.ReservePercent/100)
+		if reach >= plan.TotalDistance {
+			return
+		}
+		reach = math.Max(reach, position)
+
+		var candidates []models.FuelStation
+		var next *models.FuelStation
+		for i := range stations {
+			if stations[i].DistanceFromStart <= position {
+				continue
+			}
+			if stations[i].DistanceFromStart <= reach {
+				candidates = append(candidates, stations[i])
+			} else if next == nil {
+				next = &stations[i]
+			}
+		}
+
+		stop := models.FuelStop{Number: len(plan.Stops) + 1, Alternatives: []models.FuelStation{}}
+		switch {
+		case len(candidates) > 0:
+			chosen := candidates[len(candidates)-1]
+			stop.Station = &chosen
+			stop.DistanceFromStart = chosen.DistanceFromStart
+			stop.Alternatives = alternativeStations(candidates[:len(candidates)-1], chosen.DistanceFromStart-plan.UsableRange*fuelStationAlternativeShare)
+
+		case next != nil && next.DistanceFromStart <= position+plan.FullRange*tankLevel:
+			// The next station can only be reached by riding on the reserve
+			chosen := *next
+			stop.Station = &chosen
+			stop.DistanceFromStart = chosen.DistanceFromStart
+			plan.Warnings = append(plan.Warnings, gapWarning(position, chosen.DistanceFromStart, plan.UsableRange, "reserve",
+				"No fuel station within the usable range, the reserve is needed to reach the next station"))
+
+		default:
+			// No station before the tank runs dry; mark the point where fuel is needed anyway
+			end := plan.TotalDistance
+			if next != nil {
+				end = next.DistanceFromStart
+			}
+			stop.DistanceFromStart = math.Max(position+plan.FullRange*tankLevel, position+0.001)
+			plan.Warnings = append(plan.Warnings, gapWarning(position, end, plan.UsableRange, "critical",
+				"Gap between fuel stations exceeds a full tank, carry extra fuel"))
+		}
+
+		location := PointAlongPath(points, cumulative, stop.DistanceFromStart/scale)
+		stop.Latitude = location.Latitude
+		stop.Longitude = location.Longitude
+		stop.DistanceFromPrevious = stop.DistanceFromStart - position
+
+		// Fill up to a full tank on arrival
+		used := stop.DistanceFromPrevious * plan.FuelConsumption / 100
+		stop.FuelToAdd = math.Min(plan.TankCapacity, plan.TankCapacity*(1-tankLevel)+used)
+
+		plan.Stops = append(plan.Stops, stop)
+		position = stop.DistanceFromStart
+		tankLevel = 1
+	}
+}
+
+// alternativeStations returns up to maxFuelStationAlternatives stations at or
+// after fromKm, with the smallest detour first
+func alternativeStations(candidates []models.FuelStation, fromKm float64) []models.FuelStation {
+	alternatives := make([]models.FuelStation, 0, maxFuelStationAlternatives)
+	for _, station := range candidates {
+		if station.DistanceFromStart >= fromKm {
+			alternatives = append(alternatives, station)
+		}
+	}
+
+	sort.Slice(alternatives, func(i, j int) bool { return alternatives[i].Detour < alternatives[j].Detour })
+	if len(alternatives) > maxFuelStationAlternatives {
+		alternatives = alternatives[:maxFuelStationAlternatives]
+	}
+	return alternatives
+}
+
+func gapWarning(fromKm, toKm, usableRange float64, severity, message string) models.FuelGapWarning {
+	return models.FuelGapWarning{
+		FromKm:   fromKm,
+		ToKm:     toKm,
+		Distance: toKm - fromKm,
+		Range:    usableRange,
+		Severity: severity,
+		Message:  fmt.Sprintf("%s (%.0f km gap, %.0f km usable range)", message, toKm-fromKm, usableRange),
+	}
+}
@@ -0,0 +1,358 @@
+// File: /services/geo_service.go
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"motocosmos-api/models"
+)
+
+// Country describes a country with a simplified outline used for offline
+// "which country is this point in" lookups
+type Country struct {
+	Code   string  `json:"code"`
+	Name   string  `json:"name"`
+	MinLat float64 `json:"-"`
+	MaxLat float64 `json:"-"`
+	MinLng float64 `json:"-"`
+	MaxLng float64 `json:"-"`
+
+	rings [][]models.LatLng // outlines, built from borderLines
+}
+
+// CountryShare is the portion of a path that lies in a given country
+type CountryShare struct {
+	Code     string  `json:"code"`
+	Name     string  `json:"name"`
+	Share    float64 `json:"share"`    // 0..1
+	Distance float64 `json:"distance"` // km, when the total distance is known
+}
+
+// countryBorderMarginKm is how far outside its simplified outline a point is
+// still matched to a country, for coastal roads and islands close to shore
+const countryBorderMarginKm = 10.0
+
+// countries are matched by their outlines in countryOutlines; the bounding
+// boxes are derived from them and only speed up the lookup
+var countries = []Country{
+	{Code: "AT", Name: "Austria"},
+	{Code: "BE", Name: "Belgium"},
+	{Code: "BA", Name: "Bosnia and Herzegovina"},
+	{Code: "BG", Name: "Bulgaria"},
+	{Code: "HR", Name: "Croatia"},
+	{Code: "CZ", Name: "Czech Republic"},
+	{Code: "DK", Name: "Denmark"},
+	{Code: "FR", Name: "France"},
+	{Code: "DE", Name: "Germany"},
+	{Code: "GR", Name: "Greece"},
+	{Code: "HU", Name: "Hungary"},
+	{Code: "IT", Name: "Italy"},
+	{Code: "LI", Name: "Liechtenstein"},
+	{Code: "LU", Name: "Luxembourg"},
+	{Code: "ME", Name: "Montenegro"},
+	{Code: "NL", Name: "Netherlands"},
+	{Code: "PL", Name: "Poland"},
+	{Code: "PT", Name: "Portugal"},
+	{Code: "RO", Name: "Romania"},
+	{Code: "RS", Name: "Serbia"},
+	{Code: "SK", Name: "Slovakia"},
+	{Code: "SI", Name: "Slovenia"},
+	{Code: "ES", Name: "Spain"},
+	{Code: "CH", Name: "Switzerland"},
+}
+
+func init() {
+	for i := range countries {
+		country := &countries[i]
+		country.rings = countryRings(country.Code)
+		country.MinLat, country.MinLng = math.MaxFloat64, math.MaxFloat64
+		country.MaxLat, country.MaxLng = -math.MaxFloat64, -math.MaxFloat64
+		for _, ring := range country.rings {
+			for _, p := range ring {
+				country.MinLat = math.Min(country.MinLat, p.Latitude)
+				country.MaxLat = math.Max(country.MaxLat, p.Latitude)
+				country.MinLng = math.Min(country.MinLng, p.Longitude)
+				country.MaxLng = math.Max(country.MaxLng, p.Longitude)
+			}
+		}
+	}
+}
+
+// countryRings joins the border pieces of a country into closed rings
+func countryRings(code string) [][]models.LatLng {
+	var rings [][]models.LatLng
+	for _, pieces := range countryOutlines[code] {
+		var ring []models.LatLng
+		for _, name := range pieces {
+			line := borderLines[strings.TrimPrefix(name, "-")]
+			for i := range line {
+				p := line[i]
+				if strings.HasPrefix(name, "-") {
+					p = line[len(line)-1-i]
+				}
+				point := models.LatLng{Latitude: p[0], Longitude: p[1]}
+				// Pieces share their end points
+				if len(ring) > 0 && ring[len(ring)-1] == point {
+					continue
+				}
+				ring = append(ring, point)
+			}
+		}
+		if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+			ring = ring[:len(ring)-1]
+		}
+		rings = append(rings, ring)
+	}
+	return rings
+}
+
+// HaversineKm returns the great-circle distance between two points in km
+func HaversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371 // km
+
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLon := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*
+			math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return earthRadius * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// DestinationPoint returns the point distanceKm away from a start along an initial bearing in degrees
+func DestinationPoint(start models.LatLng, bearing, distanceKm float64) models.LatLng {
+	const earthRadius = 6371 // km
+
+	lat1 := start.Latitude * math.Pi / 180
+	lng1 := start.Longitude * math.Pi / 180
+	theta := bearing * math.Pi / 180
+	delta := distanceKm / earthRadius
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(delta) + math.Cos(lat1)*math.Sin(delta)*math.Cos(theta))
+	lng2 := lng1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(lat1), math.Cos(delta)-math.Sin(lat1)*math.Sin(lat2))
+
+	return models.LatLng{
+		Latitude:  lat2 * 180 / math.Pi,
+		Longitude: math.Mod(lng2*180/math.Pi+540, 360) - 180,
+	}
+}
+
+// PathLengthKm returns the length of an ordered path in km
+func PathLengthKm(points []models.LatLng) float64 {
+	var total float64
+	for i := 1; i < len(points); i++ {
+		total += HaversineKm(points[i-1].Latitude, points[i-1].Longitude, points[i].Latitude, points[i].Longitude)
+	}
+	return total
+}
+
+// Countries returns all countries known to the offline lookup
+func Countries() []Country {
+	return countries
+}
+
+// CountryByCode looks up a country by its ISO 3166-1 alpha-2 code or name
+func CountryByCode(code string) (Country, bool) {
+	for _, country := range countries {
+		if country.Code == code || country.Name == code {
+			return country, true
+		}
+	}
+	return Country{}, false
+}
+
+// CountryForPoint returns the country a coordinate lies in. Points just
+// outside every outline, like on a coastal road, go to the nearest country
+// within countryBorderMarginKm.
+func CountryForPoint(lat, lng float64) (Country, bool) {
+	// Degrees of latitude and longitude covered by the margin around the point
+	marginLat := countryBorderMarginKm / 111.2
+	marginLng := marginLat / math.Max(math.Cos(lat*math.Pi/180), 0.01)
+
+	var nearest Country
+	nearestKm := countryBorderMarginKm
+	found := false
+
+	for _, country := range countries {
+		if lat < country.MinLat-marginLat || lat > country.MaxLat+marginLat ||
+			lng < country.MinLng-marginLng || lng > country.MaxLng+marginLng {
+			continue
+		}
+		for _, ring := range country.rings {
+			if ringContains(ring, lat, lng) {
+				return country, true
+			}
+			if km := ringDistanceKm(ring, lat, lng); km <= nearestKm {
+				nearest, nearestKm, found = country, km, true
+			}
+		}
+	}
+
+	return nearest, found
+}
+
+// ringContains tests whether a point lies inside a ring by counting the edges
+// a ray from the point towards the east crosses
+func ringContains(ring []models.LatLng, lat, lng float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Latitude > lat) == (b.Latitude > lat) {
+			continue
+		}
+		crossLng := a.Longitude + (lat-a.Latitude)/(b.Latitude-a.Latitude)*(b.Longitude-a.Longitude)
+		if lng < crossLng {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// ringDistanceKm returns the distance from a point to the outline of a ring,
+// on a plane around the point, which is accurate enough for short distances
+func ringDistanceKm(ring []models.LatLng, lat, lng float64) float64 {
+	const kmPerDegree = 111.2
+	scale := math.Cos(lat * math.Pi / 180)
+	project := func(p models.LatLng) (float64, float64) {
+		return (p.Longitude - lng) * scale * kmPerDegree, (p.Latitude - lat) * kmPerDegree
+	}
+
+	best := math.MaxFloat64
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		ax, ay := project(ring[j])
+		bx, by := project(ring[i])
+		dx, dy := bx-ax, by-ay
+		t := 0.0
+		if length := dx*dx + dy*dy; length > 0 {
+			t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/length))
+		}
+		best = math.Min(best, math.Hypot(ax+t*dx, ay+t*dy))
+	}
+	return best
+}
+
+// CountrySharesForPoints estimates how much of a route lies in each country.
+// Points are counted individually, so the result does not depend on their order.
+func CountrySharesForPoints(points []models.LatLng, totalDistance float64) []CountryShare {
+	counts := make(map[string]int)
+	matched := 0
+	for _, point := range points {
+		if country, ok := CountryForPoint(point.Latitude, point.Longitude); ok {
+			counts[country.Code]++
+			matched++
+		}
+	}
+
+	shares := make([]CountryShare, 0, len(counts))
+	if matched == 0 {
+		return shares
+	}
+
+	for code, count := range counts {
+		country, _ := CountryByCode(code)
+		share := float64(count) / float64(matched)
+		shares = append(shares, CountryShare{
+			Code:     country.Code,
+			Name:     country.Name,
+			Share:    share,
+			Distance: share * totalDistance,
+		})
+	}
+
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Share == shares[j].Share {
+			return shares[i].Code < shares[j].Code
+		}
+		return shares[i].Share > shares[j].Share
+	})
+
+	return shares
+}
+
+// CumulativeDistancesKm returns, for every point of a path, the distance in km from its start
+func CumulativeDistancesKm(points []models.LatLng) []float64 {
+	cumulative := make([]float64, len(points))
+	for i := 1; i < len(points); i++ {
+		cumulative[i] = cumulative[i-1] + HaversineKm(points[i-1].Latitude, points[i-1].Longitude, points[i].Latitude, points[i].Longitude)
+	}
+	return cumulative
+}
+
+// PointAlongPath returns the coordinate at the given distance from the start of a path
+func PointAlongPath(points []models.LatLng, cumulative []float64, km float64) models.LatLng {
+	if len(points) == 0 {
+		return models.LatLng{}
+	}
+	if km <= 0 {
+		return points[0]
+	}
+
+	for i := 1; i < len(points); i++ {
+		if cumulative[i] < km {
+			continue
+		}
+		segment := cumulative[i] - cumulative[i-1]
+		if segment == 0 {
+			return points[i]
+		}
+		t := (km - cumulative[i-1]) / segment
+		return models.LatLng{
+			Latitude:  points[i-1].Latitude + t*(points[i].Latitude-points[i-1].Latitude),
+			Longitude: points[i-1].Longitude + t*(points[i].Longitude-points[i-1].Longitude),
+		}
+	}
+
+	return points[len(points)-1]
+}
+
+// ProjectOntoPath finds the point of a path closest to a coordinate. It returns
+// the distance of that point from the start of the path and how far the
+// coordinate is from the path, both in km.
+func ProjectOntoPath(points []models.LatLng, cumulative []float64, lat, lng float64) (along, offset float64) {
+	offset = math.MaxFloat64
+	if len(points) == 1 {
+		return 0, HaversineKm(lat, lng, points[0].Latitude, points[0].Longitude)
+	}
+
+	// Segments are short enough to treat them as straight lines on a local
+	// equirectangular projection around the coordinate
+	kmPerLng := 111.32 * math.Cos(lat*math.Pi/180)
+	const kmPerLat = 110.57
+
+	for i := 1; i < len(points); i++ {
+		ax := (points[i-1].Longitude - lng) * kmPerLng
+		ay := (points[i-1].Latitude - lat) * kmPerLat
+		bx := (points[i].Longitude - lng) * kmPerLng
+		by := (points[i].Latitude - lat) * kmPerLat
+
+		dx, dy := bx-ax, by-ay
+		t := 0.0
+		if lengthSquared := dx*dx + dy*dy; lengthSquared > 0 {
+			t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/lengthSquared))
+		}
+
+		px, py := ax+t*dx, ay+t*dy
+		if distance := math.Sqrt(px*px + py*py); distance < offset {
+			offset = distance
+			along = cumulative[i-1] + t*(cumulative[i]-cumulative[i-1])
+		}
+	}
+
+	return along, offset
+}
+
+// PathBounds returns the bounding box of a path
+func PathBounds(points []models.LatLng) (minLat, maxLat, minLng, maxLng float64) {
+	minLat, minLng = math.MaxFloat64, math.MaxFloat64
+	maxLat, maxLng = -math.MaxFloat64, -math.MaxFloat64
+	for _, point := range points {
+		minLat = math.Min(minLat, point.Latitude)
+		maxLat = math.Max(maxLat, point.Latitude)
+		minLng = math.Min(minLng, point.Longitude)
+		maxLng = math.Max(maxLng, point.Longitude)
+	}
+	return minLat, maxLat, minLng, maxLng
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"motocosmos-api/models"
+)
+
+func TestCountryForPoint(t *testing.T) {
+	tests := []struct {
+		place    string
+		lat, lng float64
+		want     string // empty when no known country should match
+	}{
+		{"Munich", 48.137, 11.575, "DE"},
+		{"Vienna", 48.208, 16.373, "AT"},
+		{"Salzburg", 47.800, 13.045, "AT"},
+		{"Freilassing", 47.838, 12.977, "DE"},
+		{"Berchtesgaden", 47.631, 13.002, "DE"},
+		{"Basel", 47.557, 7.588, "CH"},
+		{"Weil am Rhein", 47.595, 7.620, "DE"},
+		{"Saint-Louis", 47.590, 7.560, "FR"},
+		{"Bratislava", 48.148, 17.107, "SK"},
+		{"Hainburg an der Donau", 48.147, 16.940, "AT"},
+		{"Budapest", 47.498, 19.040, "HU"},
+		{"Sopron", 47.685, 16.590, "HU"},
+		{"Eisenstadt", 47.846, 16.520, "AT"},
+		{"Prague", 50.075, 14.437, "CZ"},
+		{"Passau", 48.574, 13.466, "DE"},
+		{"Schärding", 48.457, 13.431, "AT"},
+		{"Innsbruck", 47.269, 11.404, "AT"},
+		{"Bolzano", 46.498, 11.354, "IT"},
+		{"Kufstein", 47.583, 12.170, "AT"},
+		{"Füssen", 47.570, 10.700, "DE"},
+		{"Zurich", 47.377, 8.541, "CH"},
+		{"Geneva", 46.204, 6.143, "CH"},
+		{"Annemasse", 46.193, 6.235, "FR"},
+		{"Lugano", 46.004, 8.951, "CH"},
+		{"Como", 45.808, 9.085, "IT"},
+		{"Vaduz", 47.141, 9.521, "LI"},
+		{"Strasbourg", 48.573, 7.752, "FR"},
+		{"Kehl", 48.572, 7.815, "DE"},
+		{"Luxembourg", 49.611, 6.130, "LU"},
+		{"Maastricht", 50.851, 5.691, "NL"},
+		{"Aachen", 50.776, 6.084, "DE"},
+		{"Lille", 50.629, 3.057, "FR"},
+		{"Ljubljana", 46.057, 14.506, "SI"},
+		{"Koper", 45.548, 13.730, "SI"},
+		{"Trieste", 45.650, 13.780, "IT"},
+		{"Zagreb", 45.815, 15.982, "HR"},
+		{"Dubrovnik", 42.651, 18.094, "HR"},
+		{"Sarajevo", 43.856, 18.413, "BA"},
+		{"Podgorica", 42.441, 19.263, "ME"},
+		{"Belgrade", 44.787, 20.457, "RS"},
+		{"Subotica", 46.100, 19.667, "RS"},
+		{"Szeged", 46.253, 20.148, "HU"},
+		{"Oradea", 47.060, 21.930, "RO"},
+		{"Sofia", 42.698, 23.322, "BG"},
+		{"Thessaloniki", 40.640, 22.944, "GR"},
+		{"Ostrava", 49.835, 18.292, "CZ"},
+		{"Copenhagen", 55.676, 12.568, "DK"},
+		{"Flensburg", 54.782, 9.436, "DE"},
+		{"Barcelona", 41.385, 2.173, "ES"},
+		{"Perpignan", 42.699, 2.895, "FR"},
+		{"Lisbon", 38.722, -9.139, "PT"},
+		{"Badajoz", 38.879, -6.970, "ES"},
+		{"Palermo", 38.116, 13.361, "IT"},
+		{"London", 51.507, -0.128, ""},
+		{"Adriatic Sea", 43.000, 15.500, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.place, func(t *testing.T) {
+			country, ok := CountryForPoint(tt.lat, tt.lng)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("CountryForPoint(%v, %v) = %s, want no country", tt.lat, tt.lng, country.Code)
+				}
+				return
+			}
+			if !ok || country.Code != tt.want {
+				t.Errorf("CountryForPoint(%v, %v) = %s, %v, want %s", tt.lat, tt.lng, country.Code, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountryOutlinesAreClosed(t *testing.T) {
+	end := func(name string, first bool) [2]float64 {
+		line := borderLines[strings.TrimPrefix(name, "-")]
+		if strings.HasPrefix(name, "-") == first {
+			return line[len(line)-1]
+		}
+		return line[0]
+	}
+
+	for _, country := range countries {
+		rings := countryOutlines[country.Code]
+		if len(rings) == 0 {
+			t.Errorf("%s has no outline", country.Code)
+		}
+		for _, pieces := range rings {
+			for i, name := range pieces {
+				if _, ok := borderLines[strings.TrimPrefix(name, "-")]; !ok {
+					t.Fatalf("%s uses unknown border %q", country.Code, name)
+				}
+				next := pieces[(i+1)%len(pieces)]
+				if len(pieces) > 1 && end(name, false) != end(next, true) {
+					t.Errorf("%s: %q ends at %v but %q starts at %v", country.Code, name, end(name, false), next, end(next, true))
+				}
+			}
+		}
+	}
+}
+
+func TestCountrySharesForPointsAlongBorder(t *testing.T) {
+	// Munich to Passau stays in Germany although it runs close to Austria
+	points := []models.LatLng{
+		{Latitude: 48.137, Longitude: 11.575},
+		{Latitude: 48.40, Longitude: 12.30},
+		{Latitude: 48.57, Longitude: 13.00},
+		{Latitude: 48.574, Longitude: 13.466},
+	}
+	shares := CountrySharesForPoints(points, 150)
+	if len(shares) != 1 || shares[0].Code != "DE" {
+		t.Errorf("CountrySharesForPoints() = %+v, want only DE", shares)
+	}
+}
@@ -0,0 +1,124 @@
+// File: /services/geohash.go
+package services
+
+import (
+	"math"
+	"strings"
+)
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// EncodeGeohash returns the geohash of a coordinate. Points in the same cell
+// share a prefix, so prefix matches on an indexed column find nearby rows.
+func EncodeGeohash(lat, lng float64, precision int) string {
+	minLat, maxLat := -90.0, 90.0
+	minLng, maxLng := -180.0, 180.0
+
+	var hash strings.Builder
+	bit, ch := 0, 0
+	even := true
+	for hash.Len() < precision {
+		if even {
+			mid := (minLng + maxLng) / 2
+			if lng >= mid {
+				ch |= 1 << (4 - bit)
+				minLng = mid
+			} else {
+				maxLng = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if lat >= mid {
+				ch |= 1 << (4 - bit)
+				minLat = mid
+			} else {
+				maxLat = mid
+			}
+		}
+		even = !even
+
+		if bit < 4 {
+			bit++
+		} else {
+			hash.WriteByte(geohashAlphabet[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return hash.String()
+}
+
+// GeohashBounds returns the box covered by a geohash
+func GeohashBounds(hash string) (minLat, maxLat, minLng, maxLng float64) {
+	minLat, maxLat = -90.0, 90.0
+	minLng, maxLng = -180.0, 180.0
+
+	even := true
+	for i := 0; i < len(hash); i++ {
+		ch := strings.IndexByte(geohashAlphabet, hash[i])
+		for bit := 4; bit >= 0; bit-- {
+			set := ch>>bit&1 == 1
+			if even {
+				mid := (minLng + maxLng) / 2
+				if set {
+					minLng = mid
+				} else {
+					maxLng = mid
+				}
+			} else {
+				mid := (minLat + maxLat) / 2
+				if set {
+					minLat = mid
+				} else {
+					maxLat = mid
+				}
+			}
+			even = !even
+		}
+	}
+	return minLat, maxLat, minLng, maxLng
+}
+
+// geohashCellSize returns the height and width in degrees of a cell at a precision
+func geohashCellSize(precision int) (latSize, lngSize float64) {
+	bits := 5 * precision
+	lngBits := (bits + 1) / 2
+	latBits := bits / 2
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lngBits))
+}
+
+// CoveringGeohashes returns the geohashes of the finest precision, up to
+// maxPrecision, that cover a bounding box in at most maxCells cells
+func CoveringGeohashes(minLat, maxLat, minLng, maxLng float64, maxPrecision, maxCells int) []string {
+	minLat, maxLat = math.Max(minLat, -90), math.Min(maxLat, 90)
+	minLng, maxLng = math.Max(minLng, -180), math.Min(maxLng, 180)
+
+	for precision := maxPrecision; precision > 1; precision-- {
+		if cells := geohashGrid(minLat, maxLat, minLng, maxLng, precision, maxCells); cells != nil {
+			return cells
+		}
+	}
+	return geohashGrid(minLat, maxLat, minLng, maxLng, 1, len(geohashAlphabet))
+}
+
+// geohashGrid lists the cells of one precision overlapping a box, or nil when there are more than maxCells
+func geohashGrid(minLat, maxLat, minLng, maxLng float64, precision, maxCells int) []string {
+	latSize, lngSize := geohashCellSize(precision)
+	rowCount := int(math.Ceil(180 / latSize))
+	colCount := int(math.Ceil(360 / lngSize))
+
+	firstRow, lastRow := int((minLat+90)/latSize), min(int((maxLat+90)/latSize), rowCount-1)
+	firstCol, lastCol := int((minLng+180)/lngSize), min(int((maxLng+180)/lngSize), colCount-1)
+	if (lastRow-firstRow+1)*(lastCol-firstCol+1) > maxCells {
+		return nil
+	}
+
+	cells := make([]string, 0, (lastRow-firstRow+1)*(lastCol-firstCol+1))
+	for row := firstRow; row <= lastRow; row++ {
+		for col := firstCol; col <= lastCol; col++ {
+			lat := -90 + (float64(row)+0.5)*latSize
+			lng := -180 + (float64(col)+0.5)*lngSize
+			cells = append(cells, EncodeGeohash(lat, lng, precision))
+		}
+	}
+	return cells
+}
@@ -0,0 +1,320 @@
+// File: /services/hazard_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+)
+
+var (
+	ErrHazardNotFound    = errors.New("hazard not found")
+	ErrInvalidHazard     = errors.New("invalid hazard report")
+	ErrOwnHazard         = errors.New("you cannot vote on your own report")
+	ErrNotHazardReporter = errors.New("only the reporter can remove a hazard")
+)
+
+const (
+	// hazardMergeRadiusKm merges a report into an active one of the same type this close
+	hazardMergeRadiusKm = 0.1
+	// hazardAlertRadiusKm is how close a hazard must be to warn a rider without a planned route
+	hazardAlertRadiusKm = 1.0
+	// hazardRouteCorridorKm is how far from the planned route a hazard may be
+	hazardRouteCorridorKm = 0.15
+	// hazardLookaheadKm is how far ahead on the planned route riders are warned
+	hazardLookaheadKm = 15.0
+	// hazardOffRouteKm is how far from the planned route a rider is considered off it
+	hazardOffRouteKm = 1.0
+)
+
+// HazardInput is a hazard reported by a rider
+type HazardInput struct {
+	Type        string
+	Description string
+	Latitude    float64
+	Longitude   float64
+}
+
+type HazardService struct {
+	db *gorm.DB
+}
+
+func NewHazardService(db *gorm.DB) *HazardService {
+	return &HazardService{db: db}
+}
+
+// IsValidHazardType checks a type against models.HazardLifetimes
+func IsValidHazardType(hazardType string) bool {
+	_, ok := models.HazardLifetimes[hazardType]
+	return ok
+}
+
+// Report stores a hazard. A report of the same type close to an active one
+// confirms that one instead; the returned bool reports whether a new hazard
+// was created.
+func (s *HazardService) Report(userID string, input HazardInput) (*models.HazardReport, bool, error) {
+	hazardType := strings.ToLower(strings.TrimSpace(input.Type))
+	if !IsValidHazardType(hazardType) {
+		return nil, false, fmt.Errorf("%w: unknown type %q", ErrInvalidHazard, input.Type)
+	}
+	if input.Latitude < -90 || input.Latitude > 90 || input.Longitude < -180 || input.Longitude > 180 ||
+		(input.Latitude == 0 && input.Longitude == 0) {
+		return nil, false, fmt.Errorf("%w: invalid coordinates", ErrInvalidHazard)
+	}
+
+	nearby, err := s.Nearby(input.Latitude, input.Longitude, hazardMergeRadiusKm, hazardType)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(nearby) > 0 {
+		existing := nearby[0].HazardReport
+		if existing.ReporterID == userID {
+			return &existing, false, nil
+		}
+		hazard, err := s.Confirm(userID, existing.ID, true)
+		return hazard, false, err
+	}
+
+	hazard := &models.HazardReport{
+		ID:          uuid.New().String(),
+		ReporterID:  userID,
+		Type:        hazardType,
+		Description: strings.TrimSpace(input.Description),
+		Latitude:    input.Latitude,
+		Longitude:   input.Longitude,
+		ExpiresAt:   time.Now().Add(models.HazardLifetimes[hazardType]),
+	}
+	if err := s.db.Create(hazard).Error; err != nil {
+		return nil, false, err
+	}
+	return hazard, true, nil
+}
+
+// Get returns a hazard, also when it expired but was not cleaned up yet
+func (s *HazardService) Get(hazardID string) (*models.HazardReport, error) {
+	var hazard models.HazardReport
+	if err := s.db.First(&hazard, "id = ?", hazardID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHazardNotFound
+		}
+		return nil, err
+	}
+	return &hazard, nil
+}
+
+// Nearby returns the active hazards within radiusKm of a point, nearest first
+func (s *HazardService) Nearby(lat, lng, radiusKm float64, types ...string) ([]models.NearbyHazard, error) {
+	minLat, maxLat, minLng, maxLng := BoundingBox(lat, lng, radiusKm)
+	hazards, err := s.activeInBounds(minLat, maxLat, minLng, maxLng, types...)
+	if err != nil {
+		return nil, err
+	}
+
+	nearby := make([]models.NearbyHazard, 0, len(hazards))
+	for _, hazard := range hazards {
+		distance := HaversineKm(lat, lng, hazard.Latitude, hazard.Longitude)
+		if distance <= radiusKm {
+			nearby = append(nearby, models.NearbyHazard{HazardReport: hazard, Distance: distance})
+		}
+	}
+
+	sort.Slice(nearby, func(i, j int) bool { return nearby[i].Distance < nearby[j].Distance })
+	return nearby, nil
+}
+
+// Ahead returns the active hazards on a path within lookaheadKm after the
+// point at fromKm along it, in the order they will be reached
+func (s *HazardService) Ahead(points []models.LatLng, fromKm, lookaheadKm float64) ([]models.NearbyHazard, error) {
+	if len(points) < 2 {
+		return []models.NearbyHazard{}, nil
+	}
+
+	minLat, maxLat, minLng, maxLng := PathBounds(points)
+	minLat, _, minLng, _ = BoundingBox(minLat, minLng, hazardRouteCorridorKm)
+	_, maxLat, _, maxLng = BoundingBox(maxLat, maxLng, hazardRouteCorridorKm)
+	hazards, err := s.activeInBounds(minLat, maxLat, minLng, maxLng)
+	if err != nil {
+		return nil, err
+	}
+
+	cumulative := CumulativeDistancesKm(points)
+	start := PointAlongPath(points, cumulative, fromKm)
+	ahead := make([]models.NearbyHazard, 0)
+	for _, hazard := range hazards {
+		along, offset := ProjectOntoPath(points, cumulative, hazard.Latitude, hazard.Longitude)
+		if offset > hazardRouteCorridorKm || along < fromKm || along > fromKm+lookaheadKm {
+			continue
+		}
+		distanceAhead := along - fromKm
+		ahead = append(ahead, models.NearbyHazard{
+			HazardReport:  hazard,
+			Distance:      HaversineKm(start.Latitude, start.Longitude, hazard.Latitude, hazard.Longitude),
+			DistanceAhead: &distanceAhead,
+		})
+	}
+
+	sort.Slice(ahead, func(i, j int) bool { return *ahead[i].DistanceAhead < *ahead[j].DistanceAhead })
+	return ahead, nil
+}
+
+// Confirm records whether a rider saw the hazard still there. Confirmations
+// keep it active for another lifetime of its type; enough denials dispute it.
+func (s *HazardService) Confirm(userID, hazardID string, stillThere bool) (*models.HazardReport, error) {
+	var hazard *models.HazardReport
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var current models.HazardReport
+		if err := tx.First(&current, "id = ?", hazardID).Error; err != nil || !current.IsActive() {
+			return ErrHazardNotFound
+		}
+		if current.ReporterID == userID {
+			return ErrOwnHazard
+		}
+
+		var vote models.HazardConfirmation
+		err := tx.Where("hazard_id = ? AND user_id = ?", hazardID, userID).First(&vote).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			vote = models.HazardConfirmation{HazardID: hazardID, UserID: userID, StillThere: stillThere}
+			err = tx.Create(&vote).Error
+		case err == nil && vote.StillThere != stillThere:
+			err = tx.Model(&vote).Update("still_there", stillThere).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		var up, down int64
+		tx.Model(&models.HazardConfirmation{}).Where("hazard_id = ? AND still_there = ?", hazardID, true).Count(&up)
+		tx.Model(&models.HazardConfirmation{}).Where("hazard_id = ? AND still_there = ?", hazardID, false).Count(&down)
+		current.UpVotes, current.DownVotes = int(up), int(down)
+
+		updates := map[string]interface{}{"up_votes": current.UpVotes, "down_votes": current.DownVotes}
+		if stillThere {
+			if expiresAt := time.Now().Add(models.HazardLifetimes[current.Type]); expiresAt.After(current.ExpiresAt) {
+				current.ExpiresAt = expiresAt
+				updates["expires_at"] = expiresAt
+			}
+		}
+		if err := tx.Model(&current).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		hazard = &current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hazard, nil
+}
+
+// Delete removes a hazard the user reported, e.g. one reported by mistake
+func (s *HazardService) Delete(userID, hazardID string) error {
+	hazard, err := s.Get(hazardID)
+	if err != nil {
+		return err
+	}
+	if hazard.ReporterID != userID {
+		return ErrNotHazardReporter
+	}
+	return s.deleteHazards([]string{hazardID})
+}
+
+// AlertsFor returns the hazards a rider should be warned about at a position
+// during their active ride: those ahead on the ride's planned route, or
+// nearby when the ride has no route or the rider left it. Every hazard is
+// returned once per ride; riders without an active ride get none.
+func (s *HazardService) AlertsFor(userID string, lat, lng float64) ([]models.NearbyHazard, error) {
+	var ride models.RideRecord
+	if err := s.db.Where("user_id = ? AND is_completed = ?", userID, false).Order("start_time DESC").First(&ride).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	candidates, err := s.rideHazards(&ride, lat, lng)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := make([]models.NearbyHazard, 0, len(candidates))
+	for _, hazard := range candidates {
+		if hazard.ReporterID == userID {
+			continue
+		}
+		var count int64
+		s.db.Model(&models.HazardAlert{}).Where("hazard_id = ? AND ride_id = ?", hazard.ID, ride.ID).Count(&count)
+		if count > 0 {
+			continue
+		}
+		if err := s.db.Create(&models.HazardAlert{HazardID: hazard.ID, RideID: ride.ID, UserID: userID}).Error; err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, hazard)
+	}
+	return alerts, nil
+}
+
+func (s *HazardService) rideHazards(ride *models.RideRecord, lat, lng float64) ([]models.NearbyHazard, error) {
+	if ride.RouteID != nil {
+		var route models.Route
+		if err := s.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+			return db.Order("`order` ASC")
+		}).First(&route, "id = ?", *ride.RouteID).Error; err == nil {
+			points := RoutePath(&route)
+			if len(points) >= 2 {
+				cumulative := CumulativeDistancesKm(points)
+				along, offset := ProjectOntoPath(points, cumulative, lat, lng)
+				if offset <= hazardOffRouteKm {
+					return s.Ahead(points, along, hazardLookaheadKm)
+				}
+			}
+		}
+	}
+	return s.Nearby(lat, lng, hazardAlertRadiusKm)
+}
+
+// CleanupExpired deletes expired and disputed hazards with their votes and alerts
+func (s *HazardService) CleanupExpired() (int64, error) {
+	var ids []string
+	if err := s.db.Model(&models.HazardReport{}).
+		Where("expires_at < ? OR (down_votes >= ? AND down_votes > up_votes)", time.Now(), models.HazardDisputeVotes).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return int64(len(ids)), s.deleteHazards(ids)
+}
+
+func (s *HazardService) deleteHazards(ids []string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("hazard_id IN ?", ids).Delete(&models.HazardConfirmation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("hazard_id IN ?", ids).Delete(&models.HazardAlert{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&models.HazardReport{}).Error
+	})
+}
+
+func (s *HazardService) activeInBounds(minLat, maxLat, minLng, maxLng float64, types ...string) ([]models.HazardReport, error) {
+	var hazards []models.HazardReport
+	query := s.db.Where("expires_at > ? AND latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?",
+		time.Now(), minLat, maxLat, minLng, maxLng).
+		Where("NOT (down_votes >= ? AND down_votes > up_votes)", models.HazardDisputeVotes)
+	if len(types) > 0 {
+		query = query.Where("type IN ?", types)
+	}
+	err := query.Find(&hazards).Error
+	return hazards, err
+}
@@ -0,0 +1,293 @@
+// File: /services/instruction_text.go
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"motocosmos-api/models"
+)
+
+// DefaultInstructionLanguage is used when no supported language is requested
+const DefaultInstructionLanguage = "en"
+
+// instructionPhrases are the texts of one language. Voice phrases are spoken
+// after the distance to the maneuver, so they start in lower case.
+type instructionPhrases struct {
+	depart     string            // direction
+	departOn   string            // direction, road
+	directions map[string]string // compass direction -> text
+	turns      map[string]string // modifier -> text
+	onto       string            // maneuver text, road
+
+	continueStraight string
+	continueOn       string // road
+
+	roundabout      string // exit number
+	roundaboutVoice string // ordinal of the exit
+	enterRoundabout string
+	ordinals        []string // first, second, ... for the spoken exit
+
+	arrive              string
+	arriveWaypoint      string // waypoint number
+	arriveVoice         string
+	arriveWaypointVoice string
+
+	inDistance   string // distance, voice phrase
+	meters       string // whole meters
+	kilometer    string // exactly one kilometer
+	kilometers   string // kilometers with one decimal at most
+	decimalComma bool
+}
+
+var instructionLanguages = map[string]instructionPhrases{
+	"en": {
+		depart:     "Head %s",
+		departOn:   "Head %s on %s",
+		directions: map[string]string{"north": "north", "northeast": "northeast", "east": "east", "southeast": "southeast", "south": "south", "southwest": "southwest", "west": "west", "northwest": "northwest"},
+		turns: map[string]string{
+			ModifierStraight:    "Go straight",
+			ModifierSlightLeft:  "Bear left",
+			ModifierSlightRight: "Bear right",
+			ModifierLeft:        "Turn left",
+			ModifierRight:       "Turn right",
+			ModifierSharpLeft:   "Make a sharp left",
+			ModifierSharpRight:  "Make a sharp right",
+			ModifierUTurn:       "Make a U-turn",
+		},
+		onto:                "%s onto %s",
+		continueStraight:    "Continue straight",
+		continueOn:          "Continue on %s",
+		roundabout:          "At the roundabout, take exit %d",
+		roundaboutVoice:     "at the roundabout, take the %s exit",
+		enterRoundabout:     "Enter the roundabout",
+		ordinals:            []string{"first", "second", "third", "fourth", "fifth", "sixth", "seventh", "eighth", "ninth", "tenth"},
+		arrive:              "Arrive at your destination",
+		arriveWaypoint:      "Arrive at waypoint %d",
+		arriveVoice:         "you will arrive at your destination",
+		arriveWaypointVoice: "you will reach your waypoint",
+		inDistance:          "In %s, %s",
+		meters:              "%d meters",
+		kilometer:           "1 kilometer",
+		kilometers:          "%s kilometers",
+	},
+	"de": {
+		depart:     "Richtung %s fahren",
+		departOn:   "Auf %[2]s Richtung %[1]s fahren",
+		directions: map[string]string{"north": "Norden", "northeast": "Nordosten", "east": "Osten", "southeast": "Südosten", "south": "Süden", "southwest": "Südwesten", "west": "Westen", "northwest": "Nordwesten"},
+		turns: map[string]string{
+			ModifierStraight:    "Geradeaus fahren",
+			ModifierSlightLeft:  "Leicht links halten",
+			ModifierSlightRight: "Leicht rechts halten",
+			ModifierLeft:        "Links abbiegen",
+			ModifierRight:       "Rechts abbiegen",
+			ModifierSharpLeft:   "Scharf links abbiegen",
+			ModifierSharpRight:  "Scharf rechts abbiegen",
+			ModifierUTurn:       "Wenden",
+		},
+		onto:                "%s auf %s",
+		continueStraight:    "Geradeaus weiterfahren",
+		continueOn:          "Weiter auf %s",
+		roundabout:          "Im Kreisverkehr die %d. Ausfahrt nehmen",
+		roundaboutVoice:     "im Kreisverkehr die %s Ausfahrt nehmen",
+		enterRoundabout:     "In den Kreisverkehr einfahren",
+		ordinals:            []string{"erste", "zweite", "dritte", "vierte", "fünfte", "sechste", "siebte", "achte", "neunte", "zehnte"},
+		arrive:              "Ziel erreicht",
+		arriveWaypoint:      "Wegpunkt %d erreicht",
+		arriveVoice:         "erreichen Sie Ihr Ziel",
+		arriveWaypointVoice: "erreichen Sie Ihren Zwischenstopp",
+		inDistance:          "In %s %s",
+		meters:              "%d Metern",
+		kilometer:           "einem Kilometer",
+		kilometers:          "%s Kilometern",
+		decimalComma:        true,
+	},
+	"hu": {
+		depart:     "Induljon %s irányba",
+		departOn:   "Induljon %s irányba, ezen: %s",
+		directions: map[string]string{"north": "északi", "northeast": "északkeleti", "east": "keleti", "southeast": "délkeleti", "south": "déli", "southwest": "délnyugati", "west": "nyugati", "northwest": "északnyugati"},
+		turns: map[string]string{
+			ModifierStraight:    "Haladjon egyenesen",
+			ModifierSlightLeft:  "Tartson enyhén balra",
+			ModifierSlightRight: "Tartson enyhén jobbra",
+			ModifierLeft:        "Forduljon balra",
+			ModifierRight:       "Forduljon jobbra",
+			ModifierSharpLeft:   "Forduljon élesen balra",
+			ModifierSharpRight:  "Forduljon élesen jobbra",
+			ModifierUTurn:       "Forduljon vissza",
+		},
+		onto:                "%s, erre: %s",
+		continueStraight:    "Haladjon tovább egyenesen",
+		continueOn:          "Haladjon tovább ezen: %s",
+		roundabout:          "A körforgalomból hajtson ki a(z) %d. kijáraton",
+		roundaboutVoice:     "a körforgalomból hajtson ki %s kijáraton",
+		enterRoundabout:     "Hajtson be a körforgalomba",
+		ordinals:            []string{"az első", "a második", "a harmadik", "a negyedik", "az ötödik", "a hatodik", "a hetedik", "a nyolcadik", "a kilencedik", "a tizedik"},
+		arrive:              "Megérkezett az úti céljához",
+		arriveWaypoint:      "Megérkezett a(z) %d. útponthoz",
+		arriveVoice:         "megérkezik az úti céljához",
+		arriveWaypointVoice: "eléri a köztes úti célt",
+		inDistance:          "%s múlva %s",
+		meters:              "%d méter",
+		kilometer:           "1 kilométer",
+		kilometers:          "%s kilométer",
+		decimalComma:        true,
+	},
+}
+
+// InstructionLanguages lists the supported instruction languages
+func InstructionLanguages() []string {
+	return []string{"en", "de", "hu"}
+}
+
+// InstructionLanguage matches a requested language, like "de" or "de-AT", to a
+// supported one
+func InstructionLanguage(requested string) (string, bool) {
+	lang := strings.ToLower(strings.TrimSpace(requested))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	_, ok := instructionLanguages[lang]
+	return lang, ok
+}
+
+// AcceptedInstructionLanguage picks the first supported language of an
+// Accept-Language header, or the default one
+func AcceptedInstructionLanguage(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		if lang, ok := InstructionLanguage(tag); ok {
+			return lang
+		}
+	}
+	return DefaultInstructionLanguage
+}
+
+// LocalizeInstructions returns a copy of the steps with the display and voice
+// texts in the given language. Each voice text announces the distance to its
+// maneuver, which is the length of the step before.
+func LocalizeInstructions(steps []models.RouteInstruction, lang string) []models.RouteInstruction {
+	phrases, ok := instructionLanguages[lang]
+	if !ok {
+		phrases = instructionLanguages[DefaultInstructionLanguage]
+	}
+
+	localized := make([]models.RouteInstruction, len(steps))
+	for i, step := range steps {
+		text, voice := phrases.describe(step)
+		if i > 0 && step.Maneuver != models.ManeuverDepart {
+			if distance := phrases.spokenDistance(steps[i-1].Distance); distance != "" {
+				voice = fmt.Sprintf(phrases.inDistance, distance, voice)
+			}
+		}
+
+		step.Instruction = text
+		step.Voice = upperFirst(voice)
+		localized[i] = step
+	}
+	return localized
+}
+
+// describe returns the display text and the spoken phrase of a step
+func (p instructionPhrases) describe(step models.RouteInstruction) (string, string) {
+	var text string
+	switch step.Maneuver {
+	case models.ManeuverDepart:
+		direction := p.directions[step.Direction]
+		switch {
+		case direction == "" && step.Road == "":
+			text = p.continueStraight
+		case direction == "":
+			text = fmt.Sprintf(p.continueOn, step.Road)
+		case step.Road == "":
+			text = fmt.Sprintf(p.depart, direction)
+		default:
+			text = fmt.Sprintf(p.departOn, direction, step.Road)
+		}
+		return text, text
+
+	case models.ManeuverArrive:
+		if step.Waypoint > 0 {
+			return fmt.Sprintf(p.arriveWaypoint, step.Waypoint), p.arriveWaypointVoice
+		}
+		return p.arrive, p.arriveVoice
+
+	case models.ManeuverRoundabout:
+		if step.Exit <= 0 {
+			return p.enterRoundabout, lowerFirst(p.enterRoundabout)
+		}
+		text = fmt.Sprintf(p.roundabout, step.Exit)
+		voice := text
+		if step.Exit <= len(p.ordinals) {
+			voice = fmt.Sprintf(p.roundaboutVoice, p.ordinals[step.Exit-1])
+		}
+		if step.Road != "" {
+			text = fmt.Sprintf(p.onto, text, step.Road)
+			voice = fmt.Sprintf(p.onto, voice, step.Road)
+		}
+		return text, lowerFirst(voice)
+
+	case models.ManeuverTurn:
+		if turn, ok := p.turns[step.Modifier]; ok {
+			text = turn
+			if step.Road != "" {
+				text = fmt.Sprintf(p.onto, turn, step.Road)
+			}
+			return text, lowerFirst(text)
+		}
+	}
+
+	// Continue, and maneuvers without a known modifier
+	text = p.continueStraight
+	if step.Road != "" {
+		text = fmt.Sprintf(p.continueOn, step.Road)
+	}
+	return text, lowerFirst(text)
+}
+
+// spokenDistance rounds a distance in meters the way it is announced, like
+// "300 meters" or "1.5 kilometers"; it is empty when there is nothing to announce
+func (p instructionPhrases) spokenDistance(meters float64) string {
+	switch {
+	case meters < 5:
+		return ""
+	case meters < 100:
+		return fmt.Sprintf(p.meters, int(math.Max(10, math.Round(meters/10)*10)))
+	case meters < 950:
+		return fmt.Sprintf(p.meters, int(math.Round(meters/50)*50))
+	}
+
+	km := math.Round(meters/100) / 10
+	if km >= 10 {
+		km = math.Round(km)
+	}
+	if km == 1 {
+		return p.kilometer
+	}
+
+	value := strconv.FormatFloat(km, 'f', -1, 64)
+	if p.decimalComma {
+		value = strings.Replace(value, ".", ",", 1)
+	}
+	return fmt.Sprintf(p.kilometers, value)
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
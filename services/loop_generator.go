@@ -0,0 +1,299 @@
+// File: /services/loop_generator.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"motocosmos-api/models"
+)
+
+const (
+	MinLoopDistanceKm = 10.0
+	MaxLoopDistanceKm = 1000.0
+
+	defaultLoopCandidates = 3
+	maxLoopCandidates     = 6
+
+	// loopInitialDetour is the road distance expected per straight-line km
+	loopInitialDetour = 1.3
+	// loopRefinements is how often a loop is resized when the roads make it too long or short
+	loopRefinements = 2
+	// loopTolerance is the share of the target distance a loop may miss without resizing
+	loopTolerance = 0.05
+	// loopSpreadDegrees separates the candidates around a preferred direction
+	loopSpreadDegrees = 35.0
+	// loopOverlapCellPrecision is the geohash cell size (~150 m) used to find roads ridden twice
+	loopOverlapCellPrecision = 7
+)
+
+var ErrInvalidLoopRequest = errors.New("invalid loop request")
+
+var compassBearings = map[string]float64{
+	"n": 0, "north": 0,
+	"ne": 45, "northeast": 45,
+	"e": 90, "east": 90,
+	"se": 135, "southeast": 135,
+	"s": 180, "south": 180,
+	"sw": 225, "southwest": 225,
+	"w": 270, "west": 270,
+	"nw": 315, "northwest": 315,
+}
+
+var compassNames = []string{"North", "Northeast", "East", "Southeast", "South", "Southwest", "West", "Northwest"}
+
+// LoopRequest asks for round trips of about DistanceKm from Start
+type LoopRequest struct {
+	Start         models.LatLng
+	DistanceKm    float64
+	Bearing       *float64 // preferred direction in degrees, nil for any
+	PreferWinding bool
+	AvoidHighways bool
+	Profile       string
+	Candidates    int
+}
+
+// ParseLoopDirection reads a compass direction (n, ne, ... or north, northeast, ...)
+// or a bearing in degrees; an empty value or "any" means no preference
+func ParseLoopDirection(value string) (*float64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" || value == "any" {
+		return nil, nil
+	}
+	if bearing, ok := compassBearings[value]; ok {
+		return &bearing, nil
+	}
+	bearing, err := strconv.ParseFloat(value, 64)
+	if err != nil || bearing < 0 || bearing > 360 {
+		return nil, fmt.Errorf("%w: direction must be a compass direction or a bearing of 0-360 degrees", ErrInvalidLoopRequest)
+	}
+	return &bearing, nil
+}
+
+// LoopGenerator builds round trips with a routing engine. Each candidate
+// heads out in its own direction through via points placed on a circle that
+// passes through the start; the circle is resized until the routed loop is
+// close to the target distance.
+type LoopGenerator struct {
+	engine RoutingEngine
+}
+
+func NewLoopGenerator(engine RoutingEngine) *LoopGenerator {
+	return &LoopGenerator{engine: engine}
+}
+
+// Generate returns the candidate loops that could be routed, best first
+func (g *LoopGenerator) Generate(ctx context.Context, req LoopRequest) ([]models.LoopCandidate, error) {
+	if !isValidLatitude(req.Start.Latitude) || !isValidLongitude(req.Start.Longitude) {
+		return nil, fmt.Errorf("%w: invalid start coordinates", ErrInvalidLoopRequest)
+	}
+	if req.DistanceKm < MinLoopDistanceKm || req.DistanceKm > MaxLoopDistanceKm {
+		return nil, fmt.Errorf("%w: distance must be between %.0f and %.0f km", ErrInvalidLoopRequest, MinLoopDistanceKm, MaxLoopDistanceKm)
+	}
+
+	count := req.Candidates
+	if count <= 0 {
+		count = defaultLoopCandidates
+	}
+	count = min(count, maxLoopCandidates)
+
+	bearings := loopBearings(req.Bearing, count)
+	candidates := make([]*models.LoopCandidate, len(bearings))
+	errs := make([]error, len(bearings))
+
+	var wg sync.WaitGroup
+	for i, bearing := range bearings {
+		wg.Add(1)
+		go func(i int, bearing float64) {
+			defer wg.Done()
+			// Alternate the riding direction so neighbouring candidates differ more
+			candidates[i], errs[i] = g.planLoop(ctx, req, bearing, i%2 == 0)
+		}(i, bearing)
+	}
+	wg.Wait()
+
+	var loops []models.LoopCandidate
+	for _, candidate := range candidates {
+		if candidate != nil {
+			loops = append(loops, *candidate)
+		}
+	}
+	if len(loops) == 0 {
+		for _, err := range errs {
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nil, ErrNoRouteFound
+	}
+
+	sort.SliceStable(loops, func(i, j int) bool { return loops[i].Score > loops[j].Score })
+	return loops, nil
+}
+
+// loopBearings spreads the candidates around the preferred direction, or evenly without one
+func loopBearings(preferred *float64, count int) []float64 {
+	bearings := make([]float64, count)
+	for i := range bearings {
+		if preferred == nil {
+			bearings[i] = float64(i) * 360 / float64(count)
+			continue
+		}
+		// 0, +spread, -spread, +2*spread, ...
+		step := float64((i + 1) / 2)
+		if i%2 == 0 {
+			step = -step
+		}
+		bearings[i] = math.Mod(*preferred+step*loopSpreadDegrees+360, 360)
+	}
+	return bearings
+}
+
+// planLoop routes one candidate, resizing it up to loopRefinements times
+func (g *LoopGenerator) planLoop(ctx context.Context, req LoopRequest, bearing float64, clockwise bool) (*models.LoopCandidate, error) {
+	detour := loopInitialDetour
+	var best *models.RoutePlanResponse
+	var bestWaypoints []models.LatLng
+	bestDeviation := math.Inf(1)
+
+	for attempt := 0; attempt <= loopRefinements; attempt++ {
+		perimeter := req.DistanceKm / detour
+		waypoints := append([]models.LatLng{req.Start}, loopViaPoints(req.Start, bearing, perimeter, clockwise)...)
+		waypoints = append(waypoints, req.Start)
+
+		plan, err := g.engine.Route(ctx, models.RoutePlanRequest{
+			Waypoints:     waypoints,
+			AvoidHighways: req.AvoidHighways,
+			PreferWinding: req.PreferWinding,
+			Profile:       req.Profile,
+		})
+		if err != nil {
+			if best != nil {
+				break
+			}
+			return nil, err
+		}
+
+		distanceKm := plan.Distance / 1000
+		deviation := math.Abs(distanceKm-req.DistanceKm) / req.DistanceKm
+		if deviation < bestDeviation {
+			best, bestWaypoints, bestDeviation = plan, waypoints, deviation
+		}
+		if deviation <= loopTolerance || distanceKm == 0 {
+			break
+		}
+		// The via points are joined by chords, so even straight lines come out a little short
+		detour = math.Max(0.8, math.Min(3, distanceKm/perimeter))
+	}
+
+	return loopCandidate(req, best, bestWaypoints, bearing, bestDeviation), nil
+}
+
+// loopViaPoints places three via points on a circle of the given perimeter
+// that passes through the start, with its centre in the bearing's direction
+func loopViaPoints(start models.LatLng, bearing, perimeterKm float64, clockwise bool) []models.LatLng {
+	radius := perimeterKm / (2 * math.Pi)
+	center := DestinationPoint(start, bearing, radius)
+
+	// Seen from the centre the start lies in the opposite direction
+	startAngle := bearing + 180
+	turn := 90.0
+	if !clockwise {
+		turn = -90
+	}
+
+	vias := make([]models.LatLng, 3)
+	for k := range vias {
+		vias[k] = DestinationPoint(center, math.Mod(startAngle+float64(k+1)*turn+720, 360), radius)
+	}
+	return vias
+}
+
+func loopCandidate(req LoopRequest, plan *models.RoutePlanResponse, waypoints []models.LatLng, bearing, deviation float64) *models.LoopCandidate {
+	curvature := AnalyzeCurvature(plan.Geometry)
+	overlap := pathOverlap(plan.Geometry)
+	distanceKm := plan.Distance / 1000
+
+	// Hitting the distance matters most; a loop that rides the same roads out
+	// and back is barely a loop; curvy roads count when they were asked for
+	fit := 1 - math.Min(deviation/0.5, 1)
+	score := 60*fit + 40*(1-overlap)
+	if req.PreferWinding {
+		score = 45*fit + 30*(1-overlap) + 25*curvature.Score/100
+	}
+
+	stops := make([]models.LoopWaypoint, len(waypoints))
+	for i, wp := range waypoints {
+		name := fmt.Sprintf("Via %d", i)
+		switch i {
+		case 0:
+			name = "Start"
+		case len(waypoints) - 1:
+			name = "Finish"
+		}
+		stops[i] = models.LoopWaypoint{Name: name, Latitude: wp.Latitude, Longitude: wp.Longitude, Order: i + 1}
+	}
+
+	return &models.LoopCandidate{
+		Name:              fmt.Sprintf("%s loop, %.0f km", compassName(bearing), distanceKm),
+		Bearing:           roundToDecimal(bearing, 1),
+		Waypoints:         stops,
+		RouteGeometry:     plan.Geometry,
+		TotalDistance:     roundToDecimal(distanceKm, 1),
+		EstimatedTime:     int(plan.Duration),
+		DistanceDeviation: roundToDecimal(deviation, 3),
+		Overlap:           roundToDecimal(overlap, 3),
+		TwistinessScore:   curvature.Score,
+		Curvature:         &curvature,
+		Score:             roundToDecimal(score, 1),
+		Engine:            plan.Engine,
+	}
+}
+
+// pathOverlap returns the share of a path that runs through cells it already
+// passed, i.e. roads ridden twice
+func pathOverlap(points []models.LatLng) float64 {
+	visited := make(map[string]bool)
+	previous := ""
+	var steps, repeated int
+
+	visit := func(lat, lng float64) {
+		cell := EncodeGeohash(lat, lng, loopOverlapCellPrecision)
+		if cell == previous {
+			return
+		}
+		steps++
+		if visited[cell] {
+			repeated++
+		}
+		visited[cell] = true
+		previous = cell
+	}
+
+	for i, p := range points {
+		if i > 0 {
+			prev := points[i-1]
+			n := int(math.Ceil(HaversineKm(prev.Latitude, prev.Longitude, p.Latitude, p.Longitude) / 0.05))
+			for step := 1; step < n; step++ {
+				t := float64(step) / float64(n)
+				visit(prev.Latitude+t*(p.Latitude-prev.Latitude), prev.Longitude+t*(p.Longitude-prev.Longitude))
+			}
+		}
+		visit(p.Latitude, p.Longitude)
+	}
+
+	if steps == 0 {
+		return 0
+	}
+	return float64(repeated) / float64(steps)
+}
+
+func compassName(bearing float64) string {
+	return compassNames[int(math.Mod(bearing+22.5, 360)/45)%len(compassNames)]
+}
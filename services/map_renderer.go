@@ -0,0 +1,195 @@
+// File: /services/map_renderer.go
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // tiles may be JPEG
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"motocosmos-api/models"
+)
+
+const (
+	// mapTileSize is the edge of a slippy map tile in pixels
+	mapTileSize = 256
+	// mapMaxZoom is the most detailed zoom level a track is drawn at
+	mapMaxZoom = 16
+	// mapPadding keeps the track and its markers away from the image border
+	mapPadding = 24
+)
+
+var ErrTrackTooShort = errors.New("a track needs at least two points")
+
+var (
+	mapBackground   = color.RGBA{0xEC, 0xEE, 0xF0, 0xFF} // where no tile is stored
+	mapTrackOutline = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	mapTrackColor   = color.RGBA{0xF2, 0x6B, 0x1D, 0xFF}
+	mapStartColor   = color.RGBA{0x2E, 0x7D, 0x32, 0xFF}
+	mapFinishColor  = color.RGBA{0xC6, 0x28, 0x28, 0xFF}
+)
+
+// MapRenderer draws tracks over map tiles stored on disk in the slippy map
+// layout, {z}/{x}/{y}.png (or .jpg). Missing tiles are left blank, so it also
+// works without any tiles.
+type MapRenderer struct {
+	tilesPath string
+}
+
+func NewMapRenderer(tilesPath string) *MapRenderer {
+	return &MapRenderer{tilesPath: tilesPath}
+}
+
+// RenderTrack draws a track with start and finish markers as a PNG, zoomed to
+// fit the image
+func (r *MapRenderer) RenderTrack(points []models.LatLng, width, height int) ([]byte, error) {
+	if len(points) < 2 {
+		return nil, ErrTrackTooShort
+	}
+
+	zoom := fitZoom(points, width-2*mapPadding, height-2*mapPadding)
+	minX, minY, maxX, maxY := trackBounds(points, zoom)
+	originX := (minX+maxX)/2 - float64(width)/2
+	originY := (minY+maxY)/2 - float64(height)/2
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: mapBackground}, image.Point{}, draw.Src)
+	r.drawTiles(img, zoom, originX, originY)
+
+	pixels := make([]image.Point, 0, len(points))
+	for _, p := range points {
+		x, y := mercatorPixel(p, zoom)
+		pixel := image.Point{X: int(math.Round(x - originX)), Y: int(math.Round(y - originY))}
+		if len(pixels) == 0 || pixel != pixels[len(pixels)-1] {
+			pixels = append(pixels, pixel)
+		}
+	}
+
+	drawPolyline(img, pixels, 4.5, mapTrackOutline)
+	drawPolyline(img, pixels, 3, mapTrackColor)
+	finish := pixels[len(pixels)-1]
+	fillCircle(img, float64(finish.X), float64(finish.Y), 9, mapTrackOutline)
+	fillCircle(img, float64(finish.X), float64(finish.Y), 7, mapFinishColor)
+	start := pixels[0]
+	fillCircle(img, float64(start.X), float64(start.Y), 9, mapTrackOutline)
+	fillCircle(img, float64(start.X), float64(start.Y), 7, mapStartColor)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// drawTiles copies the stored tiles covering the image
+func (r *MapRenderer) drawTiles(img *image.RGBA, zoom int, originX, originY float64) {
+	tiles := 1 << zoom
+	bounds := img.Bounds()
+	firstX, lastX := int(math.Floor(originX/mapTileSize)), int(math.Floor((originX+float64(bounds.Dx())-1)/mapTileSize))
+	firstY, lastY := int(math.Floor(originY/mapTileSize)), int(math.Floor((originY+float64(bounds.Dy())-1)/mapTileSize))
+
+	for ty := max(firstY, 0); ty <= min(lastY, tiles-1); ty++ {
+		for tx := firstX; tx <= lastX; tx++ {
+			tile := r.loadTile(zoom, ((tx%tiles)+tiles)%tiles, ty)
+			if tile == nil {
+				continue
+			}
+			offset := image.Point{
+				X: tx*mapTileSize - int(math.Round(originX)),
+				Y: ty*mapTileSize - int(math.Round(originY)),
+			}
+			draw.Draw(img, tile.Bounds().Sub(tile.Bounds().Min).Add(offset), tile, tile.Bounds().Min, draw.Src)
+		}
+	}
+}
+
+// loadTile returns a stored tile, or nil when it is missing or unreadable
+func (r *MapRenderer) loadTile(zoom, x, y int) image.Image {
+	dir := filepath.Join(r.tilesPath, strconv.Itoa(zoom), strconv.Itoa(x))
+	for _, ext := range []string{".png", ".jpg", ".jpeg"} {
+		file, err := os.Open(filepath.Join(dir, strconv.Itoa(y)+ext))
+		if err != nil {
+			continue
+		}
+		tile, _, err := image.Decode(file)
+		file.Close()
+		if err == nil {
+			return tile
+		}
+	}
+	return nil
+}
+
+// mercatorPixel projects a point to Web Mercator pixels at a zoom level
+func mercatorPixel(p models.LatLng, zoom int) (float64, float64) {
+	size := mapTileSize * math.Exp2(float64(zoom))
+	lat := math.Max(-85.0511, math.Min(85.0511, p.Latitude)) * math.Pi / 180
+	x := (p.Longitude + 180) / 360 * size
+	y := (1 - math.Log(math.Tan(lat)+1/math.Cos(lat))/math.Pi) / 2 * size
+	return x, y
+}
+
+func trackBounds(points []models.LatLng, zoom int) (minX, minY, maxX, maxY float64) {
+	minX, minY = math.Inf(1), math.Inf(1)
+	maxX, maxY = math.Inf(-1), math.Inf(-1)
+	for _, p := range points {
+		x, y := mercatorPixel(p, zoom)
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+	return minX, minY, maxX, maxY
+}
+
+// fitZoom returns the most detailed zoom level the track fits in width x height at
+func fitZoom(points []models.LatLng, width, height int) int {
+	for zoom := mapMaxZoom; zoom > 0; zoom-- {
+		minX, minY, maxX, maxY := trackBounds(points, zoom)
+		if maxX-minX <= float64(width) && maxY-minY <= float64(height) {
+			return zoom
+		}
+	}
+	return 0
+}
+
+// drawPolyline strokes the segments between the pixels with round joins
+func drawPolyline(img *image.RGBA, pixels []image.Point, radius float64, c color.RGBA) {
+	for i := 1; i < len(pixels); i++ {
+		ax, ay := float64(pixels[i-1].X), float64(pixels[i-1].Y)
+		bx, by := float64(pixels[i].X), float64(pixels[i].Y)
+		steps := int(math.Ceil(math.Hypot(bx-ax, by-ay) / (radius / 2)))
+		for s := 0; s <= steps; s++ {
+			t := float64(s) / float64(max(steps, 1))
+			fillCircle(img, ax+t*(bx-ax), ay+t*(by-ay), radius, c)
+		}
+	}
+}
+
+// fillCircle paints a disc, blending its edge for smooth outlines
+func fillCircle(img *image.RGBA, cx, cy, radius float64, c color.RGBA) {
+	bounds := img.Bounds()
+	for y := max(int(cy-radius-1), bounds.Min.Y); y <= min(int(cy+radius+1), bounds.Max.Y-1); y++ {
+		for x := max(int(cx-radius-1), bounds.Min.X); x <= min(int(cx+radius+1), bounds.Max.X-1); x++ {
+			coverage := radius + 0.5 - math.Hypot(float64(x)-cx, float64(y)-cy)
+			if coverage <= 0 {
+				continue
+			}
+			if coverage >= 1 {
+				img.SetRGBA(x, y, c)
+				continue
+			}
+			under := img.RGBAAt(x, y)
+			blend := func(a, b uint8) uint8 {
+				return uint8(float64(a)*(1-coverage) + float64(b)*coverage)
+			}
+			img.SetRGBA(x, y, color.RGBA{blend(under.R, c.R), blend(under.G, c.G), blend(under.B, c.B), 0xFF})
+		}
+	}
+}
@@ -0,0 +1,427 @@
+// File: /services/mapbox_directions.go
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"motocosmos-api/config"
+	"motocosmos-api/models"
+)
+
+const (
+	// mapboxMaxWaypoints is the Directions API limit for the driving profile
+	mapboxMaxWaypoints = 25
+	// mapboxMaxMatrixPoints is the Matrix API limit for the driving profile
+	mapboxMaxMatrixPoints = 25
+	mapboxCacheSize       = 500
+	mapboxCacheTTL        = 6 * time.Hour
+	mapboxRetryDelay      = 250 * time.Millisecond
+	// mapboxWindingDetour is how much slower than the fastest alternative a
+	// curvier route may be when winding roads are preferred
+	mapboxWindingDetour = 1.3
+)
+
+var ErrMapboxRequestFailed = errors.New("mapbox directions request failed")
+
+// MapboxDirections plans routes with the Mapbox Directions API. Motorcycles
+// use the driving profile; AvoidHighways excludes motorways and PreferWinding
+// picks the curviest of the alternatives Mapbox offers. Successful responses
+// are cached in memory.
+type MapboxDirections struct {
+	client     *http.Client
+	baseURL    string
+	token      string
+	maxRetries int
+
+	cache *responseCache
+}
+
+func NewMapboxDirections(cfg *config.Config) (*MapboxDirections, error) {
+	if cfg.MapboxToken == "" || cfg.MapboxToken == "your-mapbox-token" {
+		return nil, errors.New("MAPBOX_TOKEN is required for the mapbox routing engine")
+	}
+
+	timeout := cfg.MapboxTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &MapboxDirections{
+		client:     &http.Client{Timeout: timeout},
+		baseURL:    strings.TrimRight(cfg.MapboxBaseURL, "/"),
+		token:      cfg.MapboxToken,
+		maxRetries: cfg.MapboxMaxRetries,
+		cache:      newResponseCache(mapboxCacheSize, mapboxCacheTTL),
+	}, nil
+}
+
+func (m *MapboxDirections) Name() string {
+	return RoutingEngineMapbox
+}
+
+// mapboxResponse is the subset of the Directions API response that is used
+type mapboxResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Routes  []struct {
+		Distance float64 `json:"distance"`
+		Duration float64 `json:"duration"`
+		Geometry struct {
+			Coordinates [][]float64 `json:"coordinates"` // [lng, lat]
+		} `json:"geometry"`
+		Legs []struct {
+			Summary string `json:"summary"`
+			Steps   []struct {
+				Distance float64 `json:"distance"`
+				Duration float64 `json:"duration"`
+				Name     string  `json:"name"`
+				Maneuver struct {
+					Type         string    `json:"type"`
+					Modifier     string    `json:"modifier"`
+					Exit         int       `json:"exit"`
+					BearingAfter *float64  `json:"bearing_after"`
+					Location     []float64 `json:"location"` // [lng, lat]
+				} `json:"maneuver"`
+			} `json:"steps"`
+		} `json:"legs"`
+	} `json:"routes"`
+}
+
+// Route requests the route from Mapbox, or returns a cached response for the same request
+func (m *MapboxDirections) Route(ctx context.Context, req models.RoutePlanRequest) (*models.RoutePlanResponse, error) {
+	if err := validateRoutePlanRequest(req); err != nil {
+		return nil, err
+	}
+	if len(req.Waypoints) > mapboxMaxWaypoints {
+		return nil, fmt.Errorf("mapbox supports at most %d waypoints", mapboxMaxWaypoints)
+	}
+
+	requestURL, cacheKey := m.requestURL(req)
+	if cached, ok := m.cache.get(cacheKey); ok {
+		return cached, nil
+	}
+
+	body, err := m.fetch(ctx, requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed mapboxResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("%w: invalid response: %v", ErrMapboxRequestFailed, err)
+	}
+	switch parsed.Code {
+	case "Ok":
+	case "NoRoute":
+		return nil, ErrNoRouteFound
+	case "NoSegment":
+		return nil, ErrNoRoadNearby
+	default:
+		return nil, fmt.Errorf("%w: %s %s", ErrMapboxRequestFailed, parsed.Code, parsed.Message)
+	}
+	if len(parsed.Routes) == 0 {
+		return nil, ErrNoRouteFound
+	}
+
+	chosen := 0
+	if req.PreferWinding {
+		chosen = windingAlternative(parsed)
+	}
+
+	response := mapboxPlanResponse(parsed, chosen, m.Name())
+	m.cache.put(cacheKey, response)
+	return response, nil
+}
+
+// DistanceMatrix requests the road distances from the Mapbox Matrix API. The
+// Matrix API cannot exclude motorways, so with AvoidHighways every pair is
+// routed with the Directions API instead.
+func (m *MapboxDirections) DistanceMatrix(ctx context.Context, req models.RoutePlanRequest) ([][]float64, error) {
+	if err := validateRoutePlanRequest(req); err != nil {
+		return nil, err
+	}
+	if req.AvoidHighways {
+		return pairwiseDistanceMatrix(ctx, m, req)
+	}
+	if len(req.Waypoints) > mapboxMaxMatrixPoints {
+		return nil, fmt.Errorf("mapbox supports at most %d points in a distance matrix", mapboxMaxMatrixPoints)
+	}
+
+	coordinates := make([]string, len(req.Waypoints))
+	for i, wp := range req.Waypoints {
+		coordinates[i] = fmt.Sprintf("%.6f,%.6f", wp.Longitude, wp.Latitude)
+	}
+	query := url.Values{}
+	query.Set("annotations", "distance")
+	query.Set("access_token", m.token)
+	requestURL := fmt.Sprintf("%s/directions-matrix/v1/mapbox/driving/%s?%s", m.baseURL, strings.Join(coordinates, ";"), query.Encode())
+
+	body, err := m.fetch(ctx, requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct {
+		Code      string       `json:"code"`
+		Message   string       `json:"message"`
+		Distances [][]*float64 `json:"distances"` // null when there is no route
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("%w: invalid response: %v", ErrMapboxRequestFailed, err)
+	}
+	switch parsed.Code {
+	case "Ok":
+	case "NoRoute":
+		return nil, ErrNoRouteFound
+	default:
+		return nil, fmt.Errorf("%w: %s %s", ErrMapboxRequestFailed, parsed.Code, parsed.Message)
+	}
+	if len(parsed.Distances) != len(req.Waypoints) {
+		return nil, fmt.Errorf("%w: matrix has %d rows for %d points", ErrMapboxRequestFailed, len(parsed.Distances), len(req.Waypoints))
+	}
+
+	matrix := newDistanceMatrix(len(req.Waypoints))
+	for i, row := range parsed.Distances {
+		for j := range matrix[i] {
+			if j < len(row) && row[j] != nil {
+				matrix[i][j] = *row[j]
+			} else {
+				matrix[i][j] = math.Inf(1)
+			}
+		}
+	}
+	return matrix, nil
+}
+
+// requestURL builds the Directions API URL; the cache key is the URL without the token
+func (m *MapboxDirections) requestURL(req models.RoutePlanRequest) (string, string) {
+	coordinates := make([]string, len(req.Waypoints))
+	for i, wp := range req.Waypoints {
+		coordinates[i] = fmt.Sprintf("%.6f,%.6f", wp.Longitude, wp.Latitude)
+	}
+
+	query := url.Values{}
+	query.Set("geometries", "geojson")
+	query.Set("overview", "full")
+	query.Set("steps", "true")
+	query.Set("alternatives", strconv.FormatBool(req.PreferWinding))
+	if req.AvoidHighways {
+		query.Set("exclude", "motorway")
+	}
+
+	// Motorcycles follow the car network; Mapbox has no dedicated profile
+	path := fmt.Sprintf("%s/directions/v5/mapbox/driving/%s", m.baseURL, strings.Join(coordinates, ";"))
+	key := path + "?" + query.Encode()
+
+	query.Set("access_token", m.token)
+	return path + "?" + query.Encode(), key
+}
+
+// fetch performs the request, retrying network errors, rate limits and
+// server errors with exponential backoff
+func (m *MapboxDirections) fetch(ctx context.Context, requestURL string) ([]byte, error) {
+	var lastErr error
+	for attempt := 0; attempt <= m.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(mapboxRetryDelay << (attempt - 1)):
+			}
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := m.client.Do(httpReq)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = fmt.Errorf("%w: %v", ErrMapboxRequestFailed, redactToken(err.Error(), m.token))
+			continue
+		}
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("%w: %v", ErrMapboxRequestFailed, err)
+			continue
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return body, nil
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			lastErr = fmt.Errorf("%w: status %d", ErrMapboxRequestFailed, resp.StatusCode)
+			continue
+		case resp.StatusCode == http.StatusUnprocessableEntity:
+			// NoRoute, NoSegment and invalid input are reported with a code in the body
+			return body, nil
+		default:
+			var message struct {
+				Message string `json:"message"`
+			}
+			json.Unmarshal(body, &message)
+			return nil, fmt.Errorf("%w: status %d %s", ErrMapboxRequestFailed, resp.StatusCode, message.Message)
+		}
+	}
+	return nil, lastErr
+}
+
+// windingAlternative returns the index of the curviest route that is at most
+// mapboxWindingDetour times slower than the fastest one
+func windingAlternative(parsed mapboxResponse) int {
+	fastest := parsed.Routes[0].Duration
+	for _, route := range parsed.Routes {
+		if route.Duration < fastest {
+			fastest = route.Duration
+		}
+	}
+
+	chosen, bestScore := 0, -1.0
+	for i, route := range parsed.Routes {
+		if route.Duration > fastest*mapboxWindingDetour {
+			continue
+		}
+		points := make([]models.LatLng, 0, len(route.Geometry.Coordinates))
+		for _, coordinate := range route.Geometry.Coordinates {
+			if len(coordinate) >= 2 {
+				points = append(points, models.LatLng{Latitude: coordinate[1], Longitude: coordinate[0]})
+			}
+		}
+		if score := AnalyzeCurvature(points).Score; score > bestScore {
+			chosen, bestScore = i, score
+		}
+	}
+	return chosen
+}
+
+func mapboxPlanResponse(parsed mapboxResponse, index int, engine string) *models.RoutePlanResponse {
+	route := parsed.Routes[index]
+	response := &models.RoutePlanResponse{
+		Geometry: make([]models.LatLng, 0, len(route.Geometry.Coordinates)),
+		Distance: route.Distance,
+		Duration: route.Duration,
+		Steps:    []models.RouteInstruction{},
+		Engine:   engine,
+	}
+
+	for _, coordinate := range route.Geometry.Coordinates {
+		if len(coordinate) >= 2 {
+			response.Geometry = append(response.Geometry, models.LatLng{Latitude: coordinate[1], Longitude: coordinate[0]})
+		}
+	}
+
+	var summaries []string
+	for legIndex, leg := range route.Legs {
+		if leg.Summary != "" && (len(summaries) == 0 || summaries[len(summaries)-1] != leg.Summary) {
+			summaries = append(summaries, leg.Summary)
+		}
+		for _, step := range leg.Steps {
+			instruction := models.RouteInstruction{
+				Distance: step.Distance,
+				Duration: step.Duration,
+				Maneuver: mapboxManeuver(step.Maneuver.Type, step.Maneuver.Modifier),
+				Modifier: step.Maneuver.Modifier,
+				Exit:     step.Maneuver.Exit,
+				Road:     step.Name,
+			}
+			if len(step.Maneuver.Location) >= 2 {
+				instruction.Location = &models.LatLng{Latitude: step.Maneuver.Location[1], Longitude: step.Maneuver.Location[0]}
+			}
+			switch instruction.Maneuver {
+			case models.ManeuverDepart:
+				if step.Maneuver.BearingAfter != nil {
+					instruction.Direction = compassDirection(*step.Maneuver.BearingAfter)
+				}
+			case models.ManeuverArrive:
+				instruction.Modifier = ""
+				if legIndex < len(route.Legs)-1 {
+					instruction.Waypoint = legIndex + 2
+				}
+			}
+			response.Steps = append(response.Steps, instruction)
+		}
+	}
+	response.Steps = LocalizeInstructions(response.Steps, DefaultInstructionLanguage)
+
+	response.Summary = strings.Join(summaries, "; ")
+	if response.Summary == "" {
+		response.Summary = "Planned route"
+	}
+	return response
+}
+
+// redactToken removes the access token from error messages that include the request URL
+func redactToken(message, token string) string {
+	return strings.ReplaceAll(message, "access_token="+url.QueryEscape(token), "access_token=***")
+}
+
+// responseCache keeps route responses in memory for a limited time
+type responseCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	response *models.RoutePlanResponse
+	expires  time.Time
+}
+
+func newResponseCache(size int, ttl time.Duration) *responseCache {
+	return &responseCache{size: size, ttl: ttl, entries: make(map[string]cacheEntry)}
+}
+
+func (c *responseCache) get(key string) (*models.RoutePlanResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.response, true
+}
+
+func (c *responseCache) put(key string, response *models.RoutePlanResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= c.size {
+		// Drop expired entries, or the one closest to expiry when the cache is full
+		var oldestKey string
+		var oldest time.Time
+		now := time.Now()
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+				continue
+			}
+			if oldestKey == "" || entry.expires.Before(oldest) {
+				oldestKey, oldest = k, entry.expires
+			}
+		}
+		if len(c.entries) >= c.size {
+			delete(c.entries, oldestKey)
+		}
+	}
+
+	c.entries[key] = cacheEntry{response: response, expires: time.Now().Add(c.ttl)}
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"motocosmos-api/config"
+	"motocosmos-api/models"
+)
+
+var (
+	testVienna     = models.LatLng{Latitude: 48.208, Longitude: 16.373}
+	testBratislava = models.LatLng{Latitude: 48.148, Longitude: 17.107}
+	testGyor       = models.LatLng{Latitude: 47.687, Longitude: 17.650}
+)
+
+// newTestMapbox returns a mapbox engine talking to handler
+func newTestMapbox(t *testing.T, handler http.Handler, retries int, timeout time.Duration) *MapboxDirections {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	mapbox, err := NewMapboxDirections(&config.Config{
+		MapboxToken:      "test-token",
+		MapboxBaseURL:    server.URL,
+		MapboxTimeout:    timeout,
+		MapboxMaxRetries: retries,
+	})
+	if err != nil {
+		t.Fatalf("NewMapboxDirections() error = %v", err)
+	}
+	return mapbox
+}
+
+func TestMapboxRouteRequestParameters(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     models.RoutePlanRequest
+		path    string
+		exclude string
+		alts    string
+	}{
+		{
+			name: "defaults",
+			req:  models.RoutePlanRequest{Waypoints: []models.LatLng{testVienna, testBratislava}},
+			path: "/directions/v5/mapbox/driving/16.373000,48.208000;17.107000,48.148000",
+			alts: "false",
+		},
+		{
+			name:    "motorcycle avoiding highways",
+			req:     models.RoutePlanRequest{Waypoints: []models.LatLng{testVienna, testBratislava}, Profile: "motorcycle", AvoidHighways: true},
+			path:    "/directions/v5/mapbox/driving/16.373000,48.208000;17.107000,48.148000",
+			exclude: "motorway",
+			alts:    "false",
+		},
+		{
+			name: "winding via a waypoint",
+			req:  models.RoutePlanRequest{Waypoints: []models.LatLng{testVienna, testBratislava, testGyor}, PreferWinding: true},
+			path: "/directions/v5/mapbox/driving/16.373000,48.208000;17.107000,48.148000;17.650000,47.687000",
+			alts: "true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeMapbox{token: "test-token"}
+			mapbox := newTestMapbox(t, fake, 0, time.Second)
+
+			if _, err := mapbox.Route(context.Background(), tt.req); err != nil {
+				t.Fatalf("Route() error = %v", err)
+			}
+			got := fake.LastRequest()
+			if got.Path != tt.path {
+				t.Errorf("path = %s, want %s", got.Path, tt.path)
+			}
+			query := got.Query()
+			want := map[string]string{
+				"geometries":   "geojson",
+				"overview":     "full",
+				"steps":        "true",
+				"alternatives": tt.alts,
+				"exclude":      tt.exclude,
+				"access_token": "test-token",
+			}
+			for key, value := range want {
+				if query.Get(key) != value {
+					t.Errorf("%s = %q, want %q", key, query.Get(key), value)
+				}
+			}
+		})
+	}
+}
+
+func TestMapboxRouteRejectsUnsupportedProfile(t *testing.T) {
+	fake := &fakeMapbox{}
+	mapbox := newTestMapbox(t, fake, 0, time.Second)
+
+	_, err := mapbox.Route(context.Background(), models.RoutePlanRequest{
+		Waypoints: []models.LatLng{testVienna, testBratislava},
+		Profile:   "cycling",
+	})
+	if !errors.Is(err, ErrUnsupportedProfile) {
+		t.Errorf("Route() error = %v, want %v", err, ErrUnsupportedProfile)
+	}
+	if fake.Requests() != 0 {
+		t.Errorf("sent %d requests, want none", fake.Requests())
+	}
+}
+
+func TestMapboxRouteParsesSteps(t *testing.T) {
+	const body = `{
+		"code": "Ok",
+		"routes": [{
+			"distance": 1500, "duration": 120,
+			"geometry": {"type": "LineString", "coordinates": [[16.37, 48.20], [16.38, 48.21], [16.39, 48.22]]},
+			"legs": [
+				{"summary": "Ringstraße", "steps": [
+					{"distance": 400, "duration": 30, "name": "Opernring", "maneuver": {"type": "depart", "bearing_after": 88, "location": [16.37, 48.20]}},
+					{"distance": 300, "duration": 25, "name": "Ringstraße", "maneuver": {"type": "turn", "modifier": "right", "location": [16.375, 48.205]}},
+					{"distance": 0, "duration": 0, "name": "", "maneuver": {"type": "arrive", "modifier": "left", "location": [16.38, 48.21]}}
+				]},
+				{"summary": "B1", "steps": [
+					{"distance": 500, "duration": 40, "name": "B1", "maneuver": {"type": "depart", "bearing_after": 180, "location": [16.38, 48.21]}},
+					{"distance": 300, "duration": 25, "name": "B1", "maneuver": {"type": "roundabout", "modifier": "right", "exit": 2, "location": [16.385, 48.215]}},
+					{"distance": 0, "duration": 0, "name": "", "maneuver": {"type": "arrive", "location": [16.39, 48.22]}}
+				]}
+			]
+		}]
+	}`
+	mapbox := newTestMapbox(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}), 0, time.Second)
+
+	response, err := mapbox.Route(context.Background(), models.RoutePlanRequest{
+		Waypoints: []models.LatLng{testVienna, testBratislava, testGyor},
+	})
+	if err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+
+	if response.Distance != 1500 || response.Duration != 120 || len(response.Geometry) != 3 {
+		t.Errorf("route = %v m, %v s, %d points, want 1500 m, 120 s, 3 points", response.Distance, response.Duration, len(response.Geometry))
+	}
+	if response.Geometry[0] != (models.LatLng{Latitude: 48.20, Longitude: 16.37}) {
+		t.Errorf("first point = %+v, want lat 48.20 lng 16.37", response.Geometry[0])
+	}
+	if response.Summary != "Ringstraße; B1" {
+		t.Errorf("summary = %q, want %q", response.Summary, "Ringstraße; B1")
+	}
+
+	want := []models.RouteInstruction{
+		{Maneuver: models.ManeuverDepart, Direction: "east", Road: "Opernring", Instruction: "Head east on Opernring"},
+		{Maneuver: models.ManeuverTurn, Modifier: ModifierRight, Road: "Ringstraße", Instruction: "Turn right onto Ringstraße"},
+		{Maneuver: models.ManeuverArrive, Waypoint: 2, Instruction: "Arrive at waypoint 2"},
+		{Maneuver: models.ManeuverDepart, Direction: "south", Road: "B1", Instruction: "Head south on B1"},
+		{Maneuver: models.ManeuverRoundabout, Modifier: ModifierRight, Exit: 2, Road: "B1", Instruction: "At the roundabout, take exit 2 onto B1"},
+		{Maneuver: models.ManeuverArrive, Instruction: "Arrive at your destination"},
+	}
+	if len(response.Steps) != len(want) {
+		t.Fatalf("got %d steps, want %d", len(response.Steps), len(want))
+	}
+	for i, step := range response.Steps {
+		w := want[i]
+		if step.Maneuver != w.Maneuver || step.Modifier != w.Modifier || step.Direction != w.Direction ||
+			step.Road != w.Road || step.Exit != w.Exit || step.Waypoint != w.Waypoint || step.Instruction != w.Instruction {
+			t.Errorf("step %d = %+v, want %+v", i, step, w)
+		}
+		if step.Location == nil {
+			t.Errorf("step %d has no location", i)
+		}
+	}
+	if loc := response.Steps[1].Location; loc != nil && (loc.Latitude != 48.205 || loc.Longitude != 16.375) {
+		t.Errorf("turn location = %+v, want lat 48.205 lng 16.375", *loc)
+	}
+}
+
+func TestMapboxRouteRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failStatus   int
+		failFirst    int
+		wantRequests int
+		wantErr      bool
+	}{
+		{"rate limited", http.StatusTooManyRequests, 2, 3, false},
+		{"server error", http.StatusServiceUnavailable, 1, 2, false},
+		{"server error every time", http.StatusInternalServerError, 5, 3, true},
+		{"client error", http.StatusForbidden, 1, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeMapbox{failFirst: tt.failFirst, failStatus: tt.failStatus}
+			mapbox := newTestMapbox(t, fake, 2, time.Second)
+
+			_, err := mapbox.Route(context.Background(), models.RoutePlanRequest{Waypoints: []models.LatLng{testVienna, testBratislava}})
+			if tt.wantErr && !errors.Is(err, ErrMapboxRequestFailed) {
+				t.Errorf("Route() error = %v, want %v", err, ErrMapboxRequestFailed)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Route() error = %v", err)
+			}
+			if fake.Requests() != tt.wantRequests {
+				t.Errorf("sent %d requests, want %d", fake.Requests(), tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestMapboxRouteBacksOff(t *testing.T) {
+	fake := &fakeMapbox{failFirst: 2, failStatus: http.StatusTooManyRequests}
+	mapbox := newTestMapbox(t, fake, 2, time.Second)
+
+	start := time.Now()
+	if _, err := mapbox.Route(context.Background(), models.RoutePlanRequest{Waypoints: []models.LatLng{testVienna, testBratislava}}); err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+	// Waits mapboxRetryDelay, then twice as long
+	if elapsed := time.Since(start); elapsed < 3*mapboxRetryDelay {
+		t.Errorf("retried after %v, want at least %v", elapsed, 3*mapboxRetryDelay)
+	}
+}
+
+func TestMapboxRouteTimeout(t *testing.T) {
+	t.Run("client timeout", func(t *testing.T) {
+		fake := &fakeMapbox{delay: time.Second}
+		mapbox := newTestMapbox(t, fake, 0, 50*time.Millisecond)
+
+		start := time.Now()
+		_, err := mapbox.Route(context.Background(), models.RoutePlanRequest{Waypoints: []models.LatLng{testVienna, testBratislava}})
+		if !errors.Is(err, ErrMapboxRequestFailed) {
+			t.Errorf("Route() error = %v, want %v", err, ErrMapboxRequestFailed)
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("Route() took %v, want it to give up after the timeout", elapsed)
+		}
+	})
+
+	t.Run("canceled request", func(t *testing.T) {
+		fake := &fakeMapbox{delay: time.Second}
+		mapbox := newTestMapbox(t, fake, 2, 10*time.Second)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := mapbox.Route(ctx, models.RoutePlanRequest{Waypoints: []models.LatLng{testVienna, testBratislava}})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Route() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+		if fake.Requests() != 1 {
+			t.Errorf("sent %d requests, want 1", fake.Requests())
+		}
+	})
+}
+
+func TestMapboxRouteCache(t *testing.T) {
+	fake := &fakeMapbox{}
+	mapbox := newTestMapbox(t, fake, 0, time.Second)
+	req := models.RoutePlanRequest{Waypoints: []models.LatLng{testVienna, testBratislava}}
+
+	first, err := mapbox.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+	second, err := mapbox.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+	if fake.Requests() != 1 || first != second {
+		t.Errorf("sent %d requests for the same route, want 1 and the cached response", fake.Requests())
+	}
+
+	req.AvoidHighways = true
+	if _, err := mapbox.Route(context.Background(), req); err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+	if fake.Requests() != 2 {
+		t.Errorf("sent %d requests after changing the options, want 2", fake.Requests())
+	}
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"motocosmos-api/models"
+)
+
+// fakeMapbox is a stand-in for the Mapbox Directions and Matrix APIs, used
+// to exercise the mapbox routing engine without network access or a real
+// token. It answers with straight lines between the waypoints in the Mapbox
+// format and, when alternatives are requested, a slower zigzag over a
+// mountain pass.
+type fakeMapbox struct {
+	token      string        // accepted access token, any non-empty token when empty
+	failFirst  int           // answer this many requests with failStatus to exercise retries
+	failStatus int           // 503 when zero
+	delay      time.Duration // wait before answering to exercise timeouts
+
+	mu       sync.Mutex
+	requests []*url.URL
+}
+
+// Requests returns how many requests the server received
+func (f *fakeMapbox) Requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+// LastRequest returns the URL of the latest request
+func (f *fakeMapbox) LastRequest() *url.URL {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.requests) == 0 {
+		return nil
+	}
+	return f.requests[len(f.requests)-1]
+}
+
+func (f *fakeMapbox) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, r.URL)
+	failing := len(f.requests) <= f.failFirst
+	f.mu.Unlock()
+
+	if f.delay > 0 {
+		select {
+		case <-time.After(f.delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	writeJSON := func(status int, body interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}
+
+	token := r.URL.Query().Get("access_token")
+	if token == "" || (f.token != "" && token != f.token) {
+		writeJSON(http.StatusUnauthorized, map[string]string{"message": "Not Authorized - Invalid Token"})
+		return
+	}
+	if failing {
+		status := f.failStatus
+		if status == 0 {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(status, map[string]string{"message": http.StatusText(status)})
+		return
+	}
+
+	const prefix, matrixPrefix = "/directions/v5/mapbox/", "/directions-matrix/v1/mapbox/"
+	matrix := strings.HasPrefix(r.URL.Path, matrixPrefix)
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+	if matrix {
+		path = strings.TrimPrefix(r.URL.Path, matrixPrefix)
+	}
+	profile, coordinates, ok := strings.Cut(path, "/")
+	if (!matrix && !strings.HasPrefix(r.URL.Path, prefix)) || !ok {
+		writeJSON(http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+	if profile != "driving" && profile != "driving-traffic" {
+		writeJSON(http.StatusUnprocessableEntity, map[string]string{"code": "ProfileNotFound", "message": "Profile not found"})
+		return
+	}
+
+	var points []models.LatLng
+	for _, pair := range strings.Split(coordinates, ";") {
+		lngText, latText, _ := strings.Cut(pair, ",")
+		lng, errLng := strconv.ParseFloat(lngText, 64)
+		lat, errLat := strconv.ParseFloat(latText, 64)
+		if errLng != nil || errLat != nil {
+			writeJSON(http.StatusUnprocessableEntity, map[string]string{"code": "InvalidInput", "message": "Coordinate is invalid: " + pair})
+			return
+		}
+		points = append(points, models.LatLng{Latitude: lat, Longitude: lng})
+	}
+	if len(points) < 2 {
+		writeJSON(http.StatusUnprocessableEntity, map[string]string{"code": "InvalidInput", "message": "At least two coordinates are required"})
+		return
+	}
+
+	if matrix {
+		// Distances along the straight lines the fake routes follow
+		distances := make([][]float64, len(points))
+		for i, from := range points {
+			distances[i] = make([]float64, len(points))
+			for j, to := range points {
+				distances[i][j] = HaversineKm(from.Latitude, from.Longitude, to.Latitude, to.Longitude) * 1000
+			}
+		}
+		writeJSON(http.StatusOK, map[string]interface{}{"code": "Ok", "distances": distances})
+		return
+	}
+
+	speed, road := 90/3.6, "A1"
+	if r.URL.Query().Get("exclude") == "motorway" {
+		speed, road = 60/3.6, "Country road"
+	}
+	routes := []map[string]interface{}{fakeMapboxRoute(points, points, road, speed)}
+	if r.URL.Query().Get("alternatives") == "true" {
+		routes = append(routes, fakeMapboxRoute(points, fakeZigzag(points), "Pass road", speed))
+	}
+
+	writeJSON(http.StatusOK, map[string]interface{}{"code": "Ok", "routes": routes})
+}
+
+// fakeZigzag replaces each leg with 20 alternating bends, about 8% longer
+func fakeZigzag(points []models.LatLng) []models.LatLng {
+	const turns = 20
+	result := []models.LatLng{points[0]}
+	for i := 1; i < len(points); i++ {
+		from, to := points[i-1], points[i]
+		dLat, dLng := to.Latitude-from.Latitude, to.Longitude-from.Longitude
+		for t := 1; t < turns; t++ {
+			side := 0.01
+			if t%2 == 0 {
+				side = -side
+			}
+			f := float64(t) / turns
+			result = append(result, models.LatLng{
+				Latitude:  from.Latitude + dLat*f - dLng*side,
+				Longitude: from.Longitude + dLng*f + dLat*side,
+			})
+		}
+		result = append(result, to)
+	}
+	return result
+}
+
+// fakeMapboxRoute builds a route with one leg per waypoint pair along the geometry
+func fakeMapboxRoute(waypoints, geometry []models.LatLng, road string, speed float64) map[string]interface{} {
+	coordinates := make([][]float64, len(geometry))
+	for i, p := range geometry {
+		coordinates[i] = []float64{p.Longitude, p.Latitude}
+	}
+
+	// Legs share the geometry's detour over the straight line
+	detour := 1.0
+	if straight := PathLengthKm(waypoints); straight > 0 {
+		detour = PathLengthKm(geometry) / straight
+	}
+
+	var legs []map[string]interface{}
+	var total float64
+	for i := 1; i < len(waypoints); i++ {
+		distance := HaversineKm(waypoints[i-1].Latitude, waypoints[i-1].Longitude, waypoints[i].Latitude, waypoints[i].Longitude) * 1000 * detour
+		total += distance
+
+		arrival := "You have arrived at your destination"
+		if i < len(waypoints)-1 {
+			arrival = fmt.Sprintf("You have arrived at your %s destination", ordinal(i))
+		}
+		legs = append(legs, map[string]interface{}{
+			"summary":  road,
+			"distance": distance,
+			"duration": distance / speed,
+			"steps": []map[string]interface{}{
+				fakeMapboxStep("depart", "", "Drive along "+road, road, distance, distance/speed),
+				fakeMapboxStep("arrive", "", arrival, road, 0, 0),
+			},
+		})
+	}
+
+	return map[string]interface{}{
+		"distance": total,
+		"duration": total / speed,
+		"geometry": map[string]interface{}{"type": "LineString", "coordinates": coordinates},
+		"legs":     legs,
+	}
+}
+
+func fakeMapboxStep(kind, modifier, instruction, name string, distance, duration float64) map[string]interface{} {
+	return map[string]interface{}{
+		"distance": distance,
+		"duration": duration,
+		"name":     name,
+		"maneuver": map[string]interface{}{"type": kind, "modifier": modifier, "instruction": instruction},
+	}
+}
+
+func ordinal(n int) string {
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+		return fmt.Sprintf("%dth", n)
+	case n%10 == 1:
+		return fmt.Sprintf("%dst", n)
+	case n%10 == 2:
+		return fmt.Sprintf("%dnd", n)
+	case n%10 == 3:
+		return fmt.Sprintf("%drd", n)
+	default:
+		return fmt.Sprintf("%dth", n)
+	}
+}
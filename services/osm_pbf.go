@@ -0,0 +1,575 @@
+// File: /services/osm_pbf.go
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Minimal reader for the OpenStreetMap PBF format
+// (https://wiki.openstreetmap.org/wiki/PBF_Format). Only what the road graph
+// import needs is decoded: dense and plain nodes, and ways with their tags.
+
+const (
+	maxPBFHeaderSize = 64 * 1024
+	maxPBFBlobSize   = 32 * 1024 * 1024
+)
+
+var errUnsupportedPBFFeature = errors.New("unsupported PBF feature")
+
+// pbfNode is a node with its coordinates in degrees
+type pbfNode struct {
+	ID  int64
+	Lat float64
+	Lng float64
+}
+
+// pbfWay is a way with its tags and node references
+type pbfWay struct {
+	ID   int64
+	Tags map[string]string
+	Refs []int64
+}
+
+// pbfHandler receives decoded entities; nil callbacks skip decoding that entity type
+type pbfHandler struct {
+	Node func(pbfNode)
+	Way  func(pbfWay)
+}
+
+// readPBF streams all blocks of a PBF file to the handler
+func readPBF(r io.Reader, handler pbfHandler) error {
+	var sizeBuf [4]byte
+	for {
+		if _, err := io.ReadFull(r, sizeBuf[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		headerSize := binary.BigEndian.Uint32(sizeBuf[:])
+		if headerSize > maxPBFHeaderSize {
+			return fmt.Errorf("invalid PBF block header size %d", headerSize)
+		}
+		header := make([]byte, headerSize)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return err
+		}
+
+		blobType, blobSize, err := parseBlobHeader(header)
+		if err != nil {
+			return err
+		}
+		if blobSize > maxPBFBlobSize {
+			return fmt.Errorf("invalid PBF blob size %d", blobSize)
+		}
+		blob := make([]byte, blobSize)
+		if _, err := io.ReadFull(r, blob); err != nil {
+			return err
+		}
+
+		data, err := decodeBlob(blob)
+		if err != nil {
+			return err
+		}
+
+		switch blobType {
+		case "OSMHeader":
+			if err := checkPBFHeader(data); err != nil {
+				return err
+			}
+		case "OSMData":
+			if err := decodePrimitiveBlock(data, handler); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func parseBlobHeader(data []byte) (blobType string, size int, err error) {
+	pr := protoReader{buf: data}
+	for !pr.done() {
+		field, wire, err := pr.next()
+		if err != nil {
+			return "", 0, err
+		}
+		switch {
+		case field == 1 && wire == 2:
+			b, err := pr.bytes()
+			if err != nil {
+				return "", 0, err
+			}
+			blobType = string(b)
+		case field == 3 && wire == 0:
+			v, err := pr.varint()
+			if err != nil {
+				return "", 0, err
+			}
+			size = int(v)
+		default:
+			if err := pr.skip(wire); err != nil {
+				return "", 0, err
+			}
+		}
+	}
+	return blobType, size, nil
+}
+
+func decodeBlob(data []byte) ([]byte, error) {
+	pr := protoReader{buf: data}
+	var rawSize int
+	for !pr.done() {
+		field, wire, err := pr.next()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case field == 1 && wire == 2: // raw
+			return pr.bytes()
+		case field == 2 && wire == 0: // raw_size
+			v, err := pr.varint()
+			if err != nil {
+				return nil, err
+			}
+			rawSize = int(v)
+		case field == 3 && wire == 2: // zlib_data
+			compressed, err := pr.bytes()
+			if err != nil {
+				return nil, err
+			}
+			zr, err := zlib.NewReader(bytes.NewReader(compressed))
+			if err != nil {
+				return nil, err
+			}
+			defer zr.Close()
+
+			out := bytes.NewBuffer(make([]byte, 0, rawSize))
+			if _, err := io.Copy(out, io.LimitReader(zr, maxPBFBlobSize)); err != nil {
+				return nil, err
+			}
+			return out.Bytes(), nil
+		case wire == 2 && field >= 4: // lzma, bzip2, lz4, zstd
+			return nil, fmt.Errorf("%w: blob compression field %d", errUnsupportedPBFFeature, field)
+		default:
+			if err := pr.skip(wire); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, errors.New("empty PBF blob")
+}
+
+// checkPBFHeader rejects files that need features this reader does not implement
+func checkPBFHeader(data []byte) error {
+	pr := protoReader{buf: data}
+	for !pr.done() {
+		field, wire, err := pr.next()
+		if err != nil {
+			return err
+		}
+		if field == 4 && wire == 2 { // required_features
+			b, err := pr.bytes()
+			if err != nil {
+				return err
+			}
+			if feature := string(b); feature != "OsmSchema-V0.6" && feature != "DenseNodes" {
+				return fmt.Errorf("%w: %s", errUnsupportedPBFFeature, feature)
+			}
+			continue
+		}
+		if err := pr.skip(wire); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// primitiveBlock holds the block-wide values needed to decode its groups
+type primitiveBlock struct {
+	strings     [][]byte
+	granularity int64
+	latOffset   int64
+	lonOffset   int64
+}
+
+func (b *primitiveBlock) coordinate(offset, value int64) float64 {
+	return 1e-9 * float64(offset+b.granularity*value)
+}
+
+func (b *primitiveBlock) str(index uint64) string {
+	if index < uint64(len(b.strings)) {
+		return string(b.strings[index])
+	}
+	return ""
+}
+
+func decodePrimitiveBlock(data []byte, handler pbfHandler) error {
+	block := primitiveBlock{granularity: 100}
+	var groups [][]byte
+
+	pr := protoReader{buf: data}
+	for !pr.done() {
+		field, wire, err := pr.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case field == 1 && wire == 2: // stringtable
+			table, err := pr.bytes()
+			if err != nil {
+				return err
+			}
+			if block.strings, err = decodeStringTable(table); err != nil {
+				return err
+			}
+		case field == 2 && wire == 2: // primitivegroup
+			group, err := pr.bytes()
+			if err != nil {
+				return err
+			}
+			groups = append(groups, group)
+		case field == 17 && wire == 0:
+			v, err := pr.varint()
+			if err != nil {
+				return err
+			}
+			block.granularity = int64(v)
+		case field == 19 && wire == 0:
+			v, err := pr.varint()
+			if err != nil {
+				return err
+			}
+			block.latOffset = int64(v)
+		case field == 20 && wire == 0:
+			v, err := pr.varint()
+			if err != nil {
+				return err
+			}
+			block.lonOffset = int64(v)
+		default:
+			if err := pr.skip(wire); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Groups reference the string table, which may follow them in the block
+	for _, group := range groups {
+		if err := decodePrimitiveGroup(group, &block, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeStringTable(data []byte) ([][]byte, error) {
+	var table [][]byte
+	pr := protoReader{buf: data}
+	for !pr.done() {
+		field, wire, err := pr.next()
+		if err != nil {
+			return nil, err
+		}
+		if field == 1 && wire == 2 {
+			s, err := pr.bytes()
+			if err != nil {
+				return nil, err
+			}
+			table = append(table, s)
+			continue
+		}
+		if err := pr.skip(wire); err != nil {
+			return nil, err
+		}
+	}
+	return table, nil
+}
+
+func decodePrimitiveGroup(data []byte, block *primitiveBlock, handler pbfHandler) error {
+	pr := protoReader{buf: data}
+	for !pr.done() {
+		field, wire, err := pr.next()
+		if err != nil {
+			return err
+		}
+
+		var decode func([]byte, *primitiveBlock, pbfHandler) error
+		switch {
+		case field == 1 && wire == 2 && handler.Node != nil:
+			decode = decodeNode
+		case field == 2 && wire == 2 && handler.Node != nil:
+			decode = decodeDenseNodes
+		case field == 3 && wire == 2 && handler.Way != nil:
+			decode = decodeWay
+		}
+		if decode == nil {
+			if err := pr.skip(wire); err != nil {
+				return err
+			}
+			continue
+		}
+
+		message, err := pr.bytes()
+		if err != nil {
+			return err
+		}
+		if err := decode(message, block, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeNode(data []byte, block *primitiveBlock, handler pbfHandler) error {
+	var node pbfNode
+	var lat, lon int64
+
+	pr := protoReader{buf: data}
+	for !pr.done() {
+		field, wire, err := pr.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case field == 1 && wire == 0:
+			v, err := pr.varint()
+			if err != nil {
+				return err
+			}
+			node.ID = zigzag(v)
+		case field == 8 && wire == 0:
+			v, err := pr.varint()
+			if err != nil {
+				return err
+			}
+			lat = zigzag(v)
+		case field == 9 && wire == 0:
+			v, err := pr.varint()
+			if err != nil {
+				return err
+			}
+			lon = zigzag(v)
+		default:
+			if err := pr.skip(wire); err != nil {
+				return err
+			}
+		}
+	}
+
+	node.Lat = block.coordinate(block.latOffset, lat)
+	node.Lng = block.coordinate(block.lonOffset, lon)
+	handler.Node(node)
+	return nil
+}
+
+func decodeDenseNodes(data []byte, block *primitiveBlock, handler pbfHandler) error {
+	var ids, lats, lons []int64
+
+	pr := protoReader{buf: data}
+	for !pr.done() {
+		field, wire, err := pr.next()
+		if err != nil {
+			return err
+		}
+		if wire != 2 || (field != 1 && field != 8 && field != 9) {
+			if err := pr.skip(wire); err != nil {
+				return err
+			}
+			continue
+		}
+
+		packed, err := pr.bytes()
+		if err != nil {
+			return err
+		}
+		values, err := unpackSint64(packed)
+		if err != nil {
+			return err
+		}
+		switch field {
+		case 1:
+			ids = values
+		case 8:
+			lats = values
+		case 9:
+			lons = values
+		}
+	}
+
+	if len(lats) != len(ids) || len(lons) != len(ids) {
+		return errors.New("malformed dense nodes")
+	}
+
+	// Values are delta coded
+	var id, lat, lon int64
+	for i := range ids {
+		id += ids[i]
+		lat += lats[i]
+		lon += lons[i]
+		handler.Node(pbfNode{
+			ID:  id,
+			Lat: block.coordinate(block.latOffset, lat),
+			Lng: block.coordinate(block.lonOffset, lon),
+		})
+	}
+	return nil
+}
+
+func decodeWay(data []byte, block *primitiveBlock, handler pbfHandler) error {
+	var way pbfWay
+	var keys, vals []uint64
+
+	pr := protoReader{buf: data}
+	for !pr.done() {
+		field, wire, err := pr.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case field == 1 && wire == 0:
+			v, err := pr.varint()
+			if err != nil {
+				return err
+			}
+			way.ID = int64(v)
+		case (field == 2 || field == 3) && wire == 2:
+			packed, err := pr.bytes()
+			if err != nil {
+				return err
+			}
+			values, err := unpackUvarint(packed)
+			if err != nil {
+				return err
+			}
+			if field == 2 {
+				keys = values
+			} else {
+				vals = values
+			}
+		case field == 8 && wire == 2:
+			packed, err := pr.bytes()
+			if err != nil {
+				return err
+			}
+			if way.Refs, err = unpackSint64(packed); err != nil {
+				return err
+			}
+		default:
+			if err := pr.skip(wire); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(keys) != len(vals) {
+		return errors.New("malformed way tags")
+	}
+	way.Tags = make(map[string]string, len(keys))
+	for i := range keys {
+		way.Tags[block.str(keys[i])] = block.str(vals[i])
+	}
+
+	// Node references are delta coded
+	for i := 1; i < len(way.Refs); i++ {
+		way.Refs[i] += way.Refs[i-1]
+	}
+
+	handler.Way(way)
+	return nil
+}
+
+// protoReader decodes the protobuf wire format
+type protoReader struct {
+	buf []byte
+	pos int
+}
+
+func (p *protoReader) done() bool {
+	return p.pos >= len(p.buf)
+}
+
+func (p *protoReader) next() (field int, wire int, err error) {
+	key, err := p.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(key >> 3), int(key & 7), nil
+}
+
+func (p *protoReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(p.buf[p.pos:])
+	if n <= 0 {
+		return 0, errors.New("malformed protobuf varint")
+	}
+	p.pos += n
+	return v, nil
+}
+
+func (p *protoReader) bytes() ([]byte, error) {
+	length, err := p.varint()
+	if err != nil {
+		return nil, err
+	}
+	end := p.pos + int(length)
+	if length > uint64(len(p.buf)) || end > len(p.buf) {
+		return nil, errors.New("malformed protobuf length")
+	}
+	b := p.buf[p.pos:end]
+	p.pos = end
+	return b, nil
+}
+
+func (p *protoReader) skip(wire int) error {
+	switch wire {
+	case 0:
+		_, err := p.varint()
+		return err
+	case 1:
+		p.pos += 8
+	case 2:
+		_, err := p.bytes()
+		return err
+	case 5:
+		p.pos += 4
+	default:
+		return fmt.Errorf("unsupported protobuf wire type %d", wire)
+	}
+	if p.pos > len(p.buf) {
+		return errors.New("malformed protobuf message")
+	}
+	return nil
+}
+
+func unpackUvarint(data []byte) ([]uint64, error) {
+	values := make([]uint64, 0, len(data))
+	pr := protoReader{buf: data}
+	for !pr.done() {
+		v, err := pr.varint()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func unpackSint64(data []byte) ([]int64, error) {
+	raw, err := unpackUvarint(data)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]int64, len(raw))
+	for i, v := range raw {
+		values[i] = zigzag(v)
+	}
+	return values, nil
+}
+
+func zigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
@@ -0,0 +1,355 @@
+// File: /services/osm_router.go
+package services
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"motocosmos-api/models"
+)
+
+const (
+	// maxSnapDistanceKm is how far a waypoint may be from the nearest usable road
+	maxSnapDistanceKm = 5.0
+	// maxSearchNodes bounds a single A* search so unreachable targets fail fast
+	maxSearchNodes = 3000000
+	// minWindingWeight is the cheapest edge weight with PreferWinding; the
+	// heuristic is scaled by it to stay admissible
+	minWindingWeight = 0.5
+)
+
+// OSMRouter routes on a road graph imported from OpenStreetMap with A*,
+// minimizing travel time for the motorcycle profile. With PreferWinding the
+// travel time is weighted by the twistiness of each road.
+type OSMRouter struct {
+	graphPath string
+
+	mu    sync.Mutex
+	graph *RoadGraph
+}
+
+// NewOSMRouter creates a router; the graph file is loaded on first use
+func NewOSMRouter(graphPath string) *OSMRouter {
+	return &OSMRouter{graphPath: graphPath}
+}
+
+// NewOSMRouterWithGraph creates a router on an already loaded graph
+func NewOSMRouterWithGraph(graph *RoadGraph) *OSMRouter {
+	return &OSMRouter{graph: graph}
+}
+
+func (r *OSMRouter) Name() string {
+	return RoutingEngineOSM
+}
+
+// Graph returns the road graph, loading it if needed
+func (r *OSMRouter) Graph() (*RoadGraph, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.graph == nil {
+		graph, err := LoadRoadGraph(r.graphPath)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrRoutingUnavailable, err)
+		}
+		r.graph = graph
+	}
+	return r.graph, nil
+}
+
+// Route plans the fastest route through the waypoints in order
+func (r *OSMRouter) Route(ctx context.Context, req models.RoutePlanRequest) (*models.RoutePlanResponse, error) {
+	if err := validateRoutePlanRequest(req); err != nil {
+		return nil, err
+	}
+
+	graph, err := r.Graph()
+	if err != nil {
+		return nil, err
+	}
+
+	usable := func(edge int32) bool {
+		return !req.AvoidHighways || !IsHighwayClass(graph.EdgeClass[edge])
+	}
+
+	nodes, err := snapWaypoints(graph, req.Waypoints, usable)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.RoutePlanResponse{
+		Geometry: []models.LatLng{{Latitude: graph.Lat[nodes[0]], Longitude: graph.Lng[nodes[0]]}},
+		Steps:    []models.RouteInstruction{},
+		Engine:   r.Name(),
+	}
+	roadDistances := make(map[string]float64)
+
+	for leg := 1; leg < len(nodes); leg++ {
+		path, err := r.search(ctx, graph, nodes[leg-1], nodes[leg], usable, req.PreferWinding)
+		if err != nil {
+			return nil, fmt.Errorf("%w (leg %d)", err, leg)
+		}
+
+		for _, edge := range path {
+			to := graph.EdgeTo[edge]
+			distance := float64(graph.EdgeDist[edge])
+
+			response.Geometry = append(response.Geometry, models.LatLng{Latitude: graph.Lat[to], Longitude: graph.Lng[to]})
+			response.Distance += distance
+			response.Duration += distance / (float64(graph.EdgeSpeed[edge]) / 3.6)
+			if name := graph.RoadName(edge); name != "" {
+				roadDistances[name] += distance
+			}
+		}
+
+		waypoint := leg + 1
+		if leg == len(nodes)-1 {
+			waypoint = 0
+		}
+		response.Steps = append(response.Steps, graphLegInstructions(graph, nodes[leg-1], path, waypoint)...)
+	}
+
+	response.Steps = LocalizeInstructions(response.Steps, DefaultInstructionLanguage)
+	response.Summary = routeSummary(roadDistances)
+	return response, nil
+}
+
+// DistanceMatrix measures the road distances between the waypoints with one
+// search per waypoint, following the paths Route would take
+func (r *OSMRouter) DistanceMatrix(ctx context.Context, req models.RoutePlanRequest) ([][]float64, error) {
+	if err := validateRoutePlanRequest(req); err != nil {
+		return nil, err
+	}
+
+	graph, err := r.Graph()
+	if err != nil {
+		return nil, err
+	}
+
+	usable := func(edge int32) bool {
+		return !req.AvoidHighways || !IsHighwayClass(graph.EdgeClass[edge])
+	}
+
+	nodes, err := snapWaypoints(graph, req.Waypoints, usable)
+	if err != nil {
+		return nil, err
+	}
+
+	matrix := make([][]float64, len(nodes))
+	for i, node := range nodes {
+		if matrix[i], err = r.searchMany(ctx, graph, node, nodes, usable, req.PreferWinding); err != nil {
+			return nil, err
+		}
+	}
+	return matrix, nil
+}
+
+// snapWaypoints finds the nearest usable road node of each waypoint
+func snapWaypoints(graph *RoadGraph, waypoints []models.LatLng, usable func(edge int32) bool) ([]int32, error) {
+	nodes := make([]int32, len(waypoints))
+	for i, wp := range waypoints {
+		nodes[i] = graph.Nearest(wp.Latitude, wp.Longitude, maxSnapDistanceKm, usable)
+		if nodes[i] < 0 {
+			return nil, fmt.Errorf("%w: waypoint %d", ErrNoRoadNearby, i+1)
+		}
+	}
+	return nodes, nil
+}
+
+type searchLabel struct {
+	cost    float64
+	edge    int32 // edge used to reach the node, -1 for the start
+	from    int32
+	settled bool
+}
+
+// windingWeight scales the travel time of an edge when winding roads are
+// preferred: the curviest roads cost half their time, straight roads one and
+// a half, and motorways are always weighted as straight
+func windingWeight(graph *RoadGraph, edge int32) float64 {
+	if IsHighwayClass(graph.EdgeClass[edge]) {
+		return 1.5
+	}
+	return 1.5 - float64(graph.EdgeTwist[edge])/100
+}
+
+// search runs A* from start to target and returns the edges of the cheapest path
+func (r *OSMRouter) search(ctx context.Context, graph *RoadGraph, start, target int32, usable func(edge int32) bool, preferWinding bool) ([]int32, error) {
+	if start == target {
+		return nil, nil
+	}
+
+	maxSpeed := motorcycleMaxSpeed / 3.6
+	heuristicWeight := 1.0
+	if preferWinding {
+		heuristicWeight = minWindingWeight
+	}
+	heuristic := func(node int32) float64 {
+		return HaversineKm(graph.Lat[node], graph.Lng[node], graph.Lat[target], graph.Lng[target]) * 1000 / maxSpeed * heuristicWeight
+	}
+
+	labels := map[int32]*searchLabel{start: {edge: -1, from: -1}}
+	queue := &searchQueue{{node: start, priority: heuristic(start)}}
+
+	for settled := 0; queue.Len() > 0; settled++ {
+		if settled%10000 == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if settled > maxSearchNodes {
+			break
+		}
+
+		item := heap.Pop(queue).(searchItem)
+		label := labels[item.node]
+		if label.settled {
+			continue
+		}
+		label.settled = true
+
+		if item.node == target {
+			return tracePath(labels, target), nil
+		}
+
+		for edge := graph.EdgeStart[item.node]; edge < graph.EdgeStart[item.node+1]; edge++ {
+			if !usable(edge) {
+				continue
+			}
+			to := graph.EdgeTo[edge]
+			edgeCost := float64(graph.EdgeDist[edge]) / (float64(graph.EdgeSpeed[edge]) / 3.6)
+			if preferWinding {
+				edgeCost *= windingWeight(graph, edge)
+			}
+			cost := label.cost + edgeCost
+
+			next, seen := labels[to]
+			if seen && (next.settled || next.cost <= cost) {
+				continue
+			}
+			if !seen {
+				next = &searchLabel{}
+				labels[to] = next
+			}
+			next.cost, next.edge, next.from = cost, edge, item.node
+			heap.Push(queue, searchItem{node: to, priority: cost + heuristic(to)})
+		}
+	}
+
+	return nil, ErrNoRouteFound
+}
+
+// searchMany runs Dijkstra from start until every target is settled and
+// returns the length in meters of the cheapest path to each, +Inf when a
+// target cannot be reached
+func (r *OSMRouter) searchMany(ctx context.Context, graph *RoadGraph, start int32, targets []int32, usable func(edge int32) bool, preferWinding bool) ([]float64, error) {
+	remaining := make(map[int32]bool, len(targets))
+	for _, target := range targets {
+		remaining[target] = true
+	}
+
+	labels := map[int32]*searchLabel{start: {edge: -1, from: -1}}
+	queue := &searchQueue{{node: start}}
+
+	for settled := 0; queue.Len() > 0 && len(remaining) > 0; settled++ {
+		if settled%10000 == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if settled > maxSearchNodes {
+			break
+		}
+
+		item := heap.Pop(queue).(searchItem)
+		label := labels[item.node]
+		if label.settled {
+			continue
+		}
+		label.settled = true
+		delete(remaining, item.node)
+
+		for edge := graph.EdgeStart[item.node]; edge < graph.EdgeStart[item.node+1]; edge++ {
+			if !usable(edge) {
+				continue
+			}
+			to := graph.EdgeTo[edge]
+			edgeCost := float64(graph.EdgeDist[edge]) / (float64(graph.EdgeSpeed[edge]) / 3.6)
+			if preferWinding {
+				edgeCost *= windingWeight(graph, edge)
+			}
+			cost := label.cost + edgeCost
+
+			next, seen := labels[to]
+			if seen && (next.settled || next.cost <= cost) {
+				continue
+			}
+			if !seen {
+				next = &searchLabel{}
+				labels[to] = next
+			}
+			next.cost, next.edge, next.from = cost, edge, item.node
+			heap.Push(queue, searchItem{node: to, priority: cost})
+		}
+	}
+
+	distances := make([]float64, len(targets))
+	for i, target := range targets {
+		if label, ok := labels[target]; !ok || !label.settled {
+			distances[i] = math.Inf(1)
+			continue
+		}
+		for _, edge := range tracePath(labels, target) {
+			distances[i] += float64(graph.EdgeDist[edge])
+		}
+	}
+	return distances, nil
+}
+
+func tracePath(labels map[int32]*searchLabel, target int32) []int32 {
+	var path []int32
+	for node := target; labels[node].edge >= 0; node = labels[node].from {
+		path = append(path, labels[node].edge)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+type searchItem struct {
+	node     int32
+	priority float64
+}
+
+// searchQueue is a min-heap of nodes ordered by their A* priority
+type searchQueue []searchItem
+
+func (q searchQueue) Len() int            { return len(q) }
+func (q searchQueue) Less(i, j int) bool  { return q[i].priority < q[j].priority }
+func (q searchQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *searchQueue) Push(x interface{}) { *q = append(*q, x.(searchItem)) }
+func (q *searchQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// routeSummary names the two roads the route uses most, like "M7, 71"
+func routeSummary(roadDistances map[string]float64) string {
+	names := make([]string, 0, len(roadDistances))
+	for name := range roadDistances {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return "Planned route"
+	}
+
+	sort.Slice(names, func(i, j int) bool { return roadDistances[names[i]] > roadDistances[names[j]] })
+	if len(names) > 2 {
+		names = names[:2]
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,606 @@
+// File: /services/poi_service.go
+package services
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+)
+
+var (
+	ErrPOINotFound           = errors.New("point of interest not found")
+	ErrInvalidPOI            = errors.New("invalid point of interest")
+	ErrDuplicatePOI          = errors.New("a point of interest of this category already exists here")
+	ErrNotSharedRouteCreator = errors.New("only the creator can change the shared route")
+)
+
+const (
+	// poiDuplicateRadiusKm is how close a suggestion may be to a POI of the same category
+	poiDuplicateRadiusKm = 0.05
+	maxRoutePOIs         = 500
+)
+
+// POIInput is a single POI row coming from an import file or a user suggestion
+type POIInput struct {
+	Name         string   `json:"name"`
+	Category     string   `json:"category"`
+	Latitude     float64  `json:"latitude"`
+	Longitude    float64  `json:"longitude"`
+	Brand        string   `json:"brand"`
+	Address      string   `json:"address"`
+	CountryCode  string   `json:"country_code"`
+	ExternalID   string   `json:"external_id"`
+	Description  string   `json:"description"`
+	Website      string   `json:"website"`
+	Phone        string   `json:"phone"`
+	OpeningHours string   `json:"opening_hours"`
+	Elevation    *float64 `json:"elevation"`
+}
+
+// POIImportResult summarizes a bulk import
+type POIImportResult struct {
+	Imported int      `json:"imported"`
+	Updated  int      `json:"updated"`
+	Skipped  int      `json:"skipped"`
+	Errors   []string `json:"errors"`
+}
+
+type POIService struct {
+	db *gorm.DB
+}
+
+func NewPOIService(db *gorm.DB) *POIService {
+	return &POIService{db: db}
+}
+
+// InBounds returns the approved POIs inside a bounding box, of the given
+// categories or of all categories when none are given
+func (s *POIService) InBounds(minLat, maxLat, minLng, maxLng float64, categories ...string) ([]models.PointOfInterest, error) {
+	var pois []models.PointOfInterest
+	query := s.db.Where("status = ? AND latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?",
+		models.POIStatusApproved, minLat, maxLat, minLng, maxLng)
+	if len(categories) > 0 {
+		query = query.Where("category IN ?", categories)
+	}
+	err := query.Find(&pois).Error
+	return pois, err
+}
+
+// Nearby returns the POIs within radiusKm of a point, nearest first
+func (s *POIService) Nearby(lat, lng, radiusKm float64, limit int, categories ...string) ([]models.NearbyPOI, error) {
+	minLat, maxLat, minLng, maxLng := BoundingBox(lat, lng, radiusKm)
+	pois, err := s.InBounds(minLat, maxLat, minLng, maxLng, categories...)
+	if err != nil {
+		return nil, err
+	}
+
+	nearby := make([]models.NearbyPOI, 0, len(pois))
+	for _, poi := range pois {
+		distance := HaversineKm(lat, lng, poi.Latitude, poi.Longitude)
+		if distance <= radiusKm {
+			nearby = append(nearby, models.NearbyPOI{PointOfInterest: poi, Distance: distance})
+		}
+	}
+
+	sort.Slice(nearby, func(i, j int) bool { return nearby[i].Distance < nearby[j].Distance })
+	if limit > 0 && len(nearby) > limit {
+		nearby = nearby[:limit]
+	}
+
+	return nearby, nil
+}
+
+// AlongPath returns the POIs within radiusKm of a path, in the order they
+// are passed. Distances along the path are straight path kilometres.
+func (s *POIService) AlongPath(points []models.LatLng, radiusKm float64, categories ...string) ([]models.RoutePOI, error) {
+	if len(points) == 0 {
+		return []models.RoutePOI{}, nil
+	}
+
+	minLat, maxLat, minLng, maxLng := PathBounds(points)
+	minLat, _, minLng, _ = BoundingBox(minLat, minLng, radiusKm)
+	_, maxLat, _, maxLng = BoundingBox(maxLat, maxLng, radiusKm)
+
+	pois, err := s.InBounds(minLat, maxLat, minLng, maxLng, categories...)
+	if err != nil {
+		return nil, err
+	}
+
+	cumulative := CumulativeDistancesKm(points)
+	found := make([]models.RoutePOI, 0, len(pois))
+	for _, poi := range pois {
+		along, offset := ProjectOntoPath(points, cumulative, poi.Latitude, poi.Longitude)
+		if offset > radiusKm {
+			continue
+		}
+		found = append(found, models.RoutePOI{
+			PointOfInterest:   poi,
+			Detour:            offset,
+			DistanceFromStart: along,
+		})
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].DistanceFromStart < found[j].DistanceFromStart })
+	if len(found) > maxRoutePOIs {
+		found = found[:maxRoutePOIs]
+	}
+	return found, nil
+}
+
+// Get returns an approved POI, or a suggestion to the user who made it
+func (s *POIService) Get(poiID, userID string) (*models.PointOfInterest, error) {
+	var poi models.PointOfInterest
+	err := s.db.Where("id = ? AND (status = ? OR suggested_by_id = ?)", poiID, models.POIStatusApproved, userID).First(&poi).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPOINotFound
+		}
+		return nil, err
+	}
+	return &poi, nil
+}
+
+// Save stores an approved POI, updating an existing one with the same source
+// and external ID. The returned bool reports whether a new row was created.
+func (s *POIService) Save(input POIInput, source string) (*models.PointOfInterest, bool, error) {
+	poi, err := newPOI(input, source)
+	if err != nil {
+		return nil, false, err
+	}
+	poi.Status = models.POIStatusApproved
+
+	if poi.ExternalID != "" {
+		var existing models.PointOfInterest
+		err := s.db.Where("source = ? AND external_id = ?", source, poi.ExternalID).First(&existing).Error
+		if err == nil {
+			poi.ID = existing.ID
+			poi.CreatedAt = existing.CreatedAt
+			poi.SuggestedByID = existing.SuggestedByID
+			if err := s.db.Save(poi).Error; err != nil {
+				return nil, false, err
+			}
+			return poi, false, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, err
+		}
+	}
+
+	poi.ID = uuid.New().String()
+	if err := s.db.Create(poi).Error; err != nil {
+		return nil, false, err
+	}
+	return poi, true, nil
+}
+
+// Suggest stores a POI proposed by a user. It stays pending, and only
+// visible to that user, until an admin approves it.
+func (s *POIService) Suggest(userID string, input POIInput) (*models.PointOfInterest, error) {
+	poi, err := newPOI(input, "user")
+	if err != nil {
+		return nil, err
+	}
+	if poi.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidPOI)
+	}
+
+	// Someone may have suggested or imported the place already
+	minLat, maxLat, minLng, maxLng := BoundingBox(poi.Latitude, poi.Longitude, poiDuplicateRadiusKm)
+	var nearby []models.PointOfInterest
+	if err := s.db.Where("category = ? AND status IN ? AND latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?",
+		poi.Category, []string{models.POIStatusApproved, models.POIStatusPending}, minLat, maxLat, minLng, maxLng).
+		Find(&nearby).Error; err != nil {
+		return nil, err
+	}
+	for _, existing := range nearby {
+		if HaversineKm(poi.Latitude, poi.Longitude, existing.Latitude, existing.Longitude) <= poiDuplicateRadiusKm {
+			return nil, ErrDuplicatePOI
+		}
+	}
+
+	poi.ID = uuid.New().String()
+	poi.Status = models.POIStatusPending
+	poi.SuggestedByID = &userID
+	if err := s.db.Create(poi).Error; err != nil {
+		return nil, err
+	}
+	return poi, nil
+}
+
+// Suggestions returns POIs suggested by users in a review state, oldest first
+func (s *POIService) Suggestions(status string, limit, offset int) ([]models.PointOfInterest, int64, error) {
+	var pois []models.PointOfInterest
+	var total int64
+
+	query := s.db.Model(&models.PointOfInterest{}).Where("status = ? AND suggested_by_id IS NOT NULL", status)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("created_at ASC").Limit(limit).Offset(offset).Find(&pois).Error
+	return pois, total, err
+}
+
+// Review approves or rejects a pending suggestion
+func (s *POIService) Review(poiID string, approve bool, note string) (*models.PointOfInterest, error) {
+	var poi models.PointOfInterest
+	if err := s.db.First(&poi, "id = ? AND status = ?", poiID, models.POIStatusPending).Error; err != nil {
+		return nil, ErrPOINotFound
+	}
+
+	poi.Status = models.POIStatusRejected
+	if approve {
+		poi.Status = models.POIStatusApproved
+	}
+	poi.ReviewNote = note
+	if err := s.db.Model(&poi).Updates(map[string]interface{}{"status": poi.Status, "review_note": note}).Error; err != nil {
+		return nil, err
+	}
+	return &poi, nil
+}
+
+// ValidateIDs checks that every non-empty ID is a POI the user may see
+func (s *POIService) ValidateIDs(userID string, ids []string) error {
+	unique := make(map[string]bool)
+	for _, id := range ids {
+		if id != "" {
+			unique[id] = true
+		}
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+
+	list := make([]string, 0, len(unique))
+	for id := range unique {
+		list = append(list, id)
+	}
+	var count int64
+	if err := s.db.Model(&models.PointOfInterest{}).
+		Where("id IN ? AND (status = ? OR suggested_by_id = ?)", list, models.POIStatusApproved, userID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(list) {
+		return ErrPOINotFound
+	}
+	return nil
+}
+
+// SharedRoutePOIs returns the POIs attached to a shared route
+func (s *POIService) SharedRoutePOIs(sharedRouteID string) ([]models.SharedRoutePOI, error) {
+	var attached []models.SharedRoutePOI
+	err := s.db.Preload("POI").Where("shared_route_id = ?", sharedRouteID).Order("created_at ASC").Find(&attached).Error
+	return attached, err
+}
+
+// AttachToSharedRoute adds a POI to a shared route of the user, or updates its note
+func (s *POIService) AttachToSharedRoute(userID, sharedRouteID, poiID, note string) (*models.SharedRoutePOI, error) {
+	if err := s.sharedRouteCreator(userID, sharedRouteID); err != nil {
+		return nil, err
+	}
+	if _, err := s.Get(poiID, userID); err != nil {
+		return nil, err
+	}
+
+	var attached models.SharedRoutePOI
+	err := s.db.Where("shared_route_id = ? AND poi_id = ?", sharedRouteID, poiID).First(&attached).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		attached = models.SharedRoutePOI{
+			SharedRouteID: sharedRouteID,
+			POIID:         poiID,
+			AddedByID:     userID,
+			Note:          note,
+		}
+		err = s.db.Create(&attached).Error
+	case err == nil:
+		err = s.db.Model(&attached).Update("note", note).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.db.Preload("POI").First(&attached, "id = ?", attached.ID)
+	return &attached, nil
+}
+
+// DetachFromSharedRoute removes a POI from a shared route of the user
+func (s *POIService) DetachFromSharedRoute(userID, sharedRouteID, poiID string) error {
+	if err := s.sharedRouteCreator(userID, sharedRouteID); err != nil {
+		return err
+	}
+
+	result := s.db.Where("shared_route_id = ? AND poi_id = ?", sharedRouteID, poiID).Delete(&models.SharedRoutePOI{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPOINotFound
+	}
+	return nil
+}
+
+func (s *POIService) sharedRouteCreator(userID, sharedRouteID string) error {
+	var sharedRoute models.SharedRoute
+	if err := s.db.Select("id", "creator_id").First(&sharedRoute, "id = ?", sharedRouteID).Error; err != nil {
+		return ErrRouteNotFound
+	}
+	if sharedRoute.CreatorID != userID {
+		return ErrNotSharedRouteCreator
+	}
+	return nil
+}
+
+// newPOI validates and normalizes a POI input
+func newPOI(input POIInput, source string) (*models.PointOfInterest, error) {
+	category := strings.ToLower(strings.TrimSpace(input.Category))
+	if category == "" {
+		return nil, fmt.Errorf("%w: category is required", ErrInvalidPOI)
+	}
+	if !models.IsValidPOICategory(category) {
+		return nil, fmt.Errorf("%w: unknown category %q", ErrInvalidPOI, category)
+	}
+	if input.Latitude < -90 || input.Latitude > 90 || input.Longitude < -180 || input.Longitude > 180 ||
+		(input.Latitude == 0 && input.Longitude == 0) {
+		return nil, fmt.Errorf("%w: invalid coordinates", ErrInvalidPOI)
+	}
+
+	countryCode := strings.ToUpper(strings.TrimSpace(input.CountryCode))
+	if countryCode == "" {
+		if country, ok := CountryForPoint(input.Latitude, input.Longitude); ok {
+			countryCode = country.Code
+		}
+	}
+
+	return &models.PointOfInterest{
+		Name:         strings.TrimSpace(input.Name),
+		Category:     category,
+		Latitude:     input.Latitude,
+		Longitude:    input.Longitude,
+		Brand:        strings.TrimSpace(input.Brand),
+		Address:      strings.TrimSpace(input.Address),
+		CountryCode:  countryCode,
+		Source:       source,
+		ExternalID:   strings.TrimSpace(input.ExternalID),
+		Description:  strings.TrimSpace(input.Description),
+		Website:      strings.TrimSpace(input.Website),
+		Phone:        strings.TrimSpace(input.Phone),
+		OpeningHours: strings.TrimSpace(input.OpeningHours),
+		Elevation:    input.Elevation,
+	}, nil
+}
+
+// Import stores POIs from a CSV, JSON or OSM XML document. The CSV needs a
+// header row with name, category, latitude and longitude columns and
+// optionally brand, address, country_code, external_id, description,
+// website, phone, opening_hours and elevation. OSM extracts (e.g. from
+// Overpass with "out center") are mapped to categories by their tags.
+func (s *POIService) Import(r io.Reader, format, source string) (*POIImportResult, error) {
+	var rows []POIInput
+	var err error
+
+	switch strings.ToLower(format) {
+	case "csv":
+		rows, err = parsePOICSV(r)
+	case "json":
+		err = json.NewDecoder(r).Decode(&rows)
+	case "osm":
+		rows, err = parseOSM(r)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", format, err)
+	}
+
+	result := &POIImportResult{Errors: []string{}}
+	for i, row := range rows {
+		_, created, err := s.Save(row, source)
+		switch {
+		case err != nil:
+			result.Skipped++
+			result.Errors = append(result.Errors, fmt.Sprintf("row %d: %v", i+1, err))
+		case created:
+			result.Imported++
+		default:
+			result.Updated++
+		}
+	}
+
+	return result, nil
+}
+
+// ImportFile imports a CSV, JSON or OSM file, detecting the format from its extension
+func (s *POIService) ImportFile(path string) (*POIImportResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	return s.Import(file, format, format)
+}
+
+// BoundingBox returns the box around a point that contains every point within radiusKm
+func BoundingBox(lat, lng, radiusKm float64) (minLat, maxLat, minLng, maxLng float64) {
+	latDelta := radiusKm / 111.32
+	lngDelta := radiusKm / (111.32 * math.Max(math.Cos(lat*math.Pi/180), 0.01))
+	return lat - latDelta, lat + latDelta, lng - lngDelta, lng + lngDelta
+}
+
+func parsePOICSV(r io.Reader) ([]POIInput, error) {
+	records, field, err := readImportCSV(r, "name", "category", "latitude", "longitude")
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]POIInput, 0, len(records))
+	for _, record := range records {
+		// Invalid coordinates are rejected row by row during the import
+		lat, _ := strconv.ParseFloat(field(record, "latitude"), 64)
+		lng, _ := strconv.ParseFloat(field(record, "longitude"), 64)
+		rows = append(rows, POIInput{
+			Name:         field(record, "name"),
+			Category:     field(record, "category"),
+			Latitude:     lat,
+			Longitude:    lng,
+			Brand:        field(record, "brand"),
+			Address:      field(record, "address"),
+			CountryCode:  field(record, "country_code"),
+			ExternalID:   field(record, "external_id"),
+			Description:  field(record, "description"),
+			Website:      field(record, "website"),
+			Phone:        field(record, "phone"),
+			OpeningHours: field(record, "opening_hours"),
+			Elevation:    parseElevation(field(record, "elevation")),
+		})
+	}
+
+	return rows, nil
+}
+
+// osmElement is a node, way or relation of an OSM XML document. Ways and
+// relations need a center, as written by Overpass for "out center".
+type osmElement struct {
+	XMLName xml.Name
+	ID      string  `xml:"id,attr"`
+	Lat     float64 `xml:"lat,attr"`
+	Lon     float64 `xml:"lon,attr"`
+	Center  *struct {
+		Lat float64 `xml:"lat,attr"`
+		Lon float64 `xml:"lon,attr"`
+	} `xml:"center"`
+	Tags []struct {
+		Key   string `xml:"k,attr"`
+		Value string `xml:"v,attr"`
+	} `xml:"tag"`
+}
+
+// parseOSM reads the tagged elements of an OSM XML document that map to a
+// POI category; everything else, like the plain nodes of ways, is skipped
+func parseOSM(r io.Reader) ([]POIInput, error) {
+	decoder := xml.NewDecoder(r)
+	var rows []POIInput
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || (start.Name.Local != "node" && start.Name.Local != "way" && start.Name.Local != "relation") {
+			continue
+		}
+
+		var element osmElement
+		if err := decoder.DecodeElement(&element, &start); err != nil {
+			return nil, err
+		}
+		if len(element.Tags) == 0 {
+			continue
+		}
+
+		tags := make(map[string]string, len(element.Tags))
+		for _, tag := range element.Tags {
+			tags[tag.Key] = tag.Value
+		}
+		category, ok := OSMCategory(tags)
+		if !ok {
+			continue
+		}
+
+		lat, lng := element.Lat, element.Lon
+		if element.Center != nil {
+			lat, lng = element.Center.Lat, element.Center.Lon
+		}
+
+		rows = append(rows, POIInput{
+			Name:         firstTag(tags, "name", "brand"),
+			Category:     category,
+			Latitude:     lat,
+			Longitude:    lng,
+			Brand:        tags["brand"],
+			Address:      osmAddress(tags),
+			CountryCode:  tags["addr:country"],
+			ExternalID:   start.Name.Local + "/" + element.ID,
+			Description:  tags["description"],
+			Website:      firstTag(tags, "website", "contact:website"),
+			Phone:        firstTag(tags, "phone", "contact:phone"),
+			OpeningHours: tags["opening_hours"],
+			Elevation:    parseElevation(tags["ele"]),
+		})
+	}
+
+	return rows, nil
+}
+
+// OSMCategory maps the tags of an OSM element to a POI category
+func OSMCategory(tags map[string]string) (string, bool) {
+	motorcycleFriendly := tags["motorcycle_friendly"] == "yes" || tags["motorcycle_friendly"] == "customary" || tags["biker"] == "yes"
+
+	switch {
+	case tags["amenity"] == "fuel":
+		return models.POICategoryFuel, true
+	case tags["mountain_pass"] == "yes":
+		return models.POICategoryPass, true
+	case tags["tourism"] == "viewpoint":
+		return models.POICategoryViewpoint, true
+	case tags["tourism"] == "camp_site":
+		return models.POICategoryCampsite, true
+	case tags["shop"] == "motorcycle_repair", tags["shop"] == "motorcycle", tags["service:vehicle:motorcycle"] == "yes":
+		return models.POICategoryMechanic, true
+	case motorcycleFriendly:
+		return models.POICategoryHangout, true
+	case tags["amenity"] == "cafe":
+		return models.POICategoryCafe, true
+	}
+	return "", false
+}
+
+func osmAddress(tags map[string]string) string {
+	street := strings.TrimSpace(tags["addr:street"] + " " + tags["addr:housenumber"])
+	city := strings.TrimSpace(tags["addr:postcode"] + " " + tags["addr:city"])
+	switch {
+	case street != "" && city != "":
+		return street + ", " + city
+	case street != "":
+		return street
+	}
+	return city
+}
+
+func firstTag(tags map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value := tags[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// parseElevation reads heights like "2757" or "2757 m"; empty or unreadable values give nil
+func parseElevation(value string) *float64 {
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "m"))
+	elevation, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil
+	}
+	return &elevation
+}
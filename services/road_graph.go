@@ -0,0 +1,293 @@
+// File: /services/road_graph.go
+package services
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"motocosmos-api/models"
+)
+
+// roadGraphVersion is bumped whenever the stored graph layout changes
+const roadGraphVersion = 3
+
+// roadGraphCellSize is the size of the snapping grid cells in degrees (~1 km)
+const roadGraphCellSize = 0.01
+
+// RoadGraph is a directed road network for motorcycles. Edges are stored in
+// compressed sparse row form: the outgoing edges of node n are
+// EdgeStart[n] .. EdgeStart[n+1]-1.
+type RoadGraph struct {
+	Version   int
+	Lat       []float64
+	Lng       []float64
+	EdgeStart []int32
+	EdgeTo    []int32
+	EdgeDist  []float32 // meters
+	EdgeSpeed []float32 // km/h
+	EdgeClass []uint8
+	EdgeName  []int32 // index into Names, -1 when unnamed
+	EdgeTwist []uint8 // twistiness score 0-100 of the way the edge belongs to
+	EdgeRound []bool  // edge is part of a roundabout
+	Names     []string
+
+	grid map[[2]int32][]int32
+}
+
+// RoadGraphStats summarizes a built graph
+type RoadGraphStats struct {
+	Nodes int `json:"nodes"`
+	Edges int `json:"edges"`
+	Ways  int `json:"ways"`
+}
+
+// BuildRoadGraph imports an OSM PBF extract into a road graph. The file is read
+// twice: first the ways usable by motorcycles, then the coordinates of their nodes.
+func BuildRoadGraph(pbfPath string) (*RoadGraph, *RoadGraphStats, error) {
+	type way struct {
+		refs    []int64
+		profile RoadProfile
+		name    int32
+	}
+
+	var ways []way
+	nodeIndex := make(map[int64]int32)
+	nameIndex := make(map[string]int32)
+	graph := &RoadGraph{Version: roadGraphVersion}
+
+	err := readPBFFile(pbfPath, pbfHandler{Way: func(w pbfWay) {
+		profile, ok := MotorcycleWayProfile(w.Tags)
+		if !ok || len(w.Refs) < 2 {
+			return
+		}
+
+		name := int32(-1)
+		if profile.Name != "" {
+			idx, known := nameIndex[profile.Name]
+			if !known {
+				idx = int32(len(graph.Names))
+				nameIndex[profile.Name] = idx
+				graph.Names = append(graph.Names, profile.Name)
+			}
+			name = idx
+		}
+
+		for _, ref := range w.Refs {
+			nodeIndex[ref] = -1
+		}
+		ways = append(ways, way{refs: w.Refs, profile: profile, name: name})
+	}})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(ways) == 0 {
+		return nil, nil, errors.New("the extract contains no roads usable by motorcycles")
+	}
+
+	err = readPBFFile(pbfPath, pbfHandler{Node: func(n pbfNode) {
+		if idx, needed := nodeIndex[n.ID]; needed && idx < 0 {
+			nodeIndex[n.ID] = int32(len(graph.Lat))
+			graph.Lat = append(graph.Lat, n.Lat)
+			graph.Lng = append(graph.Lng, n.Lng)
+		}
+	}})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type edge struct {
+		from, to int32
+		dist     float32
+		speed    float32
+		class    uint8
+		name     int32
+		twist    uint8
+		round    bool
+	}
+	var edges []edge
+
+	for _, w := range ways {
+		points := make([]models.LatLng, 0, len(w.refs))
+		for _, ref := range w.refs {
+			if idx := nodeIndex[ref]; idx >= 0 {
+				points = append(points, models.LatLng{Latitude: graph.Lat[idx], Longitude: graph.Lng[idx]})
+			}
+		}
+		twist := uint8(math.Round(AnalyzeCurvature(points).Score))
+
+		for i := 1; i < len(w.refs); i++ {
+			from, to := nodeIndex[w.refs[i-1]], nodeIndex[w.refs[i]]
+			if from < 0 || to < 0 || from == to {
+				continue // node outside the extract
+			}
+
+			dist := float32(HaversineKm(graph.Lat[from], graph.Lng[from], graph.Lat[to], graph.Lng[to]) * 1000)
+			speed := float32(w.profile.Speed)
+			if w.profile.Forward {
+				edges = append(edges, edge{from, to, dist, speed, w.profile.Class, w.name, twist, w.profile.Roundabout})
+			}
+			if w.profile.Backward {
+				edges = append(edges, edge{to, from, dist, speed, w.profile.Class, w.name, twist, w.profile.Roundabout})
+			}
+		}
+	}
+
+	// Counting sort of the edges by their source node
+	nodeCount := len(graph.Lat)
+	graph.EdgeStart = make([]int32, nodeCount+1)
+	for _, e := range edges {
+		graph.EdgeStart[e.from+1]++
+	}
+	for i := 1; i <= nodeCount; i++ {
+		graph.EdgeStart[i] += graph.EdgeStart[i-1]
+	}
+
+	graph.EdgeTo = make([]int32, len(edges))
+	graph.EdgeDist = make([]float32, len(edges))
+	graph.EdgeSpeed = make([]float32, len(edges))
+	graph.EdgeClass = make([]uint8, len(edges))
+	graph.EdgeName = make([]int32, len(edges))
+	graph.EdgeTwist = make([]uint8, len(edges))
+	graph.EdgeRound = make([]bool, len(edges))
+	fill := append([]int32(nil), graph.EdgeStart[:nodeCount]...)
+	for _, e := range edges {
+		i := fill[e.from]
+		fill[e.from]++
+		graph.EdgeTo[i] = e.to
+		graph.EdgeDist[i] = e.dist
+		graph.EdgeSpeed[i] = e.speed
+		graph.EdgeClass[i] = e.class
+		graph.EdgeName[i] = e.name
+		graph.EdgeTwist[i] = e.twist
+		graph.EdgeRound[i] = e.round
+	}
+
+	graph.buildGrid()
+	return graph, &RoadGraphStats{Nodes: nodeCount, Edges: len(edges), Ways: len(ways)}, nil
+}
+
+// Save writes the graph to a file, creating its directory if needed
+func (g *RoadGraph) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	if err := gob.NewEncoder(w).Encode(g); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// LoadRoadGraph reads a graph written by Save
+func LoadRoadGraph(path string) (*RoadGraph, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var graph RoadGraph
+	if err := gob.NewDecoder(bufio.NewReader(file)).Decode(&graph); err != nil {
+		return nil, fmt.Errorf("failed to read road graph: %w", err)
+	}
+	if graph.Version != roadGraphVersion {
+		return nil, fmt.Errorf("road graph version %d is outdated, re-run import-osm", graph.Version)
+	}
+
+	graph.buildGrid()
+	return &graph, nil
+}
+
+// NodeCount returns the number of nodes in the graph
+func (g *RoadGraph) NodeCount() int {
+	return len(g.Lat)
+}
+
+// RoadName returns the name or ref of the road an edge belongs to
+func (g *RoadGraph) RoadName(edge int32) string {
+	if idx := g.EdgeName[edge]; idx >= 0 && int(idx) < len(g.Names) {
+		return g.Names[idx]
+	}
+	return ""
+}
+
+// Nearest returns the closest node within maxKm that has an outgoing edge
+// accepted by usable, or -1 when there is none
+func (g *RoadGraph) Nearest(lat, lng, maxKm float64, usable func(edge int32) bool) int32 {
+	center := gridCell(lat, lng)
+	cellKm := roadGraphCellSize * 111 * math.Cos(lat*math.Pi/180) // narrowest side of a cell
+	rings := int32(math.Ceil(maxKm/cellKm)) + 1
+
+	best, bestDist := int32(-1), maxKm
+	for ring := int32(0); ring <= rings; ring++ {
+		for dy := -ring; dy <= ring; dy++ {
+			for dx := -ring; dx <= ring; dx++ {
+				if abs32(dx) != ring && abs32(dy) != ring {
+					continue // only the border of the ring
+				}
+				for _, node := range g.grid[[2]int32{center[0] + dy, center[1] + dx}] {
+					dist := HaversineKm(lat, lng, g.Lat[node], g.Lng[node])
+					if dist >= bestDist || !g.hasUsableEdge(node, usable) {
+						continue
+					}
+					best, bestDist = node, dist
+				}
+			}
+		}
+		// Nodes in further rings are at least ring*cellKm away
+		if best >= 0 && float64(ring)*cellKm >= bestDist {
+			break
+		}
+	}
+	return best
+}
+
+func (g *RoadGraph) hasUsableEdge(node int32, usable func(edge int32) bool) bool {
+	for e := g.EdgeStart[node]; e < g.EdgeStart[node+1]; e++ {
+		if usable == nil || usable(e) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *RoadGraph) buildGrid() {
+	g.grid = make(map[[2]int32][]int32)
+	for i := range g.Lat {
+		cell := gridCell(g.Lat[i], g.Lng[i])
+		g.grid[cell] = append(g.grid[cell], int32(i))
+	}
+}
+
+func gridCell(lat, lng float64) [2]int32 {
+	return [2]int32{int32(math.Floor(lat / roadGraphCellSize)), int32(math.Floor(lng / roadGraphCellSize))}
+}
+
+func abs32(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func readPBFFile(path string, handler pbfHandler) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return readPBF(bufio.NewReaderSize(file, 1<<20), handler)
+}
@@ -0,0 +1,177 @@
+// File: /services/route_collaboration_service.go
+package services
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+)
+
+var (
+	ErrNotRouteOwner           = errors.New("only the route owner can manage collaborators")
+	ErrCollaboratorNotFriend   = errors.New("collaborators must be friends of the route owner")
+	ErrCollaboratorNotFound    = errors.New("collaborator not found")
+	ErrInvalidCollaboratorRole = errors.New("role must be editor or viewer")
+	// ErrRouteVersionConflict means the route changed since the client loaded it
+	ErrRouteVersionConflict = errors.New("route was changed by someone else, reload it and apply your changes again")
+)
+
+// RouteCollaborationService manages who may view and edit a route besides its owner
+type RouteCollaborationService struct {
+	db *gorm.DB
+}
+
+func NewRouteCollaborationService(db *gorm.DB) *RouteCollaborationService {
+	return &RouteCollaborationService{db: db}
+}
+
+// LoadRoute returns a route with its collaborators, ready for the access checks of models.Route
+func (s *RouteCollaborationService) LoadRoute(routeID string) (*models.Route, error) {
+	var route models.Route
+	if err := s.db.Preload("Collaborators").First(&route, "id = ?", routeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRouteNotFound
+		}
+		return nil, err
+	}
+	return &route, nil
+}
+
+// List returns the collaborators of a route with their users
+func (s *RouteCollaborationService) List(routeID string) ([]models.RouteCollaborator, error) {
+	var collaborators []models.RouteCollaborator
+	err := s.db.Preload("User").Where("route_id = ?", routeID).Order("created_at ASC").Find(&collaborators).Error
+	return collaborators, err
+}
+
+// Invite adds a friend of the owner to a route, or changes the role of one already invited
+func (s *RouteCollaborationService) Invite(ownerID, routeID, userID, role string) (*models.RouteCollaborator, bool, error) {
+	if !models.IsValidRouteRole(role) {
+		return nil, false, ErrInvalidCollaboratorRole
+	}
+
+	route, err := s.LoadRoute(routeID)
+	if err != nil {
+		return nil, false, err
+	}
+	if route.UserID != ownerID {
+		return nil, false, ErrNotRouteOwner
+	}
+	if userID == ownerID || !s.areFriends(ownerID, userID) {
+		return nil, false, ErrCollaboratorNotFriend
+	}
+
+	var collaborator models.RouteCollaborator
+	err = s.db.Where("route_id = ? AND user_id = ?", routeID, userID).First(&collaborator).Error
+	created := errors.Is(err, gorm.ErrRecordNotFound)
+	switch {
+	case created:
+		collaborator = models.RouteCollaborator{
+			ID:          uuid.New().String(),
+			RouteID:     routeID,
+			UserID:      userID,
+			Role:        role,
+			InvitedByID: ownerID,
+		}
+		err = s.db.Create(&collaborator).Error
+	case err == nil:
+		err = s.db.Model(&collaborator).Update("role", role).Error
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	s.db.Preload("User").First(&collaborator, "id = ?", collaborator.ID)
+	return &collaborator, created, nil
+}
+
+// Remove takes a collaborator off a route. The owner can remove anyone,
+// collaborators can only leave themselves.
+func (s *RouteCollaborationService) Remove(actorID, routeID, userID string) error {
+	route, err := s.LoadRoute(routeID)
+	if err != nil {
+		return err
+	}
+	if route.UserID != actorID && actorID != userID {
+		return ErrNotRouteOwner
+	}
+
+	result := s.db.Where("route_id = ? AND user_id = ?", routeID, userID).Delete(&models.RouteCollaborator{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCollaboratorNotFound
+	}
+	return nil
+}
+
+// SharedWith returns the routes a user was invited to, most recently changed first
+func (s *RouteCollaborationService) SharedWith(userID string, limit, offset int) ([]models.Route, int64, error) {
+	var routes []models.Route
+	var total int64
+
+	query := s.db.Model(&models.Route{}).
+		Where("id IN (?)", s.db.Model(&models.RouteCollaborator{}).Select("route_id").Where("user_id = ?", userID))
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).Preload("Collaborators").Preload("User").
+		Order("updated_at DESC").Limit(limit).Offset(offset).Find(&routes).Error
+	return routes, total, err
+}
+
+// MemberIDs returns the owner and collaborators of a route except the given user,
+// i.e. who should hear about a change that user made
+func (s *RouteCollaborationService) MemberIDs(routeID, exceptUserID string) []string {
+	route, err := s.LoadRoute(routeID)
+	if err != nil {
+		return nil
+	}
+
+	var ids []string
+	if route.UserID != exceptUserID {
+		ids = append(ids, route.UserID)
+	}
+	for _, collaborator := range route.Collaborators {
+		if collaborator.UserID != exceptUserID {
+			ids = append(ids, collaborator.UserID)
+		}
+	}
+	return ids
+}
+
+// ClaimVersion raises the version of a route inside a change's transaction.
+// When expected is not zero the route must still be at that version,
+// otherwise ErrRouteVersionConflict is returned and the change must be
+// rolled back.
+func ClaimVersion(tx *gorm.DB, routeID string, expected int) error {
+	query := tx.Model(&models.Route{}).Where("id = ?", routeID)
+	if expected != 0 {
+		query = query.Where("version = ?", expected)
+	}
+
+	result := query.UpdateColumn("version", gorm.Expr("version + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRouteVersionConflict
+	}
+	return nil
+}
+
+func (s *RouteCollaborationService) areFriends(user1ID, user2ID string) bool {
+	if user1ID > user2ID {
+		user1ID, user2ID = user2ID, user1ID
+	}
+
+	var count int64
+	s.db.Model(&models.Friendship{}).Where("user1_id = ? AND user2_id = ?", user1ID, user2ID).Count(&count)
+	return count > 0
+}
@@ -0,0 +1,758 @@
+// File: /services/route_file.go
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"motocosmos-api/models"
+)
+
+// Route file formats for import and export
+const (
+	RouteFileGPX     = "gpx"
+	RouteFileKML     = "kml"
+	RouteFileGeoJSON = "geojson"
+)
+
+const (
+	maxRouteFileBytes  = 20 << 20
+	maxImportWaypoints = 50
+)
+
+var ErrInvalidRouteFile = errors.New("invalid route file")
+
+// RouteFileWaypoint is a named stop of a route file
+type RouteFileWaypoint struct {
+	Name        string
+	Description string
+	Latitude    float64
+	Longitude   float64
+}
+
+// RouteFile is a route read from or written to GPX, KML or GeoJSON: the
+// waypoints to plan through and the detailed track between them
+type RouteFile struct {
+	Name        string
+	Description string
+	Waypoints   []RouteFileWaypoint
+	Track       models.Geometry
+}
+
+// RouteFileFormat returns the format of a file name, json being read as GeoJSON
+func RouteFileFormat(filename string) (string, bool) {
+	switch strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), ".")) {
+	case "gpx":
+		return RouteFileGPX, true
+	case "kml":
+		return RouteFileKML, true
+	case "geojson", "json":
+		return RouteFileGeoJSON, true
+	}
+	return "", false
+}
+
+// IsValidRouteFileFormat reports whether a format can be imported and exported
+func IsValidRouteFileFormat(format string) bool {
+	return format == RouteFileGPX || format == RouteFileKML || format == RouteFileGeoJSON
+}
+
+// RouteFileContentType returns the MIME type of a format
+func RouteFileContentType(format string) string {
+	switch format {
+	case RouteFileGPX:
+		return "application/gpx+xml"
+	case RouteFileKML:
+		return "application/vnd.google-earth.kml+xml"
+	case RouteFileGeoJSON:
+		return "application/geo+json"
+	}
+	return "application/octet-stream"
+}
+
+// RouteFileName returns a download file name for a route title
+func RouteFileName(title, format string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			return r
+		case r == ' ':
+			return '_'
+		}
+		return -1
+	}, title)
+	if name == "" {
+		name = "route"
+	}
+	return name + "." + format
+}
+
+// ParseRouteFile reads a GPX (route or track), KML or GeoJSON file. The
+// waypoints come from the file's route points or placemarks, or are the ends
+// of the track when the file only has a track; the track falls back to the
+// waypoints when the file has no detailed geometry.
+func ParseRouteFile(r io.Reader, format string) (*RouteFile, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxRouteFileBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxRouteFileBytes {
+		return nil, fmt.Errorf("%w: file is larger than %d MB", ErrInvalidRouteFile, maxRouteFileBytes>>20)
+	}
+
+	var file *RouteFile
+	switch format {
+	case RouteFileGPX:
+		file, err = parseGPX(data)
+	case RouteFileKML:
+		file, err = parseKML(data)
+	case RouteFileGeoJSON:
+		file, err = parseGeoJSON(data)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidRouteFile, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRouteFile, err)
+	}
+
+	for _, p := range file.Track {
+		if !isValidLatitude(p.Latitude) || !isValidLongitude(p.Longitude) {
+			return nil, fmt.Errorf("%w: coordinate out of range", ErrInvalidRouteFile)
+		}
+	}
+	for _, wp := range file.Waypoints {
+		if !isValidLatitude(wp.Latitude) || !isValidLongitude(wp.Longitude) {
+			return nil, fmt.Errorf("%w: coordinate out of range", ErrInvalidRouteFile)
+		}
+	}
+
+	if len(file.Track) == 0 {
+		for _, wp := range file.Waypoints {
+			file.Track = append(file.Track, models.GeometryPoint{Latitude: wp.Latitude, Longitude: wp.Longitude})
+		}
+	}
+	if len(file.Track) < 2 {
+		return nil, fmt.Errorf("%w: the file needs a route or track with at least two points", ErrInvalidRouteFile)
+	}
+
+	if len(file.Waypoints) < 2 {
+		first, last := file.Track[0], file.Track[len(file.Track)-1]
+		file.Waypoints = []RouteFileWaypoint{
+			{Name: "Start", Latitude: first.Latitude, Longitude: first.Longitude},
+			{Name: "Finish", Latitude: last.Latitude, Longitude: last.Longitude},
+		}
+	}
+	file.Waypoints = thinWaypoints(file.Waypoints, maxImportWaypoints)
+
+	return file, nil
+}
+
+// thinWaypoints keeps the ends and evenly spread waypoints when a file has
+// more than max, e.g. a route exported point by point
+func thinWaypoints(waypoints []RouteFileWaypoint, max int) []RouteFileWaypoint {
+	if len(waypoints) <= max {
+		return waypoints
+	}
+	thinned := make([]RouteFileWaypoint, max)
+	for i := range thinned {
+		thinned[i] = waypoints[i*(len(waypoints)-1)/(max-1)]
+	}
+	return thinned
+}
+
+// GeometryAscent sums the climbing of a track from its own elevations, 0 when it has none
+func GeometryAscent(track models.Geometry) float64 {
+	var samples []models.ElevationSample
+	var distance float64
+	for i, p := range track {
+		if i > 0 {
+			prev := track[i-1]
+			distance += HaversineKm(prev.Latitude, prev.Longitude, p.Latitude, p.Longitude)
+		}
+		if p.Elevation != nil {
+			samples = append(samples, models.ElevationSample{Distance: distance, Latitude: p.Latitude, Longitude: p.Longitude, Elevation: *p.Elevation})
+		}
+	}
+	if len(samples) < 2 {
+		return 0
+	}
+
+	profile := &models.ElevationProfile{Samples: samples}
+	summarizeElevation(profile)
+	return profile.TotalAscent
+}
+
+// WriteRouteFile writes a route in one of the supported formats
+func WriteRouteFile(w io.Writer, format string, file *RouteFile) error {
+	switch format {
+	case RouteFileGPX:
+		return writeGPX(w, file)
+	case RouteFileKML:
+		return writeKML(w, file)
+	case RouteFileGeoJSON:
+		return writeGeoJSON(w, file)
+	}
+	return fmt.Errorf("%w: unsupported format %q", ErrInvalidRouteFile, format)
+}
+
+// GPX 1.0 and 1.1 share the elements read here; struct tags without a
+// namespace match either.
+
+type gpxPoint struct {
+	Lat       string   `xml:"lat,attr"`
+	Lon       string   `xml:"lon,attr"`
+	Elevation *float64 `xml:"ele,omitempty"`
+	Name      string   `xml:"name,omitempty"`
+	Desc      string   `xml:"desc,omitempty"`
+}
+
+type gpxRoute struct {
+	Name   string     `xml:"name,omitempty"`
+	Desc   string     `xml:"desc,omitempty"`
+	Points []gpxPoint `xml:"rtept"`
+}
+
+type gpxTrack struct {
+	Name     string       `xml:"name,omitempty"`
+	Desc     string       `xml:"desc,omitempty"`
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxMetadata struct {
+	Name string `xml:"name,omitempty"`
+	Desc string `xml:"desc,omitempty"`
+}
+
+type gpxDocument struct {
+	XMLName   xml.Name     `xml:"gpx"`
+	Version   string       `xml:"version,attr,omitempty"`
+	Creator   string       `xml:"creator,attr,omitempty"`
+	Xmlns     string       `xml:"xmlns,attr,omitempty"`
+	Metadata  *gpxMetadata `xml:"metadata,omitempty"`
+	Name      string       `xml:"name,omitempty"` // GPX 1.0 keeps it at the top level
+	Desc      string       `xml:"desc,omitempty"`
+	Waypoints []gpxPoint   `xml:"wpt"`
+	Routes    []gpxRoute   `xml:"rte"`
+	Tracks    []gpxTrack   `xml:"trk"`
+}
+
+func (p gpxPoint) geometryPoint() (models.GeometryPoint, error) {
+	lat, errLat := strconv.ParseFloat(strings.TrimSpace(p.Lat), 64)
+	lng, errLng := strconv.ParseFloat(strings.TrimSpace(p.Lon), 64)
+	if errLat != nil || errLng != nil {
+		return models.GeometryPoint{}, fmt.Errorf("point has an invalid lat/lon (%q, %q)", p.Lat, p.Lon)
+	}
+	return models.GeometryPoint{Latitude: lat, Longitude: lng, Elevation: p.Elevation}, nil
+}
+
+func (p gpxPoint) waypoint() (RouteFileWaypoint, error) {
+	point, err := p.geometryPoint()
+	if err != nil {
+		return RouteFileWaypoint{}, err
+	}
+	return RouteFileWaypoint{
+		Name:        strings.TrimSpace(p.Name),
+		Description: strings.TrimSpace(p.Desc),
+		Latitude:    point.Latitude,
+		Longitude:   point.Longitude,
+	}, nil
+}
+
+func parseGPX(data []byte) (*RouteFile, error) {
+	var doc gpxDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	file := &RouteFile{Name: doc.Name, Description: doc.Desc}
+	if doc.Metadata != nil && doc.Metadata.Name != "" {
+		file.Name, file.Description = doc.Metadata.Name, doc.Metadata.Desc
+	}
+
+	// Route points are the planned stops; loose waypoints only stand in for
+	// them when there is nothing else, as they are usually unordered POIs
+	stops := doc.Waypoints
+	if len(doc.Routes) > 0 {
+		stops = doc.Routes[0].Points
+		if file.Name == "" {
+			file.Name, file.Description = doc.Routes[0].Name, doc.Routes[0].Desc
+		}
+	} else if len(doc.Tracks) > 0 {
+		stops = nil
+	}
+	for _, p := range stops {
+		wp, err := p.waypoint()
+		if err != nil {
+			return nil, err
+		}
+		file.Waypoints = append(file.Waypoints, wp)
+	}
+
+	for _, track := range doc.Tracks {
+		if file.Name == "" {
+			file.Name, file.Description = track.Name, track.Desc
+		}
+		for _, segment := range track.Segments {
+			for _, p := range segment.Points {
+				point, err := p.geometryPoint()
+				if err != nil {
+					return nil, err
+				}
+				file.Track = append(file.Track, point)
+			}
+		}
+	}
+
+	// Without a track, a route's points are its geometry as well
+	if len(file.Track) == 0 && len(doc.Routes) > 0 {
+		for _, p := range doc.Routes[0].Points {
+			point, _ := p.geometryPoint()
+			file.Track = append(file.Track, point)
+		}
+	}
+
+	file.Name, file.Description = strings.TrimSpace(file.Name), strings.TrimSpace(file.Description)
+	return file, nil
+}
+
+func writeGPX(w io.Writer, file *RouteFile) error {
+	doc := gpxDocument{
+		Version:  "1.1",
+		Creator:  "MotoCosmos",
+		Xmlns:    "http://www.topografix.com/GPX/1/1",
+		Metadata: &gpxMetadata{Name: file.Name, Desc: file.Description},
+	}
+
+	route := gpxRoute{Name: file.Name}
+	for _, wp := range file.Waypoints {
+		route.Points = append(route.Points, gpxPoint{
+			Lat:  formatCoordinate(wp.Latitude),
+			Lon:  formatCoordinate(wp.Longitude),
+			Name: wp.Name,
+			Desc: wp.Description,
+		})
+	}
+	doc.Routes = []gpxRoute{route}
+
+	segment := gpxSegment{Points: make([]gpxPoint, len(file.Track))}
+	for i, p := range file.Track {
+		segment.Points[i] = gpxPoint{Lat: formatCoordinate(p.Latitude), Lon: formatCoordinate(p.Longitude), Elevation: p.Elevation}
+	}
+	doc.Tracks = []gpxTrack{{Name: file.Name, Segments: []gpxSegment{segment}}}
+
+	return writeXML(w, doc)
+}
+
+// KML is read token by token since placemarks can sit at any depth of
+// nested Documents and Folders
+
+type kmlPlacemark struct {
+	name        string
+	description string
+	point       []models.GeometryPoint
+	lines       [][]models.GeometryPoint
+}
+
+func parseKML(data []byte) (*RouteFile, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	file := &RouteFile{}
+
+	var stack []string
+	var text strings.Builder
+	var placemark *kmlPlacemark
+	var trackCoords []models.GeometryPoint
+	sawKML := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+			text.Reset()
+			switch t.Name.Local {
+			case "kml":
+				sawKML = true
+			case "Placemark":
+				placemark = &kmlPlacemark{}
+			case "Track":
+				trackCoords = nil
+			}
+
+		case xml.CharData:
+			text.Write(t)
+
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, errors.New("unbalanced KML elements")
+			}
+			parent := ""
+			if len(stack) > 1 {
+				parent = stack[len(stack)-2]
+			}
+			value := strings.TrimSpace(text.String())
+
+			switch t.Name.Local {
+			case "name":
+				if placemark != nil && parent == "Placemark" {
+					placemark.name = value
+				} else if placemark == nil && file.Name == "" && parent == "Document" {
+					file.Name = value
+				}
+			case "description":
+				if placemark != nil && parent == "Placemark" {
+					placemark.description = value
+				} else if placemark == nil && file.Description == "" && parent == "Document" {
+					file.Description = value
+				}
+			case "coordinates":
+				if placemark == nil {
+					break
+				}
+				coords, err := parseKMLCoordinates(value)
+				if err != nil {
+					return nil, err
+				}
+				switch parent {
+				case "Point":
+					placemark.point = coords
+				case "LineString":
+					placemark.lines = append(placemark.lines, coords)
+				}
+			case "coord":
+				// gx:Track holds one "lng lat alt" per gx:coord
+				coords, err := parseKMLCoordinates(strings.Join(strings.Fields(value), ","))
+				if err != nil {
+					return nil, err
+				}
+				trackCoords = append(trackCoords, coords...)
+			case "Track":
+				if placemark != nil && len(trackCoords) > 0 {
+					placemark.lines = append(placemark.lines, trackCoords)
+				}
+			case "Placemark":
+				if placemark != nil {
+					addKMLPlacemark(file, placemark)
+				}
+				placemark = nil
+			}
+
+			stack = stack[:len(stack)-1]
+			text.Reset()
+		}
+	}
+
+	if !sawKML {
+		return nil, errors.New("not a KML document")
+	}
+	return file, nil
+}
+
+func addKMLPlacemark(file *RouteFile, placemark *kmlPlacemark) {
+	if len(placemark.point) > 0 {
+		p := placemark.point[0]
+		file.Waypoints = append(file.Waypoints, RouteFileWaypoint{
+			Name:        placemark.name,
+			Description: placemark.description,
+			Latitude:    p.Latitude,
+			Longitude:   p.Longitude,
+		})
+	}
+	for _, line := range placemark.lines {
+		if file.Name == "" {
+			file.Name, file.Description = placemark.name, placemark.description
+		}
+		file.Track = append(file.Track, line...)
+	}
+}
+
+// parseKMLCoordinates reads whitespace separated "lng,lat[,alt]" tuples
+func parseKMLCoordinates(value string) ([]models.GeometryPoint, error) {
+	var points []models.GeometryPoint
+	for _, tuple := range strings.Fields(value) {
+		parts := strings.Split(tuple, ",")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid KML coordinate %q", tuple)
+		}
+		lng, errLng := strconv.ParseFloat(parts[0], 64)
+		lat, errLat := strconv.ParseFloat(parts[1], 64)
+		if errLng != nil || errLat != nil {
+			return nil, fmt.Errorf("invalid KML coordinate %q", tuple)
+		}
+		point := models.GeometryPoint{Latitude: lat, Longitude: lng}
+		if len(parts) > 2 {
+			if alt, err := strconv.ParseFloat(parts[2], 64); err == nil {
+				point.Elevation = &alt
+			}
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlLineString struct {
+	Tessellate  int    `xml:"tessellate"`
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlPlacemarkElement struct {
+	Name        string         `xml:"name,omitempty"`
+	Description string         `xml:"description,omitempty"`
+	Point       *kmlPoint      `xml:"Point,omitempty"`
+	LineString  *kmlLineString `xml:"LineString,omitempty"`
+}
+
+type kmlDocument struct {
+	XMLName xml.Name `xml:"kml"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Doc     struct {
+		Name        string                `xml:"name,omitempty"`
+		Description string                `xml:"description,omitempty"`
+		Placemarks  []kmlPlacemarkElement `xml:"Placemark"`
+	} `xml:"Document"`
+}
+
+func writeKML(w io.Writer, file *RouteFile) error {
+	doc := kmlDocument{Xmlns: "http://www.opengis.net/kml/2.2"}
+	doc.Doc.Name = file.Name
+	doc.Doc.Description = file.Description
+
+	coords := make([]string, len(file.Track))
+	for i, p := range file.Track {
+		coords[i] = formatCoordinate(p.Longitude) + "," + formatCoordinate(p.Latitude)
+		if p.Elevation != nil {
+			coords[i] += "," + strconv.FormatFloat(*p.Elevation, 'f', -1, 64)
+		}
+	}
+	doc.Doc.Placemarks = append(doc.Doc.Placemarks, kmlPlacemarkElement{
+		Name:       file.Name,
+		LineString: &kmlLineString{Tessellate: 1, Coordinates: strings.Join(coords, " ")},
+	})
+
+	for _, wp := range file.Waypoints {
+		doc.Doc.Placemarks = append(doc.Doc.Placemarks, kmlPlacemarkElement{
+			Name:        wp.Name,
+			Description: wp.Description,
+			Point:       &kmlPoint{Coordinates: formatCoordinate(wp.Longitude) + "," + formatCoordinate(wp.Latitude)},
+		})
+	}
+
+	return writeXML(w, doc)
+}
+
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// formatCoordinate writes degrees without an exponent, as GPX and KML expect decimals
+func formatCoordinate(value float64) string {
+	return strconv.FormatFloat(value, 'f', 7, 64)
+}
+
+type geoJSONObject struct {
+	Type        string                 `json:"type"`
+	Features    []geoJSONObject        `json:"features,omitempty"`
+	Geometry    *geoJSONObject         `json:"geometry,omitempty"`
+	Geometries  []geoJSONObject        `json:"geometries,omitempty"`
+	Coordinates json.RawMessage        `json:"coordinates,omitempty"`
+	Properties  map[string]interface{} `json:"properties,omitempty"`
+}
+
+func parseGeoJSON(data []byte) (*RouteFile, error) {
+	var root geoJSONObject
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	file := &RouteFile{}
+	if err := addGeoJSON(file, root, nil); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// addGeoJSON collects Points as waypoints and LineStrings as the track;
+// properties are those of the enclosing feature
+func addGeoJSON(file *RouteFile, object geoJSONObject, properties map[string]interface{}) error {
+	name, _ := properties["name"].(string)
+	description, _ := properties["description"].(string)
+
+	switch object.Type {
+	case "FeatureCollection":
+		for _, feature := range object.Features {
+			if err := addGeoJSON(file, feature, nil); err != nil {
+				return err
+			}
+		}
+	case "Feature":
+		if object.Geometry != nil {
+			return addGeoJSON(file, *object.Geometry, object.Properties)
+		}
+	case "GeometryCollection":
+		for _, geometry := range object.Geometries {
+			if err := addGeoJSON(file, geometry, properties); err != nil {
+				return err
+			}
+		}
+	case "Point":
+		var position []float64
+		if err := json.Unmarshal(object.Coordinates, &position); err != nil {
+			return err
+		}
+		point, err := geoJSONPosition(position)
+		if err != nil {
+			return err
+		}
+		file.Waypoints = append(file.Waypoints, RouteFileWaypoint{Name: name, Description: description, Latitude: point.Latitude, Longitude: point.Longitude})
+	case "MultiPoint", "LineString":
+		var positions [][]float64
+		if err := json.Unmarshal(object.Coordinates, &positions); err != nil {
+			return err
+		}
+		if object.Type == "MultiPoint" {
+			for _, position := range positions {
+				point, err := geoJSONPosition(position)
+				if err != nil {
+					return err
+				}
+				file.Waypoints = append(file.Waypoints, RouteFileWaypoint{Latitude: point.Latitude, Longitude: point.Longitude})
+			}
+			return nil
+		}
+		return addGeoJSONLine(file, positions, name, description)
+	case "MultiLineString":
+		var lines [][][]float64
+		if err := json.Unmarshal(object.Coordinates, &lines); err != nil {
+			return err
+		}
+		for _, line := range lines {
+			if err := addGeoJSONLine(file, line, name, description); err != nil {
+				return err
+			}
+		}
+	case "Polygon", "MultiPolygon":
+		// areas are not routes
+	default:
+		return fmt.Errorf("unknown GeoJSON type %q", object.Type)
+	}
+	return nil
+}
+
+func addGeoJSONLine(file *RouteFile, positions [][]float64, name, description string) error {
+	if file.Name == "" {
+		file.Name, file.Description = name, description
+	}
+	for _, position := range positions {
+		point, err := geoJSONPosition(position)
+		if err != nil {
+			return err
+		}
+		file.Track = append(file.Track, point)
+	}
+	return nil
+}
+
+func geoJSONPosition(position []float64) (models.GeometryPoint, error) {
+	if len(position) < 2 {
+		return models.GeometryPoint{}, errors.New("GeoJSON position needs longitude and latitude")
+	}
+	point := models.GeometryPoint{Latitude: position[1], Longitude: position[0]}
+	if len(position) > 2 {
+		elevation := position[2]
+		point.Elevation = &elevation
+	}
+	return point, nil
+}
+
+func writeGeoJSON(w io.Writer, file *RouteFile) error {
+	line := make([][]float64, len(file.Track))
+	for i, p := range file.Track {
+		line[i] = []float64{roundToDecimal(p.Longitude, 7), roundToDecimal(p.Latitude, 7)}
+		if p.Elevation != nil {
+			line[i] = append(line[i], *p.Elevation)
+		}
+	}
+
+	type feature struct {
+		Type       string                 `json:"type"`
+		Geometry   map[string]interface{} `json:"geometry"`
+		Properties map[string]interface{} `json:"properties"`
+	}
+	features := []feature{{
+		Type:       "Feature",
+		Geometry:   map[string]interface{}{"type": "LineString", "coordinates": line},
+		Properties: map[string]interface{}{"name": file.Name, "description": file.Description},
+	}}
+	for i, wp := range file.Waypoints {
+		features = append(features, feature{
+			Type:       "Feature",
+			Geometry:   map[string]interface{}{"type": "Point", "coordinates": []float64{roundToDecimal(wp.Longitude, 7), roundToDecimal(wp.Latitude, 7)}},
+			Properties: map[string]interface{}{"name": wp.Name, "description": wp.Description, "order": i + 1},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{"type": "FeatureCollection", "features": features})
+}
+
+// RouteFileFromRoute prepares a personal route for export; waypoints must be loaded in order
+func RouteFileFromRoute(route *models.Route) *RouteFile {
+	file := &RouteFile{Name: route.Name, Description: route.Description, Track: route.RouteGeometry}
+	for _, wp := range route.Waypoints {
+		file.Waypoints = append(file.Waypoints, RouteFileWaypoint{
+			Name:        wp.Name,
+			Description: wp.Description,
+			Latitude:    wp.Latitude,
+			Longitude:   wp.Longitude,
+		})
+	}
+	if len(file.Track) == 0 {
+		file.Track = models.GeometryFromLatLngs(route.GetWaypointsAsLatLng())
+	}
+	return file
+}
+
+// RouteFileFromSharedRoute prepares a shared route for export; shared routes
+// only store their geometry, so the stops are its start and finish
+func RouteFileFromSharedRoute(route *models.SharedRoute) *RouteFile {
+	file := &RouteFile{Name: route.Title, Description: route.Description, Track: route.RoutePoints}
+	if len(file.Track) > 0 {
+		first, last := file.Track[0], file.Track[len(file.Track)-1]
+		file.Waypoints = []RouteFileWaypoint{
+			{Name: "Start", Latitude: first.Latitude, Longitude: first.Longitude},
+			{Name: "Finish", Latitude: last.Latitude, Longitude: last.Longitude},
+		}
+	}
+	return file
+}
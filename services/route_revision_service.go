@@ -0,0 +1,321 @@
+// File: /services/route_revision_service.go
+package services
+
+import (
+	"errors"
+	"math"
+	"reflect"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+)
+
+const (
+	// waypointSameSpotMeters is how close two waypoints must be to count as the same stop
+	waypointSameSpotMeters = 25.0
+	// waypointMovedMeters is how far a matched waypoint must shift to be reported as moved
+	waypointMovedMeters = 5.0
+)
+
+var ErrRevisionNotFound = errors.New("route revision not found")
+
+type RouteRevisionService struct {
+	db *gorm.DB
+}
+
+func NewRouteRevisionService(db *gorm.DB) *RouteRevisionService {
+	return &RouteRevisionService{db: db}
+}
+
+// Record saves the current state of a route as its next revision
+func (s *RouteRevisionService) Record(routeID, authorID, action string) (*models.RouteRevision, error) {
+	var revision *models.RouteRevision
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		revision, err = recordRevision(tx, routeID, authorID, action, nil)
+		return err
+	})
+	return revision, err
+}
+
+// EnsureBaseline saves the current state of a route that has no revisions
+// yet, so routes created before revisions were kept can be restored too.
+// Call it before changing the route.
+func (s *RouteRevisionService) EnsureBaseline(routeID string) error {
+	var count int64
+	if err := s.db.Model(&models.RouteRevision{}).Where("route_id = ?", routeID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var route models.Route
+	if err := s.db.Select("id", "user_id").First(&route, "id = ?", routeID).Error; err != nil {
+		return err
+	}
+	_, err := s.Record(routeID, route.UserID, models.RouteRevisionBaseline)
+	return err
+}
+
+func recordRevision(tx *gorm.DB, routeID, authorID, action string, restoredFrom *int) (*models.RouteRevision, error) {
+	var route models.Route
+	if err := tx.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).First(&route, "id = ?", routeID).Error; err != nil {
+		return nil, err
+	}
+
+	var last int
+	if err := tx.Model(&models.RouteRevision{}).Where("route_id = ?", routeID).
+		Select("COALESCE(MAX(number), 0)").Scan(&last).Error; err != nil {
+		return nil, err
+	}
+
+	waypoints := make(models.RevisionWaypoints, len(route.Waypoints))
+	for i, wp := range route.Waypoints {
+		waypoints[i] = models.RevisionWaypoint{
+			Name:        wp.Name,
+			Description: wp.Description,
+			Latitude:    wp.Latitude,
+			Longitude:   wp.Longitude,
+			Order:       wp.Order,
+			POIID:       wp.POIID,
+		}
+	}
+
+	revision := &models.RouteRevision{
+		ID:             uuid.New().String(),
+		RouteID:        routeID,
+		Number:         last + 1,
+		AuthorID:       authorID,
+		Action:         action,
+		RestoredFrom:   restoredFrom,
+		Name:           route.Name,
+		Description:    route.Description,
+		Difficulty:     route.Difficulty,
+		Tags:           route.Tags,
+		IsPublic:       route.IsPublic,
+		TotalDistance:  route.TotalDistance,
+		TotalElevation: route.TotalElevation,
+		EstimatedTime:  route.EstimatedTime,
+		RouteGeometry:  route.RouteGeometry,
+		RouteSettings:  route.RouteSettings,
+		Waypoints:      waypoints,
+	}
+	if err := tx.Create(revision).Error; err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// List returns the revisions of a route, newest first, without their geometry
+func (s *RouteRevisionService) List(routeID string, limit, offset int) ([]models.RouteRevision, int64, error) {
+	var revisions []models.RouteRevision
+	var total int64
+
+	query := s.db.Model(&models.RouteRevision{}).Where("route_id = ?", routeID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Omit("route_geometry").Preload("Author").
+		Order("number DESC").Limit(limit).Offset(offset).Find(&revisions).Error
+	return revisions, total, err
+}
+
+// Get returns one revision of a route with its geometry
+func (s *RouteRevisionService) Get(routeID string, number int) (*models.RouteRevision, error) {
+	var revision models.RouteRevision
+	if err := s.db.Preload("Author").Where("route_id = ? AND number = ?", routeID, number).First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// Diff compares revision from with revision to; from 0 is an empty route
+func (s *RouteRevisionService) Diff(routeID string, from, to int) (*models.RouteRevisionDiff, error) {
+	older := &models.RouteRevision{}
+	if from > 0 {
+		var err error
+		if older, err = s.Get(routeID, from); err != nil {
+			return nil, err
+		}
+	}
+	newer, err := s.Get(routeID, to)
+	if err != nil {
+		return nil, err
+	}
+	return DiffRevisions(older, newer), nil
+}
+
+// Revert restores a route to an earlier revision and records the restored
+// state as a new revision, so a revert can itself be undone. A non-zero
+// expectedVersion must match the route's version, see ClaimVersion.
+func (s *RouteRevisionService) Revert(routeID string, number int, authorID string, expectedVersion int) (*models.RouteRevision, error) {
+	target, err := s.Get(routeID, number)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := models.Route{RouteGeometry: target.RouteGeometry}
+	for _, wp := range target.Waypoints {
+		snapshot.Waypoints = append(snapshot.Waypoints, models.RouteWaypoint{Latitude: wp.Latitude, Longitude: wp.Longitude, Order: wp.Order})
+	}
+	curvature := AnalyzeCurvature(RoutePath(&snapshot))
+
+	var revision *models.RouteRevision
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := ClaimVersion(tx, routeID, expectedVersion); err != nil {
+			return err
+		}
+
+		err := tx.Model(&models.Route{ID: routeID}).Updates(map[string]interface{}{
+			"name":             target.Name,
+			"description":      target.Description,
+			"difficulty":       target.Difficulty,
+			"tags":             target.Tags,
+			"is_public":        target.IsPublic,
+			"total_distance":   target.TotalDistance,
+			"total_elevation":  target.TotalElevation,
+			"estimated_time":   target.EstimatedTime,
+			"route_geometry":   target.RouteGeometry,
+			"route_settings":   target.RouteSettings,
+			"curvature":        &curvature,
+			"twistiness_score": curvature.Score,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("route_id = ?", routeID).Delete(&models.RouteWaypoint{}).Error; err != nil {
+			return err
+		}
+		for _, wp := range target.Waypoints {
+			waypoint := models.RouteWaypoint{
+				RouteID:     routeID,
+				Name:        wp.Name,
+				Description: wp.Description,
+				Latitude:    wp.Latitude,
+				Longitude:   wp.Longitude,
+				Order:       wp.Order,
+				POIID:       wp.POIID,
+			}
+			if err := tx.Create(&waypoint).Error; err != nil {
+				return err
+			}
+		}
+
+		revision, err = recordRevision(tx, routeID, authorID, models.RouteRevisionReverted, &target.Number)
+		return err
+	})
+	return revision, err
+}
+
+// DiffRevisions lists what changed from one revision to another. Waypoints
+// are matched by name, then by position, then unnamed ones by their place in
+// the route; what stays unmatched was added or removed.
+func DiffRevisions(from, to *models.RouteRevision) *models.RouteRevisionDiff {
+	diff := &models.RouteRevisionDiff{
+		From:            from.Number,
+		To:              to.Number,
+		ChangedFields:   []string{},
+		Added:           []models.RevisionWaypoint{},
+		Removed:         []models.RevisionWaypoint{},
+		Moved:           []models.WaypointMove{},
+		Reordered:       []models.WaypointReorder{},
+		GeometryChanged: !reflect.DeepEqual(from.RouteGeometry.LatLngs(), to.RouteGeometry.LatLngs()),
+		DistanceChange:  roundToDecimal(to.TotalDistance-from.TotalDistance, 2),
+		ElevationChange: roundToDecimal(to.TotalElevation-from.TotalElevation, 1),
+		TimeChange:      to.EstimatedTime - from.EstimatedTime,
+	}
+
+	fields := []struct {
+		name    string
+		changed bool
+	}{
+		{"name", from.Name != to.Name},
+		{"description", from.Description != to.Description},
+		{"difficulty", from.Difficulty != to.Difficulty},
+		{"tags", !reflect.DeepEqual([]string(from.Tags), []string(to.Tags)) && len(from.Tags)+len(to.Tags) > 0},
+		{"is_public", from.IsPublic != to.IsPublic},
+		{"route_settings", !reflect.DeepEqual(from.RouteSettings, to.RouteSettings) && len(from.RouteSettings)+len(to.RouteSettings) > 0},
+	}
+	for _, field := range fields {
+		if field.changed {
+			diff.ChangedFields = append(diff.ChangedFields, field.name)
+		}
+	}
+
+	matches := matchWaypoints(from.Waypoints, to.Waypoints)
+	matchedNew := make(map[int]bool)
+	for oldIndex, old := range from.Waypoints {
+		newIndex, ok := matches[oldIndex]
+		if !ok {
+			diff.Removed = append(diff.Removed, old)
+			continue
+		}
+		matchedNew[newIndex] = true
+		current := to.Waypoints[newIndex]
+
+		if meters := waypointDistanceMeters(old, current); meters > waypointMovedMeters {
+			diff.Moved = append(diff.Moved, models.WaypointMove{
+				Name:     current.Name,
+				From:     models.LatLng{Latitude: old.Latitude, Longitude: old.Longitude},
+				To:       models.LatLng{Latitude: current.Latitude, Longitude: current.Longitude},
+				Distance: math.Round(meters),
+			})
+		}
+		if old.Order != current.Order {
+			diff.Reordered = append(diff.Reordered, models.WaypointReorder{Name: current.Name, FromOrder: old.Order, ToOrder: current.Order})
+		}
+	}
+	for newIndex, current := range to.Waypoints {
+		if !matchedNew[newIndex] {
+			diff.Added = append(diff.Added, current)
+		}
+	}
+
+	return diff
+}
+
+// matchWaypoints pairs old waypoint indexes with new ones
+func matchWaypoints(from, to []models.RevisionWaypoint) map[int]int {
+	matches := make(map[int]int)
+	taken := make(map[int]bool)
+
+	pair := func(accept func(old, current models.RevisionWaypoint) bool) {
+		for i, old := range from {
+			if _, ok := matches[i]; ok {
+				continue
+			}
+			for j, current := range to {
+				if !taken[j] && accept(old, current) {
+					matches[i], taken[j] = j, true
+					break
+				}
+			}
+		}
+	}
+
+	pair(func(old, current models.RevisionWaypoint) bool {
+		return old.Name != "" && old.Name == current.Name
+	})
+	pair(func(old, current models.RevisionWaypoint) bool {
+		return waypointDistanceMeters(old, current) <= waypointSameSpotMeters
+	})
+	// Unnamed stops dragged further than the same spot keep their place in the route
+	pair(func(old, current models.RevisionWaypoint) bool {
+		return old.Name == current.Name && old.Order == current.Order
+	})
+
+	return matches
+}
+
+func waypointDistanceMeters(a, b models.RevisionWaypoint) float64 {
+	return HaversineKm(a.Latitude, a.Longitude, b.Latitude, b.Longitude) * 1000
+}
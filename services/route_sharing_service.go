@@ -0,0 +1,523 @@
+// File: /services/route_sharing_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+)
+
+var (
+	ErrSharedRouteNotFound = errors.New("shared route not found")
+	ErrNotRoutePublisher   = errors.New("only the route owner can publish the route")
+	ErrRouteNotPublished   = errors.New("route is not published")
+	ErrSharedRouteSynced   = errors.New("this shared route is published from a personal route, edit that route instead")
+)
+
+// maxLineageDepth bounds the walk up a fork chain
+const maxLineageDepth = 50
+
+// PublishInput holds what publishing adds to a personal route
+type PublishInput struct {
+	ImageUrls     []string // nil keeps the images of an already published route
+	CreatorName   string
+	CreatorAvatar string
+}
+
+// LineageEntry is one shared route in an attribution chain
+type LineageEntry struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	CreatorID   string `json:"creator_id"`
+	CreatorName string `json:"creator_name"`
+}
+
+// RouteLineage is where a shared route comes from and what was made of it
+type RouteLineage struct {
+	Ancestors             []LineageEntry `json:"ancestors"` // the route it was forked from first, the original last
+	Forks                 []LineageEntry `json:"forks"`     // shared routes published from its forks
+	ForksCount            int            `json:"forks_count"`
+	LikesCount            int            `json:"likes_count"`
+	DownloadsCount        int            `json:"downloads_count"`
+	LineageLikesCount     int            `json:"lineage_likes_count"`
+	LineageDownloadsCount int            `json:"lineage_downloads_count"`
+}
+
+// RouteSharingService links personal routes and shared routes: a published
+// route is mirrored into a shared route on every change, and a fork of a
+// shared route keeps a reference to it so credit flows up the chain
+type RouteSharingService struct {
+	db                *gorm.DB
+	spatialService    *SpatialService
+	difficultyService *DifficultyService
+	revisionService   *RouteRevisionService
+}
+
+func NewRouteSharingService(db *gorm.DB, difficultyService *DifficultyService) *RouteSharingService {
+	return &RouteSharingService{
+		db:                db,
+		spatialService:    NewSpatialService(db),
+		difficultyService: difficultyService,
+		revisionService:   NewRouteRevisionService(db),
+	}
+}
+
+// Publishing returns the shared route a personal route is published as, or nil
+func (s *RouteSharingService) Publishing(routeID string) (*models.SharedRoute, error) {
+	var shared models.SharedRoute
+	if err := s.db.First(&shared, "source_route_id = ?", routeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &shared, nil
+}
+
+// Publish makes a personal route public as a shared route, or refreshes the
+// shared route it is already published as; created reports which
+func (s *RouteSharingService) Publish(userID, routeID string, input PublishInput) (*models.SharedRoute, bool, error) {
+	route, err := s.loadRoute(routeID)
+	if err != nil {
+		return nil, false, err
+	}
+	if route.UserID != userID {
+		return nil, false, ErrNotRoutePublisher
+	}
+	if len(RoutePath(route)) < 2 {
+		return nil, false, ErrRouteGeometryMissing
+	}
+
+	shared, err := s.Publishing(routeID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	created := shared == nil
+	if created {
+		shared = &models.SharedRoute{
+			ID:            uuid.New().String(),
+			CreatorID:     userID,
+			CreatorName:   input.CreatorName,
+			CreatorAvatar: input.CreatorAvatar,
+			ImageUrls:     models.StringSlice(input.ImageUrls),
+			SourceRouteID: &route.ID,
+		}
+	}
+	mirrorRoute(shared, route)
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Route{}).Where("id = ?", routeID).UpdateColumn("is_public", true).Error; err != nil {
+			return err
+		}
+		if created {
+			return tx.Create(shared).Error
+		}
+
+		columns := mirroredColumns
+		if input.ImageUrls != nil {
+			shared.ImageUrls = models.StringSlice(input.ImageUrls)
+			columns = append([]string{"image_urls"}, columns...)
+		}
+		return tx.Model(shared).Select(columns).Updates(shared).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := s.spatialService.IndexRoute(models.RouteGeoCellOwnerSharedRoute, shared.ID, RoutePath(route)); err != nil {
+		fmt.Printf("Warning: Could not index shared route location: %v\n", err)
+	}
+
+	if err := s.db.First(shared, "id = ?", shared.ID).Error; err != nil {
+		return nil, false, err
+	}
+	return shared, created, nil
+}
+
+// Unpublish removes the shared route a personal route is published as and
+// makes the route private again
+func (s *RouteSharingService) Unpublish(userID, routeID string) error {
+	route, err := s.loadRoute(routeID)
+	if err != nil {
+		return err
+	}
+	if route.UserID != userID {
+		return ErrNotRoutePublisher
+	}
+
+	shared, err := s.Publishing(routeID)
+	if err != nil {
+		return err
+	}
+	if shared == nil {
+		return ErrRouteNotPublished
+	}
+	return s.Remove(shared)
+}
+
+// Sync mirrors a changed personal route into the shared route it is
+// published as; unpublished routes are left alone. A published route stays
+// public until it is unpublished, also when a revert restores an older flag.
+// Call it after the route's difficulty was classified, which it copies.
+func (s *RouteSharingService) Sync(routeID string) error {
+	shared, err := s.Publishing(routeID)
+	if err != nil || shared == nil {
+		return err
+	}
+
+	route, err := s.loadRoute(routeID)
+	if err != nil {
+		return err
+	}
+	if !route.IsPublic {
+		if err := s.db.Model(route).UpdateColumn("is_public", true).Error; err != nil {
+			return err
+		}
+	}
+
+	mirrorRoute(shared, route)
+	if err := s.db.Model(shared).Select(mirroredColumns).Updates(shared).Error; err != nil {
+		return err
+	}
+	return s.spatialService.IndexRoute(models.RouteGeoCellOwnerSharedRoute, shared.ID, RoutePath(route))
+}
+
+// mirroredColumns are the columns of a shared route that follow its source route
+var mirroredColumns = []string{
+	"title", "description", "route_points", "total_distance", "total_elevation", "estimated_duration",
+	"difficulty", "computed_difficulty", "difficulty_factors", "tags", "curvature", "twistiness_score", "forked_from_id",
+}
+
+// mirrorRoute copies the mirrored fields of a personal route into its shared route
+func mirrorRoute(shared *models.SharedRoute, route *models.Route) {
+	shared.Title = route.Name
+	shared.Description = route.Description
+	shared.RoutePoints = route.RouteGeometry
+	if len(shared.RoutePoints) < 2 {
+		shared.RoutePoints = models.GeometryFromLatLngs(route.GetWaypointsAsLatLng())
+	}
+	shared.TotalDistance = route.TotalDistance
+	shared.TotalElevation = route.TotalElevation
+	shared.EstimatedDuration = route.EstimatedTime
+	shared.Difficulty = route.Difficulty
+	if shared.Difficulty == "" {
+		shared.Difficulty = route.ComputedDifficulty
+	}
+	shared.ComputedDifficulty = route.ComputedDifficulty
+	shared.DifficultyFactors = route.DifficultyFactors
+	shared.Tags = route.Tags
+	shared.Curvature = route.Curvature
+	shared.TwistinessScore = route.TwistinessScore
+	shared.ForkedFromID = route.ForkedFromID
+}
+
+// Remove deletes a shared route. Its forks are credited to the route it was
+// forked from instead, and the lineage counts above it lose its own likes and
+// downloads. A personal route it was published from becomes private.
+func (s *RouteSharingService) Remove(shared *models.SharedRoute) error {
+	ancestors, err := s.ancestorIDs(shared)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, related := range []interface{}{&models.SharedRouteLike{}, &models.SharedRouteBookmark{}} {
+			if err := tx.Where("route_id = ?", shared.ID).Delete(related).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("shared_route_id = ?", shared.ID).Delete(&models.SharedRoutePOI{}).Error; err != nil {
+			return err
+		}
+
+		if len(ancestors) > 0 {
+			err := tx.Model(&models.SharedRoute{}).Where("id IN ?", ancestors).UpdateColumns(map[string]interface{}{
+				"lineage_likes_count":     gorm.Expr("lineage_likes_count - ?", shared.LikesCount),
+				"lineage_downloads_count": gorm.Expr("lineage_downloads_count - ?", shared.DownloadsCount),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		// Hand the forks down to the parent so the chain stays connected
+		if err := tx.Model(&models.SharedRoute{}).Where("forked_from_id = ?", shared.ID).
+			UpdateColumn("forked_from_id", shared.ForkedFromID).Error; err != nil {
+			return err
+		}
+		forks := tx.Model(&models.Route{}).Where("forked_from_id = ?", shared.ID).UpdateColumn("forked_from_id", shared.ForkedFromID)
+		if forks.Error != nil {
+			return forks.Error
+		}
+		if shared.ForkedFromID != nil && forks.RowsAffected > 0 {
+			if err := tx.Model(&models.SharedRoute{}).Where("id = ?", *shared.ForkedFromID).
+				UpdateColumn("forks_count", gorm.Expr("forks_count + ?", forks.RowsAffected)).Error; err != nil {
+				return err
+			}
+		}
+
+		if shared.SourceRouteID != nil {
+			if err := tx.Model(&models.Route{}).Where("id = ?", *shared.SourceRouteID).UpdateColumn("is_public", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(shared).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.spatialService.RemoveRoute(models.RouteGeoCellOwnerSharedRoute, shared.ID)
+}
+
+// Fork copies a shared route into a new personal route of the user that
+// remembers where it came from. Routes published from a personal route are
+// forked with that route's waypoints and settings; others get a start and a
+// finish.
+func (s *RouteSharingService) Fork(userID, sharedRouteID, name string) (*models.Route, error) {
+	var shared models.SharedRoute
+	if err := s.db.First(&shared, "id = ?", sharedRouteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSharedRouteNotFound
+		}
+		return nil, err
+	}
+	if len(shared.RoutePoints) < 2 {
+		return nil, ErrRouteGeometryMissing
+	}
+
+	if name == "" {
+		name = shared.Title
+	}
+	settings := models.JSONData{}
+	var waypoints []models.RouteWaypoint
+	if shared.SourceRouteID != nil {
+		if source, err := s.loadRoute(*shared.SourceRouteID); err == nil {
+			for k, v := range source.RouteSettings {
+				settings[k] = v
+			}
+			for _, wp := range source.Waypoints {
+				waypoints = append(waypoints, models.RouteWaypoint{
+					Name:        wp.Name,
+					Description: wp.Description,
+					Latitude:    wp.Latitude,
+					Longitude:   wp.Longitude,
+					Order:       wp.Order,
+					POIID:       wp.POIID,
+				})
+			}
+		}
+	}
+	if len(waypoints) < 2 {
+		first, last := shared.RoutePoints[0], shared.RoutePoints[len(shared.RoutePoints)-1]
+		waypoints = []models.RouteWaypoint{
+			{Name: "Start", Latitude: first.Latitude, Longitude: first.Longitude, Order: 1},
+			{Name: "Finish", Latitude: last.Latitude, Longitude: last.Longitude, Order: 2},
+		}
+	}
+	settings["forked_from"] = shared.ID
+
+	route := models.Route{
+		ID:              uuid.New().String(),
+		UserID:          userID,
+		Name:            name,
+		Description:     shared.Description,
+		TotalDistance:   shared.TotalDistance,
+		TotalElevation:  shared.TotalElevation,
+		EstimatedTime:   shared.EstimatedDuration,
+		Difficulty:      shared.Difficulty,
+		Tags:            shared.Tags,
+		RouteGeometry:   shared.RoutePoints,
+		RouteSettings:   settings,
+		Curvature:       shared.Curvature,
+		TwistinessScore: shared.TwistinessScore,
+		ForkedFromID:    &shared.ID,
+		Waypoints:       waypoints,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Waypoints are created with the route through the association
+		if err := tx.Create(&route).Error; err != nil {
+			return err
+		}
+		return tx.Model(&shared).UpdateColumn("forks_count", gorm.Expr("forks_count + ?", 1)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.spatialService.IndexRoute(models.RouteGeoCellOwnerRoute, route.ID, route.RouteGeometry.LatLngs()); err != nil {
+		fmt.Printf("Warning: Could not index route location: %v\n", err)
+	}
+	if err := s.difficultyService.ClassifyRoute(route.ID); err != nil {
+		fmt.Printf("Warning: Could not classify route difficulty: %v\n", err)
+	}
+	if _, err := s.revisionService.Record(route.ID, userID, models.RouteRevisionForked); err != nil {
+		fmt.Printf("Warning: Could not save route revision: %v\n", err)
+	}
+
+	if err := s.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).First(&route, "id = ?", route.ID).Error; err != nil {
+		return nil, err
+	}
+	return &route, nil
+}
+
+// CountLike adds delta to a shared route's likes and to the lineage likes of
+// it and every route above it
+func (s *RouteSharingService) CountLike(shared *models.SharedRoute, delta int) error {
+	return s.count(shared, "likes_count", "lineage_likes_count", delta)
+}
+
+// CountDownload counts a download of a shared route along its lineage
+func (s *RouteSharingService) CountDownload(shared *models.SharedRoute) error {
+	return s.count(shared, "downloads_count", "lineage_downloads_count", 1)
+}
+
+func (s *RouteSharingService) count(shared *models.SharedRoute, column, lineageColumn string, delta int) error {
+	ancestors, err := s.ancestorIDs(shared)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(shared).UpdateColumn(column, gorm.Expr(column+" + ?", delta)).Error; err != nil {
+			return err
+		}
+		ids := append([]string{shared.ID}, ancestors...)
+		return tx.Model(&models.SharedRoute{}).Where("id IN ?", ids).
+			UpdateColumn(lineageColumn, gorm.Expr(lineageColumn+" + ?", delta)).Error
+	})
+}
+
+// Lineage returns the attribution chain of a shared route and its forks
+func (s *RouteSharingService) Lineage(sharedRouteID string) (*RouteLineage, error) {
+	var shared models.SharedRoute
+	if err := s.db.First(&shared, "id = ?", sharedRouteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSharedRouteNotFound
+		}
+		return nil, err
+	}
+
+	lineage := &RouteLineage{
+		Ancestors:             []LineageEntry{},
+		Forks:                 []LineageEntry{},
+		ForksCount:            shared.ForksCount,
+		LikesCount:            shared.LikesCount,
+		DownloadsCount:        shared.DownloadsCount,
+		LineageLikesCount:     shared.LineageLikesCount,
+		LineageDownloadsCount: shared.LineageDownloadsCount,
+	}
+
+	ancestors, err := s.ancestors(&shared)
+	if err != nil {
+		return nil, err
+	}
+	for _, ancestor := range ancestors {
+		lineage.Ancestors = append(lineage.Ancestors, lineageEntry(&ancestor))
+	}
+
+	var forks []models.SharedRoute
+	if err := s.db.Select("id", "title", "creator_id", "creator_name").
+		Where("forked_from_id = ?", shared.ID).Order("created_at ASC").Find(&forks).Error; err != nil {
+		return nil, err
+	}
+	for i := range forks {
+		lineage.Forks = append(lineage.Forks, lineageEntry(&forks[i]))
+	}
+
+	return lineage, nil
+}
+
+func lineageEntry(route *models.SharedRoute) LineageEntry {
+	return LineageEntry{ID: route.ID, Title: route.Title, CreatorID: route.CreatorID, CreatorName: route.CreatorName}
+}
+
+// ancestors walks up the fork chain of a shared route, nearest first
+func (s *RouteSharingService) ancestors(shared *models.SharedRoute) ([]models.SharedRoute, error) {
+	var chain []models.SharedRoute
+	seen := map[string]bool{shared.ID: true}
+	next := shared.ForkedFromID
+	for next != nil && !seen[*next] && len(chain) < maxLineageDepth {
+		var parent models.SharedRoute
+		err := s.db.Select("id", "title", "creator_id", "creator_name", "forked_from_id").First(&parent, "id = ?", *next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		seen[parent.ID] = true
+		chain = append(chain, parent)
+		next = parent.ForkedFromID
+	}
+	return chain, nil
+}
+
+func (s *RouteSharingService) ancestorIDs(shared *models.SharedRoute) ([]string, error) {
+	ancestors, err := s.ancestors(shared)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(ancestors))
+	for i, ancestor := range ancestors {
+		ids[i] = ancestor.ID
+	}
+	return ids, nil
+}
+
+// RecountLineage rebuilds the fork and lineage counts of all shared routes
+// from the likes and downloads of each route
+func (s *RouteSharingService) RecountLineage() (int, error) {
+	var routes []models.SharedRoute
+	if err := s.db.Select("id", "forked_from_id", "likes_count", "downloads_count").Find(&routes).Error; err != nil {
+		return 0, err
+	}
+
+	likes := make(map[string]int, len(routes))
+	downloads := make(map[string]int, len(routes))
+	for i := range routes {
+		ancestors, err := s.ancestorIDs(&routes[i])
+		if err != nil {
+			return 0, err
+		}
+		for _, id := range append([]string{routes[i].ID}, ancestors...) {
+			likes[id] += routes[i].LikesCount
+			downloads[id] += routes[i].DownloadsCount
+		}
+	}
+
+	for _, route := range routes {
+		var forks int64
+		if err := s.db.Model(&models.Route{}).Where("forked_from_id = ?", route.ID).Count(&forks).Error; err != nil {
+			return 0, err
+		}
+		if err := s.db.Model(&route).UpdateColumns(map[string]interface{}{
+			"forks_count":             forks,
+			"lineage_likes_count":     likes[route.ID],
+			"lineage_downloads_count": downloads[route.ID],
+		}).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(routes), nil
+}
+
+func (s *RouteSharingService) loadRoute(routeID string) (*models.Route, error) {
+	var route models.Route
+	if err := s.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).First(&route, "id = ?", routeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRouteNotFound
+		}
+		return nil, err
+	}
+	return &route, nil
+}
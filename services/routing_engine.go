@@ -0,0 +1,205 @@
+// File: /services/routing_engine.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+
+	"motocosmos-api/config"
+	"motocosmos-api/models"
+)
+
+var (
+	ErrTooFewWaypoints      = errors.New("at least two waypoints are required")
+	ErrUnsupportedProfile   = errors.New("unsupported routing profile")
+	ErrNoRoadNearby         = errors.New("waypoint is too far from the road network")
+	ErrNoRouteFound         = errors.New("no route found between the waypoints")
+	ErrRoutingUnavailable   = errors.New("routing data is not available")
+	ErrUnknownRoutingEngine = errors.New("unknown routing engine")
+)
+
+// Routing engine names, selected with ROUTING_ENGINE
+const (
+	RoutingEngineOSM      = "osm"
+	RoutingEngineMapbox   = "mapbox"
+	RoutingEngineStraight = "straight"
+)
+
+// RoutingEngine plans a route through the waypoints of a request
+type RoutingEngine interface {
+	Name() string
+	Route(ctx context.Context, req models.RoutePlanRequest) (*models.RoutePlanResponse, error)
+}
+
+// DistanceMatrixEngine is implemented by engines that can measure the road
+// distances between many points at once. Distances are in meters, row i
+// holding the distances from point i; unreachable pairs are +Inf.
+type DistanceMatrixEngine interface {
+	DistanceMatrix(ctx context.Context, req models.RoutePlanRequest) ([][]float64, error)
+}
+
+// pairwiseMatrixConcurrency bounds the routes planned at once for a matrix
+const pairwiseMatrixConcurrency = 4
+
+// RoadDistanceMatrix measures the road distances between the waypoints of a
+// request, routing every pair when the engine has no matrix of its own
+func RoadDistanceMatrix(ctx context.Context, engine RoutingEngine, req models.RoutePlanRequest) ([][]float64, error) {
+	if err := validateRoutePlanRequest(req); err != nil {
+		return nil, err
+	}
+	if matrixEngine, ok := engine.(DistanceMatrixEngine); ok {
+		return matrixEngine.DistanceMatrix(ctx, req)
+	}
+	return pairwiseDistanceMatrix(ctx, engine, req)
+}
+
+func pairwiseDistanceMatrix(ctx context.Context, engine RoutingEngine, req models.RoutePlanRequest) ([][]float64, error) {
+	n := len(req.Waypoints)
+	matrix := newDistanceMatrix(n)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	slots := make(chan struct{}, pairwiseMatrixConcurrency)
+
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i == j {
+				continue
+			}
+			wg.Add(1)
+			go func(i, j int) {
+				defer wg.Done()
+				slots <- struct{}{}
+				defer func() { <-slots }()
+
+				pair := req
+				pair.Waypoints = []models.LatLng{req.Waypoints[i], req.Waypoints[j]}
+				plan, err := engine.Route(ctx, pair)
+
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					matrix[i][j] = plan.Distance
+				case errors.Is(err, ErrNoRouteFound):
+					matrix[i][j] = math.Inf(1)
+				case firstErr == nil:
+					firstErr = err
+				}
+			}(i, j)
+		}
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return matrix, nil
+}
+
+func newDistanceMatrix(n int) [][]float64 {
+	matrix := make([][]float64, n)
+	for i := range matrix {
+		matrix[i] = make([]float64, n)
+	}
+	return matrix
+}
+
+// NewRoutingEngine creates the engine selected in the configuration. The
+// offline OSM router falls back to straight lines until a road graph has
+// been imported with the import-osm command.
+func NewRoutingEngine(cfg *config.Config) (RoutingEngine, error) {
+	switch strings.ToLower(cfg.RoutingEngine) {
+	case "", RoutingEngineOSM:
+		if _, err := os.Stat(cfg.RoutingGraphPath); err != nil {
+			fmt.Printf("Road graph %s not found, using straight-line routing\n", cfg.RoutingGraphPath)
+			return NewStraightLineEngine(), nil
+		}
+		return NewOSMRouter(cfg.RoutingGraphPath), nil
+	case RoutingEngineMapbox:
+		return NewMapboxDirections(cfg)
+	case RoutingEngineStraight:
+		return NewStraightLineEngine(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRoutingEngine, cfg.RoutingEngine)
+	}
+}
+
+// IsMotorcycleProfile reports whether a requested profile is served by the motorcycle profile
+func IsMotorcycleProfile(profile string) bool {
+	switch strings.ToLower(profile) {
+	case "", "motorcycle", "driving":
+		return true
+	}
+	return false
+}
+
+// validateRoutePlanRequest checks what every engine needs
+func validateRoutePlanRequest(req models.RoutePlanRequest) error {
+	if len(req.Waypoints) < 2 {
+		return ErrTooFewWaypoints
+	}
+	if !IsMotorcycleProfile(req.Profile) {
+		return fmt.Errorf("%w: %s", ErrUnsupportedProfile, req.Profile)
+	}
+	for _, wp := range req.Waypoints {
+		if !isValidLatitude(wp.Latitude) || !isValidLongitude(wp.Longitude) {
+			return fmt.Errorf("invalid waypoint coordinates %.6f,%.6f", wp.Latitude, wp.Longitude)
+		}
+	}
+	return nil
+}
+
+// straightLineSpeed is the average speed assumed without a road network, km/h
+const straightLineSpeed = 60.0
+
+// StraightLineEngine connects the waypoints directly. It needs no data and is
+// used in development and as a fallback.
+type StraightLineEngine struct{}
+
+func NewStraightLineEngine() *StraightLineEngine {
+	return &StraightLineEngine{}
+}
+
+func (e *StraightLineEngine) Name() string {
+	return RoutingEngineStraight
+}
+
+func (e *StraightLineEngine) Route(ctx context.Context, req models.RoutePlanRequest) (*models.RoutePlanResponse, error) {
+	if err := validateRoutePlanRequest(req); err != nil {
+		return nil, err
+	}
+
+	distance := PathLengthKm(req.Waypoints) * 1000
+	return &models.RoutePlanResponse{
+		Geometry: req.Waypoints,
+		Distance: distance,
+		Duration: distance / (straightLineSpeed / 3.6),
+		Summary:  "Planned route",
+		Steps:    LocalizeInstructions(straightLineInstructions(req.Waypoints, straightLineSpeed), DefaultInstructionLanguage),
+		Engine:   e.Name(),
+	}, nil
+}
+
+// DistanceMatrix returns the straight-line distances between the waypoints
+func (e *StraightLineEngine) DistanceMatrix(ctx context.Context, req models.RoutePlanRequest) ([][]float64, error) {
+	if err := validateRoutePlanRequest(req); err != nil {
+		return nil, err
+	}
+
+	matrix := newDistanceMatrix(len(req.Waypoints))
+	for i, from := range req.Waypoints {
+		for j, to := range req.Waypoints {
+			if i != j {
+				matrix[i][j] = HaversineKm(from.Latitude, from.Longitude, to.Latitude, to.Longitude) * 1000
+			}
+		}
+	}
+	return matrix, nil
+}
@@ -0,0 +1,149 @@
+// File: /services/routing_profile.go
+package services
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Road classes stored on road graph edges, derived from the OSM highway tag
+const (
+	RoadClassMotorway uint8 = iota
+	RoadClassMotorwayLink
+	RoadClassTrunk
+	RoadClassTrunkLink
+	RoadClassPrimary
+	RoadClassPrimaryLink
+	RoadClassSecondary
+	RoadClassSecondaryLink
+	RoadClassTertiary
+	RoadClassTertiaryLink
+	RoadClassUnclassified
+	RoadClassResidential
+	RoadClassLivingStreet
+	RoadClassService
+	RoadClassRoad
+)
+
+// motorcycleRoadClasses maps the highway values a motorcycle may use to a road
+// class and the assumed travel speed in km/h when no maxspeed is tagged
+var motorcycleRoadClasses = map[string]struct {
+	class uint8
+	speed float64
+}{
+	"motorway":       {RoadClassMotorway, 110},
+	"motorway_link":  {RoadClassMotorwayLink, 60},
+	"trunk":          {RoadClassTrunk, 90},
+	"trunk_link":     {RoadClassTrunkLink, 50},
+	"primary":        {RoadClassPrimary, 75},
+	"primary_link":   {RoadClassPrimaryLink, 45},
+	"secondary":      {RoadClassSecondary, 65},
+	"secondary_link": {RoadClassSecondaryLink, 40},
+	"tertiary":       {RoadClassTertiary, 55},
+	"tertiary_link":  {RoadClassTertiaryLink, 35},
+	"unclassified":   {RoadClassUnclassified, 45},
+	"residential":    {RoadClassResidential, 30},
+	"living_street":  {RoadClassLivingStreet, 10},
+	"service":        {RoadClassService, 20},
+	"road":           {RoadClassRoad, 30},
+}
+
+// motorcycleMaxSpeed is the fastest speed the profile assigns, used by the A* heuristic
+const motorcycleMaxSpeed = 130.0
+
+// RoadProfile is how a motorcycle may travel along an OSM way
+type RoadProfile struct {
+	Class      uint8
+	Speed      float64 // km/h
+	Forward    bool
+	Backward   bool
+	Name       string
+	Roundabout bool // junction=roundabout or circular
+}
+
+// MotorcycleWayProfile evaluates the tags of an OSM way for motorcycles; ok is
+// false when the way is not a road or motorcycles may not use it
+func MotorcycleWayProfile(tags map[string]string) (profile RoadProfile, ok bool) {
+	road, known := motorcycleRoadClasses[tags["highway"]]
+	if !known || tags["area"] == "yes" {
+		return profile, false
+	}
+
+	// Private roads, parking aisles and roads closed to motor vehicles are not part of the planning network
+	if !motorcycleAccessAllowed(tags) {
+		return profile, false
+	}
+	if road.class == RoadClassService && (tags["service"] == "parking_aisle" || tags["service"] == "drive-through") {
+		return profile, false
+	}
+
+	profile = RoadProfile{
+		Class:    road.class,
+		Speed:    road.speed,
+		Forward:  true,
+		Backward: true,
+		Name:     tags["name"],
+	}
+	profile.Roundabout = tags["junction"] == "roundabout" || tags["junction"] == "circular"
+	if profile.Name == "" {
+		profile.Name = tags["ref"]
+	}
+
+	if maxSpeed, ok := parseMaxSpeed(tags["maxspeed"]); ok && maxSpeed < profile.Speed {
+		profile.Speed = maxSpeed
+	}
+	if surface := tags["surface"]; surface == "gravel" || surface == "unpaved" || surface == "dirt" || surface == "ground" {
+		profile.Speed *= 0.5
+	}
+
+	oneway := tags["oneway"]
+	switch {
+	case oneway == "-1" || oneway == "reverse":
+		profile.Forward = false
+	case oneway == "yes" || oneway == "1" || oneway == "true":
+		profile.Backward = false
+	case oneway == "no":
+	case profile.Roundabout, road.class == RoadClassMotorway, road.class == RoadClassMotorwayLink:
+		profile.Backward = false
+	}
+
+	return profile, true
+}
+
+// IsHighwayClass reports whether a road class is avoided by AvoidHighways
+func IsHighwayClass(class uint8) bool {
+	return class == RoadClassMotorway || class == RoadClassMotorwayLink
+}
+
+func motorcycleAccessAllowed(tags map[string]string) bool {
+	// The most specific tag wins
+	for _, key := range []string{"motorcycle", "motor_vehicle", "vehicle", "access"} {
+		switch tags[key] {
+		case "no", "private", "agricultural", "forestry", "delivery":
+			return false
+		case "yes", "designated", "permissive", "destination":
+			return true
+		}
+	}
+	return true
+}
+
+// parseMaxSpeed reads values like "90", "50 mph" and ignores symbolic ones like "none" or "HU:urban"
+func parseMaxSpeed(value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	factor := 1.0
+	if strings.HasSuffix(value, "mph") {
+		factor = 1.609
+		value = strings.TrimSpace(strings.TrimSuffix(value, "mph"))
+	}
+
+	speed, err := strconv.ParseFloat(value, 64)
+	if err != nil || speed <= 0 {
+		return 0, false
+	}
+	return speed * factor, true
+}
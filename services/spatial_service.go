@@ -0,0 +1,305 @@
+// File: /services/spatial_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+)
+
+const (
+	// routeCellPrecision indexes route paths in cells of about 1.2 x 0.6 km
+	routeCellPrecision = 6
+	// pointGeohashPrecision is stored for start points and event locations (~5 m)
+	pointGeohashPrecision = 9
+	// routeCellStepKm is the step used to walk a path so no cell is skipped
+	routeCellStepKm = 0.25
+	// maxQueryCells bounds the geohash prefixes matched by one query
+	maxQueryCells = 32
+
+	DefaultSearchRadiusKm = 50.0
+	maxSearchRadiusKm     = 1000.0
+)
+
+var ErrInvalidSpatialFilter = errors.New("invalid spatial filter")
+
+// NearFilter matches points within RadiusKm of a coordinate
+type NearFilter struct {
+	Latitude  float64
+	Longitude float64
+	RadiusKm  float64
+}
+
+// BoundsFilter matches points inside, or paths crossing, a bounding box
+type BoundsFilter struct {
+	MinLatitude  float64
+	MaxLatitude  float64
+	MinLongitude float64
+	MaxLongitude float64
+}
+
+// SpatialFilter holds the optional location filters of a listing
+type SpatialFilter struct {
+	Near   *NearFilter
+	Bounds *BoundsFilter
+}
+
+// ParseSpatialFilter reads the lat, lng and radius (km) and the
+// bbox=west,south,east,north query parameters
+func ParseSpatialFilter(query func(string) string) (SpatialFilter, error) {
+	var filter SpatialFilter
+
+	latText, lngText := query("lat"), query("lng")
+	if latText != "" || lngText != "" {
+		lat, errLat := strconv.ParseFloat(latText, 64)
+		lng, errLng := strconv.ParseFloat(lngText, 64)
+		if errLat != nil || errLng != nil || !isValidLatitude(lat) || !isValidLongitude(lng) {
+			return filter, fmt.Errorf("%w: lat and lng must be valid coordinates", ErrInvalidSpatialFilter)
+		}
+
+		radius := DefaultSearchRadiusKm
+		if radiusText := query("radius"); radiusText != "" {
+			value, err := strconv.ParseFloat(radiusText, 64)
+			if err != nil || value <= 0 || value > maxSearchRadiusKm {
+				return filter, fmt.Errorf("%w: radius must be between 0 and %.0f km", ErrInvalidSpatialFilter, maxSearchRadiusKm)
+			}
+			radius = value
+		}
+		filter.Near = &NearFilter{Latitude: lat, Longitude: lng, RadiusKm: radius}
+	}
+
+	if bbox := query("bbox"); bbox != "" {
+		parts := strings.Split(bbox, ",")
+		if len(parts) != 4 {
+			return filter, fmt.Errorf("%w: bbox must be west,south,east,north", ErrInvalidSpatialFilter)
+		}
+		var values [4]float64
+		for i, part := range parts {
+			value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return filter, fmt.Errorf("%w: bbox must be west,south,east,north", ErrInvalidSpatialFilter)
+			}
+			values[i] = value
+		}
+
+		bounds := &BoundsFilter{MinLongitude: values[0], MinLatitude: values[1], MaxLongitude: values[2], MaxLatitude: values[3]}
+		if !isValidLatitude(bounds.MinLatitude) || !isValidLatitude(bounds.MaxLatitude) ||
+			!isValidLongitude(bounds.MinLongitude) || !isValidLongitude(bounds.MaxLongitude) ||
+			bounds.MinLatitude > bounds.MaxLatitude || bounds.MinLongitude > bounds.MaxLongitude {
+			return filter, fmt.Errorf("%w: bbox must be west,south,east,north", ErrInvalidSpatialFilter)
+		}
+		filter.Bounds = bounds
+	}
+
+	return filter, nil
+}
+
+// FilterPoints restricts a query to rows whose point, stored in the given
+// geohash, latitude and longitude columns, matches the filter. Geohash
+// prefixes narrow the rows through the index; the exact distance or box
+// check runs on what is left.
+func FilterPoints(query *gorm.DB, geohashColumn, latColumn, lngColumn string, filter SpatialFilter) *gorm.DB {
+	if near := filter.Near; near != nil {
+		minLat, maxLat, minLng, maxLng := BoundingBox(near.Latitude, near.Longitude, near.RadiusKm)
+		query = whereGeohashPrefix(query, geohashColumn, CoveringGeohashes(minLat, maxLat, minLng, maxLng, pointGeohashPrecision, maxQueryCells))
+		query = query.Where(fmt.Sprintf("ST_Distance_Sphere(POINT(%s, %s), POINT(?, ?)) <= ?", lngColumn, latColumn),
+			near.Longitude, near.Latitude, near.RadiusKm*1000)
+	}
+
+	if b := filter.Bounds; b != nil {
+		query = whereGeohashPrefix(query, geohashColumn, CoveringGeohashes(b.MinLatitude, b.MaxLatitude, b.MinLongitude, b.MaxLongitude, pointGeohashPrecision, maxQueryCells))
+		query = query.Where(fmt.Sprintf("%s BETWEEN ? AND ? AND %s BETWEEN ? AND ?", latColumn, lngColumn),
+			b.MinLatitude, b.MaxLatitude, b.MinLongitude, b.MaxLongitude)
+	}
+
+	return query
+}
+
+func whereGeohashPrefix(query *gorm.DB, column string, prefixes []string) *gorm.DB {
+	conditions := make([]string, len(prefixes))
+	args := make([]interface{}, len(prefixes))
+	for i, prefix := range prefixes {
+		conditions[i] = column + " LIKE ?"
+		args[i] = prefix + "%"
+	}
+	return query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+}
+
+type SpatialService struct {
+	db *gorm.DB
+}
+
+func NewSpatialService(db *gorm.DB) *SpatialService {
+	return &SpatialService{db: db}
+}
+
+// FilterRoutes restricts a route or shared route query: Near matches routes
+// starting within the radius, Bounds matches routes passing through the box
+func (s *SpatialService) FilterRoutes(query *gorm.DB, ownerType string, filter SpatialFilter) *gorm.DB {
+	if filter.Near != nil {
+		query = FilterPoints(query, "start_geohash", "start_latitude", "start_longitude", SpatialFilter{Near: filter.Near})
+	}
+
+	if b := filter.Bounds; b != nil {
+		cells := s.db.Model(&models.RouteGeoCell{}).Select("owner_id").Where("owner_type = ?", ownerType)
+		cells = whereGeohashPrefix(cells, "cell", CoveringGeohashes(b.MinLatitude, b.MaxLatitude, b.MinLongitude, b.MaxLongitude, routeCellPrecision, maxQueryCells))
+		cells = cells.Where("max_latitude >= ? AND min_latitude <= ? AND max_longitude >= ? AND min_longitude <= ?",
+			b.MinLatitude, b.MaxLatitude, b.MinLongitude, b.MaxLongitude)
+		query = query.Where("id IN (?)", cells)
+	}
+
+	return query
+}
+
+// IndexRoute stores the start point and the cells of a route's path
+func (s *SpatialService) IndexRoute(ownerType, ownerID string, points []models.LatLng) error {
+	table, err := routeOwnerTable(ownerType)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).Delete(&models.RouteGeoCell{}).Error; err != nil {
+			return err
+		}
+
+		start := map[string]interface{}{"start_latitude": 0, "start_longitude": 0, "start_geohash": ""}
+		if len(points) > 0 {
+			start["start_latitude"] = points[0].Latitude
+			start["start_longitude"] = points[0].Longitude
+			start["start_geohash"] = EncodeGeohash(points[0].Latitude, points[0].Longitude, pointGeohashPrecision)
+		}
+		if err := tx.Table(table).Where("id = ?", ownerID).UpdateColumns(start).Error; err != nil {
+			return err
+		}
+
+		hashes := routeCells(points)
+		if len(hashes) == 0 {
+			return nil
+		}
+		cells := make([]models.RouteGeoCell, len(hashes))
+		for i, hash := range hashes {
+			minLat, maxLat, minLng, maxLng := GeohashBounds(hash)
+			cells[i] = models.RouteGeoCell{
+				OwnerType:    ownerType,
+				OwnerID:      ownerID,
+				Cell:         hash,
+				MinLatitude:  minLat,
+				MaxLatitude:  maxLat,
+				MinLongitude: minLng,
+				MaxLongitude: maxLng,
+			}
+		}
+		return tx.CreateInBatches(cells, 500).Error
+	})
+}
+
+// RemoveRoute deletes the cells of a deleted route
+func (s *SpatialService) RemoveRoute(ownerType, ownerID string) error {
+	return s.db.Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).Delete(&models.RouteGeoCell{}).Error
+}
+
+func routeOwnerTable(ownerType string) (string, error) {
+	switch ownerType {
+	case models.RouteGeoCellOwnerRoute:
+		return "routes", nil
+	case models.RouteGeoCellOwnerSharedRoute:
+		return "shared_routes", nil
+	}
+	return "", fmt.Errorf("unknown route owner type %q", ownerType)
+}
+
+// routeCells lists the distinct cells a path passes through in path order
+func routeCells(points []models.LatLng) []string {
+	seen := make(map[string]bool)
+	var cells []string
+	add := func(lat, lng float64) {
+		hash := EncodeGeohash(lat, lng, routeCellPrecision)
+		if !seen[hash] {
+			seen[hash] = true
+			cells = append(cells, hash)
+		}
+	}
+
+	for i, p := range points {
+		if i > 0 {
+			prev := points[i-1]
+			steps := int(math.Ceil(HaversineKm(prev.Latitude, prev.Longitude, p.Latitude, p.Longitude) / routeCellStepKm))
+			for step := 1; step < steps; step++ {
+				t := float64(step) / float64(steps)
+				add(prev.Latitude+t*(p.Latitude-prev.Latitude), prev.Longitude+t*(p.Longitude-prev.Longitude))
+			}
+		}
+		add(p.Latitude, p.Longitude)
+	}
+	return cells
+}
+
+// SpatialIndexResult counts the rows indexed by Reindex
+type SpatialIndexResult struct {
+	Routes       int `json:"routes"`
+	SharedRoutes int `json:"shared_routes"`
+	Events       int `json:"events"`
+}
+
+// Reindex rebuilds the spatial index of all routes, shared routes and events
+func (s *SpatialService) Reindex() (*SpatialIndexResult, error) {
+	result := &SpatialIndexResult{}
+
+	var routes []models.Route
+	err := s.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).FindInBatches(&routes, 200, func(tx *gorm.DB, batch int) error {
+		for i := range routes {
+			if err := s.IndexRoute(models.RouteGeoCellOwnerRoute, routes[i].ID, RoutePath(&routes[i])); err != nil {
+				return err
+			}
+			result.Routes++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var sharedRoutes []models.SharedRoute
+	err = s.db.FindInBatches(&sharedRoutes, 200, func(tx *gorm.DB, batch int) error {
+		for i := range sharedRoutes {
+			if err := s.IndexRoute(models.RouteGeoCellOwnerSharedRoute, sharedRoutes[i].ID, sharedRoutes[i].GetRoutePointsAsLatLng()); err != nil {
+				return err
+			}
+			result.SharedRoutes++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var events []models.CommunityEvent
+	err = s.db.FindInBatches(&events, 500, func(tx *gorm.DB, batch int) error {
+		for _, event := range events {
+			hash := EncodeGeohash(event.LocationLatitude, event.LocationLongitude, pointGeohashPrecision)
+			if err := s.db.Model(&event).UpdateColumn("location_geohash", hash).Error; err != nil {
+				return err
+			}
+			result.Events++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// PointGeohash returns the geohash stored for start points and event locations
+func PointGeohash(lat, lng float64) string {
+	return EncodeGeohash(lat, lng, pointGeohashPrecision)
+}
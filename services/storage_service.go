@@ -0,0 +1,146 @@
+// File: /services/storage_service.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// StorageService wraps the MinIO bucket used for user uploaded media
+type StorageService struct {
+	client     *minio.Client
+	bucketName string
+}
+
+// NewStorageService creates the MinIO client from environment variables and
+// makes sure the configured bucket exists
+func NewStorageService() (*StorageService, error) {
+	endpoint := os.Getenv("MINIO_ENDPOINT")
+	accessKey := os.Getenv("MINIO_ACCESS_KEY")
+	secretKey := os.Getenv("MINIO_SECRET_KEY")
+	useSSL := os.Getenv("MINIO_USE_SSL") == "true"
+	bucketName := os.Getenv("MINIO_BUCKET_NAME")
+
+	if bucketName == "" {
+		bucketName = "motocosmos-posts" // default bucket name
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MinIO client: %w", err)
+	}
+
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, bucketName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bucket: %w", err)
+	}
+
+	if !exists {
+		if err := client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{}); err != nil {
+			return nil, fmt.Errorf("failed to create bucket: %w", err)
+		}
+		fmt.Printf("Bucket '%s' created successfully\n", bucketName)
+
+		// Public read access for uploaded images
+		policy := fmt.Sprintf(`{
+			"Version": "2012-10-17",
+			"Statement": [{
+				"Effect": "Allow",
+				"Principal": {"AWS": ["*"]},
+				"Action": ["s3:GetObject"],
+				"Resource": ["arn:aws:s3:::%s/*"]
+			}]
+		}`, bucketName)
+
+		if err := client.SetBucketPolicy(ctx, bucketName, policy); err != nil {
+			fmt.Printf("Warning: Failed to set bucket policy: %v\n", err)
+		}
+	}
+
+	return &StorageService{
+		client:     client,
+		bucketName: bucketName,
+	}, nil
+}
+
+// Client returns the underlying MinIO client
+func (s *StorageService) Client() *minio.Client {
+	return s.client
+}
+
+// BucketName returns the bucket all objects are stored in
+func (s *StorageService) BucketName() string {
+	return s.bucketName
+}
+
+// UploadFile stores a multipart upload under the given object name
+func (s *StorageService) UploadFile(ctx context.Context, objectName string, file *multipart.FileHeader) (int64, error) {
+	src, err := file.Open()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer src.Close()
+
+	buffer := bytes.NewBuffer(nil)
+	if _, err := io.Copy(buffer, src); err != nil {
+		return 0, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	contentType := file.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	return s.PutBytes(ctx, objectName, contentType, buffer.Bytes())
+}
+
+// PutBytes stores raw bytes under the given object name
+func (s *StorageService) PutBytes(ctx context.Context, objectName, contentType string, data []byte) (int64, error) {
+	info, err := s.client.PutObject(
+		ctx,
+		s.bucketName,
+		objectName,
+		bytes.NewReader(data),
+		int64(len(data)),
+		minio.PutObjectOptions{
+			ContentType: contentType,
+		},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to upload to MinIO: %w", err)
+	}
+
+	return info.Size, nil
+}
+
+// GetObject opens an object for reading together with its metadata
+func (s *StorageService) GetObject(ctx context.Context, objectName string) (*minio.Object, minio.ObjectInfo, error) {
+	obj, err := s.client.GetObject(ctx, s.bucketName, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, minio.ObjectInfo{}, err
+	}
+
+	stat, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, minio.ObjectInfo{}, err
+	}
+
+	return obj, stat, nil
+}
+
+// RemoveObject deletes an object from the bucket
+func (s *StorageService) RemoveObject(ctx context.Context, objectName string) error {
+	return s.client.RemoveObject(ctx, s.bucketName, objectName, minio.RemoveObjectOptions{})
+}
@@ -0,0 +1,232 @@
+// File: /services/thumbnail_service.go
+package services
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"time"
+
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+)
+
+// Thumbnail kinds, used in object names and URLs
+const (
+	ThumbnailKindRoute       = "routes"
+	ThumbnailKindSharedRoute = "shared-routes"
+	ThumbnailKindRide        = "rides"
+)
+
+const (
+	ThumbnailWidth  = 640
+	ThumbnailHeight = 360
+
+	// thumbnailStyle changes the names of all thumbnails when the drawing changes
+	thumbnailStyle = "1"
+
+	// A row whose thumbnail fails is retried after thumbnailRetryDelay, twice
+	// as long after every further failure, but at least once a day
+	thumbnailRetryDelay    = 5 * time.Minute
+	thumbnailMaxRetryDelay = 24 * time.Hour
+)
+
+// ThumbnailFailure is a row whose thumbnail could not be rendered
+type ThumbnailFailure struct {
+	Kind  string
+	ID    string
+	Error error
+}
+
+// ThumbnailRunResult summarizes a RenderPending run
+type ThumbnailRunResult struct {
+	Rendered int
+	Failures []ThumbnailFailure
+}
+
+// ThumbnailService renders static maps of routes, shared routes and rides and
+// stores them in object storage. Rows are picked up when they changed after
+// their thumbnail was rendered, so rendering never holds up a request.
+type ThumbnailService struct {
+	db       *gorm.DB
+	storage  *StorageService
+	renderer *MapRenderer
+}
+
+func NewThumbnailService(db *gorm.DB, storage *StorageService, tilesPath string) *ThumbnailService {
+	return &ThumbnailService{
+		db:       db,
+		storage:  storage,
+		renderer: NewMapRenderer(tilesPath),
+	}
+}
+
+// IsThumbnailKind reports whether kind names a thumbnail kind
+func IsThumbnailKind(kind string) bool {
+	return kind == ThumbnailKindRoute || kind == ThumbnailKindSharedRoute || kind == ThumbnailKindRide
+}
+
+// ThumbnailObjectName returns the object a thumbnail is stored in
+func ThumbnailObjectName(kind, file string) string {
+	return fmt.Sprintf("thumbnails/%s/%s", kind, file)
+}
+
+// ThumbnailURL returns the URL a stored thumbnail is served at
+func ThumbnailURL(kind, file string) string {
+	return fmt.Sprintf("/api/v1/thumbnails/%s/%s", kind, file)
+}
+
+// RenderPending renders the outdated thumbnails of up to limit rows of each
+// kind, least recently changed first. Rows that fail are reported in the
+// result and skipped until their retry time; errors stop the run.
+func (s *ThumbnailService) RenderPending(ctx context.Context, limit int) (*ThumbnailRunResult, error) {
+	result := &ThumbnailRunResult{}
+	pending := func(db *gorm.DB) *gorm.DB {
+		return db.Where("thumbnail_at IS NULL OR thumbnail_at < updated_at").
+			Where("thumbnail_retry_at IS NULL OR thumbnail_retry_at <= ?", time.Now()).
+			Order("updated_at ASC").
+			Limit(limit)
+	}
+
+	var routes []models.Route
+	if err := s.db.Scopes(pending).Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).Find(&routes).Error; err != nil {
+		return result, err
+	}
+	for _, route := range routes {
+		url, err := s.store(ctx, ThumbnailKindRoute, route.ID, RoutePath(&route), route.ThumbnailURL)
+		if err != nil {
+			if err := s.markFailed(result, &models.Route{}, ThumbnailKindRoute, route.ID, route.ThumbnailFailures, err); err != nil {
+				return result, err
+			}
+			continue
+		}
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			if err := s.markRendered(tx, &models.Route{}, route.ID, url, route.UpdatedAt); err != nil {
+				return err
+			}
+			return tx.Model(&models.Post{}).Where("route_id = ?", route.ID).UpdateColumn("thumbnail_url", url).Error
+		})
+		if err != nil {
+			return result, err
+		}
+		result.Rendered++
+	}
+
+	var sharedRoutes []models.SharedRoute
+	if err := s.db.Scopes(pending).Find(&sharedRoutes).Error; err != nil {
+		return result, err
+	}
+	for _, shared := range sharedRoutes {
+		url, err := s.store(ctx, ThumbnailKindSharedRoute, shared.ID, shared.RoutePoints.LatLngs(), shared.ThumbnailURL)
+		if err != nil {
+			if err := s.markFailed(result, &models.SharedRoute{}, ThumbnailKindSharedRoute, shared.ID, shared.ThumbnailFailures, err); err != nil {
+				return result, err
+			}
+			continue
+		}
+		if err := s.markRendered(s.db, &models.SharedRoute{}, shared.ID, url, shared.UpdatedAt); err != nil {
+			return result, err
+		}
+		result.Rendered++
+	}
+
+	// Rides get their thumbnail once they are completed
+	var rides []models.RideRecord
+	if err := s.db.Scopes(pending).Where("is_completed = ?", true).Preload("RoutePoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("timestamp ASC")
+	}).Find(&rides).Error; err != nil {
+		return result, err
+	}
+	for _, ride := range rides {
+		points := make([]models.LatLng, 0, len(ride.RoutePoints))
+		for _, p := range ride.RoutePoints {
+			points = append(points, models.LatLng{Latitude: p.Latitude, Longitude: p.Longitude})
+		}
+		url, err := s.store(ctx, ThumbnailKindRide, ride.ID, points, ride.ThumbnailURL)
+		if err != nil {
+			if err := s.markFailed(result, &models.RideRecord{}, ThumbnailKindRide, ride.ID, ride.ThumbnailFailures, err); err != nil {
+				return result, err
+			}
+			continue
+		}
+		if err := s.markRendered(s.db, &models.RideRecord{}, ride.ID, url, ride.UpdatedAt); err != nil {
+			return result, err
+		}
+		result.Rendered++
+	}
+
+	return result, nil
+}
+
+// store renders and uploads the thumbnail of a track, replacing the previous
+// one, and returns its URL. Thumbnails are named after the track, so an
+// unchanged track keeps its thumbnail; a track too short to draw has none.
+func (s *ThumbnailService) store(ctx context.Context, kind, id string, points []models.LatLng, previous string) (string, error) {
+	url := ""
+	if len(points) >= 2 {
+		file := fmt.Sprintf("%s-%s.png", id, trackHash(points))
+		url = ThumbnailURL(kind, file)
+		if url == previous {
+			return url, nil
+		}
+
+		data, err := s.renderer.RenderTrack(points, ThumbnailWidth, ThumbnailHeight)
+		if err != nil {
+			return "", err
+		}
+		if _, err := s.storage.PutBytes(ctx, ThumbnailObjectName(kind, file), "image/png", data); err != nil {
+			return "", err
+		}
+	}
+
+	if previous != "" && previous != url {
+		if err := s.storage.RemoveObject(ctx, ThumbnailObjectName(kind, path.Base(previous))); err != nil {
+			fmt.Printf("Warning: Could not remove old thumbnail %s: %v\n", previous, err)
+		}
+	}
+	return url, nil
+}
+
+// markRendered records the thumbnail of a row. Rows changed while rendering
+// stay outdated and are rendered again.
+func (s *ThumbnailService) markRendered(db *gorm.DB, model interface{}, id, url string, updatedAt time.Time) error {
+	return db.Model(model).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"thumbnail_url":      url,
+		"thumbnail_at":       updatedAt,
+		"thumbnail_failures": 0,
+		"thumbnail_retry_at": nil,
+	}).Error
+}
+
+// markFailed reports a row whose thumbnail failed and postpones its next
+// attempt, so rows that keep failing don't hold up the others
+func (s *ThumbnailService) markFailed(result *ThumbnailRunResult, model interface{}, kind, id string, failures int, renderErr error) error {
+	result.Failures = append(result.Failures, ThumbnailFailure{Kind: kind, ID: id, Error: renderErr})
+	return s.db.Model(model).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"thumbnail_failures": failures + 1,
+		"thumbnail_retry_at": time.Now().Add(thumbnailBackoff(failures + 1)),
+	}).Error
+}
+
+// thumbnailBackoff returns how long to wait after a row failed failures times
+func thumbnailBackoff(failures int) time.Duration {
+	delay := thumbnailRetryDelay
+	for i := 1; i < failures && delay < thumbnailMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, thumbnailMaxRetryDelay)
+}
+
+// trackHash identifies a track and the drawing style in thumbnail names
+func trackHash(points []models.LatLng) string {
+	hash := sha1.New()
+	hash.Write([]byte(thumbnailStyle))
+	for _, p := range points {
+		fmt.Fprintf(hash, "%.5f,%.5f;", p.Latitude, p.Longitude)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
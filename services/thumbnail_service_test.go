@@ -0,0 +1,25 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThumbnailBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 5 * time.Minute},
+		{2, 10 * time.Minute},
+		{4, 40 * time.Minute},
+		{9, 21*time.Hour + 20*time.Minute},
+		{10, 24 * time.Hour},
+		{1000, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := thumbnailBackoff(tt.failures); got != tt.want {
+			t.Errorf("thumbnailBackoff(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,410 @@
+// File: /services/toll_service.go
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+)
+
+// defaultTollRadiusKm is used for section tolls stored without a radius
+const defaultTollRadiusKm = 1.0
+
+// TollRateInput is a single rate row coming from the API or an import file
+type TollRateInput struct {
+	CountryCode  string   `json:"country_code"`
+	VehicleClass string   `json:"vehicle_class"`
+	Kind         string   `json:"kind"`
+	Name         string   `json:"name"`
+	Price        float64  `json:"price"`
+	Currency     string   `json:"currency"`
+	ValidityDays int      `json:"validity_days"`
+	ValidFrom    string   `json:"valid_from"` // YYYY-MM-DD or RFC3339
+	ValidTo      string   `json:"valid_to"`
+	Latitude     *float64 `json:"latitude"`
+	Longitude    *float64 `json:"longitude"`
+	RadiusKm     float64  `json:"radius_km"`
+	Source       string   `json:"source"`
+}
+
+// TollRateImportResult summarizes a bulk import
+type TollRateImportResult struct {
+	Imported int      `json:"imported"`
+	Updated  int      `json:"updated"`
+	Skipped  int      `json:"skipped"`
+	Errors   []string `json:"errors"`
+}
+
+type TollService struct {
+	db              *gorm.DB
+	currencyService *CurrencyService
+}
+
+func NewTollService(db *gorm.DB) *TollService {
+	return &TollService{
+		db:              db,
+		currencyService: NewCurrencyService(db),
+	}
+}
+
+// RatesValidAt returns the rates of a vehicle class valid on a date, optionally limited to countries
+func (s *TollService) RatesValidAt(vehicleClass string, countryCodes []string, at time.Time) ([]models.TollRate, error) {
+	query := s.db.Where("vehicle_class = ? AND valid_from <= ? AND (valid_to IS NULL OR valid_to > ?)", vehicleClass, at, at)
+	if len(countryCodes) > 0 {
+		query = query.Where("country_code IN ?", countryCodes)
+	}
+
+	var rates []models.TollRate
+	err := query.Order("country_code ASC, kind DESC, price ASC").Find(&rates).Error
+	return rates, err
+}
+
+// TripCosts returns the vignettes and tolls a trip needs, priced in EUR.
+// Per country the cheapest combination of vignettes covering tripDays is
+// picked, mixing kinds like a 10-day and a 1-day vignette for 11 days;
+// section tolls apply when the path passes within their radius.
+func (s *TollService) TripCosts(countryCodes []string, points []models.LatLng, vehicleClass string, tripDays int, tripDate time.Time) (models.TollCostItems, float64, error) {
+	items := models.TollCostItems{}
+	if len(countryCodes) == 0 {
+		return items, 0, nil
+	}
+	if tripDays < 1 {
+		tripDays = 1
+	}
+
+	rates, err := s.RatesValidAt(vehicleClass, countryCodes, tripDate)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	vignettes := make(map[string][]vignetteOption)
+	var cumulative []float64
+	if len(points) > 1 {
+		cumulative = CumulativeDistancesKm(points)
+	}
+
+	for _, rate := range rates {
+		unitPrice, err := s.currencyService.Convert(rate.Price, rate.Currency, models.DefaultCurrency)
+		if err != nil {
+			continue
+		}
+
+		switch rate.Kind {
+		case models.TollKindVignette:
+			vignettes[rate.CountryCode] = append(vignettes[rate.CountryCode], vignetteOption{rate: rate, unitPrice: unitPrice})
+
+		case models.TollKindToll:
+			if rate.Latitude == nil || rate.Longitude == nil || cumulative == nil {
+				continue
+			}
+			radius := rate.RadiusKm
+			if radius <= 0 {
+				radius = defaultTollRadiusKm
+			}
+			if _, offset := ProjectOntoPath(points, cumulative, *rate.Latitude, *rate.Longitude); offset <= radius {
+				items = append(items, tollCostItem(rate, 1, unitPrice))
+			}
+		}
+	}
+
+	for _, options := range vignettes {
+		items = append(items, cheapestVignettes(options, tripDays)...)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].CountryCode != items[j].CountryCode {
+			return items[i].CountryCode < items[j].CountryCode
+		}
+		if items[i].Kind != items[j].Kind {
+			return items[i].Kind > items[j].Kind // vignettes first
+		}
+		return items[i].Name < items[j].Name
+	})
+
+	var total float64
+	for _, item := range items {
+		total += item.Amount
+	}
+
+	return items, total, nil
+}
+
+// vignetteOption is a vignette of a country with its price in EUR
+type vignetteOption struct {
+	rate      models.TollRate
+	unitPrice float64
+}
+
+// cheapestVignettes returns the cheapest set of vignettes of one country that
+// covers tripDays. A vignette without a validity covers any trip on its own.
+func cheapestVignettes(options []vignetteOption, tripDays int) models.TollCostItems {
+	if len(options) == 0 {
+		return nil
+	}
+	validity := func(option vignetteOption) int {
+		if option.rate.ValidityDays <= 0 {
+			return tripDays
+		}
+		return option.rate.ValidityDays
+	}
+
+	// cost[d] is the cheapest way to cover d days, last[d] the vignette it ends with
+	cost := make([]float64, tripDays+1)
+	last := make([]int, tripDays+1)
+	for d := 1; d <= tripDays; d++ {
+		cost[d] = math.Inf(1)
+		for i, option := range options {
+			if c := option.unitPrice + cost[max(d-validity(option), 0)]; c < cost[d] {
+				cost[d], last[d] = c, i
+			}
+		}
+	}
+
+	quantities := make([]int, len(options))
+	for d := tripDays; d > 0; d = max(d-validity(options[last[d]]), 0) {
+		quantities[last[d]]++
+	}
+
+	var items models.TollCostItems
+	for i, quantity := range quantities {
+		if quantity > 0 {
+			items = append(items, tollCostItem(options[i].rate, quantity, options[i].unitPrice))
+		}
+	}
+	return items
+}
+
+func tollCostItem(rate models.TollRate, quantity int, unitPrice float64) models.TollCostItem {
+	return models.TollCostItem{
+		TollRateID:   rate.ID,
+		CountryCode:  rate.CountryCode,
+		Kind:         rate.Kind,
+		Name:         rate.Name,
+		Quantity:     quantity,
+		ValidityDays: rate.ValidityDays,
+		UnitPrice:    unitPrice,
+		Amount:       unitPrice * float64(quantity),
+		Currency:     models.DefaultCurrency,
+	}
+}
+
+// Save validates an input row and stores it. A rate with the same country,
+// vehicle class, kind, name and start date is replaced; the returned bool
+// reports whether a new row was created.
+func (s *TollService) Save(input TollRateInput) (*models.TollRate, bool, error) {
+	rate, err := s.buildRate(input)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var existing models.TollRate
+	err = s.db.Where("country_code = ? AND vehicle_class = ? AND kind = ? AND name = ? AND valid_from = ?",
+		rate.CountryCode, rate.VehicleClass, rate.Kind, rate.Name, rate.ValidFrom).First(&existing).Error
+	if err == nil {
+		rate.ID = existing.ID
+		rate.CreatedAt = existing.CreatedAt
+		if err := s.db.Save(rate).Error; err != nil {
+			return nil, false, err
+		}
+		return rate, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	if err := s.db.Create(rate).Error; err != nil {
+		return nil, false, err
+	}
+	return rate, true, nil
+}
+
+// Import stores rates from a CSV or JSON document. The CSV needs a header row
+// with country_code, kind, name and price columns and optionally
+// vehicle_class, currency, validity_days, valid_from, valid_to, latitude,
+// longitude, radius_km and source.
+func (s *TollService) Import(r io.Reader, format string) (*TollRateImportResult, error) {
+	var rows []TollRateInput
+	var err error
+
+	switch strings.ToLower(format) {
+	case "csv":
+		rows, err = parseTollRateCSV(r)
+	case "json":
+		err = json.NewDecoder(r).Decode(&rows)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", format, err)
+	}
+
+	result := &TollRateImportResult{Errors: []string{}}
+	for i, row := range rows {
+		if row.Source == "" {
+			row.Source = "import"
+		}
+		_, created, err := s.Save(row)
+		switch {
+		case err != nil:
+			result.Skipped++
+			result.Errors = append(result.Errors, fmt.Sprintf("row %d: %v", i+1, err))
+		case created:
+			result.Imported++
+		default:
+			result.Updated++
+		}
+	}
+
+	return result, nil
+}
+
+// ImportFile imports a CSV or JSON file, detecting the format from its extension
+func (s *TollService) ImportFile(path string) (*TollRateImportResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	return s.Import(file, format)
+}
+
+func (s *TollService) buildRate(input TollRateInput) (*models.TollRate, error) {
+	country, ok := CountryByCode(strings.ToUpper(strings.TrimSpace(input.CountryCode)))
+	if !ok {
+		return nil, fmt.Errorf("unknown country %q", input.CountryCode)
+	}
+
+	vehicleClass := strings.ToLower(strings.TrimSpace(input.VehicleClass))
+	if vehicleClass == "" {
+		vehicleClass = models.VehicleClassMotorcycle
+	}
+	if vehicleClass != models.VehicleClassMotorcycle && vehicleClass != models.VehicleClassCar {
+		return nil, fmt.Errorf("invalid vehicle class %q", input.VehicleClass)
+	}
+
+	kind := strings.ToLower(strings.TrimSpace(input.Kind))
+	switch kind {
+	case models.TollKindVignette:
+		if input.ValidityDays <= 0 {
+			return nil, errors.New("vignettes need validity_days")
+		}
+	case models.TollKindToll:
+		if input.Latitude == nil || input.Longitude == nil {
+			return nil, errors.New("tolls need a latitude and longitude")
+		}
+	default:
+		return nil, fmt.Errorf("invalid kind %q, must be vignette or toll", input.Kind)
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	if input.Price < 0 {
+		return nil, errors.New("price cannot be negative")
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(input.Currency))
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	if !models.IsSupportedCurrency(currency) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+
+	validFrom := time.Now().Truncate(24 * time.Hour)
+	if input.ValidFrom != "" {
+		parsed, err := parseImportDate(input.ValidFrom)
+		if err != nil {
+			return nil, err
+		}
+		validFrom = parsed
+	}
+
+	var validTo *time.Time
+	if input.ValidTo != "" {
+		parsed, err := parseImportDate(input.ValidTo)
+		if err != nil {
+			return nil, err
+		}
+		if !parsed.After(validFrom) {
+			return nil, errors.New("valid_to must be after valid_from")
+		}
+		validTo = &parsed
+	}
+
+	source := input.Source
+	if source == "" {
+		source = "manual"
+	}
+
+	return &models.TollRate{
+		ID:           uuid.New().String(),
+		CountryCode:  country.Code,
+		VehicleClass: vehicleClass,
+		Kind:         kind,
+		Name:         name,
+		Price:        input.Price,
+		Currency:     currency,
+		ValidityDays: input.ValidityDays,
+		ValidFrom:    validFrom,
+		ValidTo:      validTo,
+		Latitude:     input.Latitude,
+		Longitude:    input.Longitude,
+		RadiusKm:     input.RadiusKm,
+		Source:       source,
+	}, nil
+}
+
+func parseTollRateCSV(r io.Reader) ([]TollRateInput, error) {
+	records, field, err := readImportCSV(r, "country_code", "kind", "name", "price")
+	if err != nil {
+		return nil, err
+	}
+
+	optionalFloat := func(value string) *float64 {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return &parsed
+		}
+		return nil
+	}
+
+	rows := make([]TollRateInput, 0, len(records))
+	for _, record := range records {
+		// Invalid numbers are rejected row by row during the import
+		price, _ := strconv.ParseFloat(strings.Replace(field(record, "price"), ",", ".", 1), 64)
+		validityDays, _ := strconv.Atoi(field(record, "validity_days"))
+		radius, _ := strconv.ParseFloat(field(record, "radius_km"), 64)
+
+		rows = append(rows, TollRateInput{
+			CountryCode:  field(record, "country_code"),
+			VehicleClass: field(record, "vehicle_class"),
+			Kind:         field(record, "kind"),
+			Name:         field(record, "name"),
+			Price:        price,
+			Currency:     field(record, "currency"),
+			ValidityDays: validityDays,
+			ValidFrom:    field(record, "valid_from"),
+			ValidTo:      field(record, "valid_to"),
+			Latitude:     optionalFloat(field(record, "latitude")),
+			Longitude:    optionalFloat(field(record, "longitude")),
+			RadiusKm:     radius,
+			Source:       field(record, "source"),
+		})
+	}
+
+	return rows, nil
+}
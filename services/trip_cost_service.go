@@ -0,0 +1,326 @@
+// File: /services/trip_cost_service.go
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+)
+
+var (
+	ErrRouteNotFound      = errors.New("route not found or not accessible")
+	ErrMotorcycleNotFound = errors.New("motorcycle not found or access denied")
+)
+
+// DefaultFuelPrices are fallback 95 octane prices in EUR/L keyed by country
+// code, used for countries without a price in the fuel price database
+var DefaultFuelPrices = map[string]float64{
+	"DE": 1.65,
+	"FR": 1.72,
+	"IT": 1.68,
+	"ES": 1.45,
+	"NL": 1.78,
+	"AT": 1.52,
+	"CH": 1.85,
+	"CZ": 1.38,
+	"HU": 1.45,
+	"PL": 1.35,
+	"SK": 1.42,
+	"SI": 1.48,
+}
+
+const (
+	// defaultFuelPrice is used when no country along the route has a known price
+	defaultFuelPrice = 1.55
+	// defaultMotorcycleConsumption is a mid-size motorcycle in L/100km
+	defaultMotorcycleConsumption = 5.0
+)
+
+// TripInput describes what the user provided for a trip cost calculation.
+// Zero values are derived from the route and motorcycle when possible.
+type TripInput struct {
+	RouteID                string
+	SharedRouteID          string
+	MotorcycleID           string
+	FuelGrade              string // 95, 98, diesel; defaults to 95
+	Currency               string // currency of the manual money values and the result; defaults to EUR
+	RoadLength             float64
+	AverageFuelPrice       float64
+	AverageFuelConsumption float64
+	OtherCosts             float64
+
+	// Tolls and vignettes of the crossed countries are added to OtherCosts
+	IncludeTolls bool
+	VehicleClass string    // defaults to motorcycle
+	TripDays     int       // how long vignettes must be valid, defaults to 1
+	TripDate     time.Time // defaults to now
+}
+
+// TripEstimate is the resolved trip cost calculation
+type TripEstimate struct {
+	RouteName              string               `json:"route_name,omitempty"`
+	RouteID                *string              `json:"route_id,omitempty"`
+	SharedRouteID          *string              `json:"shared_route_id,omitempty"`
+	MotorcycleID           *string              `json:"motorcycle_id,omitempty"`
+	RoadLength             float64              `json:"road_length"`
+	AverageFuelPrice       float64              `json:"average_fuel_price"`
+	AverageFuelConsumption float64              `json:"average_fuel_consumption"`
+	FuelGrade              string               `json:"fuel_grade"`
+	ConsumptionSource      string               `json:"consumption_source"` // manual, fuel_log, spec, default
+	FuelPriceSource        string               `json:"fuel_price_source"`  // manual, route_countries, default
+	Countries              []CountryShare       `json:"countries"`
+	FuelNeeded             float64              `json:"fuel_needed_liters"`
+	FuelCost               float64              `json:"fuel_cost"`
+	OtherCosts             float64              `json:"other_costs"` // manual costs plus tolls
+	ManualOtherCosts       float64              `json:"manual_other_costs"`
+	TollCosts              float64              `json:"toll_costs"`
+	TollItems              models.TollCostItems `json:"toll_items"`
+	TotalCost              float64              `json:"total_cost"`
+	CostPerKm              float64              `json:"cost_per_km"`
+	Currency               string               `json:"currency"`
+	ExchangeRate           float64              `json:"exchange_rate"` // units of Currency per 1 EUR
+}
+
+// CountryCodes returns the codes of the countries the trip passes through
+func (e *TripEstimate) CountryCodes() []string {
+	codes := make([]string, 0, len(e.Countries))
+	for _, country := range e.Countries {
+		codes = append(codes, country.Code)
+	}
+	return codes
+}
+
+type TripCostService struct {
+	db               *gorm.DB
+	fuelPriceService *FuelPriceService
+	currencyService  *CurrencyService
+	tollService      *TollService
+}
+
+func NewTripCostService(db *gorm.DB) *TripCostService {
+	return &TripCostService{
+		db:               db,
+		fuelPriceService: NewFuelPriceService(db),
+		currencyService:  NewCurrencyService(db),
+		tollService:      NewTollService(db),
+	}
+}
+
+// Estimate resolves distance, consumption and fuel price for a trip and calculates its cost.
+// Costs are calculated in EUR and converted to the requested currency at the end.
+func (s *TripCostService) Estimate(userID string, input TripInput) (*TripEstimate, error) {
+	currency := input.Currency
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	rate, err := s.currencyService.Rate(models.DefaultCurrency, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	estimate := &TripEstimate{
+		RoadLength:             input.RoadLength,
+		AverageFuelPrice:       input.AverageFuelPrice / rate,
+		AverageFuelConsumption: input.AverageFuelConsumption,
+		OtherCosts:             input.OtherCosts / rate,
+		ManualOtherCosts:       input.OtherCosts / rate,
+		TollItems:              models.TollCostItems{},
+		FuelGrade:              input.FuelGrade,
+		Countries:              []CountryShare{},
+		Currency:               currency,
+		ExchangeRate:           rate,
+	}
+	if estimate.FuelGrade == "" {
+		estimate.FuelGrade = models.FuelGrade95
+	}
+
+	var points []models.LatLng
+	var routeDistance float64
+
+	switch {
+	case input.RouteID != "":
+		var route models.Route
+		if err := s.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+			return db.Order("`order` ASC")
+		}).Preload("Collaborators").First(&route, "id = ?", input.RouteID).Error; err != nil || !route.IsAccessibleBy(userID) {
+			return nil, ErrRouteNotFound
+		}
+
+		estimate.RouteName = route.Name
+		estimate.RouteID = &route.ID
+		points = route.GetRouteGeometryAsLatLng()
+		if len(points) == 0 {
+			points = route.GetWaypointsAsLatLng()
+		}
+		routeDistance = route.TotalDistance
+		if routeDistance == 0 {
+			routeDistance = PathLengthKm(route.GetWaypointsAsLatLng())
+		}
+
+	case input.SharedRouteID != "":
+		var sharedRoute models.SharedRoute
+		if err := s.db.First(&sharedRoute, "id = ?", input.SharedRouteID).Error; err != nil {
+			return nil, ErrRouteNotFound
+		}
+
+		estimate.RouteName = sharedRoute.Title
+		estimate.SharedRouteID = &sharedRoute.ID
+		points = sharedRoute.GetRoutePointsAsLatLng()
+		routeDistance = sharedRoute.TotalDistance
+	}
+
+	if estimate.RoadLength == 0 {
+		estimate.RoadLength = routeDistance
+	}
+
+	// Fuel consumption: manual value, then the bike's fuel log, then its spec
+	estimate.ConsumptionSource = "manual"
+	if input.MotorcycleID != "" {
+		var motorcycle models.Motorcycle
+		if err := s.db.First(&motorcycle, "id = ? AND user_id = ?", input.MotorcycleID, userID).Error; err != nil {
+			return nil, ErrMotorcycleNotFound
+		}
+		estimate.MotorcycleID = &motorcycle.ID
+
+		if estimate.AverageFuelConsumption == 0 {
+			estimate.AverageFuelConsumption, estimate.ConsumptionSource = s.MotorcycleConsumption(&motorcycle)
+		}
+	}
+	if estimate.AverageFuelConsumption == 0 {
+		estimate.AverageFuelConsumption = defaultMotorcycleConsumption
+		estimate.ConsumptionSource = "default"
+	}
+
+	// Fuel price: manual value, then the latest prices of the countries the route passes through
+	if len(points) > 0 {
+		estimate.Countries = CountrySharesForPoints(points, estimate.RoadLength)
+	}
+	estimate.FuelPriceSource = "manual"
+	if estimate.AverageFuelPrice == 0 {
+		if price, ok := s.FuelPriceForCountries(estimate.Countries, estimate.FuelGrade); ok {
+			estimate.AverageFuelPrice = price
+			estimate.FuelPriceSource = "route_countries"
+		} else {
+			estimate.AverageFuelPrice = defaultFuelPrice
+			estimate.FuelPriceSource = "default"
+		}
+	}
+
+	// Vignettes and tolls of the countries the route crosses
+	if input.IncludeTolls && len(estimate.Countries) > 0 {
+		vehicleClass := input.VehicleClass
+		if vehicleClass == "" {
+			vehicleClass = models.VehicleClassMotorcycle
+		}
+		tripDate := input.TripDate
+		if tripDate.IsZero() {
+			tripDate = time.Now()
+		}
+
+		items, tollCosts, err := s.tollService.TripCosts(estimate.CountryCodes(), points, vehicleClass, input.TripDays, tripDate)
+		if err != nil {
+			return nil, err
+		}
+		estimate.TollItems = items
+		estimate.TollCosts = tollCosts
+		estimate.OtherCosts += tollCosts
+	}
+
+	estimate.FuelNeeded = (estimate.RoadLength * estimate.AverageFuelConsumption) / 100
+	estimate.FuelCost = estimate.FuelNeeded * estimate.AverageFuelPrice
+	estimate.TotalCost = estimate.FuelCost + estimate.OtherCosts
+	if estimate.RoadLength > 0 {
+		estimate.CostPerKm = estimate.TotalCost / estimate.RoadLength
+	}
+
+	estimate.AverageFuelPrice *= rate
+	estimate.FuelCost *= rate
+	estimate.OtherCosts *= rate
+	estimate.ManualOtherCosts *= rate
+	estimate.TollCosts *= rate
+	for i := range estimate.TollItems {
+		estimate.TollItems[i].UnitPrice *= rate
+		estimate.TollItems[i].Amount *= rate
+		estimate.TollItems[i].Currency = currency
+	}
+	estimate.TotalCost *= rate
+	estimate.CostPerKm *= rate
+
+	return estimate, nil
+}
+
+// MotorcycleConsumption returns the best known consumption of a motorcycle and where it came from
+func (s *TripCostService) MotorcycleConsumption(motorcycle *models.Motorcycle) (float64, string) {
+	var logs []models.FuelLog
+	s.db.Where("motorcycle_id = ?", motorcycle.ID).Order("odometer ASC").Find(&logs)
+
+	if consumption, ok := FuelConsumptionFromLogs(logs); ok {
+		return consumption, "fuel_log"
+	}
+	if motorcycle.FuelConsumption > 0 {
+		return motorcycle.FuelConsumption, "spec"
+	}
+	return 0, ""
+}
+
+// FuelPriceForCountries returns the distance weighted fuel price of a grade along a route
+func (s *TripCostService) FuelPriceForCountries(shares []CountryShare, grade string) (float64, bool) {
+	prices := s.fuelPriceService.NationalPrices(grade)
+
+	var weightedPrice, knownShare float64
+	for _, share := range shares {
+		price, ok := prices[share.Code]
+		if !ok {
+			continue
+		}
+		weightedPrice += price * share.Share
+		knownShare += share.Share
+	}
+
+	if knownShare == 0 {
+		return 0, false
+	}
+	return weightedPrice / knownShare, true
+}
+
+// FuelConsumptionFromLogs calculates the average consumption (L/100km) between
+// the first and the last full-tank refuelling. Liters of every fill after the
+// first full tank are counted, so partial fills in between are included.
+func FuelConsumptionFromLogs(logs []models.FuelLog) (float64, bool) {
+	sorted := make([]models.FuelLog, 0, len(logs))
+	for _, log := range logs {
+		if log.Odometer > 0 {
+			sorted = append(sorted, log)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Odometer < sorted[j].Odometer })
+
+	first, last := -1, -1
+	for i, log := range sorted {
+		if !log.IsFullTank {
+			continue
+		}
+		if first == -1 {
+			first = i
+		}
+		last = i
+	}
+	if first == -1 || last <= first {
+		return 0, false
+	}
+
+	distance := sorted[last].Odometer - sorted[first].Odometer
+	if distance <= 0 {
+		return 0, false
+	}
+
+	var liters float64
+	for i := first + 1; i <= last; i++ {
+		liters += sorted[i].Liters
+	}
+
+	return liters / distance * 100, true
+}
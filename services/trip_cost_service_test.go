@@ -0,0 +1,104 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"motocosmos-api/models"
+)
+
+func TestTripEstimateCountryCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []models.LatLng
+		want   []string
+	}{
+		{
+			// Used to pick up an Austrian vignette in Munich
+			name: "Germany only via Munich",
+			points: []models.LatLng{
+				{Latitude: 48.775, Longitude: 9.182}, // Stuttgart
+				{Latitude: 48.366, Longitude: 10.898},
+				{Latitude: 48.137, Longitude: 11.575}, // Munich
+				{Latitude: 47.856, Longitude: 12.129}, // Rosenheim
+			},
+			want: []string{"DE"},
+		},
+		{
+			// Used to pick up a Hungarian e-matrica in Vienna
+			name: "Austria only via Vienna",
+			points: []models.LatLng{
+				{Latitude: 48.306, Longitude: 14.286}, // Linz
+				{Latitude: 48.204, Longitude: 15.626}, // St. Pölten
+				{Latitude: 48.208, Longitude: 16.373}, // Vienna
+				{Latitude: 47.846, Longitude: 16.520}, // Eisenstadt
+			},
+			want: []string{"AT"},
+		},
+		{
+			name: "Vienna to Budapest",
+			points: []models.LatLng{
+				{Latitude: 48.208, Longitude: 16.373},
+				{Latitude: 47.930, Longitude: 17.000},
+				{Latitude: 47.687, Longitude: 17.650}, // Győr
+				{Latitude: 47.498, Longitude: 19.040},
+			},
+			want: []string{"AT", "HU"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimate := TripEstimate{Countries: CountrySharesForPoints(tt.points, PathLengthKm(tt.points))}
+			codes := estimate.CountryCodes()
+			if len(codes) != len(tt.want) {
+				t.Fatalf("CountryCodes() = %v, want %v", codes, tt.want)
+			}
+			got := make(map[string]bool)
+			for _, code := range codes {
+				got[code] = true
+			}
+			for _, code := range tt.want {
+				if !got[code] {
+					t.Errorf("CountryCodes() = %v, want %v", codes, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheapestVignettes(t *testing.T) {
+	vignette := func(id string, days int, price float64) vignetteOption {
+		return vignetteOption{
+			rate:      models.TollRate{ID: id, CountryCode: "AT", Kind: models.TollKindVignette, Name: id, ValidityDays: days},
+			unitPrice: price,
+		}
+	}
+	options := []vignetteOption{
+		vignette("1-day", 1, 9.3),
+		vignette("10-day", 10, 12.4),
+		vignette("2-month", 60, 31.1),
+		vignette("annual", 0, 103.8),
+	}
+
+	tests := []struct {
+		days int
+		want map[string]int // vignette -> quantity
+	}{
+		{1, map[string]int{"1-day": 1}},
+		{2, map[string]int{"10-day": 1}},
+		{11, map[string]int{"10-day": 1, "1-day": 1}},
+		{25, map[string]int{"2-month": 1}},
+		{400, map[string]int{"annual": 1}},
+	}
+
+	for _, tt := range tests {
+		got := make(map[string]int)
+		for _, item := range cheapestVignettes(options, tt.days) {
+			got[item.Name] = item.Quantity
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("cheapestVignettes(%d days) = %v, want %v", tt.days, got, tt.want)
+		}
+	}
+}
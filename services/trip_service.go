@@ -0,0 +1,626 @@
+// File: /services/trip_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"motocosmos-api/models"
+)
+
+var (
+	ErrTripNotFound = errors.New("trip not found")
+	ErrInvalidTrip  = errors.New("invalid trip")
+)
+
+const (
+	maxTripDays = 60
+	// defaultTripSpeedKmh turns riding time limits into distances for routes
+	// without an estimated time, matching the 1 minute per km route estimate
+	defaultTripSpeedKmh = 60.0
+	// splitSnapShare is how much shorter than its even share a day may be
+	// so that it ends at one of the route's waypoints
+	splitSnapShare = 0.15
+	// splitWaypointOffsetKm is how far from the path a waypoint may be to serve as a day end
+	splitWaypointOffsetKm = 1.0
+)
+
+// TripDayInput is one day leg of a trip. Distance and riding time are taken
+// from the route when left zero; the date follows from the trip start date.
+type TripDayInput struct {
+	RouteID                string
+	Date                   *time.Time
+	Title                  string
+	Notes                  string
+	Distance               float64
+	RidingTime             int
+	AccommodationName      string
+	AccommodationAddress   string
+	AccommodationLatitude  *float64
+	AccommodationLongitude *float64
+	AccommodationCost      float64
+	AccommodationURL       string
+}
+
+// TripPlanInput creates or replaces a trip with its day legs in order
+type TripPlanInput struct {
+	Name        string
+	Description string
+	StartDate   *time.Time
+	Currency    string // defaults to the user's preferred currency
+	Days        []TripDayInput
+}
+
+// TripSplitInput splits one long route into day legs. At least one of the
+// daily limits must be set; when both are, the stricter one applies.
+type TripSplitInput struct {
+	RouteID          string
+	Name             string // defaults to the route name
+	StartDate        *time.Time
+	Currency         string
+	MaxDailyDistance float64 // km
+	MaxDailyTime     int     // riding seconds
+}
+
+// TripBudgetInput selects the motorcycle and prices for a trip budget
+type TripBudgetInput struct {
+	MotorcycleID string
+	FuelGrade    string
+	IncludeTolls bool
+	VehicleClass string
+}
+
+type TripService struct {
+	db                *gorm.DB
+	tripCostService   *TripCostService
+	currencyService   *CurrencyService
+	spatialService    *SpatialService
+	revisionService   *RouteRevisionService
+	difficultyService *DifficultyService
+}
+
+func NewTripService(db *gorm.DB, difficultyService *DifficultyService) *TripService {
+	return &TripService{
+		db:                db,
+		tripCostService:   NewTripCostService(db),
+		currencyService:   NewCurrencyService(db),
+		spatialService:    NewSpatialService(db),
+		revisionService:   NewRouteRevisionService(db),
+		difficultyService: difficultyService,
+	}
+}
+
+// List returns the user's trips, next departures first
+func (s *TripService) List(userID string, limit, offset int) ([]models.Trip, int64, error) {
+	var trips []models.Trip
+	var total int64
+
+	query := s.db.Model(&models.Trip{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Days", func(db *gorm.DB) *gorm.DB {
+		return db.Order("day_number ASC")
+	}).Order("start_date IS NULL, start_date ASC, created_at DESC").Limit(limit).Offset(offset).Find(&trips).Error
+	return trips, total, err
+}
+
+// Get returns a trip with its days and their routes, without the route geometry
+func (s *TripService) Get(userID, tripID string) (*models.Trip, error) {
+	var trip models.Trip
+	err := s.db.Preload("Days", func(db *gorm.DB) *gorm.DB {
+		return db.Order("day_number ASC")
+	}).Preload("Days.Route", func(db *gorm.DB) *gorm.DB {
+		return db.Omit("route_geometry")
+	}).Preload("Days.Route.Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).First(&trip, "id = ? AND user_id = ?", tripID, userID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTripNotFound
+		}
+		return nil, err
+	}
+	return &trip, nil
+}
+
+// Create saves a new trip
+func (s *TripService) Create(userID string, input TripPlanInput) (*models.Trip, error) {
+	trip := &models.Trip{ID: uuid.New().String(), UserID: userID}
+	days, err := s.resolveTrip(userID, trip, input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(trip).Error; err != nil {
+			return err
+		}
+		return createTripDays(tx, days)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(userID, trip.ID)
+}
+
+// Update replaces a trip's details and day legs
+func (s *TripService) Update(userID, tripID string, input TripPlanInput) (*models.Trip, error) {
+	var trip models.Trip
+	if err := s.db.First(&trip, "id = ? AND user_id = ?", tripID, userID).Error; err != nil {
+		return nil, ErrTripNotFound
+	}
+
+	days, err := s.resolveTrip(userID, &trip, input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&trip).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("trip_id = ?", tripID).Delete(&models.TripDay{}).Error; err != nil {
+			return err
+		}
+		return createTripDays(tx, days)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(userID, tripID)
+}
+
+// Delete removes a trip and its days; the routes of the days are kept
+func (s *TripService) Delete(userID, tripID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", tripID, userID).Delete(&models.Trip{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTripNotFound
+		}
+		return tx.Where("trip_id = ?", tripID).Delete(&models.TripDay{}).Error
+	})
+}
+
+func createTripDays(tx *gorm.DB, days []models.TripDay) error {
+	for i := range days {
+		if err := tx.Create(&days[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveTrip validates the input, fills the trip and builds its days with
+// distances, riding times and dates
+func (s *TripService) resolveTrip(userID string, trip *models.Trip, input TripPlanInput) ([]models.TripDay, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidTrip)
+	}
+	if len(input.Days) > maxTripDays {
+		return nil, fmt.Errorf("%w: a trip can have at most %d days", ErrInvalidTrip, maxTripDays)
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(input.Currency))
+	if currency == "" {
+		currency = trip.Currency
+	}
+	if currency == "" {
+		currency = s.currencyService.PreferredCurrency(userID)
+	}
+	if !models.IsSupportedCurrency(currency) {
+		return nil, ErrUnsupportedCurrency
+	}
+
+	trip.Name = name
+	trip.Description = input.Description
+	trip.StartDate = input.StartDate
+	trip.EndDate = nil
+	trip.Currency = currency
+	trip.TotalDistance = 0
+	trip.TotalTime = 0
+
+	days := make([]models.TripDay, len(input.Days))
+	for i, in := range input.Days {
+		day := models.TripDay{
+			TripID:                 trip.ID,
+			DayNumber:              i + 1,
+			Date:                   in.Date,
+			Title:                  in.Title,
+			Notes:                  in.Notes,
+			Distance:               in.Distance,
+			RidingTime:             in.RidingTime,
+			AccommodationName:      in.AccommodationName,
+			AccommodationAddress:   in.AccommodationAddress,
+			AccommodationLatitude:  in.AccommodationLatitude,
+			AccommodationLongitude: in.AccommodationLongitude,
+			AccommodationCost:      in.AccommodationCost,
+			AccommodationURL:       in.AccommodationURL,
+		}
+		if in.Distance < 0 || in.RidingTime < 0 || in.AccommodationCost < 0 {
+			return nil, fmt.Errorf("%w: day %d has a negative distance, riding time or cost", ErrInvalidTrip, i+1)
+		}
+
+		if in.RouteID != "" {
+			var route models.Route
+			if err := s.db.Omit("route_geometry").Preload("Collaborators").First(&route, "id = ?", in.RouteID).Error; err != nil || !route.IsAccessibleBy(userID) {
+				return nil, fmt.Errorf("%w: day %d", ErrRouteNotFound, i+1)
+			}
+			routeID := route.ID
+			day.RouteID = &routeID
+			if day.Title == "" {
+				day.Title = route.Name
+			}
+			if day.Distance == 0 {
+				day.Distance = route.TotalDistance
+			}
+			if day.RidingTime == 0 {
+				day.RidingTime = route.EstimatedTime
+			}
+		}
+
+		if day.Date == nil && trip.StartDate != nil {
+			date := trip.StartDate.AddDate(0, 0, i)
+			day.Date = &date
+		}
+		if day.Date != nil {
+			if i > 0 && days[i-1].Date != nil && day.Date.Before(*days[i-1].Date) {
+				return nil, fmt.Errorf("%w: day %d is dated before the day before it", ErrInvalidTrip, i+1)
+			}
+			if trip.EndDate == nil || day.Date.After(*trip.EndDate) {
+				trip.EndDate = day.Date
+			}
+		}
+
+		trip.TotalDistance += day.Distance
+		trip.TotalTime += day.RidingTime
+		days[i] = day
+	}
+	if trip.StartDate == nil && len(days) > 0 {
+		trip.StartDate = days[0].Date
+	}
+	trip.TotalDistance = roundToDecimal(trip.TotalDistance, 2)
+
+	return days, nil
+}
+
+// SplitRoute creates a trip from one long route. The route is cut into
+// legs of about equal length within the daily limit, ending at one of the
+// route's waypoints when one is close before the cut. Every leg is saved as
+// a new Route of its own.
+func (s *TripService) SplitRoute(userID string, input TripSplitInput) (*models.Trip, error) {
+	if input.MaxDailyDistance <= 0 && input.MaxDailyTime <= 0 {
+		return nil, fmt.Errorf("%w: max_daily_distance or max_daily_time is required", ErrInvalidTrip)
+	}
+
+	var route models.Route
+	if err := s.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("`order` ASC")
+	}).Preload("Collaborators").First(&route, "id = ?", input.RouteID).Error; err != nil || !route.IsAccessibleBy(userID) {
+		return nil, ErrRouteNotFound
+	}
+
+	geometry := route.RouteGeometry
+	if len(geometry) < 2 {
+		geometry = models.GeometryFromLatLngs(route.GetWaypointsAsLatLng())
+	}
+	points := geometry.LatLngs()
+	if len(points) < 2 {
+		return nil, ErrRouteGeometryMissing
+	}
+	cumulative := CumulativeDistancesKm(points)
+	pathKm := cumulative[len(cumulative)-1]
+	if pathKm == 0 {
+		return nil, ErrRouteGeometryMissing
+	}
+
+	// Cuts are placed on the drawn path; the route's own distance and time
+	// scale them to road kilometres and riding time
+	roadKm := route.TotalDistance
+	if roadKm <= 0 {
+		roadKm = pathKm
+	}
+	scale := roadKm / pathKm
+	speedKmh := defaultTripSpeedKmh
+	if route.EstimatedTime > 0 {
+		speedKmh = roadKm / (float64(route.EstimatedTime) / 3600)
+	}
+
+	dailyKm := math.Inf(1)
+	if input.MaxDailyDistance > 0 {
+		dailyKm = input.MaxDailyDistance
+	}
+	if input.MaxDailyTime > 0 {
+		dailyKm = math.Min(dailyKm, float64(input.MaxDailyTime)/3600*speedKmh)
+	}
+	if math.Ceil(roadKm/dailyKm) > maxTripDays {
+		return nil, fmt.Errorf("%w: the daily limit would need more than %d days", ErrInvalidTrip, maxTripDays)
+	}
+
+	stops := make(map[float64]string) // waypoints near the path by their distance along it
+	var along []float64
+	for _, wp := range route.Waypoints {
+		at, offset := ProjectOntoPath(points, cumulative, wp.Latitude, wp.Longitude)
+		if offset <= splitWaypointOffsetKm && at > 0 && at < pathKm {
+			stops[at] = wp.Name
+			along = append(along, at)
+		}
+	}
+	sort.Float64s(along)
+
+	cuts := SplitPathDistances(pathKm, dailyKm/scale, along)
+	bounds := append(append([]float64{0}, cuts...), pathKm)
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		name = route.Name
+	}
+
+	legs := make([]models.Route, len(bounds)-1)
+	dayInputs := make([]TripDayInput, len(legs))
+	for i := range legs {
+		from, to := bounds[i], bounds[i+1]
+		leg := splitGeometry(geometry, cumulative, from, to)
+		legKm := (to - from) * scale
+
+		startName, endName := fmt.Sprintf("Overnight stop %d", i), fmt.Sprintf("Overnight stop %d", i+1)
+		if stopName, ok := stops[from]; ok && stopName != "" {
+			startName = stopName
+		}
+		if stopName, ok := stops[to]; ok && stopName != "" {
+			endName = stopName
+		}
+		if i == 0 {
+			startName = "Start"
+			if len(route.Waypoints) > 0 && route.Waypoints[0].Name != "" {
+				startName = route.Waypoints[0].Name
+			}
+		}
+		if i == len(legs)-1 {
+			endName = "Finish"
+			if n := len(route.Waypoints); n > 0 && route.Waypoints[n-1].Name != "" {
+				endName = route.Waypoints[n-1].Name
+			}
+		}
+
+		waypoints := []models.RouteWaypoint{{Name: startName, Latitude: leg[0].Latitude, Longitude: leg[0].Longitude}}
+		for _, at := range along {
+			if at > from && at < to {
+				p := PointAlongPath(points, cumulative, at)
+				waypoints = append(waypoints, models.RouteWaypoint{Name: stops[at], Latitude: p.Latitude, Longitude: p.Longitude})
+			}
+		}
+		last := leg[len(leg)-1]
+		waypoints = append(waypoints, models.RouteWaypoint{Name: endName, Latitude: last.Latitude, Longitude: last.Longitude})
+		for j := range waypoints {
+			waypoints[j].Order = j + 1
+		}
+
+		ascent := GeometryAscent(leg)
+		if ascent == 0 && roadKm > 0 {
+			ascent = route.TotalElevation * legKm / roadKm
+		}
+		ridingTime := int(legKm / speedKmh * 3600)
+		curvature := AnalyzeCurvature(leg.LatLngs())
+
+		settings := models.JSONData{}
+		for k, v := range route.RouteSettings {
+			settings[k] = v
+		}
+		settings["split_from"] = route.ID
+		settings["day"] = i + 1
+
+		legs[i] = models.Route{
+			ID:              uuid.New().String(),
+			UserID:          userID,
+			Name:            fmt.Sprintf("%s - Day %d", name, i+1),
+			Description:     fmt.Sprintf("Day %d of %d: %s to %s", i+1, len(legs), startName, endName),
+			TotalDistance:   roundToDecimal(legKm, 2),
+			TotalElevation:  math.Round(ascent),
+			EstimatedTime:   ridingTime,
+			Difficulty:      route.Difficulty,
+			Tags:            route.Tags,
+			RouteGeometry:   leg,
+			RouteSettings:   settings,
+			Curvature:       &curvature,
+			TwistinessScore: curvature.Score,
+			Waypoints:       waypoints,
+		}
+
+		dayInputs[i] = TripDayInput{Title: fmt.Sprintf("%s to %s", startName, endName)}
+		if i < len(legs)-1 {
+			lat, lng := last.Latitude, last.Longitude
+			dayInputs[i].AccommodationLatitude = &lat
+			dayInputs[i].AccommodationLongitude = &lng
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for i := range legs {
+			// Waypoints are created with the route through the association
+			if err := tx.Create(&legs[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range legs {
+		if err := s.spatialService.IndexRoute(models.RouteGeoCellOwnerRoute, legs[i].ID, legs[i].RouteGeometry.LatLngs()); err != nil {
+			fmt.Printf("Warning: Could not index route location: %v\n", err)
+		}
+		if err := s.difficultyService.ClassifyRoute(legs[i].ID); err != nil {
+			fmt.Printf("Warning: Could not classify route difficulty: %v\n", err)
+		}
+		if _, err := s.revisionService.Record(legs[i].ID, userID, models.RouteRevisionCreated); err != nil {
+			fmt.Printf("Warning: Could not save route revision: %v\n", err)
+		}
+		dayInputs[i].RouteID = legs[i].ID
+	}
+
+	return s.Create(userID, TripPlanInput{
+		Name:        name,
+		Description: fmt.Sprintf("%s split into %d days", route.Name, len(legs)),
+		StartDate:   input.StartDate,
+		Currency:    input.Currency,
+		Days:        dayInputs,
+	})
+}
+
+// SplitPathDistances returns where to end each day on a path of pathKm so
+// that no day is longer than dailyKm. Days share what is left evenly; a day
+// ends at the latest stop (distances along the path, sorted) that is at most
+// splitSnapShare shorter than its share.
+func SplitPathDistances(pathKm, dailyKm float64, stops []float64) []float64 {
+	var cuts []float64
+	previous := 0.0
+	for pathKm-previous > dailyKm*(1+1e-9) {
+		remaining := pathKm - previous
+		days := math.Ceil(remaining / dailyKm)
+		upper := previous + remaining/days
+		lower := upper - splitSnapShare*(upper-previous)
+
+		cut := upper
+		for _, stop := range stops {
+			if stop > lower && stop <= upper {
+				cut = stop
+			}
+		}
+		cuts = append(cuts, cut)
+		previous = cut
+	}
+	return cuts
+}
+
+// splitGeometry returns the part of a geometry between two distances along it
+func splitGeometry(geometry models.Geometry, cumulative []float64, from, to float64) models.Geometry {
+	points := geometry.LatLngs()
+	start := PointAlongPath(points, cumulative, from)
+	end := PointAlongPath(points, cumulative, to)
+
+	leg := models.Geometry{{Latitude: start.Latitude, Longitude: start.Longitude}}
+	for i, p := range geometry {
+		if cumulative[i] > from && cumulative[i] < to {
+			leg = append(leg, p)
+		}
+	}
+	return append(leg, models.GeometryPoint{Latitude: end.Latitude, Longitude: end.Longitude})
+}
+
+// Budget estimates the cost of a trip with the trip cost calculator: fuel
+// for every day leg, tolls and vignettes once for the whole trip, and the
+// accommodation costs, all in the trip currency
+func (s *TripService) Budget(userID, tripID string, input TripBudgetInput) (*models.TripBudget, error) {
+	trip, err := s.Get(userID, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	budget := &models.TripBudget{
+		TripID:    trip.ID,
+		Currency:  trip.Currency,
+		TollItems: models.TollCostItems{},
+		Days:      make([]models.TripDayBudget, 0, len(trip.Days)),
+	}
+
+	var points []models.LatLng
+	for _, day := range trip.Days {
+		dayBudget := models.TripDayBudget{
+			DayNumber:         day.DayNumber,
+			Distance:          day.Distance,
+			AccommodationCost: day.AccommodationCost,
+		}
+
+		if day.Distance > 0 {
+			tripInput := TripInput{
+				MotorcycleID: input.MotorcycleID,
+				FuelGrade:    input.FuelGrade,
+				Currency:     trip.Currency,
+				RoadLength:   day.Distance,
+			}
+			if day.RouteID != nil {
+				tripInput.RouteID = *day.RouteID
+			}
+			estimate, err := s.tripCostService.Estimate(userID, tripInput)
+			if err != nil {
+				return nil, err
+			}
+			dayBudget.FuelNeeded = roundToDecimal(estimate.FuelNeeded, 2)
+			dayBudget.FuelCost = roundToDecimal(estimate.FuelCost, 2)
+		}
+		dayBudget.TotalCost = roundToDecimal(dayBudget.FuelCost+dayBudget.AccommodationCost, 2)
+
+		if day.RouteID != nil {
+			var route models.Route
+			if err := s.db.Preload("Waypoints", func(db *gorm.DB) *gorm.DB {
+				return db.Order("`order` ASC")
+			}).First(&route, "id = ?", *day.RouteID).Error; err == nil {
+				points = append(points, RoutePath(&route)...)
+			}
+		}
+
+		budget.TotalDistance += day.Distance
+		budget.FuelNeeded += dayBudget.FuelNeeded
+		budget.FuelCost += dayBudget.FuelCost
+		budget.AccommodationCost += day.AccommodationCost
+		budget.Days = append(budget.Days, dayBudget)
+	}
+
+	// Vignettes are bought once for the whole trip, so tolls are not summed per day
+	if input.IncludeTolls && len(points) > 0 {
+		vehicleClass := input.VehicleClass
+		if vehicleClass == "" {
+			vehicleClass = models.VehicleClassMotorcycle
+		}
+		tripDate := time.Now()
+		tripDays := len(trip.Days)
+		if trip.StartDate != nil {
+			tripDate = *trip.StartDate
+			if trip.EndDate != nil {
+				tripDays = int(trip.EndDate.Sub(*trip.StartDate).Hours()/24) + 1
+			}
+		}
+
+		var codes []string
+		for _, share := range CountrySharesForPoints(points, budget.TotalDistance) {
+			codes = append(codes, share.Code)
+		}
+		items, tollCosts, err := s.tripCostService.tollService.TripCosts(codes, points, vehicleClass, tripDays, tripDate)
+		if err != nil {
+			return nil, err
+		}
+
+		rate, err := s.currencyService.Rate(models.DefaultCurrency, trip.Currency)
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			items[i].UnitPrice *= rate
+			items[i].Amount *= rate
+			items[i].Currency = trip.Currency
+		}
+		budget.TollItems = items
+		budget.TollCosts = roundToDecimal(tollCosts*rate, 2)
+	}
+
+	budget.TotalDistance = roundToDecimal(budget.TotalDistance, 2)
+	budget.FuelNeeded = roundToDecimal(budget.FuelNeeded, 2)
+	budget.FuelCost = roundToDecimal(budget.FuelCost, 2)
+	budget.AccommodationCost = roundToDecimal(budget.AccommodationCost, 2)
+	budget.TotalCost = roundToDecimal(budget.FuelCost+budget.TollCosts+budget.AccommodationCost, 2)
+	return budget, nil
+}
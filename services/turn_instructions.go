@@ -0,0 +1,214 @@
+// File: /services/turn_instructions.go
+package services
+
+import (
+	"math"
+	"strings"
+
+	"motocosmos-api/models"
+)
+
+// Turn modifiers of a RouteInstruction, compatible with the Mapbox names
+const (
+	ModifierStraight    = "straight"
+	ModifierSlightLeft  = "slight left"
+	ModifierSlightRight = "slight right"
+	ModifierLeft        = "left"
+	ModifierRight       = "right"
+	ModifierSharpLeft   = "sharp left"
+	ModifierSharpRight  = "sharp right"
+	ModifierUTurn       = "uturn"
+)
+
+// junctionTurnAngle is the smallest change of heading announced at a junction
+// when the road keeps its name
+const junctionTurnAngle = 40.0
+
+// turnAngle returns the change of heading from one bearing to the next,
+// -180 to 180 degrees with right turns positive
+func turnAngle(from, to float64) float64 {
+	return math.Mod(to-from+540, 360) - 180
+}
+
+// turnModifier classifies a change of heading
+func turnModifier(angle float64) string {
+	left := angle < 0
+	pick := func(l, r string) string {
+		if left {
+			return l
+		}
+		return r
+	}
+
+	switch a := math.Abs(angle); {
+	case a < 25:
+		return ModifierStraight
+	case a < 60:
+		return pick(ModifierSlightLeft, ModifierSlightRight)
+	case a < 135:
+		return pick(ModifierLeft, ModifierRight)
+	case a < 170:
+		return pick(ModifierSharpLeft, ModifierSharpRight)
+	default:
+		return ModifierUTurn
+	}
+}
+
+// compassDirection names a bearing for depart instructions, like "northeast"
+func compassDirection(bearing float64) string {
+	return strings.ToLower(compassName(bearing))
+}
+
+// graphLegInstructions derives the maneuvers of one leg routed on the road
+// graph: turns where the road changes or a junction is left at an angle,
+// roundabouts with the number of the exit taken, and the arrival. waypoint is
+// the position of the waypoint the leg ends at, 0 for the destination.
+func graphLegInstructions(graph *RoadGraph, from int32, path []int32, waypoint int) []models.RouteInstruction {
+	point := func(node int32) models.LatLng {
+		return models.LatLng{Latitude: graph.Lat[node], Longitude: graph.Lng[node]}
+	}
+	location := func(node int32) *models.LatLng {
+		p := point(node)
+		return &p
+	}
+
+	var steps []models.RouteInstruction
+	prev, node := int32(-1), from
+	for i, edge := range path {
+		to := graph.EdgeTo[edge]
+		name := graph.RoadName(edge)
+		onRoundabout := graph.EdgeRound[edge]
+
+		switch {
+		case i == 0:
+			steps = append(steps, models.RouteInstruction{
+				Maneuver:  models.ManeuverDepart,
+				Direction: compassDirection(bearingDegrees(point(node), point(to))),
+				Road:      name,
+				Location:  location(node),
+			})
+			if onRoundabout {
+				// Starting on a roundabout: count the exits from here
+				steps = append(steps, models.RouteInstruction{Maneuver: models.ManeuverRoundabout, Location: location(node)})
+			}
+
+		case onRoundabout && !graph.EdgeRound[path[i-1]]:
+			steps = append(steps, models.RouteInstruction{Maneuver: models.ManeuverRoundabout, Location: location(node)})
+
+		case onRoundabout:
+			if graph.hasRoundaboutExit(node) {
+				steps[len(steps)-1].Exit++
+			}
+
+		case graph.EdgeRound[path[i-1]]:
+			// Leaving the roundabout through this exit
+			step := &steps[len(steps)-1]
+			step.Exit++
+			step.Road = name
+
+		default:
+			angle := turnAngle(bearingDegrees(point(prev), point(node)), bearingDegrees(point(node), point(to)))
+			renamed := name != graph.RoadName(path[i-1])
+			if !renamed && (math.Abs(angle) < junctionTurnAngle || !graph.isJunction(node, prev)) {
+				break // following the same road
+			}
+
+			modifier := turnModifier(angle)
+			maneuver := models.ManeuverTurn
+			if modifier == ModifierStraight {
+				maneuver = models.ManeuverContinue
+			}
+			steps = append(steps, models.RouteInstruction{
+				Maneuver: maneuver,
+				Modifier: modifier,
+				Road:     name,
+				Location: location(node),
+			})
+		}
+
+		distance := float64(graph.EdgeDist[edge])
+		steps[len(steps)-1].Distance += distance
+		steps[len(steps)-1].Duration += distance / (float64(graph.EdgeSpeed[edge]) / 3.6)
+		prev, node = node, to
+	}
+
+	return append(steps, models.RouteInstruction{
+		Maneuver: models.ManeuverArrive,
+		Waypoint: waypoint,
+		Location: location(node),
+	})
+}
+
+// hasRoundaboutExit reports whether a node on a roundabout has a road leaving it
+func (g *RoadGraph) hasRoundaboutExit(node int32) bool {
+	for e := g.EdgeStart[node]; e < g.EdgeStart[node+1]; e++ {
+		if !g.EdgeRound[e] {
+			return true
+		}
+	}
+	return false
+}
+
+// isJunction reports whether more than one road continues from a node when
+// arriving from prev
+func (g *RoadGraph) isJunction(node, prev int32) bool {
+	var next int32 = -1
+	for e := g.EdgeStart[node]; e < g.EdgeStart[node+1]; e++ {
+		to := g.EdgeTo[e]
+		if to == prev || to == next {
+			continue
+		}
+		if next >= 0 {
+			return true
+		}
+		next = to
+	}
+	return false
+}
+
+// straightLineInstructions describes a route that connects the waypoints
+// directly: a depart and an arrival for every leg
+func straightLineInstructions(waypoints []models.LatLng, speed float64) []models.RouteInstruction {
+	var steps []models.RouteInstruction
+	for i := 1; i < len(waypoints); i++ {
+		from, to := waypoints[i-1], waypoints[i]
+		distance := HaversineKm(from.Latitude, from.Longitude, to.Latitude, to.Longitude) * 1000
+
+		waypoint := i + 1
+		if i == len(waypoints)-1 {
+			waypoint = 0
+		}
+		steps = append(steps,
+			models.RouteInstruction{
+				Maneuver:  models.ManeuverDepart,
+				Direction: compassDirection(bearingDegrees(from, to)),
+				Distance:  distance,
+				Duration:  distance / (speed / 3.6),
+				Location:  &models.LatLng{Latitude: from.Latitude, Longitude: from.Longitude},
+			},
+			models.RouteInstruction{
+				Maneuver: models.ManeuverArrive,
+				Waypoint: waypoint,
+				Location: &models.LatLng{Latitude: to.Latitude, Longitude: to.Longitude},
+			},
+		)
+	}
+	return steps
+}
+
+// mapboxManeuver maps a Mapbox maneuver type onto the maneuvers produced for
+// the road graph, so every engine's steps can be localized the same way
+func mapboxManeuver(kind, modifier string) string {
+	switch kind {
+	case "depart":
+		return models.ManeuverDepart
+	case "arrive":
+		return models.ManeuverArrive
+	case "roundabout", "rotary":
+		return models.ManeuverRoundabout
+	}
+	if modifier == "" || modifier == ModifierStraight {
+		return models.ManeuverContinue
+	}
+	return models.ManeuverTurn
+}
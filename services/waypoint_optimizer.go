@@ -0,0 +1,268 @@
+// File: /services/waypoint_optimizer.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"motocosmos-api/models"
+)
+
+// Waypoint order optimization modes
+const (
+	// WaypointOrderFixedEnds keeps the first and last waypoint and reorders the stops between them
+	WaypointOrderFixedEnds = "fixed_ends"
+	// WaypointOrderRoundTrip keeps the first waypoint, visits all others and returns to it
+	WaypointOrderRoundTrip = "round_trip"
+)
+
+const (
+	maxOptimizedWaypoints = 25
+	// exactOrderLimit is the number of reorderable stops solved exactly; the
+	// exact search grows with 2^n, larger sets use a local search
+	exactOrderLimit = 12
+)
+
+var ErrInvalidOptimization = errors.New("invalid waypoint optimization")
+
+// IsValidWaypointOrderMode reports whether a mode is supported
+func IsValidWaypointOrderMode(mode string) bool {
+	return mode == WaypointOrderFixedEnds || mode == WaypointOrderRoundTrip
+}
+
+// WaypointOptimizer reorders the stops of a route over the road distances
+// measured by a routing engine
+type WaypointOptimizer struct {
+	engine RoutingEngine
+}
+
+func NewWaypointOptimizer(engine RoutingEngine) *WaypointOptimizer {
+	return &WaypointOptimizer{engine: engine}
+}
+
+// Optimize finds the shortest order of the request's waypoints. A round trip
+// may repeat the start as its last waypoint; either way the returned
+// waypoints end back at the start, ready to be planned.
+func (o *WaypointOptimizer) Optimize(ctx context.Context, req models.RoutePlanRequest, mode string) (*models.WaypointOptimization, []models.LatLng, error) {
+	if !IsValidWaypointOrderMode(mode) {
+		return nil, nil, fmt.Errorf("%w: mode must be %s or %s", ErrInvalidOptimization, WaypointOrderFixedEnds, WaypointOrderRoundTrip)
+	}
+	if n := len(req.Waypoints); mode == WaypointOrderRoundTrip && n > 2 && req.Waypoints[0] == req.Waypoints[n-1] {
+		req.Waypoints = req.Waypoints[:n-1]
+	}
+	if len(req.Waypoints) > maxOptimizedWaypoints {
+		return nil, nil, fmt.Errorf("%w: at most %d waypoints can be optimized", ErrInvalidOptimization, maxOptimizedWaypoints)
+	}
+
+	matrix, err := RoadDistanceMatrix(ctx, o.engine, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	order, exact := OptimalWaypointOrder(matrix, mode)
+	original := make([]int, len(req.Waypoints))
+	for i := range original {
+		original[i] = i
+	}
+
+	originalDistance := orderDistance(matrix, original, mode)
+	optimizedDistance := orderDistance(matrix, order, mode)
+	if math.IsInf(optimizedDistance, 1) {
+		return nil, nil, ErrNoRouteFound
+	}
+	// The local search can only match the submitted order, never lose to it
+	if optimizedDistance > originalDistance {
+		order, optimizedDistance = original, originalDistance
+	}
+
+	waypoints := make([]models.LatLng, 0, len(order)+1)
+	for _, index := range order {
+		waypoints = append(waypoints, req.Waypoints[index])
+	}
+	if mode == WaypointOrderRoundTrip {
+		waypoints = append(waypoints, req.Waypoints[order[0]])
+	}
+
+	saved := originalDistance - optimizedDistance
+	if math.IsInf(originalDistance, 1) {
+		saved = 0 // the submitted order could not be routed at all
+	}
+
+	return &models.WaypointOptimization{
+		Mode:              mode,
+		Order:             order,
+		OriginalDistance:  math.Round(originalDistance),
+		OptimizedDistance: math.Round(optimizedDistance),
+		DistanceSaved:     math.Round(saved),
+		Exact:             exact,
+	}, waypoints, nil
+}
+
+// OptimalWaypointOrder returns the visiting order of the points of a distance
+// matrix, starting with point 0; with fixed ends the last point stays last.
+// Up to exactOrderLimit reorderable stops the order is optimal (exact is
+// true), beyond that it is improved by 2-opt and relocation moves.
+func OptimalWaypointOrder(matrix [][]float64, mode string) (order []int, exact bool) {
+	n := len(matrix)
+	end := 0
+	var stops []int
+	for i := 1; i < n; i++ {
+		if mode == WaypointOrderFixedEnds && i == n-1 {
+			end = i
+			continue
+		}
+		stops = append(stops, i)
+	}
+
+	var sequence []int
+	if len(stops) <= exactOrderLimit {
+		sequence, exact = heldKarpOrder(matrix, 0, end, stops), true
+	} else {
+		sequence = localSearchOrder(matrix, 0, end, stops)
+	}
+
+	order = append([]int{0}, sequence...)
+	if mode == WaypointOrderFixedEnds && n > 1 {
+		order = append(order, end)
+	}
+	return order, exact
+}
+
+// orderDistance is the length of a visiting order, including the way back for a round trip
+func orderDistance(matrix [][]float64, order []int, mode string) float64 {
+	var total float64
+	for i := 1; i < len(order); i++ {
+		total += matrix[order[i-1]][order[i]]
+	}
+	if mode == WaypointOrderRoundTrip && len(order) > 1 {
+		total += matrix[order[len(order)-1]][order[0]]
+	}
+	return total
+}
+
+// sequenceDistance is the length of start -> stops... -> end
+func sequenceDistance(matrix [][]float64, start, end int, stops []int) float64 {
+	total := 0.0
+	previous := start
+	for _, stop := range stops {
+		total += matrix[previous][stop]
+		previous = stop
+	}
+	return total + matrix[previous][end]
+}
+
+// heldKarpOrder solves the stop order exactly with dynamic programming over
+// subsets: best[mask][j] is the shortest path from start through the stops
+// in mask ending at stop j
+func heldKarpOrder(matrix [][]float64, start, end int, stops []int) []int {
+	k := len(stops)
+	if k == 0 {
+		return nil
+	}
+
+	full := 1<<k - 1
+	best := make([][]float64, full+1)
+	parent := make([][]int8, full+1)
+	for mask := range best {
+		best[mask] = make([]float64, k)
+		parent[mask] = make([]int8, k)
+		for j := range best[mask] {
+			best[mask][j] = math.Inf(1)
+		}
+	}
+	for j, stop := range stops {
+		best[1<<j][j] = matrix[start][stop]
+		parent[1<<j][j] = -1
+	}
+
+	for mask := 1; mask <= full; mask++ {
+		for j := 0; j < k; j++ {
+			if mask&(1<<j) == 0 || math.IsInf(best[mask][j], 1) {
+				continue
+			}
+			for next := 0; next < k; next++ {
+				if mask&(1<<next) != 0 {
+					continue
+				}
+				nextMask := mask | 1<<next
+				if cost := best[mask][j] + matrix[stops[j]][stops[next]]; cost < best[nextMask][next] {
+					best[nextMask][next] = cost
+					parent[nextMask][next] = int8(j)
+				}
+			}
+		}
+	}
+
+	last, bestCost := 0, math.Inf(1)
+	for j, stop := range stops {
+		if cost := best[full][j] + matrix[stop][end]; cost < bestCost {
+			last, bestCost = j, cost
+		}
+	}
+
+	sequence := make([]int, k)
+	for mask, j, i := full, last, k-1; i >= 0; i-- {
+		sequence[i] = stops[j]
+		previous := int(parent[mask][j])
+		mask &^= 1 << j
+		j = previous
+	}
+	return sequence
+}
+
+// localSearchOrder builds a nearest neighbour order and improves it with
+// 2-opt reversals and single stop relocations until neither helps. Lengths
+// are recomputed in full, so one-way distances are handled correctly.
+func localSearchOrder(matrix [][]float64, start, end int, stops []int) []int {
+	remaining := append([]int(nil), stops...)
+	sequence := make([]int, 0, len(stops))
+	current := start
+	for len(remaining) > 0 {
+		nearest := 0
+		for i, stop := range remaining {
+			if matrix[current][stop] < matrix[current][remaining[nearest]] {
+				nearest = i
+			}
+		}
+		current = remaining[nearest]
+		sequence = append(sequence, current)
+		remaining = append(remaining[:nearest], remaining[nearest+1:]...)
+	}
+
+	bestCost := sequenceDistance(matrix, start, end, sequence)
+	candidate := make([]int, len(sequence))
+	for improved := true; improved; {
+		improved = false
+
+		for i := 0; i < len(sequence)-1; i++ {
+			for j := i + 1; j < len(sequence); j++ {
+				copy(candidate, sequence)
+				for a, b := i, j; a < b; a, b = a+1, b-1 {
+					candidate[a], candidate[b] = candidate[b], candidate[a]
+				}
+				if cost := sequenceDistance(matrix, start, end, candidate); cost < bestCost-1e-9 {
+					copy(sequence, candidate)
+					bestCost, improved = cost, true
+				}
+			}
+		}
+
+		for i := range sequence {
+			for j := range sequence {
+				if i == j {
+					continue
+				}
+				moved := append([]int(nil), sequence[:i]...)
+				moved = append(moved, sequence[i+1:]...)
+				moved = append(moved[:j], append([]int{sequence[i]}, moved[j:]...)...)
+				if cost := sequenceDistance(matrix, start, end, moved); cost < bestCost-1e-9 {
+					copy(sequence, moved)
+					bestCost, improved = cost, true
+				}
+			}
+		}
+	}
+	return sequence
+}